@@ -48,11 +48,7 @@ func main() {
 	s := server.DefaultAPIServer()
 
 	// Add some types to it and setup the store and supported methods
-	s.Schemas.MustImportAndCustomize(Foo{}, func(schema *types.APISchema) {
-		schema.Store = &FooStore{}
-		schema.CollectionMethods = []string{http.MethodGet}
-		schema.ResourceMethods = []string{http.MethodGet}
-	})
+	server.RegisterResource(s.Schemas, Foo{}, &FooStore{}, []string{http.MethodGet}, []string{http.MethodGet})
 
 	// Register root handler to list api versions
 	apiroot.Register(s.Schemas, []string{"v1", "v2"})
@@ -0,0 +1,181 @@
+package apierror
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+)
+
+// DefaultLanguage is the language messages fall back to when none of the languages accepted by the client has a
+// translation.
+const DefaultLanguage = "en"
+
+// Message keys of the errors returned by the server, for applications translating them.
+const (
+	CanNotCreateKey = "canNotCreate"
+	CanNotGetKey    = "canNotGet"
+	CanNotListKey   = "canNotList"
+	CanNotUpdateKey = "canNotUpdate"
+	CanNotDeleteKey = "canNotDelete"
+	NoSuchActionKey = "noSuchAction"
+)
+
+// DefaultCatalog is the catalog used to localize error messages. It holds English messages for the error codes,
+// keyed by code; applications add their own keys and languages with Add.
+var DefaultCatalog = NewCatalog()
+
+func init() {
+	DefaultCatalog.Add(DefaultLanguage, map[string]string{
+		validation.Unauthorized.Code:       "Unauthorized",
+		validation.PermissionDenied.Code:   "Permission denied",
+		validation.NotFound.Code:           "Not found",
+		validation.MethodNotAllowed.Code:   "Method not allowed",
+		validation.Conflict.Code:           "Conflict",
+		validation.InvalidDateFormat.Code:  "Invalid date format",
+		validation.InvalidFormat.Code:      "Invalid format",
+		validation.InvalidReference.Code:   "Invalid reference",
+		validation.NotNullable.Code:        "Value can not be null",
+		validation.NotUnique.Code:          "Value is not unique",
+		validation.MinLimitExceeded.Code:   "Value is below the minimum",
+		validation.MaxLimitExceeded.Code:   "Value is above the maximum",
+		validation.MinLengthExceeded.Code:  "Value is shorter than the minimum length",
+		validation.MaxLengthExceeded.Code:  "Value is longer than the maximum length",
+		validation.InvalidOption.Code:      "Value is not a valid option",
+		validation.InvalidCharacters.Code:  "Value contains invalid characters",
+		validation.MissingRequired.Code:    "Missing required field",
+		validation.InvalidCSRFToken.Code:   "Invalid CSRF token",
+		validation.InvalidAction.Code:      "Invalid action",
+		validation.InvalidBodyContent.Code: "Invalid body content",
+		validation.InvalidType.Code:        "Invalid type",
+		validation.ActionNotAvailable.Code: "Action not available",
+		validation.InvalidState.Code:       "Invalid state",
+		validation.ServerError.Code:        "Server error",
+		validation.ClusterUnavailable.Code: "Cluster unavailable",
+		BadRequest.Code:                    "Bad request",
+		RequestEntityTooLarge.Code:         "Request entity too large",
+		Timeout.Code:                       "Request timed out",
+		ServiceUnavailable.Code:            "Service unavailable",
+		Gone.Code:                          "Gone",
+		PreconditionFailed.Code:            "Precondition failed",
+		NotWatchable.Code:                  "Resource does not support watching",
+		CanNotCreateKey:                    "can not create %s",
+		CanNotGetKey:                       "can not get %s",
+		CanNotListKey:                      "can not list %s",
+		CanNotUpdateKey:                    "can not update %s",
+		CanNotDeleteKey:                    "can not delete %s",
+		NoSuchActionKey:                    "no such action %s",
+	})
+}
+
+// Catalog holds message formats by language and message key. Formats are expanded with fmt.Sprintf.
+type Catalog struct {
+	lock     sync.RWMutex
+	messages map[string]map[string]string
+}
+
+func NewCatalog() *Catalog {
+	return &Catalog{
+		messages: map[string]map[string]string{},
+	}
+}
+
+// Add adds messages, keyed by message key, for language, which is a tag such as "de" or "pt-BR". Existing
+// messages with the same key are replaced.
+func (c *Catalog) Add(language string, messages map[string]string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	language = strings.ToLower(language)
+	if c.messages[language] == nil {
+		c.messages[language] = map[string]string{}
+	}
+	for k, v := range messages {
+		c.messages[language][k] = v
+	}
+}
+
+// Localize returns the message of err in the first language of acceptLanguage, an Accept-Language header value,
+// that has a translation, falling back to DefaultLanguage, along with the language used. Errors without a message
+// key use their code as key if they have no message. The message of err is returned unchanged, with an empty
+// language, if no translation is found.
+func (c *Catalog) Localize(acceptLanguage string, err *APIError) (string, string) {
+	key := err.MessageKey
+	if key == "" {
+		if err.Message != "" {
+			return err.Message, ""
+		}
+		key = err.Code.Code
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	for _, language := range append(parseAcceptLanguage(acceptLanguage), DefaultLanguage) {
+		for _, candidate := range []string{language, baseLanguage(language)} {
+			if format, ok := c.messages[candidate][key]; ok {
+				return sprintf(format, err.Args), candidate
+			}
+		}
+	}
+	return err.Message, ""
+}
+
+func sprintf(format string, args []interface{}) string {
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+func baseLanguage(language string) string {
+	if i := strings.IndexByte(language, '-'); i > 0 {
+		return language[:i]
+	}
+	return language
+}
+
+// parseAcceptLanguage returns the lower-cased language tags of an Accept-Language header ordered by preference.
+// Wildcards and languages with a quality of zero are dropped.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.TrimSpace(name) != "q" {
+				continue
+			}
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag: tag, q: q})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		result = append(result, tag.tag)
+	}
+	return result
+}
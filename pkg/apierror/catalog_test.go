@@ -0,0 +1,119 @@
+package apierror
+
+import (
+	"testing"
+
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCatalog() *Catalog {
+	catalog := NewCatalog()
+	catalog.Add("en", map[string]string{
+		"NotFound":      "Not found",
+		"quotaExceeded": "Quota of %d %s exceeded",
+	})
+	catalog.Add("de", map[string]string{
+		"NotFound":      "Nicht gefunden",
+		"quotaExceeded": "Kontingent von %d %s überschritten",
+	})
+	catalog.Add("pt-BR", map[string]string{
+		"NotFound": "Não encontrado",
+	})
+	return catalog
+}
+
+func TestLocalize(t *testing.T) {
+	quota := &APIError{Code: validation.Conflict, Message: "quota", MessageKey: "quotaExceeded", Args: []interface{}{3, "pods"}}
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		err            *APIError
+		wantMessage    string
+		wantLanguage   string
+	}{
+		{
+			name:           "exact language",
+			acceptLanguage: "de",
+			err:            quota,
+			wantMessage:    "Kontingent von 3 pods überschritten",
+			wantLanguage:   "de",
+		},
+		{
+			name:           "base language of region",
+			acceptLanguage: "de-AT",
+			err:            quota,
+			wantMessage:    "Kontingent von 3 pods überschritten",
+			wantLanguage:   "de",
+		},
+		{
+			name:           "region",
+			acceptLanguage: "pt-br",
+			err:            &APIError{Code: validation.NotFound},
+			wantMessage:    "Não encontrado",
+			wantLanguage:   "pt-br",
+		},
+		{
+			name:           "quality order",
+			acceptLanguage: "fr;q=0.9, pt-BR;q=0.5, de;q=0.7",
+			err:            &APIError{Code: validation.NotFound},
+			wantMessage:    "Nicht gefunden",
+			wantLanguage:   "de",
+		},
+		{
+			name:           "zero quality skipped",
+			acceptLanguage: "de;q=0, pt-BR",
+			err:            &APIError{Code: validation.NotFound},
+			wantMessage:    "Não encontrado",
+			wantLanguage:   "pt-br",
+		},
+		{
+			name:           "missing translation falls back to English",
+			acceptLanguage: "pt-BR",
+			err:            quota,
+			wantMessage:    "Quota of 3 pods exceeded",
+			wantLanguage:   "en",
+		},
+		{
+			name:         "no Accept-Language",
+			err:          &APIError{Code: validation.NotFound},
+			wantMessage:  "Not found",
+			wantLanguage: "en",
+		},
+		{
+			name:           "plain message is not translated",
+			acceptLanguage: "de",
+			err:            &APIError{Code: validation.NotFound, Message: "no such schema"},
+			wantMessage:    "no such schema",
+		},
+		{
+			name:           "unknown key keeps message",
+			acceptLanguage: "de",
+			err:            &APIError{Code: validation.Conflict, Message: "in use", MessageKey: "inUse"},
+			wantMessage:    "in use",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, language := newTestCatalog().Localize(tt.acceptLanguage, tt.err)
+			assert.Equal(t, tt.wantMessage, message)
+			assert.Equal(t, tt.wantLanguage, language)
+		})
+	}
+}
+
+func TestNewLocalizedAPIError(t *testing.T) {
+	defaultCatalog := DefaultCatalog
+	t.Cleanup(func() { DefaultCatalog = defaultCatalog })
+	DefaultCatalog = NewCatalog()
+	DefaultCatalog.Add("en", map[string]string{"testQuota": "Quota of %d exceeded"})
+
+	err := NewLocalizedAPIError(validation.Conflict, "testQuota", 3).(*APIError)
+	assert.Equal(t, "Quota of 3 exceeded", err.Message)
+	assert.Equal(t, "testQuota", err.MessageKey)
+	assert.Equal(t, []interface{}{3}, err.Args)
+
+	err = NewLocalizedAPIError(validation.Conflict, "testUnknown").(*APIError)
+	assert.Equal(t, "testUnknown", err.Message)
+}
@@ -0,0 +1,17 @@
+package apierror
+
+import (
+	"net/http"
+
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+)
+
+var (
+	BadRequest            = validation.ErrorCode{Code: "BadRequest", Status: http.StatusBadRequest}
+	RequestEntityTooLarge = validation.ErrorCode{Code: "RequestEntityTooLarge", Status: http.StatusRequestEntityTooLarge}
+	Timeout               = validation.ErrorCode{Code: "Timeout", Status: http.StatusGatewayTimeout}
+	ServiceUnavailable    = validation.ErrorCode{Code: "ServiceUnavailable", Status: http.StatusServiceUnavailable}
+	Gone                  = validation.ErrorCode{Code: "Gone", Status: http.StatusGone}
+	PreconditionFailed    = validation.ErrorCode{Code: "PreconditionFailed", Status: http.StatusPreconditionFailed}
+	NotWatchable          = validation.ErrorCode{Code: "NotWatchable", Status: http.StatusBadRequest}
+)
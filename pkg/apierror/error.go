@@ -11,6 +11,37 @@ type APIError struct {
 	Message   string
 	Cause     error
 	FieldName string
+	// MessageKey and Args are resolved against the message catalog when the error is written, to localize the
+	// message for the client. Message is used if the key has no translation.
+	MessageKey string
+	Args       []interface{}
+	// Suggestions are alternatives to what was requested, such as the names of similar types, that are returned
+	// to the client.
+	Suggestions []string
+	// Details, if set, are returned to the client as the details of the error, for clients handling errors
+	// programmatically.
+	Details *Details
+}
+
+// Details are machine readable details of an error, laid out like the details of a Kubernetes Status so clients
+// already parsing those can handle them the same way.
+type Details struct {
+	// Kind is the type of the resource the error is about.
+	Kind string `json:"kind,omitempty"`
+	// Name is the name of the resource the error is about.
+	Name string `json:"name,omitempty"`
+	// Causes lists the individual reasons for the error, such as each invalid field.
+	Causes []Cause `json:"causes,omitempty"`
+	// RetryAfterSeconds is how long the client should wait before retrying. It is also sent as the Retry-After
+	// header.
+	RetryAfterSeconds int `json:"retryAfterSeconds,omitempty"`
+}
+
+// Cause is one of the reasons for an error.
+type Cause struct {
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	Field   string `json:"field,omitempty"`
 }
 
 func NewAPIError(code validation.ErrorCode, message string) error {
@@ -20,6 +51,21 @@ func NewAPIError(code validation.ErrorCode, message string) error {
 	}
 }
 
+// NewLocalizedAPIError returns an error whose message is looked up by key in the message catalog, in the
+// language of the client, and formatted with args. The DefaultLanguage message is used as its Message.
+func NewLocalizedAPIError(code validation.ErrorCode, key string, args ...interface{}) error {
+	message, _ := DefaultCatalog.Localize("", &APIError{MessageKey: key, Args: args})
+	if message == "" {
+		message = key
+	}
+	return &APIError{
+		Code:       code,
+		Message:    message,
+		MessageKey: key,
+		Args:       args,
+	}
+}
+
 func NewFieldAPIError(code validation.ErrorCode, fieldName, message string) error {
 	return &APIError{
 		Code:      code,
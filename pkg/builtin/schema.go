@@ -62,10 +62,27 @@ var (
 		},
 	}
 
+	// Capabilities is the type of the document describing the features of the server.
+	Capabilities = types.APISchema{
+		Schema: &schemas.Schema{
+			ID:                "capabilities",
+			ResourceMethods:   []string{},
+			CollectionMethods: []string{},
+			ResourceFields: map[string]schemas.Field{
+				"formats":             {Type: "array[string]"},
+				"encodings":           {Type: "array[string]"},
+				"subscribeTransports": {Type: "array[string]"},
+				"listAction":          {Type: "string"},
+				"features":            {Type: "map[boolean]"},
+			},
+		},
+	}
+
 	Schemas = types.EmptyAPISchemas().
 		MustAddSchema(Schema).
 		MustAddSchema(Error).
-		MustAddSchema(Collection)
+		MustAddSchema(Collection).
+		MustAddSchema(Capabilities)
 )
 
 func SchemaFormatter(apiOp *types.APIRequest, resource *types.RawResource) {
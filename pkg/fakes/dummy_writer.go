@@ -23,7 +23,7 @@ func (d *DummyWriter) Buffer() []byte {
 
 func (d *DummyWriter) Write(p []byte) (n int, err error) {
 	d.buffer = append(d.buffer, p...)
-	return 0, nil
+	return len(p), nil
 }
 
 func (d *DummyWriter) WriteHeader(int) {
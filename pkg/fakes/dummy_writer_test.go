@@ -0,0 +1,17 @@
+package fakes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDummyWriterWrite(t *testing.T) {
+	w := NewDummyWriter()
+	for _, p := range []string{"hello", "", " world"} {
+		n, err := w.Write([]byte(p))
+		assert.NoError(t, err)
+		assert.Equal(t, len(p), n)
+	}
+	assert.Equal(t, "hello world", string(w.Buffer()))
+}
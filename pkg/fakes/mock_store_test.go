@@ -0,0 +1,36 @@
+package fakes_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/rancher/apiserver/pkg/fakes"
+	"github.com/rancher/apiserver/pkg/handlers"
+	"github.com/rancher/apiserver/pkg/testutil"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := fakes.NewMockStore(ctrl)
+	apiSchemas := testutil.Schemas(t, testutil.SchemaSpec{ID: "pod", Store: store})
+
+	web := types.APIObject{Type: "pod", ID: "default/web", Object: map[string]interface{}{}}
+	store.EXPECT().
+		ByID(gomock.Any(), apiSchemas.LookupSchema("pod"), "default/web").
+		Return(web, nil)
+	store.EXPECT().
+		ByID(gomock.Any(), gomock.Any(), "default/db").
+		Return(types.APIObject{}, errors.New("not found"))
+
+	obj, err := handlers.ByIDHandler(testutil.Request(t, apiSchemas, http.MethodGet, "pod", "default/web"))
+	require.NoError(t, err)
+	assert.Equal(t, web, obj)
+
+	_, err = handlers.ByIDHandler(testutil.Request(t, apiSchemas, http.MethodGet, "pod", "default/db"))
+	assert.EqualError(t, err, "not found")
+}
@@ -0,0 +1,86 @@
+package fakes
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+)
+
+// contentTypeFormats maps the content types written by the response writers of the server to their format.
+var contentTypeFormats = map[string]string{
+	"application/json":         "json",
+	"application/jsonl":        "jsonl",
+	"application/yaml":         "yaml",
+	"text/html":                "html",
+	"text/csv":                 "csv",
+	"text/event-stream":        "sse",
+	"application/octet-stream": "raw",
+}
+
+// RecordingWriter is an http.ResponseWriter recording the status, headers and body of a response, so tests can
+// assert on what a handler wrote. Unlike DummyWriter, it behaves like the ResponseWriter of a server.
+type RecordingWriter struct {
+	header      http.Header
+	sentHeader  http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func NewRecordingWriter() *RecordingWriter {
+	return &RecordingWriter{header: http.Header{}}
+}
+
+func (r *RecordingWriter) Header() http.Header {
+	return r.header
+}
+
+// WriteHeader records the status and the headers sent with it. Later calls are ignored, like by a server.
+func (r *RecordingWriter) WriteHeader(statusCode int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.status = statusCode
+	r.sentHeader = r.header.Clone()
+}
+
+// Write records p as part of the body, sending a 200 status first if none was sent.
+func (r *RecordingWriter) Write(p []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(p)
+}
+
+func (r *RecordingWriter) Flush() {
+	r.WriteHeader(http.StatusOK)
+}
+
+// Status returns the status of the response, or 0 if nothing was written.
+func (r *RecordingWriter) Status() int {
+	return r.status
+}
+
+// SentHeader returns the headers as they were when the status was sent, or nil if nothing was written.
+func (r *RecordingWriter) SentHeader() http.Header {
+	return r.sentHeader
+}
+
+// Body returns what was written of the body.
+func (r *RecordingWriter) Body() []byte {
+	return r.body.Bytes()
+}
+
+// ContentType returns the media type of the response, without its parameters.
+func (r *RecordingWriter) ContentType() string {
+	mediaType, _, err := mime.ParseMediaType(r.sentHeader.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// Format returns the response format the response was written in, such as "json" or "yaml", from its content
+// type, or an empty string if the content type is not one of the server's.
+func (r *RecordingWriter) Format() string {
+	return contentTypeFormats[r.ContentType()]
+}
@@ -0,0 +1,85 @@
+package fakes_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/fakes"
+	"github.com/rancher/apiserver/pkg/server"
+	"github.com/rancher/apiserver/pkg/testutil"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordingWriter(t *testing.T) {
+	rw := fakes.NewRecordingWriter()
+	assert.Equal(t, 0, rw.Status())
+
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.WriteHeader(http.StatusCreated)
+	rw.WriteHeader(http.StatusInternalServerError)
+	rw.Header().Set("Content-Type", "text/plain")
+	n, err := rw.Write([]byte(`{"id":"foo"}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 12, n)
+	assert.Equal(t, http.StatusCreated, rw.Status())
+	assert.Equal(t, "application/json", rw.ContentType())
+	assert.Equal(t, "json", rw.Format())
+	assert.Equal(t, `{"id":"foo"}`, string(rw.Body()))
+}
+
+func TestRecordingWriterImplicitStatus(t *testing.T) {
+	rw := fakes.NewRecordingWriter()
+	_, _ = rw.Write([]byte("ok"))
+	assert.Equal(t, http.StatusOK, rw.Status())
+	assert.Empty(t, rw.Format())
+}
+
+func TestRecordingWriterServer(t *testing.T) {
+	tests := []struct {
+		name       string
+		accept     string
+		method     string
+		wantStatus int
+		wantFormat string
+	}{
+		{
+			name:       "json",
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantFormat: "json",
+		},
+		{
+			name:       "yaml",
+			accept:     "application/yaml",
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantFormat: "yaml",
+		},
+		{
+			name:       "method not supported",
+			method:     http.MethodDelete,
+			wantStatus: http.StatusForbidden,
+			wantFormat: "json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := server.DefaultAPIServer()
+			srv.Schemas.MustAddSchemas(testutil.Schemas(t, testutil.SchemaSpec{ID: "pod"}))
+
+			rw := fakes.NewRecordingWriter()
+			apiOp := testutil.Request(t, srv.Schemas, tt.method, "pod", "")
+			apiOp.Request.Header.Set("Accept", tt.accept)
+			apiOp.Response = rw
+			apiOp.Schema.ListHandler = func(*types.APIRequest) (types.APIObjectList, error) {
+				return types.APIObjectList{}, nil
+			}
+			srv.Handle(apiOp)
+
+			assert.Equal(t, tt.wantStatus, rw.Status(), string(rw.Body()))
+			assert.Equal(t, tt.wantFormat, rw.Format())
+		})
+	}
+}
@@ -14,7 +14,7 @@ func CreateHandler(apiOp *types.APIRequest) (types.APIObject, error) {
 		return types.APIObject{}, err
 	}
 
-	data, err := parse.Body(apiOp.Request)
+	data, err := parse.RequestBody(apiOp)
 	if err != nil {
 		return types.APIObject{}, err
 	}
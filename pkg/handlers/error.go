@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"net/url"
+	"strconv"
 
 	"github.com/rancher/apiserver/pkg/apierror"
 	"github.com/rancher/apiserver/pkg/types"
@@ -15,48 +16,114 @@ func ErrorHandler(request *types.APIRequest, err error) {
 		return
 	}
 
-	if ec, ok := err.(validation.ErrorCode); ok {
-		err = apierror.NewAPIError(ec, "")
+	error := toAPIError(request, err)
+	if error.Code.Status == http.StatusNoContent {
+		request.Response.WriteHeader(http.StatusNoContent)
+		return
 	}
 
-	var error *apierror.APIError
-	if apiError, ok := err.(*apierror.APIError); ok {
-		if apiError.Cause != nil {
-			url, _ := url.PathUnescape(request.Request.URL.String())
-			if url == "" {
-				url = request.Request.URL.String()
-			}
-			logrus.Errorf("API error response %v for %v %v. Cause: %v", apiError.Code.Status, request.Request.Method,
-				url, apiError.Cause)
+	message, language := apierror.DefaultCatalog.Localize(request.Request.Header.Get("Accept-Language"), error)
+	if language != "" {
+		request.Response.Header().Set("Content-Language", language)
+	}
+	if error.Details != nil && error.Details.RetryAfterSeconds > 0 {
+		request.Response.Header().Set("Retry-After", strconv.Itoa(error.Details.RetryAfterSeconds))
+	}
+
+	data := toError(error, message)
+	request.WriteResponse(error.Code.Status, data)
+}
+
+// SanitizeServerErrors returns an ErrorHandler passing errors on to next with the message of server errors, those
+// with a 5xx status, replaced by the text of their status, so that details such as queries or host names do not
+// reach clients. The original message is logged. Client errors are passed on unchanged, since clients can act on
+// their message.
+func SanitizeServerErrors(next types.ErrorHandler) types.ErrorHandler {
+	return func(request *types.APIRequest, err error) {
+		if err == validation.ErrComplete {
+			next(request, err)
+			return
 		}
-		error = apiError
-	} else {
-		logrus.Errorf("Unknown error: %v", err)
-		error = &apierror.APIError{
-			Code:    validation.ServerError,
-			Message: err.Error(),
+
+		if asAPIError(err).Code.Status < http.StatusInternalServerError {
+			// next logs the cause, if any
+			next(request, err)
+			return
+		}
+
+		apiError := toAPIError(request, err)
+		if apierror.IsAPIError(err) {
+			// other errors were logged when converted
+			logrus.Errorf("API error response %v for %v %v: %v", apiError.Code.Status, request.Request.Method,
+				request.Request.URL.Path, apiError.Message)
+		}
+
+		sanitized := &apierror.APIError{
+			Code:    apiError.Code,
+			Message: http.StatusText(apiError.Code.Status),
+		}
+		if apiError.Details != nil && apiError.Details.RetryAfterSeconds > 0 {
+			sanitized.Details = &apierror.Details{RetryAfterSeconds: apiError.Details.RetryAfterSeconds}
 		}
+		next(request, sanitized)
 	}
+}
 
-	if error.Code.Status == http.StatusNoContent {
-		request.Response.WriteHeader(http.StatusNoContent)
-		return
+// ErrorObject returns the error resource ErrorHandler would write for err, for responses reporting several errors,
+// such as the results of a batch.
+func ErrorObject(request *types.APIRequest, err error) types.APIObject {
+	error := toAPIError(request, err)
+	message, _ := apierror.DefaultCatalog.Localize(request.Request.Header.Get("Accept-Language"), error)
+	return toError(error, message)
+}
+
+// toAPIError converts err to an APIError like asAPIError, logging the cause of the error or, if it is not an
+// APIError, the error itself.
+func toAPIError(request *types.APIRequest, err error) *apierror.APIError {
+	apiError := asAPIError(err)
+	if _, ok := err.(validation.ErrorCode); !ok && !apierror.IsAPIError(err) {
+		logrus.Errorf("Unknown error: %v", err)
+	} else if apiError.Cause != nil {
+		url, _ := url.PathUnescape(request.Request.URL.String())
+		if url == "" {
+			url = request.Request.URL.String()
+		}
+		logrus.Errorf("API error response %v for %v %v. Cause: %v", apiError.Code.Status, request.Request.Method,
+			url, apiError.Cause)
 	}
+	return apiError
+}
 
-	data := toError(error)
-	request.WriteResponse(error.Code.Status, data)
+// asAPIError converts err to an APIError. Errors that are neither an APIError nor an ErrorCode are server errors.
+func asAPIError(err error) *apierror.APIError {
+	if ec, ok := err.(validation.ErrorCode); ok {
+		return apierror.NewAPIError(ec, "").(*apierror.APIError)
+	}
+	if apiError, ok := err.(*apierror.APIError); ok {
+		return apiError
+	}
+	return &apierror.APIError{
+		Code:    validation.ServerError,
+		Message: err.Error(),
+	}
 }
 
-func toError(apiError *apierror.APIError) types.APIObject {
+func toError(apiError *apierror.APIError, message string) types.APIObject {
 	e := map[string]interface{}{
 		"type":    "error",
 		"status":  apiError.Code.Status,
 		"code":    apiError.Code.Code,
-		"message": apiError.Message,
+		"message": message,
 	}
 	if apiError.FieldName != "" {
 		e["fieldName"] = apiError.FieldName
 	}
+	if len(apiError.Suggestions) > 0 {
+		e["suggestions"] = apiError.Suggestions
+	}
+	if apiError.Details != nil {
+		e["details"] = apiError.Details
+	}
 
 	return types.APIObject{
 		Type:   "error",
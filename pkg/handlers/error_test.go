@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type objectWriter struct {
+	code int
+	obj  types.APIObject
+}
+
+func (o *objectWriter) Write(apiOp *types.APIRequest, code int, obj types.APIObject) {
+	o.code = code
+	o.obj = obj
+}
+
+func (o *objectWriter) WriteList(apiOp *types.APIRequest, code int, list types.APIObjectList) {}
+
+func TestErrorHandlerLocalizesMessage(t *testing.T) {
+	defaultCatalog := apierror.DefaultCatalog
+	t.Cleanup(func() { apierror.DefaultCatalog = defaultCatalog })
+	apierror.DefaultCatalog = apierror.NewCatalog()
+	apierror.DefaultCatalog.Add(apierror.DefaultLanguage, map[string]string{validation.NotFound.Code: "Not found"})
+	apierror.DefaultCatalog.Add("fr", map[string]string{validation.NotFound.Code: "Introuvable"})
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		wantMessage    string
+		wantLanguage   string
+	}{
+		{name: "translated", acceptLanguage: "fr-CA, en;q=0.5", wantMessage: "Introuvable", wantLanguage: "fr"},
+		{name: "fallback", acceptLanguage: "ja", wantMessage: "Not found", wantLanguage: "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/foos/foo", nil)
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			resp := httptest.NewRecorder()
+			writer := &objectWriter{}
+
+			ErrorHandler(&types.APIRequest{
+				Request:        req,
+				Response:       resp,
+				ResponseWriter: writer,
+			}, validation.NotFound)
+
+			assert.Equal(t, http.StatusNotFound, writer.code)
+			data, err := json.Marshal(writer.obj.Object)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"type":"error","status":404,"code":"NotFound","message":"`+tt.wantMessage+`"}`, string(data))
+			assert.Equal(t, tt.wantLanguage, resp.Header().Get("Content-Language"))
+		})
+	}
+}
+
+func TestErrorHandlerDetails(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantBody       string
+		wantRetryAfter string
+	}{
+		{
+			name: "conflict with retry details",
+			err: &apierror.APIError{
+				Code:    validation.Conflict,
+				Message: "the object has been modified",
+				Details: &apierror.Details{
+					Kind: "foo",
+					Name: "foo1",
+					Causes: []apierror.Cause{
+						{Reason: "FieldValueInvalid", Message: "stale revision", Field: "metadata.resourceVersion"},
+					},
+					RetryAfterSeconds: 5,
+				},
+			},
+			wantBody: `{"type":"error","status":409,"code":"Conflict","message":"the object has been modified",` +
+				`"details":{"kind":"foo","name":"foo1","causes":[{"reason":"FieldValueInvalid","message":"stale revision",` +
+				`"field":"metadata.resourceVersion"}],"retryAfterSeconds":5}}`,
+			wantRetryAfter: "5",
+		},
+		{
+			name: "details without retry",
+			err: &apierror.APIError{
+				Code:    validation.Conflict,
+				Message: "already exists",
+				Details: &apierror.Details{Kind: "foo", Name: "foo1"},
+			},
+			wantBody: `{"type":"error","status":409,"code":"Conflict","message":"already exists",` +
+				`"details":{"kind":"foo","name":"foo1"}}`,
+		},
+		{
+			name:     "no details",
+			err:      apierror.NewAPIError(validation.Conflict, "already exists"),
+			wantBody: `{"type":"error","status":409,"code":"Conflict","message":"already exists"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			writer := &objectWriter{}
+
+			ErrorHandler(&types.APIRequest{
+				Request:        httptest.NewRequest(http.MethodPut, "/v1/foos/foo1", nil),
+				Response:       resp,
+				ResponseWriter: writer,
+			}, tt.err)
+
+			assert.Equal(t, http.StatusConflict, writer.code)
+			data, err := json.Marshal(writer.obj.Object)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.wantBody, string(data))
+			assert.Equal(t, tt.wantRetryAfter, resp.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestSanitizeServerErrorsLogsMessage(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	writer := &objectWriter{}
+	handler := SanitizeServerErrors(ErrorHandler)
+	handler(&types.APIRequest{
+		Request:        httptest.NewRequest(http.MethodGet, "/v1/foos/foo", nil),
+		Response:       httptest.NewRecorder(),
+		ResponseWriter: writer,
+	}, apierror.NewAPIError(validation.ServerError, "pq: connection to db.internal refused"))
+
+	assert.Equal(t, http.StatusInternalServerError, writer.code)
+	assert.Equal(t, "Internal Server Error", writer.obj.Data()["message"])
+	require.NotNil(t, hook.LastEntry())
+	assert.Contains(t, hook.LastEntry().Message, "pq: connection to db.internal refused")
+}
+
+func TestSanitizeServerErrorsLogsClientErrorOnce(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	writer := &objectWriter{}
+	handler := SanitizeServerErrors(ErrorHandler)
+	handler(&types.APIRequest{
+		Request:        httptest.NewRequest(http.MethodGet, "/v1/foos/foo", nil),
+		Response:       httptest.NewRecorder(),
+		ResponseWriter: writer,
+	}, apierror.WrapAPIError(errors.New("no row for foo"), validation.NotFound, "foo not found"))
+
+	assert.Equal(t, http.StatusNotFound, writer.code)
+	assert.Equal(t, "foo not found", writer.obj.Data()["message"])
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Contains(t, hook.LastEntry().Message, "no row for foo")
+}
@@ -8,19 +8,22 @@ import (
 	"github.com/rancher/apiserver/pkg/types"
 )
 
+// metricsEnabled is replaced in tests, since metrics can only be turned on through the environment.
+var metricsEnabled = metrics.Enabled
+
 func MetricsHandler(successCode string, next func(apiRequest *types.APIRequest) (types.APIObject, error)) func(apiRequest *types.APIRequest) (types.APIObject, error) {
 	return func(request *types.APIRequest) (types.APIObject, error) {
 		obj, err := next(request)
 		if err != nil {
-			if apiError, ok := err.(*apierror.APIError); ok {
-
-				metrics.IncTotalResponses(request.Schema.ID, request.Method, strconv.Itoa(apiError.Code.Status))
+			if apiError, ok := err.(*apierror.APIError); ok && metricsEnabled() {
+				incTotalResponses(resourceLabel(request), request.Method, strconv.Itoa(apiError.Code.Status))
 			}
 			return types.APIObject{}, err
 		}
-
-		metrics.IncTotalResponses(request.Schema.ID, request.Method, successCode)
-		return obj, err
+		if metricsEnabled() {
+			incTotalResponses(resourceLabel(request), request.Method, successCode)
+		}
+		return obj, nil
 	}
 }
 
@@ -28,13 +31,31 @@ func MetricsListHandler(successCode string, next func(apiRequest *types.APIReque
 	return func(request *types.APIRequest) (types.APIObjectList, error) {
 		objList, err := next(request)
 		if err != nil {
-			if apiError, ok := err.(*apierror.APIError); ok {
-				metrics.IncTotalResponses(request.Schema.ID, request.Method, strconv.Itoa(apiError.Code.Status))
+			if apiError, ok := err.(*apierror.APIError); ok && metricsEnabled() {
+				incTotalResponses(resourceLabel(request), request.Method, strconv.Itoa(apiError.Code.Status))
 			}
 			return types.APIObjectList{}, err
 		}
+		if metricsEnabled() {
+			incTotalResponses(resourceLabel(request), request.Method, successCode)
+		}
+		return objList, nil
+	}
+}
 
-		metrics.IncTotalResponses(request.Schema.ID, request.Method, successCode)
-		return objList, err
+// resourceLabel returns the resource label for the metrics of request, which may not have a schema if it failed
+// before one was matched.
+func resourceLabel(request *types.APIRequest) string {
+	if request.Schema != nil {
+		return request.Schema.ID
+	}
+	if request.Type != "" {
+		return request.Type
 	}
+	return "unknown"
+}
+
+// incTotalResponses counts a response in metrics.TotalResponses. Callers check metricsEnabled first.
+func incTotalResponses(resource, method, code string) {
+	metrics.TotalResponses.WithLabelValues(resource, method, code).Inc()
 }
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/metrics"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	metricsRequest = &types.APIRequest{
+		Schema: &types.APISchema{Schema: &schemas.Schema{ID: "pods"}},
+		Method: http.MethodGet,
+	}
+	notFound = apierror.NewAPIError(validation.NotFound, "not found")
+)
+
+func byID(*types.APIRequest) (types.APIObject, error) {
+	return types.APIObject{}, nil
+}
+
+func byIDNotFound(*types.APIRequest) (types.APIObject, error) {
+	return types.APIObject{}, notFound
+}
+
+func TestMetricsHandlerDisabledAllocs(t *testing.T) {
+	for _, next := range []func(*types.APIRequest) (types.APIObject, error){byID, byIDNotFound} {
+		handler := MetricsHandler("200", next)
+		allocs := testing.AllocsPerRun(100, func() {
+			_, _ = handler(metricsRequest)
+		})
+		assert.Zero(t, allocs)
+	}
+}
+
+func BenchmarkMetricsHandlerDisabled(b *testing.B) {
+	handler := MetricsHandler("200", byIDNotFound)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = handler(metricsRequest)
+	}
+}
+
+func TestMetricsHandlerNilSchema(t *testing.T) {
+	metricsEnabled = func() bool { return true }
+	defer func() { metricsEnabled = metrics.Enabled }()
+
+	request := &types.APIRequest{Method: http.MethodGet}
+	counter := metrics.TotalResponses.WithLabelValues("unknown", http.MethodGet, "404")
+	before := testutil.ToFloat64(counter)
+
+	assert.NotPanics(t, func() {
+		_, err := MetricsHandler("200", byIDNotFound)(request)
+		assert.Equal(t, notFound, err)
+	})
+	assert.NotPanics(t, func() {
+		_, err := MetricsListHandler("200", func(*types.APIRequest) (types.APIObjectList, error) {
+			return types.APIObjectList{}, notFound
+		})(request)
+		assert.Equal(t, notFound, err)
+	})
+	assert.Equal(t, float64(2), testutil.ToFloat64(counter)-before)
+}
+
+func TestMetricsHandlerErrorDropsObject(t *testing.T) {
+	defer func() { metricsEnabled = metrics.Enabled }()
+	partial := func(*types.APIRequest) (types.APIObject, error) {
+		return types.APIObject{Type: "pods", ID: "half-created"}, notFound
+	}
+	partialList := func(*types.APIRequest) (types.APIObjectList, error) {
+		return types.APIObjectList{Objects: []types.APIObject{{ID: "half-listed"}}}, notFound
+	}
+
+	for _, enabled := range []bool{false, true} {
+		metricsEnabled = func() bool { return enabled }
+		obj, err := MetricsHandler("201", partial)(metricsRequest)
+		assert.Equal(t, notFound, err)
+		assert.Equal(t, types.APIObject{}, obj, "metrics enabled: %v", enabled)
+
+		list, err := MetricsListHandler("200", partialList)(metricsRequest)
+		assert.Equal(t, notFound, err)
+		assert.Equal(t, types.APIObjectList{}, list, "metrics enabled: %v", enabled)
+	}
+}
+
+func TestResourceLabel(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *types.APIRequest
+		expect  string
+	}{
+		{
+			name:    "schema",
+			request: metricsRequest,
+			expect:  "pods",
+		},
+		{
+			name:    "unmatched type",
+			request: &types.APIRequest{Type: "podz"},
+			expect:  "podz",
+		},
+		{
+			name:    "no type",
+			request: &types.APIRequest{},
+			expect:  "unknown",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expect, resourceLabel(test.request))
+		})
+	}
+}
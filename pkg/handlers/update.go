@@ -19,7 +19,7 @@ func UpdateHandler(apiOp *types.APIRequest) (types.APIObject, error) {
 		err  error
 	)
 	if apiOp.Method != http.MethodPatch {
-		data, err = parse.Body(apiOp.Request)
+		data, err = parse.RequestBody(apiOp)
 		if err != nil {
 			return types.APIObject{}, err
 		}
@@ -8,7 +8,9 @@ const (
 	resourceLabel = "resource"
 	methodLabel   = "method"
 	codeLabel     = "code"
+	opLabel       = "operation"
 )
+
 var (
 	// https://prometheus.io/docs/practices/instrumentation/#use-labels explains logic of having 1 total_requests
 	// counter with code label vs a counter for each code
@@ -29,8 +31,30 @@ var (
 			Help:      "Request times in ms",
 		},
 		[]string{resourceLabel, methodLabel, codeLabel})
+
+	StoreTime = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "steve_api",
+			Name:      "store_time",
+			Help:      "Store call times in ms",
+		},
+		[]string{resourceLabel, opLabel})
+
+	DroppedEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "steve_api",
+			Name:      "subscribe_dropped_events",
+			Help:      "Total count of watch events dropped because a subscriber could not keep up",
+		},
+		[]string{resourceLabel})
 )
 
+// Enabled returns whether metrics are being recorded. Callers can use it to skip building labels when they would
+// only be discarded.
+func Enabled() bool {
+	return prometheusMetrics
+}
+
 func IncTotalResponses(resource, method, code string) {
 	if prometheusMetrics {
 		TotalResponses.With(
@@ -13,5 +13,7 @@ func init() {
 		prometheusMetrics = true
 		prometheus.MustRegister(TotalResponses)
 		prometheus.MustRegister(ResponseTime)
+		prometheus.MustRegister(StoreTime)
+		prometheus.MustRegister(DroppedEvents)
 	}
 }
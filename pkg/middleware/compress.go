@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gorilla/mux"
+)
+
+// Encoding is a content coding responses can be compressed with.
+type Encoding struct {
+	// Name is the token of the encoding in the Accept-Encoding and Content-Encoding headers, such as "br".
+	Name string
+	// NewWriter returns a writer compressing to w. It is closed once the response is complete.
+	NewWriter func(w io.Writer) io.WriteCloser
+}
+
+// BrotliEncoding compresses responses with brotli, at its default quality.
+var BrotliEncoding = Encoding{
+	Name: "br",
+	NewWriter: func(w io.Writer) io.WriteCloser {
+		return brotli.NewWriter(w)
+	},
+}
+
+// GzipEncoding compresses responses with gzip.
+var GzipEncoding = Encoding{
+	Name: "gzip",
+	NewWriter: func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	},
+}
+
+// Compress returns a middleware compressing responses with the first of encodings accepted by the client. If no
+// encoding is given, brotli is preferred over gzip.
+//
+// Like Gzip, the Content-Encoding header is only set once the response is written, and the Content-Length is
+// removed. Responses whose Content-Encoding is already set by the handler are sent as they are.
+func Compress(encodings ...Encoding) mux.MiddlewareFunc {
+	if len(encodings) == 0 {
+		encodings = []Encoding{BrotliEncoding, GzipEncoding}
+	}
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding, ok := negotiateEncoding(r.Header.Get("Accept-Encoding"), encodings)
+			if !ok {
+				handler.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressResponseWriter{ResponseWriter: w, encoding: encoding}
+			defer cw.Close()
+			handler.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding returns the first of encodings that the Accept-Encoding header accepts with a non-zero quality,
+// either by name or with "*".
+func negotiateEncoding(acceptEncoding string, encodings []Encoding) (Encoding, bool) {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		accepted[name] = true
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if quality, err := strconv.ParseFloat(q, 64); err == nil && quality == 0 {
+				accepted[name] = false
+			}
+		}
+	}
+
+	for _, encoding := range encodings {
+		if use, ok := accepted[encoding.Name]; ok {
+			if use {
+				return encoding, true
+			}
+			continue
+		}
+		if accepted["*"] {
+			return encoding, true
+		}
+	}
+	return Encoding{}, false
+}
+
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding Encoding
+	writer   io.WriteCloser
+	started  bool
+	// passthrough is set when the handler encoded the response itself.
+	passthrough bool
+}
+
+// start sets the Content-Encoding of the response, unless the handler already did.
+func (c *compressResponseWriter) start() {
+	if c.started {
+		return
+	}
+	c.started = true
+
+	header := c.Header()
+	if header.Get("Content-Encoding") != "" {
+		c.passthrough = true
+		return
+	}
+	header.Set("Content-Encoding", c.encoding.Name)
+	header.Del("Content-Length")
+	header.Add("Vary", "Accept-Encoding")
+}
+
+func (c *compressResponseWriter) WriteHeader(statusCode int) {
+	c.start()
+	c.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (c *compressResponseWriter) Write(b []byte) (int, error) {
+	c.start()
+	if c.passthrough {
+		return c.ResponseWriter.Write(b)
+	}
+	if c.writer == nil {
+		c.writer = c.encoding.NewWriter(c.ResponseWriter)
+	}
+	return c.writer.Write(b)
+}
+
+// Close writes the end of the compressed response, if anything was written.
+func (c *compressResponseWriter) Close() error {
+	if c.writer == nil {
+		return nil
+	}
+	return c.writer.Close()
+}
+
+func (c *compressResponseWriter) Flush() {
+	if flusher, ok := c.writer.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped ResponseWriter, for http.ResponseController.
+func (c *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+func (c *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := c.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, fmt.Errorf("Upstream ResponseWriter of type %v does not implement http.Hijacker", reflect.TypeOf(c.ResponseWriter))
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompress(t *testing.T) {
+	const body = "a body that is compressed"
+	write := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Length", "25")
+		_, _ = rw.Write([]byte(body))
+	})
+
+	tests := []struct {
+		name           string
+		encodings      []Encoding
+		acceptEncoding string
+		handler        http.Handler
+		wantEncoding   string
+	}{
+		{
+			name:           "brotli preferred",
+			encodings:      []Encoding{BrotliEncoding, GzipEncoding},
+			acceptEncoding: "gzip, deflate, br",
+			handler:        write,
+			wantEncoding:   "br",
+		},
+		{
+			name:           "gzip fallback",
+			encodings:      []Encoding{BrotliEncoding, GzipEncoding},
+			acceptEncoding: "gzip, deflate",
+			handler:        write,
+			wantEncoding:   "gzip",
+		},
+		{
+			name:           "brotli refused",
+			encodings:      []Encoding{BrotliEncoding, GzipEncoding},
+			acceptEncoding: "br;q=0, gzip;q=0.5",
+			handler:        write,
+			wantEncoding:   "gzip",
+		},
+		{
+			name:           "any encoding",
+			encodings:      []Encoding{BrotliEncoding, GzipEncoding},
+			acceptEncoding: "*",
+			handler:        write,
+			wantEncoding:   "br",
+		},
+		{
+			name:           "brotli by default",
+			acceptEncoding: "gzip, br",
+			handler:        write,
+			wantEncoding:   "br",
+		},
+		{
+			name:           "gzip by default",
+			acceptEncoding: "gzip",
+			handler:        write,
+			wantEncoding:   "gzip",
+		},
+		{
+			name:           "no accepted encoding",
+			encodings:      []Encoding{BrotliEncoding, GzipEncoding},
+			acceptEncoding: "identity",
+			handler:        write,
+		},
+		{
+			name:           "nothing written",
+			encodings:      []Encoding{BrotliEncoding, GzipEncoding},
+			acceptEncoding: "br",
+			handler:        http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/pods", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			rw := httptest.NewRecorder()
+
+			Compress(tt.encodings...)(tt.handler).ServeHTTP(rw, req)
+
+			assert.Equal(t, tt.wantEncoding, rw.Header().Get("Content-Encoding"))
+			var reader io.Reader = rw.Body
+			switch tt.wantEncoding {
+			case "":
+				return
+			case "br":
+				reader = brotli.NewReader(rw.Body)
+			case "gzip":
+				gz, err := gzip.NewReader(rw.Body)
+				require.NoError(t, err)
+				reader = gz
+			}
+			assert.Empty(t, rw.Header().Get("Content-Length"))
+			assert.Equal(t, "Accept-Encoding", rw.Header().Get("Vary"))
+			decoded, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, body, string(decoded))
+		})
+	}
+}
+
+func TestCompressAlreadyEncoded(t *testing.T) {
+	encoded := []byte{0x1f, 0x8b, 0x08}
+	handler := Compress(BrotliEncoding, GzipEncoding)(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Encoding", "gzip")
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write(encoded)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/pods", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+
+	assert.Equal(t, "gzip", rw.Header().Get("Content-Encoding"))
+	assert.Equal(t, encoded, rw.Body.Bytes(), "the response is not encoded twice")
+}
+
+func TestCompressFlush(t *testing.T) {
+	rw := httptest.NewRecorder()
+	var flushed []byte
+	handler := Compress(GzipEncoding)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("event"))
+		w.(http.Flusher).Flush()
+		flushed = append(flushed, rw.Body.Bytes()...)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/subscribe", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	handler.ServeHTTP(rw, req)
+
+	assert.True(t, rw.Flushed)
+	// what was flushed decodes to what was written so far, before the gzip stream is closed
+	gz, err := gzip.NewReader(bytes.NewReader(flushed))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(gz, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "event", string(buf))
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/apierror"
+)
+
+// ConcurrencyLimitMiddleware returns a middleware handling at most limit requests at the same time. A request
+// arriving while all slots are taken waits up to wait for one to free up, and is rejected with a 503 otherwise. A
+// wait of zero or less rejects it at once. It panics if limit is less than 1. See ConcurrencyLimit.
+func ConcurrencyLimitMiddleware(limit int, wait time.Duration) mux.MiddlewareFunc {
+	return func(handler http.Handler) http.Handler {
+		return ConcurrencyLimit(handler, limit, wait)
+	}
+}
+
+// ConcurrencyLimit allows at most limit requests to be handled at the same time. Requests arriving while the limit
+// is reached wait up to wait for a slot to become available, and are rejected with a 503 and a Retry-After header
+// if none does. It panics if limit is less than 1, which would reject every request.
+func ConcurrencyLimit(handler http.Handler, limit int, wait time.Duration) http.Handler {
+	slots := newSemaphore(limit)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slots.acquire(r, wait) {
+			tooManyRequests(w, r)
+			return
+		}
+		defer slots.release()
+		handler.ServeHTTP(w, r)
+	})
+}
+
+type semaphore chan struct{}
+
+func newSemaphore(size int) semaphore {
+	if size < 1 {
+		panic(fmt.Sprintf("concurrency limit must be at least 1, got %d", size))
+	}
+	return make(semaphore, size)
+}
+
+// acquire takes a slot, waiting up to wait for one to be released. It gives up early if the request is canceled.
+func (s semaphore) acquire(r *http.Request, wait time.Duration) bool {
+	select {
+	case s <- struct{}{}:
+		return true
+	default:
+	}
+	if wait <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case s <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func (s semaphore) release() {
+	<-s
+}
+
+func tooManyRequests(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "1")
+	WriteError(w, r, apierror.NewAPIError(apierror.ServiceUnavailable, "the server is handling too many requests, please try again later"))
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingHandler blocks every request until release is closed, signalling started when it begins.
+type blockingHandler struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{
+		started: make(chan struct{}, 10),
+		release: make(chan struct{}),
+	}
+}
+
+func (b *blockingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.started <- struct{}{}
+	<-b.release
+}
+
+func serve(handler http.Handler) *httptest.ResponseRecorder {
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/pods", nil))
+	return rw
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	blocking := newBlockingHandler()
+	handler := ConcurrencyLimit(blocking, 2, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, http.StatusOK, serve(handler).Code)
+		}()
+		<-blocking.started
+	}
+
+	rw := serve(handler)
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+	assert.Equal(t, "1", rw.Header().Get("Retry-After"))
+
+	close(blocking.release)
+	wg.Wait()
+	assert.Equal(t, http.StatusOK, serve(handler).Code)
+}
+
+func TestConcurrencyLimitWait(t *testing.T) {
+	blocking := newBlockingHandler()
+	handler := ConcurrencyLimit(blocking, 1, time.Second)
+
+	go serve(handler)
+	<-blocking.started
+
+	done := make(chan int)
+	go func() {
+		done <- serve(handler).Code
+	}()
+	close(blocking.release)
+
+	assert.Equal(t, http.StatusOK, <-done)
+}
+
+func TestConcurrencyLimitReleasesOnPanic(t *testing.T) {
+	handler := ConcurrencyLimit(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}), 1, 0)
+
+	for i := 0; i < 2; i++ {
+		assert.PanicsWithValue(t, "boom", func() {
+			serve(handler)
+		})
+	}
+}
+
+func TestConcurrencyLimitInvalid(t *testing.T) {
+	for _, limit := range []int{0, -1} {
+		assert.Panics(t, func() { ConcurrencyLimit(http.NotFoundHandler(), limit, 0) })
+		assert.Panics(t, func() { NewReadWritePriorityAdmission(limit, 1, 0) })
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// DefaultCORSMethods are the methods allowed by CORS when CORSOptions.AllowedMethods is empty.
+var DefaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// CORSOptions configures the cross-origin requests allowed by CORS.
+type CORSOptions struct {
+	// AllowedOrigins are the origins allowed to make requests, such as "https://ui.example.com". "*" allows any
+	// origin, and a "*" within an origin matches any part of it, as in "https://*.example.com".
+	AllowedOrigins []string
+	// AllowedMethods are the methods allowed by preflight requests. If empty, DefaultCORSMethods are allowed.
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed by preflight requests. If empty, the headers the preflight
+	// request asks for are allowed.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers the client is allowed to read, on top of the safelisted ones.
+	ExposedHeaders []string
+	// AllowCredentials allows requests with cookies or authorization headers. The origin of the request is then
+	// returned instead of "*", as browsers require.
+	AllowCredentials bool
+	// MaxAge is how long the result of a preflight request can be cached. If zero, browsers use their default.
+	MaxAge time.Duration
+}
+
+// CORS returns a middleware allowing the cross-origin requests described by opts. Preflight requests from an
+// allowed origin are answered with a 204 and the Access-Control-* headers, without calling the handler. Other
+// requests from an allowed origin get the Access-Control-Allow-Origin header. Requests from other origins are
+// passed on as they are, and are rejected by the browser.
+func CORS(opts CORSOptions) mux.MiddlewareFunc {
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = DefaultCORSMethods
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(opts.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(opts.ExposedHeaders, ", ")
+	anyOrigin := false
+	for _, origin := range opts.AllowedOrigins {
+		anyOrigin = anyOrigin || origin == "*"
+	}
+
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				handler.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			// the response depends on the origin, caches must not give it to other origins
+			header.Add("Vary", "Origin")
+			if !matchOrigin(origin, opts.AllowedOrigins) {
+				handler.ServeHTTP(w, r)
+				return
+			}
+
+			if anyOrigin && !opts.AllowCredentials {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			if opts.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+				if exposeHeaders != "" {
+					header.Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
+				handler.ServeHTTP(w, r)
+				return
+			}
+
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Methods", allowMethods)
+			if allowHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", allowHeaders)
+			} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				header.Set("Access-Control-Allow-Headers", requested)
+			}
+			if opts.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// matchOrigin returns whether origin is one of allowed, which may hold "*" wildcards.
+func matchOrigin(origin string, allowed []string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		prefix, suffix, wildcard := strings.Cut(pattern, "*")
+		if !wildcard {
+			if origin == pattern {
+				return true
+			}
+			continue
+		}
+		if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchOrigin(t *testing.T) {
+	allowed := []string{"https://ui.example.com", "https://*.dev.example.com"}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{origin: "https://ui.example.com", want: true},
+		{origin: "https://UI.example.com", want: true},
+		{origin: "https://feature.dev.example.com", want: true},
+		{origin: "https://.dev.example.com"},
+		{origin: "http://ui.example.com"},
+		{origin: "https://ui.example.com.evil.io"},
+		{origin: "https://evil.io"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchOrigin(tt.origin, allowed))
+		})
+	}
+	assert.True(t, matchOrigin("https://anything.io", []string{"*"}))
+}
+
+func TestCORS(t *testing.T) {
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("X-Handled", "true")
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name        string
+		opts        CORSOptions
+		method      string
+		headers     map[string]string
+		wantStatus  int
+		wantHandled bool
+		wantHeaders map[string]string
+	}{
+		{
+			name:        "same origin",
+			opts:        CORSOptions{AllowedOrigins: []string{"https://ui.example.com"}},
+			method:      http.MethodGet,
+			wantStatus:  http.StatusOK,
+			wantHandled: true,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "", "Vary": ""},
+		},
+		{
+			name:        "allowed origin",
+			opts:        CORSOptions{AllowedOrigins: []string{"https://ui.example.com"}, ExposedHeaders: []string{"X-Api-Warning"}},
+			method:      http.MethodGet,
+			headers:     map[string]string{"Origin": "https://ui.example.com"},
+			wantStatus:  http.StatusOK,
+			wantHandled: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":   "https://ui.example.com",
+				"Access-Control-Expose-Headers": "X-Api-Warning",
+				"Vary":                          "Origin",
+			},
+		},
+		{
+			name:        "other origin",
+			opts:        CORSOptions{AllowedOrigins: []string{"https://ui.example.com"}},
+			method:      http.MethodGet,
+			headers:     map[string]string{"Origin": "https://evil.io"},
+			wantStatus:  http.StatusOK,
+			wantHandled: true,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "", "Vary": "Origin"},
+		},
+		{
+			name:        "any origin",
+			opts:        CORSOptions{AllowedOrigins: []string{"*"}},
+			method:      http.MethodGet,
+			headers:     map[string]string{"Origin": "https://ui.example.com"},
+			wantStatus:  http.StatusOK,
+			wantHandled: true,
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "*"},
+		},
+		{
+			name:        "any origin with credentials",
+			opts:        CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			method:      http.MethodGet,
+			headers:     map[string]string{"Origin": "https://ui.example.com"},
+			wantStatus:  http.StatusOK,
+			wantHandled: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://ui.example.com",
+				"Access-Control-Allow-Credentials": "true",
+			},
+		},
+		{
+			name: "preflight",
+			opts: CORSOptions{
+				AllowedOrigins: []string{"https://*.example.com"},
+				AllowedHeaders: []string{"Content-Type", "X-Api-Csrf"},
+				MaxAge:         10 * time.Minute,
+			},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://ui.example.com",
+				"Access-Control-Request-Method":  http.MethodPut,
+				"Access-Control-Request-Headers": "content-type",
+			},
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "https://ui.example.com",
+				"Access-Control-Allow-Methods": "GET, HEAD, POST, PUT, PATCH, DELETE",
+				"Access-Control-Allow-Headers": "Content-Type, X-Api-Csrf",
+				"Access-Control-Max-Age":       "600",
+			},
+		},
+		{
+			name:   "preflight echoing requested headers",
+			opts:   CORSOptions{AllowedOrigins: []string{"https://ui.example.com"}, AllowedMethods: []string{http.MethodGet}},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://ui.example.com",
+				"Access-Control-Request-Method":  http.MethodGet,
+				"Access-Control-Request-Headers": "x-requested-with",
+			},
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Methods": "GET",
+				"Access-Control-Allow-Headers": "x-requested-with",
+				"Access-Control-Max-Age":       "",
+			},
+		},
+		{
+			name:        "options without preflight",
+			opts:        CORSOptions{AllowedOrigins: []string{"https://ui.example.com"}},
+			method:      http.MethodOptions,
+			headers:     map[string]string{"Origin": "https://ui.example.com"},
+			wantStatus:  http.StatusOK,
+			wantHandled: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "https://ui.example.com",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/v1/pods", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rw := httptest.NewRecorder()
+
+			CORS(tt.opts)(next).ServeHTTP(rw, req)
+
+			assert.Equal(t, tt.wantStatus, rw.Code)
+			assert.Equal(t, tt.wantHandled, rw.Header().Get("X-Handled") == "true")
+			for k, v := range tt.wantHeaders {
+				assert.Equal(t, v, rw.Header().Get(k), k)
+			}
+		})
+	}
+}
+
+func TestCORSChain(t *testing.T) {
+	handler := Chain{
+		CORS(CORSOptions{AllowedOrigins: []string{"https://ui.example.com"}}),
+		FrameOptions,
+		ContentTypeOptions,
+	}.Handler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/pods", nil)
+	req.Header.Set("Origin", "https://ui.example.com")
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "https://ui.example.com", rw.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "SAMEORIGIN", rw.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", rw.Header().Get("X-Content-Type-Options"))
+
+	// preflight requests are answered before the rest of the chain
+	req = httptest.NewRequest(http.MethodOptions, "/v1/pods", nil)
+	req.Header.Set("Origin", "https://ui.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+	rw = httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusNoContent, rw.Code)
+	assert.Empty(t, rw.Header().Get("X-Frame-Options"))
+}
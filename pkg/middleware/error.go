@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/builtin"
+	"github.com/rancher/apiserver/pkg/handlers"
+	"github.com/rancher/apiserver/pkg/parse"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/apiserver/pkg/writer"
+)
+
+// WriteError writes err as the response of a request rejected by a middleware, in the same shape as the errors
+// returned by the API. Errors that are not an apierror.APIError are written as server errors. It can be replaced
+// to write errors like a server with custom response writers does.
+var WriteError = writeError
+
+// errorSchemas holds only the error schema, so middleware errors do not advertise the schemas of any server.
+var errorSchemas = types.EmptyAPISchemas().MustAddSchema(builtin.Error)
+
+var errorWriters = map[string]types.ResponseWriter{
+	"json": &writer.EncodingResponseWriter{
+		ContentType: "application/json",
+		Encoder:     types.JSONEncoder,
+	},
+	"yaml": &writer.EncodingResponseWriter{
+		ContentType: "application/yaml",
+		Encoder:     types.YAMLEncoder,
+	},
+}
+
+// writeError writes err as JSON, or YAML if the request asks for it.
+func writeError(rw http.ResponseWriter, req *http.Request, err error) {
+	responseWriter, ok := errorWriters[parse.ResponseFormat(req)]
+	if !ok {
+		responseWriter = errorWriters["json"]
+	}
+	apiOp := &types.APIRequest{
+		Method:         req.Method,
+		Schemas:        errorSchemas,
+		ResponseWriter: responseWriter,
+		Request:        req,
+		Response:       rw,
+	}
+	handlers.ErrorHandler(apiOp, err)
+}
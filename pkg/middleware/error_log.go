@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// LoggedError is a request answered with an error status, as recorded by ErrorLog.
+type LoggedError struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Status int       `json:"status"`
+}
+
+// ErrorLog is a middleware that keeps the last requests answered with a status of 400 or more in memory, to debug
+// a server without a logging pipeline. The oldest error is forgotten once the log is full, so its size is fixed.
+// The errors are only exposed if Handler is served.
+type ErrorLog struct {
+	lock   sync.Mutex
+	errors []LoggedError
+	next   int
+	full   bool
+}
+
+// NewErrorLog returns an ErrorLog keeping the last size errors.
+func NewErrorLog(size int) *ErrorLog {
+	if size < 1 {
+		size = 1
+	}
+	return &ErrorLog{
+		errors: make([]LoggedError, size),
+	}
+}
+
+// Middleware wraps handler with the log.
+func (l *ErrorLog) Middleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &statusRecordingWriter{ResponseWriter: w}
+		handler.ServeHTTP(rw, r)
+		if rw.status >= http.StatusBadRequest {
+			l.add(LoggedError{
+				Time:   time.Now(),
+				Method: r.Method,
+				Path:   r.URL.Path,
+				Status: rw.status,
+			})
+		}
+	})
+}
+
+func (l *ErrorLog) add(err LoggedError) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.errors[l.next] = err
+	l.next = (l.next + 1) % len(l.errors)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Errors returns the logged errors, the most recent first.
+func (l *ErrorLog) Errors() []LoggedError {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	count := l.next
+	if l.full {
+		count = len(l.errors)
+	}
+	result := make([]LoggedError, 0, count)
+	for i := 1; i <= count; i++ {
+		result = append(result, l.errors[(l.next-i+len(l.errors))%len(l.errors)])
+	}
+	return result
+}
+
+// Handler returns a handler writing the logged errors as JSON, the most recent first. It exposes the paths requested
+// by every client, so it should only be served to administrators.
+func (l *ErrorLog) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"errors": l.Errors(),
+		})
+	})
+}
+
+// statusRecordingWriter records the status of the response written through it.
+type statusRecordingWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecordingWriter) WriteHeader(statusCode int) {
+	if s.status == 0 {
+		s.status = statusCode
+	}
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (s *statusRecordingWriter) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecordingWriter) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (s *statusRecordingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := s.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, fmt.Errorf("Upstream ResponseWriter of type %v does not implement http.Hijacker", reflect.TypeOf(s.ResponseWriter))
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusHandler answers each request with the status in its status query parameter.
+var statusHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+	if status == 0 {
+		_, _ = w.Write([]byte("ok"))
+		return
+	}
+	w.WriteHeader(status)
+})
+
+func TestErrorLog(t *testing.T) {
+	log := NewErrorLog(3)
+	handler := log.Middleware(statusHandler)
+
+	for _, target := range []string{"/v1/pods", "/v1/pods/a?status=404", "/v1/pods/b?status=200", "/v1/nodes?status=500"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/v1/pods/c?status=403", nil))
+
+	errors := log.Errors()
+	require.Len(t, errors, 3)
+	assert.Equal(t, LoggedError{Time: errors[0].Time, Method: http.MethodDelete, Path: "/v1/pods/c", Status: 403}, errors[0])
+	assert.Equal(t, "/v1/nodes", errors[1].Path)
+	assert.Equal(t, 500, errors[1].Status)
+	assert.Equal(t, "/v1/pods/a", errors[2].Path)
+	assert.False(t, errors[0].Time.Before(errors[1].Time))
+}
+
+func TestErrorLogRollover(t *testing.T) {
+	log := NewErrorLog(3)
+	handler := log.Middleware(statusHandler)
+	assert.Empty(t, log.Errors())
+
+	for i := 0; i < 7; i++ {
+		status := 400 + i
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/pods?status="+strconv.Itoa(status), nil))
+
+		errors := log.Errors()
+		require.Len(t, errors, min(i+1, 3))
+		for j, err := range errors {
+			assert.Equal(t, status-j, err.Status, "errors are listed from the most recent")
+		}
+	}
+}
+
+func TestErrorLogHandler(t *testing.T) {
+	log := NewErrorLog(10)
+	log.Middleware(statusHandler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/v1/pods/a?status=409", nil))
+
+	rw := httptest.NewRecorder()
+	log.Handler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/debug/errors", nil))
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "application/json", rw.Header().Get("Content-Type"))
+	var body struct {
+		Errors []LoggedError `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &body))
+	require.Len(t, body.Errors, 1)
+	assert.Equal(t, http.MethodPut, body.Errors[0].Method)
+	assert.Equal(t, "/v1/pods/a", body.Errors[0].Path)
+	assert.Equal(t, http.StatusConflict, body.Errors[0].Status)
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+func TestMiddlewareErrors(t *testing.T) {
+	full := newBlockingHandler()
+	limited := ConcurrencyLimit(full, 1, 0)
+	go serve(limited)
+	<-full.started
+	defer close(full.release)
+
+	tests := []struct {
+		name           string
+		handler        http.Handler
+		accept         string
+		wantStatus     int
+		wantCode       string
+		wantMessage    string
+		wantType       string
+		wantRetryAfter string
+	}{
+		{
+			name:        "tls required",
+			handler:     NewRequireTLS(false).Middleware(http.NotFoundHandler()),
+			wantStatus:  http.StatusForbidden,
+			wantCode:    "PermissionDenied",
+			wantMessage: "TLS is required",
+			wantType:    "application/json",
+		},
+		{
+			name:        "tls required as yaml",
+			handler:     NewRequireTLS(false).Middleware(http.NotFoundHandler()),
+			accept:      "application/yaml",
+			wantStatus:  http.StatusForbidden,
+			wantCode:    "PermissionDenied",
+			wantMessage: "TLS is required",
+			wantType:    "application/yaml",
+		},
+		{
+			name:           "concurrency limit",
+			handler:        limited,
+			wantStatus:     http.StatusServiceUnavailable,
+			wantCode:       "ServiceUnavailable",
+			wantMessage:    "the server is handling too many requests, please try again later",
+			wantType:       "application/json",
+			wantRetryAfter: "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/pods", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rw := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rw, req)
+
+			assert.Equal(t, tt.wantStatus, rw.Code)
+			assert.Equal(t, tt.wantType, rw.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantRetryAfter, rw.Header().Get("Retry-After"))
+
+			body := map[string]interface{}{}
+			if tt.wantType == "application/yaml" {
+				require.NoError(t, yaml.Unmarshal(rw.Body.Bytes(), &body))
+			} else {
+				require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &body))
+			}
+			assert.Equal(t, "error", body["type"])
+			assert.Equal(t, float64(tt.wantStatus), body["status"])
+			assert.Equal(t, tt.wantCode, body["code"])
+			assert.Equal(t, tt.wantMessage, body["message"])
+		})
+	}
+}
+
+func TestWriteErrorOverride(t *testing.T) {
+	defer func(old func(http.ResponseWriter, *http.Request, error)) { WriteError = old }(WriteError)
+	var written error
+	WriteError = func(rw http.ResponseWriter, req *http.Request, err error) {
+		written = err
+		rw.WriteHeader(http.StatusTeapot)
+	}
+
+	rw := serve(NewRequireTLS(false).Middleware(http.NotFoundHandler()))
+	assert.Equal(t, http.StatusTeapot, rw.Code)
+	assert.EqualError(t, written, "PermissionDenied 403: TLS is required")
+}
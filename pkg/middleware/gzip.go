@@ -9,6 +9,9 @@ import (
 	"net/http"
 	"reflect"
 	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
 )
 
 type gzipResponseWriter struct {
@@ -38,21 +41,46 @@ func (g gzipResponseWriter) WriteHeader(statusCode int) {
 	g.ResponseWriter.WriteHeader(statusCode)
 }
 
+// gzipPools holds the gzip writers of each compression level, from gzip.DefaultCompression to
+// gzip.BestCompression, so they can be reused across requests.
+var gzipPools [gzip.BestCompression - gzip.DefaultCompression + 1]sync.Pool
+
 // Gzip creates a gzip writer if gzip encoding is accepted.
 func Gzip(handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-			handler.ServeHTTP(w, r)
-			return
-		}
-		gz := gzip.NewWriter(w)
+	return GzipLevel(gzip.DefaultCompression)(handler)
+}
 
-		gzw := &gzipResponseWriter{Writer: gz, ResponseWriter: w}
-		defer gzw.Close(gz)
+// GzipLevel returns a middleware that compresses responses like Gzip, with the given compression level, trading
+// size for speed with levels closer to gzip.BestSpeed. The level must be gzip.DefaultCompression or between
+// gzip.BestSpeed and gzip.BestCompression, GzipLevel panics otherwise.
+func GzipLevel(level int) mux.MiddlewareFunc {
+	if level != gzip.DefaultCompression && (level < gzip.BestSpeed || level > gzip.BestCompression) {
+		panic(fmt.Sprintf("invalid gzip compression level %d", level))
+	}
+	pool := &gzipPools[level-gzip.DefaultCompression]
 
-		// Content encoding will be set once Write or WriteHeader is called, to avoid gzipping empty messages
-		handler.ServeHTTP(gzw, r)
-	})
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+				handler.ServeHTTP(w, r)
+				return
+			}
+			gz, ok := pool.Get().(*gzip.Writer)
+			if ok {
+				gz.Reset(w)
+			} else {
+				// the level is valid, so there is no error
+				gz, _ = gzip.NewWriterLevel(w, level)
+			}
+			defer pool.Put(gz)
+
+			gzw := &gzipResponseWriter{Writer: gz, ResponseWriter: w}
+			defer gzw.Close(gz)
+
+			// Content encoding will be set once Write or WriteHeader is called, to avoid gzipping empty messages
+			handler.ServeHTTP(gzw, r)
+		})
+	}
 }
 
 // Hijack must be implemented to properly chain with handlers expecting a hijacker handler to be passed
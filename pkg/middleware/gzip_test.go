@@ -2,11 +2,16 @@ package middleware
 
 import (
 	"compress/gzip"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/rancher/apiserver/pkg/fakes"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func NewRequest(accept string) *http.Request {
@@ -132,3 +137,36 @@ func TestMultipleWrites(t *testing.T) {
 	assert.Equal("gzip", rw.Header().Get("Content-Encoding"))
 	assert.NotEqual(multiWriteResult, oneWriteResult)
 }
+
+func TestGzipLevel(t *testing.T) {
+	body := strings.Repeat("a compressible body ", 100)
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(body))
+	})
+
+	for _, level := range []int{gzip.DefaultCompression, gzip.BestSpeed, gzip.BestCompression} {
+		t.Run(strconv.Itoa(level), func(t *testing.T) {
+			middleware := GzipLevel(level)(handler)
+			// the second response reuses the writer of the first one
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest(http.MethodGet, "/v1/pods", nil)
+				req.Header.Set("Accept-Encoding", "gzip")
+				rw := httptest.NewRecorder()
+				middleware.ServeHTTP(rw, req)
+
+				require.Equal(t, "gzip", rw.Header().Get("Content-Encoding"))
+				gz, err := gzip.NewReader(rw.Body)
+				require.NoError(t, err)
+				decoded, err := io.ReadAll(gz)
+				require.NoError(t, err)
+				assert.Equal(t, body, string(decoded))
+			}
+		})
+	}
+}
+
+func TestGzipLevelInvalid(t *testing.T) {
+	for _, level := range []int{gzip.HuffmanOnly, gzip.NoCompression, gzip.BestCompression + 1} {
+		assert.Panics(t, func() { GzipLevel(level) }, "level %d", level)
+	}
+}
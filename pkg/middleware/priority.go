@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ReadsBucket  = "reads"
+	WritesBucket = "writes"
+)
+
+// PriorityBucket is a class of requests with its own concurrency limit.
+type PriorityBucket struct {
+	Name string
+	// Limit is the number of requests of the bucket handled at the same time. It must be at least 1.
+	Limit int
+	// Wait is how long a request waits for a slot in the bucket before being rejected.
+	Wait time.Duration
+}
+
+// PriorityAdmission sorts requests into buckets that each limit concurrency separately, so that a flood of
+// requests of one kind can't block requests of another.
+type PriorityAdmission struct {
+	// Classify returns the name of the bucket of the request. Requests that don't match a bucket are not limited.
+	Classify func(r *http.Request) string
+	buckets  map[string]semaphore
+	waits    map[string]time.Duration
+}
+
+// NewPriorityAdmission returns a PriorityAdmission with the given buckets, using classify to sort requests into
+// them. If classify is nil, ClassifyReadsWrites is used. It panics if the limit of a bucket is less than 1.
+func NewPriorityAdmission(classify func(r *http.Request) string, buckets ...PriorityBucket) *PriorityAdmission {
+	if classify == nil {
+		classify = ClassifyReadsWrites
+	}
+	p := &PriorityAdmission{
+		Classify: classify,
+		buckets:  map[string]semaphore{},
+		waits:    map[string]time.Duration{},
+	}
+	for _, bucket := range buckets {
+		p.buckets[bucket.Name] = newSemaphore(bucket.Limit)
+		p.waits[bucket.Name] = bucket.Wait
+	}
+	return p
+}
+
+// NewReadWritePriorityAdmission returns a PriorityAdmission limiting reads and writes separately.
+func NewReadWritePriorityAdmission(readLimit, writeLimit int, wait time.Duration) *PriorityAdmission {
+	return NewPriorityAdmission(ClassifyReadsWrites,
+		PriorityBucket{Name: ReadsBucket, Limit: readLimit, Wait: wait},
+		PriorityBucket{Name: WritesBucket, Limit: writeLimit, Wait: wait},
+	)
+}
+
+// ClassifyReadsWrites puts GET, HEAD and OPTIONS requests in the reads bucket and all others in the writes bucket.
+// Subscriptions, websocket upgrades and server-sent event streams alike, are not put in a bucket, as they would
+// hold on to a slot for as long as they are open.
+func ClassifyReadsWrites(r *http.Request) string {
+	if isSubscription(r) {
+		return ""
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ReadsBucket
+	default:
+		return WritesBucket
+	}
+}
+
+// isSubscription returns whether r opens a websocket or asks for a server-sent event stream.
+func isSubscription(r *http.Request) bool {
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return true
+	}
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), "text/event-stream") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Middleware wraps handler with the admission control.
+func (p *PriorityAdmission) Middleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := p.Classify(r)
+		slots, ok := p.buckets[name]
+		if !ok {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		if !slots.acquire(r, p.waits[name]) {
+			tooManyRequests(w, r)
+			return
+		}
+		defer slots.release()
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveMethod(handler http.Handler, method string, header http.Header) int {
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(method, "/v1/pods", nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	handler.ServeHTTP(rw, req)
+	return rw.Code
+}
+
+func TestPriorityAdmissionIsolation(t *testing.T) {
+	blocking := newBlockingHandler()
+	handler := NewReadWritePriorityAdmission(1, 1, 0).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("block") == "true" {
+			blocking.ServeHTTP(w, r)
+		}
+	}))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/pods?block=true", nil))
+	}()
+	<-blocking.started
+
+	// the reads bucket is full, but writes and watches are unaffected
+	assert.Equal(t, http.StatusServiceUnavailable, serveMethod(handler, http.MethodGet, nil))
+	assert.Equal(t, http.StatusOK, serveMethod(handler, http.MethodPost, nil))
+	assert.Equal(t, http.StatusOK, serveMethod(handler, http.MethodGet, http.Header{"Upgrade": {"websocket"}}))
+	assert.Equal(t, http.StatusOK, serveMethod(handler, http.MethodGet, http.Header{"Accept": {"application/json, text/event-stream;q=0.9"}}))
+
+	close(blocking.release)
+	wg.Wait()
+	assert.Equal(t, http.StatusOK, serveMethod(handler, http.MethodGet, nil))
+}
+
+func TestPriorityAdmissionClassify(t *testing.T) {
+	blocking := newBlockingHandler()
+	admission := NewPriorityAdmission(func(r *http.Request) string {
+		if r.URL.Query().Get("watch") == "true" {
+			return "watches"
+		}
+		return "lists"
+	}, PriorityBucket{Name: "lists", Limit: 1}, PriorityBucket{Name: "watches", Limit: 1})
+	handler := admission.Middleware(blocking)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serve(handler)
+	}()
+	<-blocking.started
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/pods", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+
+	// the watch is admitted while the lists bucket is full
+	watchDone := make(chan struct{})
+	go func() {
+		defer close(watchDone)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/pods?watch=true", nil))
+	}()
+	<-blocking.started
+
+	close(blocking.release)
+	<-done
+	<-watchDone
+}
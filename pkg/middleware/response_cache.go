@@ -0,0 +1,240 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// ResponseCache is a middleware that caches the serialized response of successful GET requests for a fixed TTL and
+// serves it to subsequent identical requests. Requests are considered identical if they have the same method, path,
+// query, Accept and Accept-Encoding headers, as well as the same value returned by KeyFunc, if set.
+//
+// A cached response is served without running the handler, so access control and stores filtering by user are
+// skipped: a ResponseCache placed behind authentication must set a KeyFunc identifying the caller. Without one,
+// requests that identify their caller, through an Authorization, Proxy-Authorization or Cookie header, a TLS client
+// certificate or a user in the context such as the one added by WithUser, are never cached. This is a safeguard
+// only, as a cache placed before the authentication middleware sees no user.
+//
+// Subscriptions, websocket upgrades and server-sent event streams alike, are never cached, nor are responses larger
+// than MaxEntrySize. Set-Cookie headers are never cached, so a cookie set for one client is not handed to others.
+type ResponseCache struct {
+	// TTL is how long a cached response is served before the request is passed to the handler again.
+	TTL time.Duration
+	// MaxEntries bounds the number of cached responses. The least recently used response is evicted when the
+	// limit is reached.
+	MaxEntries int
+	// KeyFunc, if set, is added to the cache key. Responses that differ by caller, such as those filtered by
+	// access control, must set it to something identifying the caller so they are not served to other users.
+	// Requests identifying their caller are only cached when it is set.
+	KeyFunc func(req *http.Request) string
+	// MaxEntrySize is the size in bytes above which a response body is not cached. DefaultMaxEntrySize is used if
+	// it is zero or less.
+	MaxEntrySize int
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+// DefaultMaxEntrySize is the size of the largest response body a ResponseCache caches when MaxEntrySize is not set.
+const DefaultMaxEntrySize = 1 << 20
+
+type cachedResponse struct {
+	key     string
+	expires time.Time
+	status  int
+	header  http.Header
+	body    []byte
+}
+
+// NewResponseCache returns a ResponseCache that caches up to maxEntries responses for the duration of ttl.
+func NewResponseCache(ttl time.Duration, maxEntries int) *ResponseCache {
+	return &ResponseCache{
+		TTL:        ttl,
+		MaxEntries: maxEntries,
+	}
+}
+
+// Middleware wraps handler with the cache.
+func (c *ResponseCache) Middleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || noStore(r.Header) || isSubscription(r) || (c.KeyFunc == nil && hasCredentials(r)) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		key := c.key(r)
+		if resp := c.get(key); resp != nil {
+			for k, v := range resp.header {
+				w.Header()[k] = append([]string(nil), v...)
+			}
+			w.WriteHeader(resp.status)
+			_, _ = w.Write(resp.body)
+			return
+		}
+
+		maxSize := c.MaxEntrySize
+		if maxSize <= 0 {
+			maxSize = DefaultMaxEntrySize
+		}
+		rw := &cachingResponseWriter{ResponseWriter: w, maxSize: maxSize}
+		handler.ServeHTTP(rw, r)
+
+		if rw.hijacked || rw.tooLarge || rw.status != http.StatusOK || noStore(w.Header()) {
+			return
+		}
+		header := w.Header().Clone()
+		header.Del("Set-Cookie")
+		c.add(&cachedResponse{
+			key:     key,
+			expires: time.Now().Add(c.TTL),
+			status:  rw.status,
+			header:  header,
+			body:    rw.body.Bytes(),
+		})
+	})
+}
+
+// Purge removes all cached responses.
+func (c *ResponseCache) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries = nil
+	c.lru = nil
+}
+
+func (c *ResponseCache) key(r *http.Request) string {
+	key := strings.Join([]string{
+		r.Method,
+		r.URL.RequestURI(),
+		r.Header.Get("Accept"),
+		r.Header.Get("Accept-Encoding"),
+	}, "\x00")
+	if c.KeyFunc != nil {
+		key += "\x00" + c.KeyFunc(r)
+	}
+	return key
+}
+
+func (c *ResponseCache) get(key string) *cachedResponse {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	resp := elem.Value.(*cachedResponse)
+	if time.Now().After(resp.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	return resp
+}
+
+func (c *ResponseCache) add(resp *cachedResponse) {
+	if c.MaxEntries <= 0 || c.TTL <= 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[string]*list.Element{}
+		c.lru = list.New()
+	}
+	if elem, ok := c.entries[resp.key]; ok {
+		elem.Value = resp
+		c.lru.MoveToFront(elem)
+		return
+	}
+	for c.lru.Len() >= c.MaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResponse).key)
+	}
+	c.entries[resp.key] = c.lru.PushFront(resp)
+}
+
+// hasCredentials returns whether r identifies its caller, whose response may then differ from other callers'.
+func hasCredentials(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" || r.Header.Get("Proxy-Authorization") != "" || r.Header.Get("Cookie") != "" {
+		return true
+	}
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		return true
+	}
+	_, ok := request.UserFrom(r.Context())
+	return ok
+}
+
+func noStore(header http.Header) bool {
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cachingResponseWriter writes the response through to the client while keeping a copy of it for the cache. The
+// copy is dropped once it grows past maxSize.
+type cachingResponseWriter struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	maxSize  int
+	tooLarge bool
+	hijacked bool
+}
+
+func (c *cachingResponseWriter) WriteHeader(statusCode int) {
+	if c.status == 0 {
+		c.status = statusCode
+	}
+	c.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (c *cachingResponseWriter) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	if !c.tooLarge {
+		if c.body.Len()+len(b) > c.maxSize {
+			c.tooLarge = true
+			c.body = bytes.Buffer{}
+		} else {
+			c.body.Write(b)
+		}
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+func (c *cachingResponseWriter) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (c *cachingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	c.hijacked = true
+	if hijacker, ok := c.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, fmt.Errorf("Upstream ResponseWriter of type %v does not implement http.Hijacker", reflect.TypeOf(c.ResponseWriter))
+}
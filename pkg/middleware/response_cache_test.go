@@ -0,0 +1,249 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+type countingHandler struct {
+	calls  int
+	status int
+}
+
+func (c *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.calls++
+	if c.status != 0 {
+		w.WriteHeader(c.status)
+	}
+	fmt.Fprintf(w, "response %d", c.calls)
+}
+
+func serveCached(handler http.Handler, method, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, req)
+	return rw
+}
+
+func TestResponseCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		status    int
+		header    http.Header
+		wantCalls int
+	}{
+		{
+			name:      "successful GET is cached",
+			method:    http.MethodGet,
+			wantCalls: 1,
+		},
+		{
+			name:      "POST is not cached",
+			method:    http.MethodPost,
+			wantCalls: 2,
+		},
+		{
+			name:      "failed GET is not cached",
+			method:    http.MethodGet,
+			status:    http.StatusNotFound,
+			wantCalls: 2,
+		},
+		{
+			name:      "authorized GET is not cached without a key func",
+			method:    http.MethodGet,
+			header:    http.Header{"Authorization": {"Bearer token"}},
+			wantCalls: 2,
+		},
+		{
+			name:      "GET with cookies is not cached without a key func",
+			method:    http.MethodGet,
+			header:    http.Header{"Cookie": {"R_SESS=token"}},
+			wantCalls: 2,
+		},
+		{
+			name:      "server-sent event stream is not cached",
+			method:    http.MethodGet,
+			header:    http.Header{"Accept": {"text/event-stream"}},
+			wantCalls: 2,
+		},
+		{
+			name:      "websocket upgrade is not cached",
+			method:    http.MethodGet,
+			header:    http.Header{"Upgrade": {"websocket"}},
+			wantCalls: 2,
+		},
+		{
+			name:      "GET with proxy credentials is not cached without a key func",
+			method:    http.MethodGet,
+			header:    http.Header{"Proxy-Authorization": {"Basic dXNlcg=="}},
+			wantCalls: 2,
+		},
+		{
+			name:      "client no-store is respected",
+			method:    http.MethodGet,
+			header:    http.Header{"Cache-Control": {"no-cache, no-store"}},
+			wantCalls: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next := &countingHandler{status: test.status}
+			handler := NewResponseCache(time.Minute, 10).Middleware(next)
+
+			first := serveCached(handler, test.method, "/v1/pods?limit=5", test.header)
+			second := serveCached(handler, test.method, "/v1/pods?limit=5", test.header)
+
+			assert.Equal(t, test.wantCalls, next.calls)
+			assert.Equal(t, first.Code, second.Code)
+			if test.wantCalls == 1 {
+				assert.Equal(t, first.Body.String(), second.Body.String())
+			}
+		})
+	}
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	next := &countingHandler{}
+	handler := NewResponseCache(time.Minute, 10).Middleware(next)
+
+	serveCached(handler, http.MethodGet, "/v1/pods", nil)
+	serveCached(handler, http.MethodGet, "/v1/pods?limit=5", nil)
+	serveCached(handler, http.MethodGet, "/v1/pods", http.Header{"Accept": {"application/yaml"}})
+	serveCached(handler, http.MethodGet, "/v1/pods", http.Header{"Accept-Encoding": {"gzip"}})
+	serveCached(handler, http.MethodGet, "/v1/pods", nil)
+
+	assert.Equal(t, 4, next.calls)
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	next := &countingHandler{}
+	handler := NewResponseCache(time.Millisecond, 10).Middleware(next)
+
+	serveCached(handler, http.MethodGet, "/v1/pods", nil)
+	time.Sleep(5 * time.Millisecond)
+	rw := serveCached(handler, http.MethodGet, "/v1/pods", nil)
+
+	assert.Equal(t, 2, next.calls)
+	assert.Equal(t, "response 2", rw.Body.String())
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	next := &countingHandler{}
+	handler := NewResponseCache(time.Minute, 2).Middleware(next)
+
+	serveCached(handler, http.MethodGet, "/v1/a", nil)
+	serveCached(handler, http.MethodGet, "/v1/b", nil)
+	// a is now more recently used than b, so b is evicted when c is added
+	serveCached(handler, http.MethodGet, "/v1/a", nil)
+	serveCached(handler, http.MethodGet, "/v1/c", nil)
+	assert.Equal(t, 3, next.calls)
+
+	serveCached(handler, http.MethodGet, "/v1/a", nil)
+	assert.Equal(t, 3, next.calls)
+	serveCached(handler, http.MethodGet, "/v1/b", nil)
+	assert.Equal(t, 4, next.calls)
+}
+
+func TestResponseCachePurge(t *testing.T) {
+	next := &countingHandler{}
+	cache := NewResponseCache(time.Minute, 10)
+	handler := cache.Middleware(next)
+
+	serveCached(handler, http.MethodGet, "/v1/pods", nil)
+	cache.Purge()
+	serveCached(handler, http.MethodGet, "/v1/pods", nil)
+
+	assert.Equal(t, 2, next.calls)
+}
+
+func TestResponseCacheCredentialsWithKeyFunc(t *testing.T) {
+	next := &countingHandler{}
+	cache := NewResponseCache(time.Minute, 10)
+	cache.KeyFunc = func(req *http.Request) string {
+		return req.Header.Get("Authorization")
+	}
+	handler := cache.Middleware(next)
+
+	alice := http.Header{"Authorization": {"Bearer alice"}}
+	serveCached(handler, http.MethodGet, "/v1/pods", alice)
+	serveCached(handler, http.MethodGet, "/v1/pods", alice)
+	serveCached(handler, http.MethodGet, "/v1/pods", http.Header{"Authorization": {"Bearer bob"}})
+
+	assert.Equal(t, 2, next.calls)
+}
+
+func TestResponseCacheSetCookie(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "CSRF", Value: "secret"})
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+	})
+	handler := NewResponseCache(time.Minute, 10).Middleware(next)
+
+	first := serveCached(handler, http.MethodGet, "/v1/pods", nil)
+	assert.NotEmpty(t, first.Header().Get("Set-Cookie"))
+
+	second := serveCached(handler, http.MethodGet, "/v1/pods", nil)
+	assert.Empty(t, second.Header().Get("Set-Cookie"))
+	assert.Equal(t, "application/json", second.Header().Get("Content-Type"))
+	assert.Equal(t, "{}", second.Body.String())
+}
+
+func TestResponseCacheIdentity(t *testing.T) {
+	tests := []struct {
+		name     string
+		identify func(r *http.Request) *http.Request
+	}{
+		{
+			name: "client certificate",
+			identify: func(r *http.Request) *http.Request {
+				r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{}}}
+				return r
+			},
+		},
+		{
+			name: "user in the context",
+			identify: func(r *http.Request) *http.Request {
+				return r.WithContext(request.WithUser(r.Context(), &user.DefaultInfo{Name: "alice"}))
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next := &countingHandler{}
+			handler := NewResponseCache(time.Minute, 10).Middleware(next)
+
+			for i := 0; i < 2; i++ {
+				handler.ServeHTTP(httptest.NewRecorder(), test.identify(httptest.NewRequest(http.MethodGet, "/v1/pods", nil)))
+			}
+			assert.Equal(t, 2, next.calls)
+		})
+	}
+}
+
+func TestResponseCacheMaxEntrySize(t *testing.T) {
+	next := &countingHandler{}
+	cache := NewResponseCache(time.Minute, 10)
+	cache.MaxEntrySize = len("response 1") - 1
+	handler := cache.Middleware(next)
+
+	first := serveCached(handler, http.MethodGet, "/v1/pods", nil)
+	second := serveCached(handler, http.MethodGet, "/v1/pods", nil)
+
+	assert.Equal(t, 2, next.calls)
+	assert.Equal(t, "response 1", first.Body.String())
+	assert.Equal(t, "response 2", second.Body.String())
+}
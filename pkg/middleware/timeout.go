@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/apierror"
+)
+
+// Timeout returns a middleware bounding the time a handler has to start its response. If the handler has not
+// written anything after d, the context of the request is canceled, so stores can stop, and the client is sent a
+// 504 with an apierror.Timeout error, written with WriteError. Anything the handler writes afterwards is discarded.
+//
+// Responses the handler started in time are left alone, as are hijacked connections, so event streams and
+// websockets of subscriptions last as long as they need.
+func Timeout(d time.Duration) mux.MiddlewareFunc {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{rw: w, header: w.Header().Clone()}
+			done := make(chan struct{})
+			panics := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panics <- p
+					}
+				}()
+				handler.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			timer := time.NewTimer(d)
+			defer timer.Stop()
+			select {
+			case <-done:
+				return
+			case p := <-panics:
+				panic(p)
+			case <-timer.C:
+			}
+
+			tw.lock.Lock()
+			if tw.wroteHeader || tw.hijacked {
+				tw.lock.Unlock()
+				// the handler owns the response
+				select {
+				case <-done:
+				case p := <-panics:
+					panic(p)
+				}
+				return
+			}
+			tw.timedOut = true
+			tw.lock.Unlock()
+
+			cancel()
+			WriteError(w, r, apierror.NewAPIError(apierror.Timeout, ""))
+		})
+	}
+}
+
+// timeoutWriter holds the headers of the handler apart from those of the response until the handler writes, so
+// that an error can be written instead without racing with the handler.
+type timeoutWriter struct {
+	rw     http.ResponseWriter
+	header http.Header
+
+	lock        sync.Mutex
+	wroteHeader bool
+	hijacked    bool
+	timedOut    bool
+}
+
+func (t *timeoutWriter) Header() http.Header {
+	return t.header
+}
+
+func (t *timeoutWriter) WriteHeader(statusCode int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.timedOut {
+		return
+	}
+	t.writeHeader(statusCode)
+}
+
+func (t *timeoutWriter) writeHeader(statusCode int) {
+	if t.wroteHeader {
+		return
+	}
+	t.wroteHeader = true
+	header := t.rw.Header()
+	for k := range header {
+		if _, ok := t.header[k]; !ok {
+			delete(header, k)
+		}
+	}
+	for k, v := range t.header {
+		header[k] = v
+	}
+	t.rw.WriteHeader(statusCode)
+}
+
+func (t *timeoutWriter) Write(b []byte) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	t.writeHeader(http.StatusOK)
+	return t.rw.Write(b)
+}
+
+func (t *timeoutWriter) Flush() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.timedOut {
+		return
+	}
+	t.writeHeader(http.StatusOK)
+	if flusher, ok := t.rw.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped ResponseWriter, for http.ResponseController.
+func (t *timeoutWriter) Unwrap() http.ResponseWriter {
+	return t.rw
+}
+
+func (t *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	hijacker, ok := t.rw.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("Upstream ResponseWriter of type %v does not implement http.Hijacker", reflect.TypeOf(t.rw))
+	}
+	t.hijacked = true
+	return hijacker.Hijack()
+}
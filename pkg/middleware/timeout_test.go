@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout(t *testing.T) {
+	canceled := make(chan error, 1)
+	hanging := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		// a store that only returns once the request is canceled
+		<-req.Context().Done()
+		canceled <- req.Context().Err()
+		rw.Header().Set("X-Late", "true")
+		_, err := rw.Write([]byte("too late"))
+		assert.ErrorIs(t, err, http.ErrHandlerTimeout)
+	})
+
+	rw := httptest.NewRecorder()
+	rw.Header().Set("X-Frame-Options", "SAMEORIGIN")
+	Timeout(20*time.Millisecond)(hanging).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/pods", nil))
+
+	assert.Equal(t, http.StatusGatewayTimeout, rw.Code)
+	assert.Equal(t, "application/json", rw.Header().Get("Content-Type"))
+	assert.Equal(t, "SAMEORIGIN", rw.Header().Get("X-Frame-Options"))
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &body))
+	assert.Equal(t, "Timeout", body["code"])
+	assert.Equal(t, float64(http.StatusGatewayTimeout), body["status"])
+
+	select {
+	case err := <-canceled:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request context is not canceled")
+	}
+	assert.Empty(t, rw.Header().Get("X-Late"))
+	assert.NotContains(t, rw.Body.String(), "too late")
+}
+
+func TestTimeoutInTime(t *testing.T) {
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain")
+		rw.WriteHeader(http.StatusCreated)
+		_, _ = rw.Write([]byte("created"))
+	})
+
+	rw := httptest.NewRecorder()
+	Timeout(time.Second)(handler).ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/v1/pods", nil))
+
+	assert.Equal(t, http.StatusCreated, rw.Code)
+	assert.Equal(t, "text/plain", rw.Header().Get("Content-Type"))
+	assert.Equal(t, "created", rw.Body.String())
+}
+
+func TestTimeoutStartedResponse(t *testing.T) {
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		// an event stream writes right away and keeps going past the timeout
+		_, _ = rw.Write([]byte("event: resource.start\n\n"))
+		rw.(http.Flusher).Flush()
+		time.Sleep(50 * time.Millisecond)
+		assert.NoError(t, req.Context().Err())
+		_, _ = rw.Write([]byte("event: resource.change\n\n"))
+	})
+
+	rw := httptest.NewRecorder()
+	Timeout(10*time.Millisecond)(handler).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/subscribe", nil))
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "event: resource.start\n\nevent: resource.change\n\n", rw.Body.String())
+}
+
+func TestTimeoutHijacked(t *testing.T) {
+	handler := Timeout(10 * time.Millisecond)(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		conn, buf, err := rw.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		// a websocket outlives the timeout
+		time.Sleep(50 * time.Millisecond)
+		assert.NoError(t, req.Context().Err())
+		_, _ = buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 6\r\nConnection: close\r\n\r\nhello\n")
+		_ = buf.Flush()
+	}))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(bufio.NewReader(resp.Body))
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(body))
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	handler := Timeout(time.Second)(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/pods", nil))
+	})
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+)
+
+// DefaultTLSExemptPaths are the health check paths that RequireTLS serves over plain HTTP, since probes usually
+// can not use TLS.
+var DefaultTLSExemptPaths = []string{"/healthz", "/livez", "/readyz", "/ping"}
+
+// RequireTLS is a middleware that only lets requests made over TLS through. Other requests are rejected with a 403,
+// or redirected to the https URL.
+type RequireTLS struct {
+	// Redirect sends a 308 to the same URL with the https scheme instead of rejecting the request.
+	Redirect bool
+	// TrustForwardedProto considers requests with an X-Forwarded-Proto of https or wss to be made over TLS. It
+	// must only be set when the server is behind a proxy that terminates TLS and sets the header.
+	TrustForwardedProto bool
+	// ExemptPaths are served without TLS. Paths are matched exactly.
+	ExemptPaths []string
+}
+
+// NewRequireTLS returns a RequireTLS that exempts DefaultTLSExemptPaths, and redirects instead of rejecting if
+// redirect is set.
+func NewRequireTLS(redirect bool) *RequireTLS {
+	return &RequireTLS{
+		Redirect:    redirect,
+		ExemptPaths: DefaultTLSExemptPaths,
+	}
+}
+
+func (t *RequireTLS) Middleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t.isTLS(r) || t.exempt(r) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		if !t.Redirect {
+			WriteError(w, r, apierror.NewAPIError(validation.PermissionDenied, "TLS is required"))
+			return
+		}
+		target := "https://" + r.Host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
+
+func (t *RequireTLS) isTLS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	if !t.TrustForwardedProto {
+		return false
+	}
+	proto := strings.TrimSpace(strings.Split(r.Header.Get("X-Forwarded-Proto"), ",")[0])
+	return strings.EqualFold(proto, "https") || strings.EqualFold(proto, "wss")
+}
+
+func (t *RequireTLS) exempt(r *http.Request) bool {
+	for _, path := range t.ExemptPaths {
+		if r.URL.Path == path {
+			return true
+		}
+	}
+	return false
+}
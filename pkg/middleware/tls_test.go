@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireTLS(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		redirect       bool
+		trustForwarded bool
+		target         string
+		tls            bool
+		forwardedProto string
+		wantStatus     int
+		wantLocation   string
+	}{
+		{
+			name:       "tls",
+			target:     "https://example.com/v1/pods",
+			tls:        true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "reject plain http",
+			target:     "http://example.com/v1/pods",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:         "redirect plain http",
+			redirect:     true,
+			target:       "http://example.com/v1/pods?limit=10",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "https://example.com/v1/pods?limit=10",
+		},
+		{
+			name:       "health check exempt",
+			target:     "http://example.com/healthz",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:           "trusted forwarded proto",
+			trustForwarded: true,
+			target:         "http://example.com/v1/pods",
+			forwardedProto: "https",
+			wantStatus:     http.StatusOK,
+		},
+		{
+			name:           "trusted forwarded proto of plain http",
+			trustForwarded: true,
+			target:         "http://example.com/v1/pods",
+			forwardedProto: "http",
+			wantStatus:     http.StatusForbidden,
+		},
+		{
+			name:           "untrusted forwarded proto",
+			target:         "http://example.com/v1/pods",
+			forwardedProto: "https",
+			wantStatus:     http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireTLS := NewRequireTLS(tt.redirect)
+			requireTLS.TrustForwardedProto = tt.trustForwarded
+
+			req := httptest.NewRequest(http.MethodPost, tt.target, nil)
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			} else {
+				req.TLS = nil
+			}
+			if tt.forwardedProto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.forwardedProto)
+			}
+			rw := httptest.NewRecorder()
+			requireTLS.Middleware(ok).ServeHTTP(rw, req)
+
+			assert.Equal(t, tt.wantStatus, rw.Code)
+			assert.Equal(t, tt.wantLocation, rw.Header().Get("Location"))
+		})
+	}
+}
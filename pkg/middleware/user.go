@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// WithUserMiddleware returns a middleware adding the user returned by extract to the request context with
+// request.WithUser, where stores and handlers read it through APIRequest.GetUserInfo. extract returns nil for
+// unauthenticated requests, which are passed on without a user. See WithUser.
+func WithUserMiddleware(extract func(*http.Request) user.Info) mux.MiddlewareFunc {
+	return func(handler http.Handler) http.Handler {
+		return WithUser(handler, extract)
+	}
+}
+
+// WithUser adds the user returned by extract, typically the result of authenticating the request, to the request
+// context. Stores and handlers get it from APIRequest.GetUserInfo. Requests for which extract returns nil are
+// passed through unchanged.
+func WithUser(handler http.Handler, extract func(*http.Request) user.Info) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info := extract(r); info != nil {
+			r = r.WithContext(request.WithUser(r.Context(), info))
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
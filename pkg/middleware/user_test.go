@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/server"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+type userRecordingStore struct {
+	empty.Store
+	user  user.Info
+	found bool
+}
+
+func (u *userRecordingStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	u.user, u.found = apiOp.GetUserInfo()
+	return types.APIObjectList{}, nil
+}
+
+type userFoo struct{}
+
+func TestWithUser(t *testing.T) {
+	extract := func(r *http.Request) user.Info {
+		name := r.Header.Get("X-Test-User")
+		if name == "" {
+			return nil
+		}
+		return &user.DefaultInfo{Name: name, Groups: []string{"system:authenticated"}}
+	}
+
+	tests := []struct {
+		name      string
+		header    string
+		wantFound bool
+		wantUser  string
+	}{
+		{name: "authenticated", header: "alice", wantFound: true, wantUser: "alice"},
+		{name: "anonymous"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &userRecordingStore{}
+			srv := server.DefaultAPIServer()
+			server.RegisterResource(srv.Schemas, userFoo{}, store, []string{http.MethodGet}, nil)
+
+			handler := WithUserMiddleware(extract)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				srv.Handle(&types.APIRequest{Request: r, Response: w, Type: "userFoo"})
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/userfoos", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Test-User", tt.header)
+			}
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, req)
+
+			require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+			assert.Equal(t, tt.wantFound, store.found)
+			if tt.wantFound {
+				assert.Equal(t, tt.wantUser, store.user.GetName())
+				assert.Equal(t, []string{"system:authenticated"}, store.user.GetGroups())
+			}
+		})
+	}
+}
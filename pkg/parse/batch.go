@@ -0,0 +1,77 @@
+package parse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+)
+
+// DecodeBatch reads the JSON array in the body of req one element at a time, calling item with the index and raw
+// JSON of each element as soon as it is decoded, so the array is never held in memory as a whole.
+//
+// Each element is limited to the size of a request body, and a larger element fails the batch with a
+// RequestEntityTooLarge error, so that memory stays bounded whatever the size of the array.
+//
+// An error is returned if the body is not an array. If the array turns out to be malformed after some elements
+// were passed to item, the error is returned along with the index of the element that could not be decoded.
+func DecodeBatch(req *http.Request, item func(index int, raw json.RawMessage)) (int, error) {
+	body := &elementLimitReader{reader: req.Body, limit: maxFormSize}
+	decoder := json.NewDecoder(body)
+	token, err := decoder.Token()
+	if err != nil || token != json.Delim('[') {
+		return 0, apierror.NewAPIError(apierror.BadRequest, "Failed to parse body: a batch must be a JSON array")
+	}
+
+	index := 0
+	for ; decoder.More(); index++ {
+		// the decoder reads no further than one byte past the limit of the element, enough to tell it is too large
+		body.limit = decoder.InputOffset() + maxFormSize + 1
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return index, batchError(err)
+		}
+		if len(raw) > maxFormSize {
+			return index, batchError(errElementTooLarge)
+		}
+		item(index, raw)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return index, batchError(err)
+	}
+	return index, nil
+}
+
+var errElementTooLarge = fmt.Errorf("batch element exceeds the maximum size of %d bytes", maxFormSize)
+
+// elementLimitReader fails reads past limit bytes from the start of the body.
+type elementLimitReader struct {
+	reader io.Reader
+	read   int64
+	limit  int64
+}
+
+func (e *elementLimitReader) Read(p []byte) (int, error) {
+	if e.read >= e.limit {
+		return 0, errElementTooLarge
+	}
+	if int64(len(p)) > e.limit-e.read {
+		p = p[:e.limit-e.read]
+	}
+	n, err := e.reader.Read(p)
+	e.read += int64(n)
+	return n, err
+}
+
+func batchError(err error) error {
+	if errors.Is(err, errElementTooLarge) {
+		return apierror.NewAPIError(apierror.RequestEntityTooLarge, err.Error())
+	}
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("Failed to parse body: %v", err))
+}
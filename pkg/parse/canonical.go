@@ -0,0 +1,49 @@
+package parse
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+)
+
+// CanonicalPath returns path with repeated slashes collapsed and without a trailing slash, such as "/v1/foos" for
+// "/v1//foos/". Dot segments are left alone, as they are rejected rather than resolved.
+func CanonicalPath(path string) string {
+	var b strings.Builder
+	b.Grow(len(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && i > 0 && path[i-1] == '/' {
+			continue
+		}
+		b.WriteByte(path[i])
+	}
+	canonical := b.String()
+	if len(canonical) > 1 {
+		canonical = strings.TrimSuffix(canonical, "/")
+	}
+	return canonical
+}
+
+// CanonicalizePaths wraps parser to permanently redirect requests whose path is not canonical, as returned by
+// CanonicalPath, so every resource is cached under a single URL. The redirect is a 308, so clients repeat the
+// request with the same method and body, and the query is kept.
+func CanonicalizePaths(parser Parser) Parser {
+	return func(apiOp *types.APIRequest, urlParser URLParser) error {
+		if apiOp.Request != nil && apiOp.Response != nil {
+			path := apiOp.Request.URL.EscapedPath()
+			if canonical := CanonicalPath(path); canonical != path {
+				location := canonical
+				if apiOp.Request.URL.RawQuery != "" {
+					location += "?" + apiOp.Request.URL.RawQuery
+				}
+				// not http.Redirect, which would also resolve dot segments
+				apiOp.Response.Header().Set("Location", location)
+				apiOp.Response.WriteHeader(http.StatusPermanentRedirect)
+				return validation.ErrComplete
+			}
+		}
+		return parser(apiOp, urlParser)
+	}
+}
@@ -0,0 +1,95 @@
+package parse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalPath(t *testing.T) {
+	tests := map[string]string{
+		"/":                     "/",
+		"//":                    "/",
+		"/v1/foos":              "/v1/foos",
+		"/v1/foos/":             "/v1/foos",
+		"/v1//foos":             "/v1/foos",
+		"//v1///foos//bar//":    "/v1/foos/bar",
+		"/v1/foos/a%2F%2Fb":     "/v1/foos/a%2F%2Fb",
+		"/v1/foos/./bar":        "/v1/foos/./bar",
+		"/v1/namespaces/ns/foo": "/v1/namespaces/ns/foo",
+	}
+	for path, want := range tests {
+		t.Run(path, func(t *testing.T) {
+			assert.Equal(t, want, CanonicalPath(path))
+		})
+	}
+}
+
+func TestCanonicalizePaths(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		wantLocation string
+	}{
+		{
+			name:   "canonical",
+			method: http.MethodGet,
+			target: "/v1/foos?limit=10",
+		},
+		{
+			name:         "double slash",
+			method:       http.MethodGet,
+			target:       "/v1//foos?limit=10",
+			wantLocation: "/v1/foos?limit=10",
+		},
+		{
+			name:         "trailing slash",
+			method:       http.MethodGet,
+			target:       "/v1/foos/bar/",
+			wantLocation: "/v1/foos/bar",
+		},
+		{
+			name:         "post keeps query",
+			method:       http.MethodPost,
+			target:       "/v1/foos//?action=run&x=%2F",
+			wantLocation: "/v1/foos?action=run&x=%2F",
+		},
+		{
+			name:         "dot segments kept",
+			method:       http.MethodGet,
+			target:       "/v1//foos/../bar",
+			wantLocation: "/v1/foos/../bar",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := false
+			parser := CanonicalizePaths(func(apiOp *types.APIRequest, urlParser URLParser) error {
+				parsed = true
+				return nil
+			})
+
+			resp := httptest.NewRecorder()
+			err := parser(&types.APIRequest{
+				Request:  httptest.NewRequest(tt.method, tt.target, strings.NewReader("{}")),
+				Response: resp,
+			}, MuxURLParser)
+
+			if tt.wantLocation == "" {
+				assert.NoError(t, err)
+				assert.True(t, parsed)
+				return
+			}
+			assert.Equal(t, validation.ErrComplete, err)
+			assert.False(t, parsed)
+			assert.Equal(t, http.StatusPermanentRedirect, resp.Code)
+			assert.Equal(t, tt.wantLocation, resp.Header().Get("Location"))
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package parse
+
+import (
+	"context"
+	"net/http"
+)
+
+const (
+	// DefaultMaxBodyDepth is the maximum nesting depth of objects and arrays accepted in JSON and YAML request
+	// bodies, unless BodyLimits sets another.
+	DefaultMaxBodyDepth = 100
+	// DefaultMaxMultipartParts is the maximum number of fields and files accepted in a multipart/form-data body,
+	// unless BodyLimits sets another.
+	DefaultMaxMultipartParts = 100
+	// DefaultMaxMultipartSize is the maximum combined size in bytes of all parts of a multipart/form-data body,
+	// unless BodyLimits sets another.
+	DefaultMaxMultipartSize int64 = maxFormSize
+)
+
+// BodyLimits are the limits applied when reading request bodies. Fields left zero use the matching default.
+type BodyLimits struct {
+	// MaxDepth is the maximum nesting depth of objects and arrays in JSON and YAML bodies.
+	MaxDepth int
+	// MaxMultipartParts is the maximum number of fields and files in a multipart/form-data body.
+	MaxMultipartParts int
+	// MaxMultipartSize is the maximum combined size in bytes of all parts of a multipart/form-data body.
+	MaxMultipartSize int64
+}
+
+type bodyLimitsKey struct{}
+
+// WithBodyLimits returns a context for requests whose bodies are read with limits.
+func WithBodyLimits(ctx context.Context, limits BodyLimits) context.Context {
+	return context.WithValue(ctx, bodyLimitsKey{}, limits)
+}
+
+func bodyLimits(req *http.Request) BodyLimits {
+	limits, _ := req.Context().Value(bodyLimitsKey{}).(BodyLimits)
+	if limits.MaxDepth <= 0 {
+		limits.MaxDepth = DefaultMaxBodyDepth
+	}
+	if limits.MaxMultipartParts <= 0 {
+		limits.MaxMultipartParts = DefaultMaxMultipartParts
+	}
+	if limits.MaxMultipartSize <= 0 {
+		limits.MaxMultipartSize = DefaultMaxMultipartSize
+	}
+	return limits
+}
@@ -0,0 +1,146 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+const (
+	filterParam   = "filter"
+	sortParam     = "sort"
+	pageParam     = "page"
+	pageSizeParam = "pagesize"
+)
+
+// parseListOptions reads the list options of a request from its query: any number of filter parameters such as
+// filter=spec.replicas=3 or filter=metadata.name!=web, a comma separated sort such as sort=metadata.name,-spec.replicas
+// where a leading "-" sorts in descending order, and the page and pagesize parameters. It returns nil if none are set.
+func parseListOptions(query url.Values) (*types.ListOptions, error) {
+	var (
+		opts types.ListOptions
+		set  bool
+	)
+
+	for _, filter := range query[filterParam] {
+		parsed, err := parseListFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		opts.Filters = append(opts.Filters, parsed)
+		set = true
+	}
+
+	for _, field := range strings.Split(query.Get(sortParam), ",") {
+		descending := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+		if field == "" {
+			continue
+		}
+		opts.Sort = append(opts.Sort, types.ListSort{Field: strings.Split(field, "."), Descending: descending})
+		set = true
+	}
+
+	var err error
+	if opts.Page, err = positiveQuery(query, pageParam); err != nil {
+		return nil, err
+	}
+	if opts.PageSize, err = positiveQuery(query, pageSizeParam); err != nil {
+		return nil, err
+	}
+	if opts.Page > 0 && opts.PageSize == 0 {
+		return nil, apierror.NewAPIError(apierror.BadRequest, "page requires pagesize")
+	}
+	set = set || opts.PageSize > 0
+
+	if !set {
+		return nil, nil
+	}
+	return &opts, nil
+}
+
+func parseListFilter(filter string) (types.ListFilter, error) {
+	modifier := types.ModifierEQ
+	field, value, ok := strings.Cut(filter, "!=")
+	if ok {
+		modifier = types.ModifierNE
+	} else {
+		field, value, ok = strings.Cut(filter, "=")
+	}
+	if !ok || field == "" {
+		return types.ListFilter{}, apierror.NewAPIError(apierror.BadRequest,
+			fmt.Sprintf("invalid filter %q, expected field=value or field!=value", filter))
+	}
+	return types.ListFilter{
+		Field:    strings.Split(field, "."),
+		Modifier: modifier,
+		Value:    value,
+	}, nil
+}
+
+func positiveQuery(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("%s must be a positive integer", name))
+	}
+	return n, nil
+}
+
+// ListOptionsBody reads the list options of a request from its body, for queries too large for a URL. The body is
+// a ListOptions object such as {"filters": [{"field": ["spec", "tier"], "value": "back"}], "sort": [{"field":
+// ["metadata", "name"]}], "page": 2, "pageSize": 50}, the modifier of filters defaulting to "eq". It returns nil if
+// the body is empty.
+func ListOptionsBody(req *http.Request) (*types.ListOptions, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	content, err := io.ReadAll(io.LimitReader(req.Body, maxFormSize))
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("Failed to read body: %v", err))
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, nil
+	}
+
+	opts := &types.ListOptions{}
+	if err := getDecoder(req, bytes.NewReader(content))(opts); err != nil {
+		return nil, apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("Failed to parse body: %v", err))
+	}
+
+	for i, filter := range opts.Filters {
+		if len(filter.Field) == 0 {
+			return nil, apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("filter %d has no field", i))
+		}
+		switch filter.Modifier {
+		case "":
+			opts.Filters[i].Modifier = types.ModifierEQ
+		case types.ModifierEQ, types.ModifierNE:
+		default:
+			return nil, apierror.NewAPIError(apierror.BadRequest,
+				fmt.Sprintf("filter %d has an unsupported modifier %q", i, filter.Modifier))
+		}
+	}
+	for i, sort := range opts.Sort {
+		if len(sort.Field) == 0 {
+			return nil, apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("sort %d has no field", i))
+		}
+	}
+	if opts.Page < 0 || opts.PageSize < 0 {
+		return nil, apierror.NewAPIError(apierror.BadRequest, "page and pageSize must be positive")
+	}
+	if opts.Page > 0 && opts.PageSize == 0 {
+		return nil, apierror.NewAPIError(apierror.BadRequest, "page requires pageSize")
+	}
+	return opts, nil
+}
@@ -0,0 +1,150 @@
+package parse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    *types.ListOptions
+		wantErr string
+	}{
+		{
+			name:  "no options",
+			query: "limit=10",
+		},
+		{
+			name:  "filters",
+			query: "filter=spec.tier=back&filter=metadata.name!=web&filter=label=a=b",
+			want: &types.ListOptions{Filters: []types.ListFilter{
+				{Field: []string{"spec", "tier"}, Modifier: types.ModifierEQ, Value: "back"},
+				{Field: []string{"metadata", "name"}, Modifier: types.ModifierNE, Value: "web"},
+				{Field: []string{"label"}, Modifier: types.ModifierEQ, Value: "a=b"},
+			}},
+		},
+		{
+			name:  "sort",
+			query: "sort=metadata.name,-spec.replicas",
+			want: &types.ListOptions{Sort: []types.ListSort{
+				{Field: []string{"metadata", "name"}},
+				{Field: []string{"spec", "replicas"}, Descending: true},
+			}},
+		},
+		{
+			name:  "page",
+			query: "page=2&pagesize=50",
+			want:  &types.ListOptions{Page: 2, PageSize: 50},
+		},
+		{
+			name:    "invalid filter",
+			query:   "filter=spec.tier",
+			wantErr: `invalid filter "spec.tier", expected field=value or field!=value`,
+		},
+		{
+			name:    "invalid page size",
+			query:   "pagesize=0",
+			wantErr: "pagesize must be a positive integer",
+		},
+		{
+			name:    "page without size",
+			query:   "page=2",
+			wantErr: "page requires pagesize",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			require.NoError(t, err)
+
+			got, err := parseListOptions(query)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Equal(t, apierror.BadRequest, err.(*apierror.APIError).Code)
+				assert.Equal(t, tt.wantErr, err.(*apierror.APIError).Message)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestListOptionsBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        *types.ListOptions
+		wantErr     string
+	}{
+		{
+			name: "empty body",
+		},
+		{
+			name: "json",
+			body: `{"filters": [{"field": ["spec", "tier"], "value": "back"}, {"field": ["name"], "modifier": "ne", "value": "web"}], "sort": [{"field": ["name"]}]}`,
+			want: &types.ListOptions{
+				Filters: []types.ListFilter{
+					{Field: []string{"spec", "tier"}, Modifier: types.ModifierEQ, Value: "back"},
+					{Field: []string{"name"}, Modifier: types.ModifierNE, Value: "web"},
+				},
+				Sort: []types.ListSort{{Field: []string{"name"}}},
+			},
+		},
+		{
+			name:        "yaml",
+			contentType: "application/yaml",
+			body:        "page: 3\npageSize: 20\n",
+			want:        &types.ListOptions{Page: 3, PageSize: 20},
+		},
+		{
+			name:    "filter without field",
+			body:    `{"filters": [{"value": "back"}]}`,
+			wantErr: "filter 0 has no field",
+		},
+		{
+			name:    "unsupported modifier",
+			body:    `{"filters": [{"field": ["name"], "modifier": "in", "value": "a"}]}`,
+			wantErr: `filter 0 has an unsupported modifier "in"`,
+		},
+		{
+			name:    "page without size",
+			body:    `{"page": 2}`,
+			wantErr: "page requires pageSize",
+		},
+		{
+			name:    "malformed",
+			body:    `{"filters": `,
+			wantErr: "Failed to parse body: unexpected EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/things?action=list", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			got, err := ListOptionsBody(req)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Equal(t, apierror.BadRequest, err.(*apierror.APIError).Code)
+				assert.Equal(t, tt.wantErr, err.(*apierror.APIError).Message)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package parse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+func isMultipart(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// readMultipart reads a multipart/form-data body, returning the form fields as the object and the file parts
+// as uploaded files. The number of parts and their combined size are limited by the BodyLimits of the
+// request.
+func readMultipart(req *http.Request) (types.APIObject, []*types.UploadedFile, error) {
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return types.APIObject{}, nil, apierror.NewAPIError(apierror.BadRequest,
+			fmt.Sprintf("Failed to parse body: %v", err))
+	}
+
+	limits := bodyLimits(req)
+	var (
+		values    = map[string][]string{}
+		files     []*types.UploadedFile
+		parts     int
+		remaining = limits.MaxMultipartSize
+	)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return types.APIObject{}, nil, apierror.NewAPIError(apierror.BadRequest,
+				fmt.Sprintf("Failed to parse body: %v", err))
+		}
+
+		parts++
+		if parts > limits.MaxMultipartParts {
+			part.Close()
+			return types.APIObject{}, nil, apierror.NewAPIError(apierror.RequestEntityTooLarge,
+				fmt.Sprintf("Request body exceeds the maximum of %d parts", limits.MaxMultipartParts))
+		}
+
+		content, err := io.ReadAll(io.LimitReader(part, remaining+1))
+		part.Close()
+		if err != nil {
+			return types.APIObject{}, nil, apierror.NewAPIError(apierror.BadRequest,
+				fmt.Sprintf("Failed to parse body: %v", err))
+		}
+
+		remaining -= int64(len(content))
+		if remaining < 0 {
+			return types.APIObject{}, nil, apierror.NewAPIError(apierror.RequestEntityTooLarge,
+				fmt.Sprintf("Request body exceeds the maximum size of %d bytes", limits.MaxMultipartSize))
+		}
+
+		if part.FileName() == "" {
+			values[part.FormName()] = append(values[part.FormName()], string(content))
+			continue
+		}
+
+		files = append(files, &types.UploadedFile{
+			FieldName:   part.FormName(),
+			Filename:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Size:        int64(len(content)),
+			Reader:      bytes.NewReader(content),
+		})
+	}
+
+	return valuesToBody(values), files, nil
+}
@@ -0,0 +1,90 @@
+package parse
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMultipartRequest(t *testing.T, fields map[string]string, files map[string]string) *http.Request {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	for k, v := range fields {
+		require.NoError(t, w.WriteField(k, v))
+	}
+	for name, content := range files {
+		fw, err := w.CreateFormFile(name, name+".txt")
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/certs", buf)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestRequestBodyMultipart(t *testing.T) {
+	apiOp := &types.APIRequest{
+		Request: newMultipartRequest(t, map[string]string{"name": "my-cert"}, map[string]string{"cert": "certificate data"}),
+	}
+
+	obj, err := RequestBody(apiOp)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": []string{"my-cert"}}, obj.Object)
+
+	require.Len(t, apiOp.Files, 1)
+	file := apiOp.Files[0]
+	assert.Equal(t, "cert", file.FieldName)
+	assert.Equal(t, "cert.txt", file.Filename)
+	assert.Equal(t, "application/octet-stream", file.ContentType)
+	assert.Equal(t, int64(len("certificate data")), file.Size)
+	content, err := io.ReadAll(file.Reader)
+	require.NoError(t, err)
+	assert.Equal(t, "certificate data", string(content))
+}
+
+func TestRequestBodyMultipartTooLarge(t *testing.T) {
+	apiOp := &types.APIRequest{
+		Request: newMultipartRequest(t, nil, map[string]string{"cert": strings.Repeat("a", int(DefaultMaxMultipartSize)+1)}),
+	}
+
+	_, err := RequestBody(apiOp)
+	require.Error(t, err)
+	assert.Equal(t, apierror.RequestEntityTooLarge, err.(*apierror.APIError).Code)
+	assert.Empty(t, apiOp.Files)
+}
+
+func TestRequestBodyMultipartTooManyParts(t *testing.T) {
+	req := newMultipartRequest(t, map[string]string{"a": "1", "b": "2", "c": "3"}, nil)
+	apiOp := &types.APIRequest{
+		Request: req.WithContext(WithBodyLimits(req.Context(), BodyLimits{MaxMultipartParts: 2})),
+	}
+
+	_, err := RequestBody(apiOp)
+	require.Error(t, err)
+	assert.Equal(t, apierror.RequestEntityTooLarge, err.(*apierror.APIError).Code)
+}
+
+func TestRequestBodyMultipartConfiguredSize(t *testing.T) {
+	req := newMultipartRequest(t, map[string]string{"name": "0123456789"}, map[string]string{"cert": "x"})
+	apiOp := &types.APIRequest{
+		Request: req.WithContext(WithBodyLimits(req.Context(), BodyLimits{MaxMultipartSize: 10})),
+	}
+
+	_, err := RequestBody(apiOp)
+	require.Error(t, err)
+	assert.Equal(t, apierror.RequestEntityTooLarge, err.(*apierror.APIError).Code)
+}
@@ -5,21 +5,39 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/rancher/apiserver/pkg/apierror"
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/apiserver/pkg/urlbuilder"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
 )
 
 const (
 	maxFormSize = 2 * 1 << 20
+
+	// rawMediaType asks for the raw content of resources that can be downloaded.
+	rawMediaType = "application/octet-stream"
 )
 
 var (
 	allowedFormats = map[string]bool{
+		"csv":   true,
 		"html":  true,
 		"json":  true,
 		"jsonl": true,
+		"raw":   true,
 		"yaml":  true,
 	}
+
+	// formatAliases maps alternative _format values to the format they are written with.
+	formatAliases = map[string]string{
+		"ndjson": "jsonl",
+	}
+
+	jsonlMediaTypes = []string{
+		"application/jsonl",
+		"application/x-ndjson",
+		"application/ndjson",
+	}
 )
 
 type ParsedURL struct {
@@ -86,6 +104,17 @@ func Parse(apiOp *types.APIRequest, urlParser URLParser) error {
 		apiOp.Namespace = parsedURL.Namespace
 	}
 
+	// The schema is looked up before checking the error so that the requested resource is known to error
+	// handling, logging and metrics even if the request fails.
+	requestedType := apiOp.Type
+	if apiOp.Schema == nil && apiOp.Schemas != nil {
+		apiOp.Schema = apiOp.Schemas.LookupSchema(apiOp.Type)
+	}
+
+	if apiOp.Schema != nil {
+		apiOp.Type = apiOp.Schema.ID
+	}
+
 	if apiOp.URLBuilder == nil {
 		// make error local to not override the outer error we have yet to check
 		var err error
@@ -95,24 +124,51 @@ func Parse(apiOp *types.APIRequest, urlParser URLParser) error {
 		if err != nil {
 			return err
 		}
+		if apiOp.URLBuilderFunc != nil {
+			builder, err := apiOp.URLBuilderFunc(apiOp)
+			if err != nil {
+				// the default builder is kept so that the error can be written
+				return err
+			}
+			apiOp.URLBuilder = builder
+		}
 	}
 
 	if err != nil {
 		return err
 	}
 
-	if apiOp.Schema == nil && apiOp.Schemas != nil {
-		apiOp.Schema = apiOp.Schemas.LookupSchema(apiOp.Type)
-	}
-
-	if apiOp.Schema != nil {
-		apiOp.Type = apiOp.Schema.ID
+	if apiOp.Schema != nil && apiOp.Schemas != nil {
+		if alias, ok := apiOp.Schemas.LookupAlias(requestedType); ok && alias.Redirect {
+			return redirectAlias(apiOp)
+		}
 	}
 
 	if apiOp.Schema != nil && apiOp.ErrorHandler != nil {
 		apiOp.ErrorHandler = apiOp.Schema.ErrorHandler
 	}
 
+	if apiOp.ListOptions == nil && apiOp.Method == http.MethodGet && apiOp.Name == "" && apiOp.Link == "" {
+		if apiOp.ListOptions, err = parseListOptions(apiOp.Query); err != nil {
+			return err
+		}
+	}
+
+	switch apiOp.Method {
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		if apiOp.IfUnmodifiedSince.IsZero() {
+			apiOp.IfUnmodifiedSince = parseIfUnmodifiedSince(apiOp.Request)
+		}
+	}
+
+	// a client waiting for a 100 Continue only sends the body once it is read, which must not happen before the
+	// request is authorized
+	if !ExpectsContinue(apiOp.Request) {
+		if err := ParseBody(apiOp); err != nil {
+			return err
+		}
+	}
+
 	if err := ValidateMethod(apiOp); err != nil {
 		return err
 	}
@@ -120,11 +176,64 @@ func Parse(apiOp *types.APIRequest, urlParser URLParser) error {
 	return nil
 }
 
+// ParseBody reads the parts of the request that come from the body: the preconditions of a delete, and the JSON
+// Patch of an action. Parse calls it, unless the request expects a 100 Continue, in which case it is up to the
+// caller to call it once the request is authorized. Parts that were already read are not read again.
+func ParseBody(apiOp *types.APIRequest) error {
+	var err error
+	if apiOp.Method == http.MethodDelete && apiOp.Preconditions == nil {
+		if apiOp.Preconditions, err = parsePreconditions(apiOp.Request); err != nil {
+			return err
+		}
+	}
+
+	if apiOp.Action != "" && apiOp.Method == http.MethodPost && apiOp.Patch == nil && IsJSONPatch(apiOp.Request) {
+		if apiOp.Patch, err = parseJSONPatch(apiOp.Request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExpectsContinue returns whether the client waits for a 100 Continue before sending the body of the request.
+// The interim response is sent when the body is first read.
+func ExpectsContinue(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Expect"), "100-continue")
+}
+
+// redirectAlias sends a permanent redirect from a request to an aliased type to the same resource of the canonical
+// schema, keeping the query.
+func redirectAlias(apiOp *types.APIRequest) error {
+	location := apiOp.URLBuilder.Collection(apiOp.Schema)
+	if apiOp.Name != "" {
+		id := apiOp.Name
+		if apiOp.Namespace != "" {
+			id = apiOp.Namespace + "/" + id
+		}
+		location = apiOp.URLBuilder.ResourceLink(apiOp.Schema, id)
+	}
+	if apiOp.Request.URL.RawQuery != "" {
+		location += "?" + apiOp.Request.URL.RawQuery
+	}
+
+	http.Redirect(apiOp.Response, apiOp.Request, location, http.StatusPermanentRedirect)
+	return validation.ErrComplete
+}
+
+// ResponseFormat returns the format the response to req is written in, from its _format query parameter or its
+// Accept header, for code writing responses before the request is parsed.
+func ResponseFormat(req *http.Request) string {
+	return parseResponseFormat(req)
+}
+
 func parseResponseFormat(req *http.Request) string {
 	format := req.URL.Query().Get("_format")
 
 	if format != "" {
 		format = strings.TrimSpace(strings.ToLower(format))
+		if alias, ok := formatAliases[format]; ok {
+			format = alias
+		}
 	}
 
 	/* Format specified */
@@ -145,6 +254,14 @@ func parseResponseFormat(req *http.Request) string {
 		return "jsonl"
 	}
 
+	if isCSV(req) {
+		return "csv"
+	}
+
+	if strings.Contains(req.Header.Get("Accept"), rawMediaType) {
+		return "raw"
+	}
+
 	return "json"
 }
 
@@ -152,8 +269,18 @@ func isYaml(req *http.Request) bool {
 	return strings.Contains(req.Header.Get("Accept"), "application/yaml")
 }
 
+func isCSV(req *http.Request) bool {
+	return strings.Contains(req.Header.Get("Accept"), "text/csv")
+}
+
 func isJSONL(req *http.Request) bool {
-	return strings.Contains(req.Header.Get("Accept"), "application/jsonl")
+	accept := req.Header.Get("Accept")
+	for _, mediaType := range jsonlMediaTypes {
+		if strings.Contains(accept, mediaType) {
+			return true
+		}
+	}
+	return false
 }
 
 func parseMethod(req *http.Request) string {
@@ -165,16 +292,43 @@ func parseMethod(req *http.Request) string {
 }
 
 func Body(req *http.Request) (types.APIObject, error) {
-	req.ParseMultipartForm(maxFormSize)
-	if req.MultipartForm != nil {
-		return valuesToBody(req.MultipartForm.Value), nil
+	obj, _, err := body(req)
+	return obj, err
+}
+
+// RequestBody reads the body of the request like Body and additionally stores any files uploaded as part of a
+// multipart/form-data body on apiOp.Files. The body is passed through the InputTransformer of the schema, if any.
+func RequestBody(apiOp *types.APIRequest) (types.APIObject, error) {
+	obj, files, err := body(apiOp.Request)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	apiOp.Files = files
+
+	if apiOp.Schema != nil && apiOp.Schema.InputTransformer != nil {
+		obj, err = apiOp.Schema.InputTransformer(apiOp, obj)
+		if err != nil {
+			if _, ok := err.(*apierror.APIError); ok {
+				return types.APIObject{}, err
+			}
+			return types.APIObject{}, apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+		}
+	}
+	return obj, nil
+}
+
+func body(req *http.Request) (types.APIObject, []*types.UploadedFile, error) {
+	if bodyMethods[req.Method] && isMultipart(req) {
+		return readMultipart(req)
 	}
 
+	req.ParseForm()
 	if req.PostForm != nil && len(req.PostForm) > 0 {
-		return valuesToBody(map[string][]string(req.Form)), nil
+		return valuesToBody(map[string][]string(req.Form)), nil, nil
 	}
 
-	return ReadBody(req)
+	obj, err := ReadBody(req)
+	return obj, nil, err
 }
 
 func valuesToBody(input map[string][]string) types.APIObject {
@@ -0,0 +1,217 @@
+package parse
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAliasSchemas(redirect bool) *types.APISchemas {
+	s := types.EmptyAPISchemas()
+	s.MustAddSchema(types.APISchema{Schema: &schemas.Schema{
+		ID:                "thing",
+		PluralName:        "things",
+		CollectionMethods: []string{http.MethodGet},
+		ResourceMethods:   []string{http.MethodGet},
+	}})
+	s.AddAlias("oldthings", "thing", redirect)
+	return s
+}
+
+func staticURLParser(parsed ParsedURL) URLParser {
+	return func(rw http.ResponseWriter, req *http.Request, schemas *types.APISchemas) (ParsedURL, error) {
+		parsed.Query = req.URL.Query()
+		return parsed, nil
+	}
+}
+
+func TestParseAlias(t *testing.T) {
+	tests := []struct {
+		name         string
+		redirect     bool
+		target       string
+		parsed       ParsedURL
+		wantErr      error
+		wantLocation string
+	}{
+		{
+			name:   "alias is served by canonical schema",
+			target: "/v1/oldthings/ns/a?limit=1",
+			parsed: ParsedURL{Type: "oldthings", Namespace: "ns", Name: "a", Prefix: "/v1"},
+		},
+		{
+			name:         "alias redirects to resource",
+			redirect:     true,
+			target:       "/v1/oldthings/ns/a?limit=1",
+			parsed:       ParsedURL{Type: "oldthings", Namespace: "ns", Name: "a", Prefix: "/v1"},
+			wantErr:      validation.ErrComplete,
+			wantLocation: "http://example.com/v1/things/ns/a?limit=1",
+		},
+		{
+			name:         "alias redirects to collection",
+			redirect:     true,
+			target:       "/v1/oldthings",
+			parsed:       ParsedURL{Type: "oldthings", Prefix: "/v1"},
+			wantErr:      validation.ErrComplete,
+			wantLocation: "http://example.com/v1/things",
+		},
+		{
+			name:     "canonical type is not redirected",
+			redirect: true,
+			target:   "/v1/things",
+			parsed:   ParsedURL{Type: "things", Prefix: "/v1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			apiOp := &types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodGet, test.target, nil),
+				Response: rw,
+				Schemas:  newAliasSchemas(test.redirect),
+			}
+
+			err := Parse(apiOp, staticURLParser(test.parsed))
+			if test.wantErr != nil {
+				assert.Equal(t, test.wantErr, err)
+				assert.Equal(t, http.StatusPermanentRedirect, rw.Code)
+				assert.Equal(t, test.wantLocation, rw.Header().Get("Location"))
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, apiOp.Schema)
+			assert.Equal(t, "thing", apiOp.Schema.ID)
+			assert.Equal(t, "thing", apiOp.Type)
+			assert.Equal(t, test.parsed.Name, apiOp.Name)
+			assert.Equal(t, test.parsed.Namespace, apiOp.Namespace)
+		})
+	}
+}
+
+func TestParseSetsSchemaOnError(t *testing.T) {
+	parseErr := errors.New("bad url")
+	apiOp := &types.APIRequest{
+		Request:  httptest.NewRequest(http.MethodGet, "/v1/things/a", nil),
+		Response: httptest.NewRecorder(),
+		Schemas:  newAliasSchemas(false),
+	}
+
+	err := Parse(apiOp, func(http.ResponseWriter, *http.Request, *types.APISchemas) (ParsedURL, error) {
+		return ParsedURL{Type: "things", Name: "a"}, parseErr
+	})
+	assert.Equal(t, parseErr, err)
+	require.NotNil(t, apiOp.Schema)
+	assert.Equal(t, "thing", apiOp.Schema.ID)
+	assert.Equal(t, "thing", apiOp.Type)
+}
+
+func TestParseResponseFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		accept string
+		want   string
+	}{
+		{name: "default", target: "/v1/foos", want: "json"},
+		{name: "jsonl media type", target: "/v1/foos", accept: "application/jsonl", want: "jsonl"},
+		{name: "x-ndjson media type", target: "/v1/foos", accept: "application/x-ndjson", want: "jsonl"},
+		{name: "ndjson media type", target: "/v1/foos", accept: "application/ndjson", want: "jsonl"},
+		{name: "jsonl format", target: "/v1/foos?_format=jsonl", want: "jsonl"},
+		{name: "ndjson format", target: "/v1/foos?_format=ndjson", want: "jsonl"},
+		{name: "ndjson format mixed case", target: "/v1/foos?_format=NDJSON", want: "jsonl"},
+		{name: "yaml media type", target: "/v1/foos", accept: "application/yaml", want: "yaml"},
+		{name: "csv format", target: "/v1/foos?_format=csv", want: "csv"},
+		{name: "csv media type", target: "/v1/foos", accept: "text/csv", want: "csv"},
+		{name: "xml format", target: "/v1/foos?_format=xml", want: "json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			assert.Equal(t, tt.want, parseResponseFormat(req))
+		})
+	}
+}
+
+func TestRequestBodyInputTransformer(t *testing.T) {
+	lowercaseName := func(request *types.APIRequest, obj types.APIObject) (types.APIObject, error) {
+		data := obj.Data()
+		data.SetNested(strings.ToLower(data.String("name")), "name")
+		return obj, nil
+	}
+	requireName := func(request *types.APIRequest, obj types.APIObject) (types.APIObject, error) {
+		if obj.Data().String("name") == "" {
+			return obj, errors.New("name is required")
+		}
+		return obj, nil
+	}
+	forbidName := func(request *types.APIRequest, obj types.APIObject) (types.APIObject, error) {
+		return obj, apierror.NewAPIError(validation.PermissionDenied, "name can not be set")
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		transformer types.InputTransformer
+		want        map[string]interface{}
+		wantStatus  int
+	}{
+		{
+			name:        "normalized",
+			body:        `{"name":"MyThing"}`,
+			transformer: lowercaseName,
+			want:        map[string]interface{}{"name": "mything"},
+		},
+		{
+			name:        "rejected",
+			body:        `{"size":1}`,
+			transformer: requireName,
+			wantStatus:  http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "rejected with an API error",
+			body:        `{"name":"MyThing"}`,
+			transformer: forbidName,
+			wantStatus:  http.StatusForbidden,
+		},
+		{
+			name: "no transformer",
+			body: `{"name":"MyThing"}`,
+			want: map[string]interface{}{"name": "MyThing"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/things", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			apiOp := &types.APIRequest{
+				Request: req,
+				Schema: &types.APISchema{
+					Schema:           &schemas.Schema{ID: "thing"},
+					InputTransformer: tt.transformer,
+				},
+			}
+
+			obj, err := RequestBody(apiOp)
+			if tt.wantStatus != 0 {
+				var apiErr *apierror.APIError
+				require.ErrorAs(t, err, &apiErr)
+				assert.Equal(t, tt.wantStatus, apiErr.Code.Status)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, map[string]interface{}(obj.Data()))
+		})
+	}
+}
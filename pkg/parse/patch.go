@@ -0,0 +1,80 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+)
+
+// IsJSONPatch returns whether the body of req is a JSON Patch.
+func IsJSONPatch(req *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return mediaType == types.JSONPatchContentType
+}
+
+// parseJSONPatch reads and validates the JSON Patch in the body of req. The body is restored after it is read.
+func parseJSONPatch(req *http.Request) ([]types.PatchOperation, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, apierror.NewAPIError(apierror.BadRequest, "JSON Patch body is empty")
+	}
+
+	content, err := io.ReadAll(io.LimitReader(req.Body, maxFormSize))
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("Failed to read body: %v", err))
+	}
+	req.Body = io.NopCloser(bytes.NewReader(content))
+
+	// the raw fields are kept to tell a null value apart from a missing one
+	var patch []types.PatchOperation
+	var fields []map[string]json.RawMessage
+	if err := json.Unmarshal(content, &patch); err != nil {
+		return nil, apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("Failed to parse JSON Patch: %v", err))
+	}
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil, apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("Failed to parse JSON Patch: %v", err))
+	}
+
+	for i, op := range patch {
+		if err := validatePatchOperation(op, fields[i]); err != nil {
+			return nil, apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("Invalid JSON Patch operation %d: %v", i, err))
+		}
+	}
+	if patch == nil {
+		patch = []types.PatchOperation{}
+	}
+	return patch, nil
+}
+
+func validatePatchOperation(op types.PatchOperation, fields map[string]json.RawMessage) error {
+	if !isJSONPointer(op.Path) {
+		return fmt.Errorf("path %q is not a JSON pointer", op.Path)
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		// a null value is valid, so check the field is present rather than op.Value
+		if _, ok := fields["value"]; !ok {
+			return fmt.Errorf("%s requires a value", op.Op)
+		}
+	case "move", "copy":
+		if _, ok := fields["from"]; !ok || !isJSONPointer(op.From) {
+			return fmt.Errorf("%s requires a from JSON pointer", op.Op)
+		}
+	case "remove":
+	default:
+		return fmt.Errorf("unknown op %q", op.Op)
+	}
+	return nil
+}
+
+func isJSONPointer(path string) bool {
+	return path == "" || strings.HasPrefix(path, "/")
+}
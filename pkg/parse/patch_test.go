@@ -0,0 +1,84 @@
+package parse
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsJSONPatch(t *testing.T) {
+	tests := map[string]bool{
+		"application/json-patch+json":                true,
+		"application/json-patch+json; charset=utf-8": true,
+		"application/json":                           false,
+		"application/merge-patch+json":               false,
+		"":                                           false,
+	}
+	for contentType, want := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Content-Type", contentType)
+		assert.Equal(t, want, IsJSONPatch(req), contentType)
+	}
+}
+
+func TestParseJSONPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		want     []types.PatchOperation
+		wantCode validation.ErrorCode
+	}{
+		{
+			name: "valid",
+			body: `[
+				{"op": "replace", "path": "/spec/replicas", "value": 3},
+				{"op": "add", "path": "/metadata/labels/app", "value": null},
+				{"op": "remove", "path": "/status"},
+				{"op": "move", "from": "/a", "path": "/b"},
+				{"op": "test", "path": "", "value": {}}
+			]`,
+			want: []types.PatchOperation{
+				{Op: "replace", Path: "/spec/replicas", Value: float64(3)},
+				{Op: "add", Path: "/metadata/labels/app"},
+				{Op: "remove", Path: "/status"},
+				{Op: "move", From: "/a", Path: "/b"},
+				{Op: "test", Path: "", Value: map[string]interface{}{}},
+			},
+		},
+		{name: "empty patch", body: `[]`, want: []types.PatchOperation{}},
+		{name: "empty body", wantCode: apierror.BadRequest},
+		{name: "malformed", body: `[{"op": `, wantCode: apierror.BadRequest},
+		{name: "not an array", body: `{"op": "remove", "path": "/a"}`, wantCode: apierror.BadRequest},
+		{name: "unknown op", body: `[{"op": "merge", "path": "/a"}]`, wantCode: validation.InvalidBodyContent},
+		{name: "missing value", body: `[{"op": "replace", "path": "/a"}]`, wantCode: validation.InvalidBodyContent},
+		{name: "missing from", body: `[{"op": "copy", "path": "/a"}]`, wantCode: validation.InvalidBodyContent},
+		{name: "relative path", body: `[{"op": "remove", "path": "a"}]`, wantCode: validation.InvalidBodyContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/foos?action=edit", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", types.JSONPatchContentType)
+
+			patch, err := parseJSONPatch(req)
+			if tt.wantCode.Code != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantCode, err.(*apierror.APIError).Code)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, patch)
+
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, string(body), "body is restored")
+		})
+	}
+}
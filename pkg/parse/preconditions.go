@@ -0,0 +1,66 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+const (
+	uidParam             = "uid"
+	resourceVersionParam = "resourceVersion"
+)
+
+type preconditionsBody struct {
+	Preconditions *types.Preconditions `json:"preconditions,omitempty"`
+}
+
+// parseIfUnmodifiedSince returns the date of the If-Unmodified-Since header of req. Like other invalid dates in
+// HTTP, an invalid date is ignored, and zero is returned.
+func parseIfUnmodifiedSince(req *http.Request) time.Time {
+	value := req.Header.Get("If-Unmodified-Since")
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// parsePreconditions reads the preconditions of a request from the uid and resourceVersion query parameters or,
+// if those are not set, from the preconditions field of the body. The body is restored after it is read.
+func parsePreconditions(req *http.Request) (*types.Preconditions, error) {
+	query := req.URL.Query()
+	if query.Get(uidParam) != "" || query.Get(resourceVersionParam) != "" {
+		return &types.Preconditions{
+			UID:             query.Get(uidParam),
+			ResourceVersion: query.Get(resourceVersionParam),
+		}, nil
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	content, err := io.ReadAll(io.LimitReader(req.Body, maxFormSize))
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("Failed to read body: %v", err))
+	}
+	req.Body = io.NopCloser(bytes.NewReader(content))
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, nil
+	}
+
+	body := preconditionsBody{}
+	if err := getDecoder(req, bytes.NewReader(content))(&body); err != nil {
+		return nil, apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("Failed to parse body: %v", err))
+	}
+	return body.Preconditions, nil
+}
@@ -0,0 +1,137 @@
+package parse
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePreconditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		body     string
+		expect   *types.Preconditions
+		wantCode validation.ErrorCode
+	}{
+		{
+			name:   "none",
+			target: "/v1/things/a",
+		},
+		{
+			name:   "query",
+			target: "/v1/things/a?uid=123&resourceVersion=5",
+			expect: &types.Preconditions{UID: "123", ResourceVersion: "5"},
+		},
+		{
+			name:   "body",
+			target: "/v1/things/a",
+			body:   `{"preconditions": {"uid": "123"}}`,
+			expect: &types.Preconditions{UID: "123"},
+		},
+		{
+			name:   "query takes precedence over body",
+			target: "/v1/things/a?resourceVersion=6",
+			body:   `{"preconditions": {"uid": "123"}}`,
+			expect: &types.Preconditions{ResourceVersion: "6"},
+		},
+		{
+			name:     "malformed body",
+			target:   "/v1/things/a",
+			body:     `{"preconditions"`,
+			wantCode: apierror.BadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, test.target, strings.NewReader(test.body))
+			apiOp := &types.APIRequest{
+				Request:  req,
+				Response: httptest.NewRecorder(),
+				Schemas:  newAliasSchemas(false),
+			}
+			apiOp.Schemas.LookupSchema("thing").ResourceMethods = []string{http.MethodDelete}
+
+			err := Parse(apiOp, staticURLParser(ParsedURL{Type: "things", Name: "a"}))
+			if test.wantCode.Code != "" {
+				require.Error(t, err)
+				assert.Equal(t, test.wantCode, err.(*apierror.APIError).Code)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expect, apiOp.Preconditions)
+
+			body, err := io.ReadAll(apiOp.Request.Body)
+			require.NoError(t, err)
+			assert.Equal(t, test.body, string(body))
+		})
+	}
+}
+
+func TestParseIfUnmodifiedSince(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		header string
+		expect time.Time
+	}{
+		{
+			name:   "update",
+			method: http.MethodPut,
+			header: "Fri, 01 Mar 2024 12:00:00 GMT",
+			expect: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "delete",
+			method: http.MethodDelete,
+			header: "Fri, 01 Mar 2024 12:00:00 GMT",
+			expect: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "obsolete date format",
+			method: http.MethodPatch,
+			header: "Friday, 01-Mar-24 12:00:00 GMT",
+			expect: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "invalid date",
+			method: http.MethodPut,
+			header: "yesterday",
+		},
+		{
+			name:   "read",
+			method: http.MethodGet,
+			header: "Fri, 01 Mar 2024 12:00:00 GMT",
+		},
+		{
+			name:   "no header",
+			method: http.MethodPut,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, "/v1/things/a", nil)
+			if test.header != "" {
+				req.Header.Set("If-Unmodified-Since", test.header)
+			}
+			apiOp := &types.APIRequest{
+				Request:  req,
+				Response: httptest.NewRecorder(),
+				Schemas:  newAliasSchemas(false),
+			}
+			apiOp.Schemas.LookupSchema("thing").ResourceMethods = []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+			require.NoError(t, Parse(apiOp, staticURLParser(ParsedURL{Type: "things", Name: "a"})))
+			assert.True(t, test.expect.Equal(apiOp.IfUnmodifiedSince), "got %v", apiOp.IfUnmodifiedSince)
+		})
+	}
+}
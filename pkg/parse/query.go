@@ -0,0 +1,92 @@
+package parse
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+const (
+	includeDeletedParam = "includeDeleted"
+	methodsParam        = "_methods"
+	batchParam          = "_batch"
+)
+
+// KnownQueryParameters are the query parameters accepted for every type by ValidateQuery. Applications that read
+// additional parameters should add them here.
+var KnownQueryParameters = map[string]bool{
+	"_format":            true,
+	"_method":            true,
+	"action":             true,
+	"link":               true,
+	"continue":           true,
+	"CSRF":               true,
+	includeDeletedParam:  true,
+	methodsParam:         true,
+	batchParam:           true,
+	uidParam:             true,
+	resourceVersionParam: true,
+	filterParam:          true,
+	sortParam:            true,
+	pageParam:            true,
+	pageSizeParam:        true,
+}
+
+// ValidateQuery returns a BadRequest error listing the query parameters of the request that are neither in
+// KnownQueryParameters nor in the QueryParameters of the requested schema.
+func ValidateQuery(apiOp *types.APIRequest) error {
+	query := apiOp.Query
+	if query == nil && apiOp.Request != nil {
+		query = apiOp.Request.URL.Query()
+	}
+
+	var unknown []string
+	for key := range query {
+		if KnownQueryParameters[key] {
+			continue
+		}
+		if apiOp.Schema != nil && slices.Contains(apiOp.Schema.QueryParameters, key) {
+			continue
+		}
+		unknown = append(unknown, key)
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	sort.Strings(unknown)
+	return apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("Unknown query parameters: %s", strings.Join(unknown, ", ")))
+}
+
+// IncludeDeleted returns whether the includeDeleted query parameter of the request is set to true, asking for
+// soft-deleted objects to be returned.
+func IncludeDeleted(apiOp *types.APIRequest) bool {
+	return boolQuery(apiOp, includeDeletedParam)
+}
+
+// IncludeMethods returns whether the _methods query parameter of the request is set to true, asking for the
+// methods the caller is allowed to use to be added to collections and resources.
+func IncludeMethods(apiOp *types.APIRequest) bool {
+	return boolQuery(apiOp, methodsParam)
+}
+
+// BatchCreate returns whether the request creates the objects of a JSON array, which it asks for by posting to a
+// collection with the _batch query parameter set to true.
+func BatchCreate(apiOp *types.APIRequest) bool {
+	return apiOp.Method == http.MethodPost && apiOp.Name == "" && apiOp.Action == "" && boolQuery(apiOp, batchParam)
+}
+
+func boolQuery(apiOp *types.APIRequest, name string) bool {
+	query := apiOp.Query
+	if query == nil && apiOp.Request != nil {
+		query = apiOp.Request.URL.Query()
+	}
+	value, _ := strconv.ParseBool(query.Get(name))
+	return value
+}
@@ -0,0 +1,95 @@
+package parse
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncludeDeleted(t *testing.T) {
+	tests := []struct {
+		name   string
+		apiOp  *types.APIRequest
+		expect bool
+	}{
+		{
+			name:  "not set",
+			apiOp: &types.APIRequest{Request: httptest.NewRequest("GET", "/v1/configs", nil)},
+		},
+		{
+			name:   "set on request",
+			apiOp:  &types.APIRequest{Request: httptest.NewRequest("GET", "/v1/configs?includeDeleted=true", nil)},
+			expect: true,
+		},
+		{
+			name:  "invalid value",
+			apiOp: &types.APIRequest{Request: httptest.NewRequest("GET", "/v1/configs?includeDeleted=yes", nil)},
+		},
+		{
+			name:   "set on parsed query",
+			apiOp:  &types.APIRequest{Query: url.Values{"includeDeleted": {"1"}}},
+			expect: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expect, IncludeDeleted(test.apiOp))
+		})
+	}
+}
+
+func TestValidateQuery(t *testing.T) {
+	schema := &types.APISchema{QueryParameters: []string{"labelSelector"}}
+
+	tests := []struct {
+		name    string
+		target  string
+		schema  *types.APISchema
+		wantErr string
+	}{
+		{
+			name:   "no query",
+			target: "/v1/configs",
+		},
+		{
+			name:   "known parameters",
+			target: "/v1/configs?_format=yaml&continue=abc&includeDeleted=true",
+		},
+		{
+			name:   "schema parameter",
+			target: "/v1/configs?labelSelector=a",
+			schema: schema,
+		},
+		{
+			name:    "schema parameter without schema",
+			target:  "/v1/configs?labelSelector=a",
+			wantErr: "Unknown query parameters: labelSelector",
+		},
+		{
+			name:    "unknown parameters are listed",
+			target:  "/v1/configs?_fromat=yaml&zzz=1&labelSelector=a&continue=abc",
+			schema:  schema,
+			wantErr: "Unknown query parameters: _fromat, zzz",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateQuery(&types.APIRequest{
+				Request: httptest.NewRequest("GET", test.target, nil),
+				Schema:  test.schema,
+			})
+			if test.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, apierror.BadRequest, err.(*apierror.APIError).Code)
+			assert.Equal(t, test.wantErr, err.(*apierror.APIError).Message)
+		})
+	}
+}
@@ -1,15 +1,16 @@
 package parse
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 
 	"github.com/rancher/apiserver/pkg/apierror"
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/wrangler/v3/pkg/data/convert"
-	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
 	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
@@ -22,22 +23,85 @@ var bodyMethods = map[string]bool{
 
 type Decode func(interface{}) error
 
+// BodyValidator checks a request body once it is decoded, before it is passed to the handler. raw is the body as
+// it was sent and data the object decoded from it. An error rejects the request, with a BadRequest unless it is
+// an APIError.
+type BodyValidator func(req *http.Request, raw []byte, data map[string]interface{}) error
+
+// BodyValidators are the validators run by ReadBody, keyed by the media type of the body they check, such as
+// "application/yaml". Bodies without a Content-Type are decoded, and validated, as "application/json". Validators
+// should be registered before the server starts handling requests.
+var BodyValidators = map[string][]BodyValidator{}
+
 func ReadBody(req *http.Request) (types.APIObject, error) {
 	if !bodyMethods[req.Method] {
 		return types.APIObject{}, nil
 	}
 
-	decode := getDecoder(req, io.LimitReader(req.Body, maxFormSize))
+	reader := io.LimitReader(req.Body, maxFormSize)
+	validators := BodyValidators[bodyMediaType(req)]
+	var raw []byte
+	if len(validators) > 0 {
+		// validators need the body as it was sent, which the decoder does not keep
+		var err error
+		if raw, err = io.ReadAll(reader); err != nil {
+			return types.APIObject{}, apierror.NewAPIError(apierror.BadRequest,
+				fmt.Sprintf("Failed to read body: %v", err))
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	decode := getDecoder(req, reader)
 
 	data := map[string]interface{}{}
 	if err := decode(&data); err != nil {
-		return types.APIObject{}, apierror.NewAPIError(validation.InvalidBodyContent,
+		return types.APIObject{}, apierror.NewAPIError(apierror.BadRequest,
 			fmt.Sprintf("Failed to parse body: %v", err))
 	}
 
+	for _, validator := range validators {
+		if err := validator(req, raw, data); err != nil {
+			if _, ok := err.(*apierror.APIError); ok {
+				return types.APIObject{}, err
+			}
+			return types.APIObject{}, apierror.NewAPIError(apierror.BadRequest,
+				fmt.Sprintf("Invalid body: %v", err))
+		}
+	}
+
 	return toAPI(data), nil
 }
 
+// ReadBodyBytes reads the body of req as it was sent, for handlers that need it whole. Bodies larger than the
+// limit of request bodies are rejected with a RequestEntityTooLarge error rather than truncated.
+func ReadBodyBytes(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	content, err := io.ReadAll(io.LimitReader(req.Body, maxFormSize+1))
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("Failed to read body: %v", err))
+	}
+	if len(content) > maxFormSize {
+		return nil, apierror.NewAPIError(apierror.RequestEntityTooLarge,
+			fmt.Sprintf("body exceeds the maximum size of %d bytes", maxFormSize))
+	}
+	return content, nil
+}
+
+// bodyMediaType returns the media type of the body of req, without its parameters.
+func bodyMediaType(req *http.Request) string {
+	contentType := req.Header.Get("Content-Type")
+	if contentType == "" {
+		return "application/json"
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mediaType
+}
+
 func toAPI(data map[string]interface{}) types.APIObject {
 	return types.APIObject{
 		Type:   convert.ToString(data["type"]),
@@ -47,10 +111,67 @@ func toAPI(data map[string]interface{}) types.APIObject {
 }
 
 func getDecoder(req *http.Request, reader io.Reader) Decode {
+	maxDepth := bodyLimits(req).MaxDepth
 	if req.Header.Get("Content-type") == "application/yaml" {
-		return yaml.NewYAMLToJSONDecoder(reader).Decode
+		decoder := yaml.NewYAMLToJSONDecoder(reader)
+		return func(v interface{}) error {
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				return err
+			}
+			if _, err := io.Copy(io.Discard, newDepthLimitReader(bytes.NewReader(raw), maxDepth)); err != nil {
+				return err
+			}
+			return json.Unmarshal(raw, v)
+		}
 	}
-	decoder := json.NewDecoder(reader)
+	decoder := json.NewDecoder(newDepthLimitReader(reader, maxDepth))
 	decoder.UseNumber()
 	return decoder.Decode
 }
+
+// depthLimitReader fails reads once the JSON passing through it nests objects and arrays deeper than max, so that
+// the decoder never has to process such a body.
+type depthLimitReader struct {
+	reader   io.Reader
+	max      int
+	depth    int
+	inString bool
+	escaped  bool
+}
+
+func newDepthLimitReader(reader io.Reader, max int) *depthLimitReader {
+	return &depthLimitReader{
+		reader: reader,
+		max:    max,
+	}
+}
+
+func (d *depthLimitReader) Read(p []byte) (int, error) {
+	n, err := d.reader.Read(p)
+	for _, b := range p[:n] {
+		if d.inString {
+			switch {
+			case d.escaped:
+				d.escaped = false
+			case b == '\\':
+				d.escaped = true
+			case b == '"':
+				d.inString = false
+			}
+			continue
+		}
+		switch b {
+		case '"':
+			d.inString = true
+		case '{', '[':
+			d.depth++
+			if d.depth > d.max {
+				return 0, fmt.Errorf("body exceeds the maximum nesting depth of %d", d.max)
+			}
+		case '}', ']':
+			d.depth--
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,136 @@
+package parse
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nestedJSON(depth int) string {
+	return `{"a":` + strings.Repeat(`[`, depth-1) + strings.Repeat(`]`, depth-1) + `}`
+}
+
+func nestedYAML(depth int) string {
+	return "a: " + strings.Repeat("[", depth-1) + strings.Repeat("]", depth-1) + "\n"
+}
+
+func TestReadBodyMaxDepth(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantErr     bool
+	}{
+		{name: "json at limit", body: nestedJSON(10)},
+		{name: "json over limit", body: nestedJSON(11), wantErr: true},
+		{name: "json over limit much later", body: `{"pad":"` + strings.Repeat("x", 8192) + `","a":` + nestedJSON(20) + `}`, wantErr: true},
+		{name: "brackets in strings", body: `{"a":"` + strings.Repeat(`[{`, 20) + `\"[{"}`},
+		{name: "yaml at limit", contentType: "application/yaml", body: nestedYAML(10)},
+		{name: "yaml over limit", contentType: "application/yaml", body: nestedYAML(11), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/foos", strings.NewReader(tt.body))
+			req = req.WithContext(WithBodyLimits(req.Context(), BodyLimits{MaxDepth: 10}))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			obj, err := ReadBody(req)
+			if !tt.wantErr {
+				require.NoError(t, err)
+				assert.Contains(t, obj.Data(), "a")
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, apierror.BadRequest, err.(*apierror.APIError).Code)
+			assert.Contains(t, err.Error(), "maximum nesting depth of 10")
+		})
+	}
+}
+
+func TestReadBodyValidators(t *testing.T) {
+	defer func(old map[string][]BodyValidator) { BodyValidators = old }(BodyValidators)
+	BodyValidators = map[string][]BodyValidator{
+		"application/yaml": {
+			func(req *http.Request, raw []byte, data map[string]interface{}) error {
+				if strings.Contains(string(raw), "&") {
+					return errors.New("YAML anchors are not allowed")
+				}
+				return nil
+			},
+		},
+		"application/json": {
+			func(req *http.Request, raw []byte, data map[string]interface{}) error {
+				if data["name"] == "reserved" {
+					return apierror.NewAPIError(validation.InvalidFormat, "name is reserved")
+				}
+				return nil
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    validation.ErrorCode
+		wantErr     string
+	}{
+		{
+			name:        "yaml without anchors",
+			contentType: "application/yaml",
+			body:        "name: foo\n",
+		},
+		{
+			name:        "yaml with anchors",
+			contentType: "application/yaml",
+			body:        "a: &anchor foo\nname: *anchor\n",
+			wantCode:    apierror.BadRequest,
+			wantErr:     "YAML anchors are not allowed",
+		},
+		{
+			name:        "json not checked by the yaml validator",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"name":"&foo"}`,
+		},
+		{
+			name:        "json rejected with an API error",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"name":"reserved"}`,
+			wantCode:    validation.InvalidFormat,
+			wantErr:     "name is reserved",
+		},
+		{
+			name:     "no content type validated as json",
+			body:     `{"name":"reserved"}`,
+			wantCode: validation.InvalidFormat,
+			wantErr:  "name is reserved",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/foos", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			obj, err := ReadBody(req)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				assert.Contains(t, obj.Data(), "name")
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.wantCode, err.(*apierror.APIError).Code)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -3,6 +3,10 @@ package parse
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/rancher/apiserver/pkg/apierror"
 	"github.com/rancher/apiserver/pkg/types"
@@ -24,6 +28,11 @@ func ValidateMethod(request *types.APIRequest) error {
 		return nil
 	}
 
+	if request.Method == http.MethodOptions && request.Type == "" {
+		// the capabilities of the server
+		return nil
+	}
+
 	if !supportedMethods[request.Method] {
 		return apierror.NewAPIError(validation.MethodNotAllowed, fmt.Sprintf("Invalid method %s not supported", request.Method))
 	}
@@ -45,3 +54,91 @@ func ValidateMethod(request *types.APIRequest) error {
 
 	return apierror.NewAPIError(validation.PermissionDenied, fmt.Sprintf("Method %s not supported", request.Method))
 }
+
+// NamePolicy decides how resource names and namespaces that are unusual, but not path traversals, are handled.
+type NamePolicy int
+
+const (
+	// AcceptNames passes names on as they are, only rejecting path traversals. It is the default.
+	AcceptNames NamePolicy = iota
+	// RejectInvalidNames also rejects names with leading or trailing whitespace, a trailing dot, control
+	// characters or invalid UTF-8, as often pasted by mistake, with a 400.
+	RejectInvalidNames
+	// NormalizeNames trims leading and trailing whitespace and trailing dots from names before rejecting the
+	// names RejectInvalidNames rejects. Names that are empty once trimmed are rejected.
+	NormalizeNames
+)
+
+// ValidateName rejects resource names and namespaces that contain "." or ".." path segments, including
+// percent-encoded ones, so that they can not be confused with other paths by stores. An empty name is a collection
+// request and is valid.
+func ValidateName(request *types.APIRequest) error {
+	return ValidateNameWithPolicy(request, AcceptNames)
+}
+
+// ValidateNameWithPolicy rejects path traversals like ValidateName, then handles the other invalid names according
+// to policy. Names normalized by NormalizeNames are replaced in request.
+func ValidateNameWithPolicy(request *types.APIRequest, policy NamePolicy) error {
+	for _, name := range []*string{&request.Namespace, &request.Name} {
+		if isPathTraversal(*name) {
+			return apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("Invalid resource name %q", *name))
+		}
+		if policy == AcceptNames || *name == "" {
+			continue
+		}
+		if policy == NormalizeNames {
+			normalized := normalizeName(*name)
+			if normalized == "" {
+				return apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("Invalid resource name %q", *name))
+			}
+			*name = normalized
+		}
+		if reason := invalidName(*name); reason != "" {
+			return apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("Invalid resource name %q: %s", *name, reason))
+		}
+	}
+	return nil
+}
+
+func normalizeName(name string) string {
+	name = strings.TrimLeftFunc(name, unicode.IsSpace)
+	return strings.TrimRightFunc(name, func(r rune) bool {
+		return r == '.' || unicode.IsSpace(r)
+	})
+}
+
+// invalidName returns why name is invalid, or an empty string if it is valid.
+func invalidName(name string) string {
+	switch {
+	case !utf8.ValidString(name):
+		return "invalid UTF-8"
+	case strings.TrimSpace(name) != name:
+		return "leading or trailing whitespace"
+	case strings.HasSuffix(name, "."):
+		return "trailing dot"
+	case strings.IndexFunc(name, unicode.IsControl) >= 0:
+		return "control character"
+	}
+	return ""
+}
+
+func isPathTraversal(name string) bool {
+	// decode repeatedly, to catch segments that were encoded more than once
+	for i := 0; i < 3; i++ {
+		for _, segment := range strings.FieldsFunc(name, isPathSeparator) {
+			if segment == "." || segment == ".." {
+				return true
+			}
+		}
+		decoded, err := url.PathUnescape(name)
+		if err != nil || decoded == name {
+			return false
+		}
+		name = decoded
+	}
+	return strings.Contains(name, "%")
+}
+
+func isPathSeparator(r rune) bool {
+	return r == '/' || r == '\\'
+}
@@ -0,0 +1,94 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		wantErr   bool
+	}{
+		{name: ""},
+		{name: "foo"},
+		{name: "foo.bar"},
+		{name: "..foo"},
+		{name: "50%off"},
+		{name: "foo", namespace: "default"},
+		{name: ".", wantErr: true},
+		{name: "..", wantErr: true},
+		{name: "../secrets", wantErr: true},
+		{name: "foo/../bar", wantErr: true},
+		{name: `..\secrets`, wantErr: true},
+		{name: "%2e%2e", wantErr: true},
+		{name: "%2E", wantErr: true},
+		{name: "%2e%2e%2fsecrets", wantErr: true},
+		{name: "%252e%252e", wantErr: true},
+		{name: "foo", namespace: "..", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.namespace+"/"+tt.name, func(t *testing.T) {
+			err := ValidateName(&types.APIRequest{Name: tt.name, Namespace: tt.namespace})
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, apierror.BadRequest, err.(*apierror.APIError).Code)
+		})
+	}
+}
+
+func TestValidateNameWithPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		namespace     string
+		policy        NamePolicy
+		wantName      string
+		wantNamespace string
+		wantErr       string
+	}{
+		{name: "foo.", policy: AcceptNames, wantName: "foo."},
+		{name: " foo ", policy: AcceptNames, wantName: " foo "},
+		{name: ".", policy: AcceptNames, wantErr: `Invalid resource name "."`},
+		{name: "foo.bar", policy: RejectInvalidNames, wantName: "foo.bar"},
+		{name: "", policy: RejectInvalidNames},
+		{name: "foo.", policy: RejectInvalidNames, wantErr: `Invalid resource name "foo.": trailing dot`},
+		{name: "foo..", policy: RejectInvalidNames, wantErr: `Invalid resource name "foo..": trailing dot`},
+		{name: "foo ", policy: RejectInvalidNames, wantErr: `Invalid resource name "foo ": leading or trailing whitespace`},
+		{name: "\tfoo", policy: RejectInvalidNames, wantErr: `Invalid resource name "\tfoo": leading or trailing whitespace`},
+		{name: "foo\x00bar", policy: RejectInvalidNames, wantErr: `Invalid resource name "foo\x00bar": control character`},
+		{name: "foo\xffbar", policy: RejectInvalidNames, wantErr: `Invalid resource name "foo\xffbar": invalid UTF-8`},
+		{name: "foo", namespace: "default.", policy: RejectInvalidNames, wantErr: `Invalid resource name "default.": trailing dot`},
+		{name: "web server", policy: RejectInvalidNames, wantName: "web server"},
+		{name: "foo.", policy: NormalizeNames, wantName: "foo"},
+		{name: " foo. \n", policy: NormalizeNames, wantName: "foo"},
+		{name: "foo . .", policy: NormalizeNames, wantName: "foo"},
+		{name: "foo.bar.", policy: NormalizeNames, wantName: "foo.bar"},
+		{name: "foo", namespace: " default ", policy: NormalizeNames, wantName: "foo", wantNamespace: "default"},
+		{name: " . ", policy: NormalizeNames, wantErr: `Invalid resource name " . "`},
+		{name: "..", policy: NormalizeNames, wantErr: `Invalid resource name ".."`},
+		{name: "foo\x07.", policy: NormalizeNames, wantErr: `Invalid resource name "foo\a": control character`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.namespace+"/"+tt.name, func(t *testing.T) {
+			request := &types.APIRequest{Name: tt.name, Namespace: tt.namespace}
+			err := ValidateNameWithPolicy(request, tt.policy)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Equal(t, apierror.BadRequest, err.(*apierror.APIError).Code)
+				assert.Equal(t, tt.wantErr, err.(*apierror.APIError).Message)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantName, request.Name)
+			assert.Equal(t, tt.wantNamespace, request.Namespace)
+		})
+	}
+}
@@ -18,35 +18,35 @@ func (*SchemaBasedAccess) CanCreate(apiOp *types.APIRequest, schema *types.APISc
 	if slice.ContainsString(schema.CollectionMethods, http.MethodPost) {
 		return nil
 	}
-	return apierror.NewAPIError(validation.PermissionDenied, "can not create "+schema.ID)
+	return apierror.NewLocalizedAPIError(validation.PermissionDenied, apierror.CanNotCreateKey, schema.ID)
 }
 
 func (*SchemaBasedAccess) CanGet(apiOp *types.APIRequest, schema *types.APISchema) error {
 	if slice.ContainsString(schema.ResourceMethods, http.MethodGet) {
 		return nil
 	}
-	return apierror.NewAPIError(validation.PermissionDenied, "can not get "+schema.ID)
+	return apierror.NewLocalizedAPIError(validation.PermissionDenied, apierror.CanNotGetKey, schema.ID)
 }
 
 func (*SchemaBasedAccess) CanList(apiOp *types.APIRequest, schema *types.APISchema) error {
 	if slice.ContainsString(schema.CollectionMethods, http.MethodGet) || slice.ContainsString(schema.CollectionMethods, http.MethodPost) {
 		return nil
 	}
-	return apierror.NewAPIError(validation.PermissionDenied, "can not list "+schema.ID)
+	return apierror.NewLocalizedAPIError(validation.PermissionDenied, apierror.CanNotListKey, schema.ID)
 }
 
 func (*SchemaBasedAccess) CanUpdate(apiOp *types.APIRequest, obj types.APIObject, schema *types.APISchema) error {
 	if slice.ContainsString(schema.ResourceMethods, http.MethodPut) {
 		return nil
 	}
-	return apierror.NewAPIError(validation.PermissionDenied, "can not update "+schema.ID)
+	return apierror.NewLocalizedAPIError(validation.PermissionDenied, apierror.CanNotUpdateKey, schema.ID)
 }
 
 func (*SchemaBasedAccess) CanDelete(apiOp *types.APIRequest, obj types.APIObject, schema *types.APISchema) error {
 	if slice.ContainsString(schema.ResourceMethods, http.MethodDelete) {
 		return nil
 	}
-	return apierror.NewAPIError(validation.PermissionDenied, "can not delete "+schema.ID)
+	return apierror.NewLocalizedAPIError(validation.PermissionDenied, apierror.CanNotDeleteKey, schema.ID)
 }
 
 func (a *SchemaBasedAccess) CanWatch(apiOp *types.APIRequest, schema *types.APISchema) error {
@@ -73,8 +73,11 @@ func (a *SchemaBasedAccess) CanDo(apiOp *types.APIRequest, resource, verb, names
 }
 
 func (*SchemaBasedAccess) CanAction(apiOp *types.APIRequest, schema *types.APISchema, name string) error {
+	if _, ok := schema.ActionRequestHandlers[name]; ok {
+		return nil
+	}
 	if _, ok := schema.ActionHandlers[name]; !ok {
-		return apierror.NewAPIError(validation.PermissionDenied, "no such action "+name)
+		return apierror.NewLocalizedAPIError(validation.PermissionDenied, apierror.NoSuchActionKey, name)
 	}
 	return nil
 }
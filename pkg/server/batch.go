@@ -0,0 +1,57 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/handlers"
+	"github.com/rancher/apiserver/pkg/parse"
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// handleBatchCreate creates each element of the JSON array in the body of the request as it is decoded, by passing
+// it to the create handler of the schema as the body of its own request. The result lists the created objects, or
+// the error of each element that could not be created, in the order of the array. Errors carry the index of their
+// element, as they cannot be told apart otherwise.
+func handleBatchCreate(apiOp *types.APIRequest) (types.APIObjectList, error) {
+	var result types.APIObjectList
+	add := func(index int, obj types.APIObject, err error) {
+		if err != nil {
+			obj = handlers.ErrorObject(apiOp, err)
+			obj.Object.(map[string]interface{})["index"] = index
+		}
+		result.Objects = append(result.Objects, obj)
+	}
+
+	index, err := parse.DecodeBatch(apiOp.Request, func(index int, raw json.RawMessage) {
+		obj, err := handle(batchItem(apiOp, raw), apiOp.Schema.CreateHandler, handlers.MetricsHandler("201", handlers.CreateHandler))
+		add(index, obj, err)
+	})
+	if err != nil {
+		if len(result.Objects) == 0 {
+			return result, err
+		}
+		// the elements before the malformed one were created, which the client needs to know
+		add(index, types.APIObject{}, err)
+	}
+	return result, nil
+}
+
+// batchItem returns a create request with raw as its body.
+func batchItem(apiOp *types.APIRequest, raw json.RawMessage) *types.APIRequest {
+	req := apiOp.Request.Clone(apiOp.Context())
+	req.Body = io.NopCloser(bytes.NewReader(raw))
+	req.ContentLength = int64(len(raw))
+	req.Header.Set("Content-Type", "application/json")
+	req.Form = nil
+	req.PostForm = nil
+	req.MultipartForm = nil
+
+	item := apiOp.Clone()
+	item.Request = req
+	item.Method = http.MethodPost
+	item.Files = nil
+	return item
+}
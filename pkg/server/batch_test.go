@@ -0,0 +1,177 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/parse"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBatchServer returns a server whose foo create handler calls created with the ID of each object it creates.
+// An object with the ID "conflict" fails to be created.
+func newBatchServer(created func(id string)) *Server {
+	srv := DefaultAPIServer()
+	srv.Schemas.MustAddSchema(types.APISchema{
+		Schema: &schemas.Schema{
+			ID:                "foo",
+			CollectionMethods: []string{http.MethodPost},
+		},
+		CreateHandler: func(apiOp *types.APIRequest) (types.APIObject, error) {
+			obj, err := parse.RequestBody(apiOp)
+			if err != nil {
+				return types.APIObject{}, err
+			}
+			if obj.ID == "conflict" {
+				return types.APIObject{}, apierror.NewAPIError(validation.Conflict, "already exists")
+			}
+			if created != nil {
+				created(obj.ID)
+			}
+			return types.APIObject{Type: "foo", ID: obj.ID, Object: obj.Object}, nil
+		},
+	})
+	return srv
+}
+
+func batchRequest(body io.Reader) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/foos?_batch=true", body)
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestBatchCreate(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		want       []map[string]interface{}
+	}{
+		{
+			name:       "per item results",
+			body:       `[{"id":"a"},{"id":"conflict"},42,{"id":"b"}]`,
+			wantStatus: http.StatusOK,
+			want: []map[string]interface{}{
+				{"type": "foo", "id": "a"},
+				{"type": "error", "index": float64(1), "status": float64(http.StatusConflict)},
+				{"type": "error", "index": float64(2), "status": float64(http.StatusBadRequest)},
+				{"type": "foo", "id": "b"},
+			},
+		},
+		{
+			name:       "empty",
+			body:       `[]`,
+			wantStatus: http.StatusOK,
+			want:       []map[string]interface{}{},
+		},
+		{
+			name:       "malformed after an item",
+			body:       `[{"id":"a"},{"id":`,
+			wantStatus: http.StatusOK,
+			want: []map[string]interface{}{
+				{"type": "foo", "id": "a"},
+				{"type": "error", "index": float64(1), "status": float64(http.StatusBadRequest)},
+			},
+		},
+		{
+			name:       "oversized item",
+			body:       `[{"id":"a","data":"` + strings.Repeat("x", 3<<20) + `"}]`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "oversized item after an item",
+			body:       `[{"id":"a"}, {"id":"b","data":"` + strings.Repeat("x", 3<<20) + `"}, {"id":"c"}]`,
+			wantStatus: http.StatusOK,
+			want: []map[string]interface{}{
+				{"type": "foo", "id": "a"},
+				{"type": "error", "index": float64(1), "status": float64(http.StatusRequestEntityTooLarge)},
+			},
+		},
+		{
+			name:       "not an array",
+			body:       `{"id":"a"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newBatchServer(nil)
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  batchRequest(strings.NewReader(tt.body)),
+				Response: resp,
+				Type:     "foo",
+			})
+
+			require.Equal(t, tt.wantStatus, resp.Code, resp.Body.String())
+			if tt.want == nil {
+				return
+			}
+			var collection struct {
+				Data []map[string]interface{} `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &collection))
+			require.Len(t, collection.Data, len(tt.want))
+			for i, want := range tt.want {
+				for key, value := range want {
+					assert.Equal(t, value, collection.Data[i][key], "item %d %s", i, key)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchCreateStreams(t *testing.T) {
+	const items = 1000
+
+	created := make(chan string)
+	srv := newBatchServer(func(id string) {
+		created <- id
+	})
+
+	body, bodyWriter := io.Pipe()
+	resp := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.Handle(&types.APIRequest{
+			Request:  batchRequest(body),
+			Response: resp,
+			Type:     "foo",
+		})
+	}()
+
+	// each item must be created before the next one is sent, so the array is never read as a whole
+	_, err := io.WriteString(bodyWriter, "[")
+	require.NoError(t, err)
+	for i := 0; i < items; i++ {
+		separator := ","
+		if i == 0 {
+			separator = ""
+		}
+		_, err := fmt.Fprintf(bodyWriter, `%s{"id":"item-%d","data":"%s"}`, separator, i, strings.Repeat("x", 1024))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("item-%d", i), <-created)
+	}
+	_, err = io.WriteString(bodyWriter, "]")
+	require.NoError(t, err)
+	require.NoError(t, bodyWriter.Close())
+	<-done
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	var collection struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &collection))
+	assert.Len(t, collection.Data, items)
+}
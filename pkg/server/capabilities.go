@@ -0,0 +1,85 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/rancher/apiserver/pkg/builtin"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/apiserver/pkg/writer"
+)
+
+// Capabilities describes what a server supports, so clients can detect features instead of assuming them. It is
+// returned for OPTIONS requests to the root of the API.
+type Capabilities struct {
+	// Formats are the response formats, as accepted by the _format query parameter.
+	Formats []string `json:"formats"`
+	// Encodings are the content encodings responses can be compressed with.
+	Encodings []string `json:"encodings"`
+	// SubscribeTransports are the transports of subscriptions, "websocket" and "sse" for server-sent events.
+	SubscribeTransports []string `json:"subscribeTransports"`
+	// ListAction is the collection action listing resources with the ListOptions in the body.
+	ListAction string `json:"listAction"`
+	// Features tells which optional features are enabled, by name.
+	Features map[string]bool `json:"features"`
+}
+
+// isCapabilitiesRequest returns whether apiOp asks for the capabilities of the server.
+func isCapabilitiesRequest(apiOp *types.APIRequest) bool {
+	return apiOp.Method == http.MethodOptions && apiOp.Type == ""
+}
+
+func (s *Server) handleCapabilities(apiOp *types.APIRequest) types.APIObject {
+	if apiOp.Schema == nil {
+		schema := builtin.Capabilities
+		apiOp.Schema = &schema
+	}
+	capabilities := s.capabilities(apiOp)
+	if s.CustomizeCapabilities != nil {
+		s.CustomizeCapabilities(capabilities)
+	}
+	return types.APIObject{
+		Type:   builtin.Capabilities.ID,
+		Object: capabilities,
+	}
+}
+
+func (s *Server) capabilities(apiOp *types.APIRequest) *Capabilities {
+	c := &Capabilities{
+		Formats:             []string{},
+		Encodings:           []string{},
+		SubscribeTransports: []string{},
+		ListAction:          s.listAction(),
+	}
+
+	gzip := false
+	for format, responseWriter := range s.ResponseWriters {
+		c.Formats = append(c.Formats, format)
+		if _, ok := responseWriter.(*writer.GzipWriter); ok {
+			gzip = true
+		}
+	}
+	sort.Strings(c.Formats)
+	if gzip {
+		c.Encodings = append(c.Encodings, "gzip")
+	}
+
+	subscribe := apiOp.Schemas.LookupSchema("subscribe") != nil
+	if subscribe {
+		c.SubscribeTransports = append(c.SubscribeTransports, "websocket", "sse")
+	}
+
+	c.Features = map[string]bool{
+		"filtering":             true,
+		"sorting":               true,
+		"pagination":            true,
+		"batchCreate":           true,
+		"schemaWatch":           subscribe,
+		"bufferedResponses":     s.BufferResponseBytes > 0,
+		"maxResponseBytes":      s.MaxResponseBytes > 0,
+		"strictQueryParameters": s.StrictQueryParameters,
+		"suggestTypes":          s.SuggestTypes,
+		"sanitizeServerErrors":  s.SanitizeServerErrors,
+	}
+	return c
+}
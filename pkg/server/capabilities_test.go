@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(srv *Server)
+		check     func(t *testing.T, c Capabilities)
+	}{
+		{
+			name: "default server",
+			check: func(t *testing.T, c Capabilities) {
+				assert.Equal(t, []string{"csv", "html", "json", "jsonl", "yaml"}, c.Formats)
+				assert.Equal(t, []string{"gzip"}, c.Encodings)
+				assert.Equal(t, []string{"websocket", "sse"}, c.SubscribeTransports)
+				assert.Equal(t, DefaultListAction, c.ListAction)
+				assert.True(t, c.Features["pagination"])
+				assert.True(t, c.Features["filtering"])
+				assert.True(t, c.Features["schemaWatch"])
+				assert.False(t, c.Features["strictQueryParameters"])
+				assert.False(t, c.Features["bufferedResponses"])
+			},
+		},
+		{
+			name: "enabled features",
+			configure: func(srv *Server) {
+				srv.StrictQueryParameters = true
+				srv.BufferResponseBytes = 1 << 20
+				srv.SuggestTypes = true
+				srv.ListAction = "query"
+			},
+			check: func(t *testing.T, c Capabilities) {
+				assert.Equal(t, "query", c.ListAction)
+				assert.True(t, c.Features["strictQueryParameters"])
+				assert.True(t, c.Features["bufferedResponses"])
+				assert.True(t, c.Features["suggestTypes"])
+			},
+		},
+		{
+			name: "custom formats without subscriptions",
+			configure: func(srv *Server) {
+				srv.ResponseWriters = map[string]types.ResponseWriter{"json": srv.ResponseWriters["json"]}
+				srv.Schemas = types.EmptyAPISchemas()
+			},
+			check: func(t *testing.T, c Capabilities) {
+				assert.Equal(t, []string{"json"}, c.Formats)
+				assert.Empty(t, c.SubscribeTransports)
+				assert.False(t, c.Features["schemaWatch"])
+			},
+		},
+		{
+			name: "customized",
+			configure: func(srv *Server) {
+				srv.CustomizeCapabilities = func(c *Capabilities) {
+					c.Encodings = append([]string{"br"}, c.Encodings...)
+				}
+			},
+			check: func(t *testing.T, c Capabilities) {
+				assert.Equal(t, []string{"br", "gzip"}, c.Encodings)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			if tt.configure != nil {
+				tt.configure(srv)
+			}
+
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodOptions, "/v1", nil),
+				Response: resp,
+			})
+
+			require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+			var c Capabilities
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &c))
+			assert.Contains(t, resp.Body.String(), `"type":"capabilities"`)
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestOptionsOnResource(t *testing.T) {
+	srv := DefaultAPIServer()
+
+	resp := httptest.NewRecorder()
+	srv.Handle(&types.APIRequest{
+		Request:  httptest.NewRequest(http.MethodOptions, "/v1/schemas", nil),
+		Response: resp,
+		Type:     "schema",
+	})
+
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// defaultCSRFTokenLength is the number of random bytes of a token when CSRFTokens.Length is not set. 16 bytes are
+// 128 bits of entropy.
+const defaultCSRFTokenLength = 16
+
+// defaultCSRFTokens generates the tokens of servers without CSRFTokens, and of CheckCSRF.
+var defaultCSRFTokens = &CSRFTokens{}
+
+// CSRFTokens generates and checks the tokens of the CSRF cookie. Browser clients must echo the cookie in the
+// X-API-CSRF header, or the CSRF query parameter, of requests changing state.
+//
+// CSRFTokens is also an http.Handler issuing a token, so clients that cannot read cookies, or would rather not
+// wait for their first GET to receive one, can ask for it.
+type CSRFTokens struct {
+	// Length is the number of random bytes of a token. If zero, 16 bytes are used.
+	Length int
+	// Key, if set, signs tokens with HMAC-SHA256. Cookies holding a token that was not signed with the key, such
+	// as one planted by a sibling domain, are then rejected.
+	Key []byte
+}
+
+// Generate returns a new token.
+func (c *CSRFTokens) Generate() (string, error) {
+	length := c.Length
+	if length <= 0 {
+		length = defaultCSRFTokenLength
+	}
+	bytes := make([]byte, length)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(bytes)
+	if len(c.Key) > 0 {
+		token += "." + c.sign(token)
+	}
+	return token, nil
+}
+
+// Valid returns whether token could have been returned by Generate. Without a Key, any token is valid.
+func (c *CSRFTokens) Valid(token string) bool {
+	if len(c.Key) == 0 {
+		return token != ""
+	}
+	value, signature, ok := strings.Cut(token, ".")
+	return ok && hmac.Equal([]byte(signature), []byte(c.sign(value)))
+}
+
+func (c *CSRFTokens) sign(value string) string {
+	mac := hmac.New(sha256.New, c.Key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// cookie returns the CSRF cookie holding token.
+func (c *CSRFTokens) cookie(token string) *http.Cookie {
+	return &http.Cookie{
+		Name:   csrfCookie,
+		Value:  token,
+		Path:   "/",
+		Secure: true,
+	}
+}
+
+// ServeHTTP returns the token of the CSRF cookie of the request as {"token": "..."}, setting the cookie to a new
+// token if the request has none, or has one that is not valid.
+func (c *CSRFTokens) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	var token string
+	if cookie, err := req.Cookie(csrfCookie); err == nil && c.Valid(cookie.Value) {
+		token = cookie.Value
+	} else {
+		if token, err = c.Generate(); err != nil {
+			http.Error(rw, "Failed in CSRF processing", http.StatusInternalServerError)
+			return
+		}
+		http.SetCookie(rw, c.cookie(token))
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(rw).Encode(map[string]string{"token": token})
+}
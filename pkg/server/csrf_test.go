@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRFTokens(t *testing.T) {
+	unsigned := &CSRFTokens{Length: 8}
+	token, err := unsigned.Generate()
+	require.NoError(t, err)
+	assert.Len(t, token, 16)
+	assert.True(t, unsigned.Valid(token))
+	assert.True(t, unsigned.Valid("anything"))
+
+	signed := &CSRFTokens{Key: []byte("secret")}
+	token, err = signed.Generate()
+	require.NoError(t, err)
+	assert.True(t, signed.Valid(token))
+	assert.False(t, signed.Valid(strings.Repeat("0", 32)), "unsigned")
+	tampered := "f" + token[1:]
+	if token[0] == 'f' {
+		tampered = "0" + token[1:]
+	}
+	assert.False(t, signed.Valid(tampered), "tampered")
+	assert.False(t, (&CSRFTokens{Key: []byte("other")}).Valid(token), "other key")
+}
+
+// issueCSRFToken asks tokens for a token, returning the token and the cookie it was set in, if any.
+func issueCSRFToken(t *testing.T, tokens *CSRFTokens, cookie *http.Cookie) (string, *http.Cookie) {
+	req := httptest.NewRequest(http.MethodGet, "/csrf", nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	resp := httptest.NewRecorder()
+	tokens.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "no-store", resp.Header().Get("Cache-Control"))
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+
+	cookies := resp.Result().Cookies()
+	if len(cookies) == 0 {
+		return body.Token, nil
+	}
+	require.Len(t, cookies, 1)
+	assert.Equal(t, csrfCookie, cookies[0].Name)
+	assert.Equal(t, body.Token, cookies[0].Value)
+	return body.Token, cookies[0]
+}
+
+func TestCSRFTokenEndpoint(t *testing.T) {
+	tokens := &CSRFTokens{Key: []byte("secret")}
+	srv := DefaultAPIServer()
+	srv.CSRFTokens = tokens
+	srv.Schemas.MustAddSchema(types.APISchema{
+		Schema: &schemas.Schema{
+			ID:                "foo",
+			CollectionMethods: []string{http.MethodPost},
+		},
+		CreateHandler: func(apiOp *types.APIRequest) (types.APIObject, error) {
+			return types.APIObject{Type: "foo", ID: "foo1"}, nil
+		},
+	})
+	create := func(cookie *http.Cookie, token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/foos", strings.NewReader("{}"))
+		req.Header.Set("User-Agent", "Mozilla/5.0")
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(csrfHeader, token)
+		req.AddCookie(cookie)
+		resp := httptest.NewRecorder()
+		srv.Handle(&types.APIRequest{Request: req, Response: resp, Type: "foo"})
+		return resp.Code
+	}
+
+	token, cookie := issueCSRFToken(t, tokens, nil)
+	require.NotNil(t, cookie, "a new token must be set in the cookie")
+	assert.True(t, tokens.Valid(token))
+
+	// the token of a valid cookie is returned as is
+	again, newCookie := issueCSRFToken(t, tokens, cookie)
+	assert.Equal(t, token, again)
+	assert.Nil(t, newCookie)
+
+	assert.Equal(t, http.StatusCreated, create(cookie, token))
+	assert.Equal(t, validation.InvalidCSRFToken.Status, create(cookie, "other"))
+
+	// a cookie the server did not sign is replaced by the endpoint, and rejected by the server
+	forged := &http.Cookie{Name: csrfCookie, Value: "forged"}
+	replaced, newCookie := issueCSRFToken(t, tokens, forged)
+	require.NotNil(t, newCookie)
+	assert.NotEqual(t, "forged", replaced)
+	assert.Equal(t, validation.InvalidCSRFToken.Status, create(forged, "forged"))
+}
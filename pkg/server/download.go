@@ -0,0 +1,46 @@
+package server
+
+import (
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+)
+
+// handleDownload writes the raw content returned by the DownloadHandler of the schema.
+func handleDownload(apiOp *types.APIRequest) error {
+	if err := apiOp.AccessControl.CanGet(apiOp, apiOp.Schema); err != nil {
+		return err
+	}
+
+	download, err := apiOp.Schema.DownloadHandler(apiOp)
+	if err != nil {
+		return err
+	}
+	if download.Content == nil {
+		return validation.NotFound
+	}
+	defer download.Content.Close()
+
+	contentType := download.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := apiOp.Response.Header()
+	header.Set("Content-Type", contentType)
+	header.Set("X-Content-Type-Options", "nosniff")
+	if download.Filename != "" {
+		header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": download.Filename}))
+	}
+	if content, ok := download.Content.(io.ReadSeeker); ok {
+		// ServeContent answers range requests and advertises it with Accept-Ranges
+		http.ServeContent(apiOp.Response, apiOp.Request, "", download.ModTime, content)
+		return validation.ErrComplete
+	}
+	header.Set("Accept-Ranges", "none")
+	apiOp.Response.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(apiOp.Response, download.Content)
+	return validation.ErrComplete
+}
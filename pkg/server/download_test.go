@@ -0,0 +1,217 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownload(t *testing.T) {
+	const kubeconfig = "apiVersion: v1\nkind: Config\n"
+
+	tests := []struct {
+		name            string
+		query           string
+		accept          string
+		download        types.DownloadHandler
+		wantStatus      int
+		wantContentType string
+		wantDisposition string
+		wantBody        string
+	}{
+		{
+			name:  "raw format",
+			query: "?_format=raw",
+			download: func(apiOp *types.APIRequest) (types.Download, error) {
+				return types.Download{
+					ContentType: "application/yaml",
+					Filename:    apiOp.Name + ".yaml",
+					Content:     io.NopCloser(strings.NewReader(kubeconfig)),
+				}, nil
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/yaml",
+			wantDisposition: `attachment; filename=foo1.yaml`,
+			wantBody:        kubeconfig,
+		},
+		{
+			name:   "octet-stream accepted",
+			accept: "application/octet-stream",
+			download: func(apiOp *types.APIRequest) (types.Download, error) {
+				return types.Download{
+					Filename: "logs archive.tar.gz",
+					Content:  io.NopCloser(strings.NewReader("archive")),
+				}, nil
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/octet-stream",
+			wantDisposition: `attachment; filename="logs archive.tar.gz"`,
+			wantBody:        "archive",
+		},
+		{
+			name:  "no filename",
+			query: "?_format=raw",
+			download: func(apiOp *types.APIRequest) (types.Download, error) {
+				return types.Download{Content: io.NopCloser(strings.NewReader("archive"))}, nil
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/octet-stream",
+			wantBody:        "archive",
+		},
+		{
+			name: "json without raw format",
+			download: func(apiOp *types.APIRequest) (types.Download, error) {
+				t.Fatal("the download handler must not be called")
+				return types.Download{}, nil
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "raw format without download handler",
+			query:           "?_format=raw",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+		},
+		{
+			name:  "not found",
+			query: "?_format=raw",
+			download: func(apiOp *types.APIRequest) (types.Download, error) {
+				return types.Download{}, nil
+			},
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "application/json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.Schemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:              "foo",
+					ResourceMethods: []string{http.MethodGet},
+				},
+				ByIDHandler: func(apiOp *types.APIRequest) (types.APIObject, error) {
+					return types.APIObject{Type: "foo", ID: apiOp.Name, Object: map[string]interface{}{}}, nil
+				},
+				DownloadHandler: tt.download,
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/foos/foo1"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  req,
+				Response: resp,
+				Type:     "foo",
+				Name:     "foo1",
+			})
+
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			assert.Equal(t, tt.wantContentType, resp.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantDisposition, resp.Header().Get("Content-Disposition"))
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, resp.Body.String())
+			}
+		})
+	}
+}
+
+// seekableContent is download content supporting range requests.
+type seekableContent struct {
+	*strings.Reader
+}
+
+func (seekableContent) Close() error {
+	return nil
+}
+
+func TestAcceptRanges(t *testing.T) {
+	tests := []struct {
+		name             string
+		query            string
+		rangeHeader      string
+		download         types.DownloadHandler
+		wantStatus       int
+		wantAcceptRanges string
+		wantBody         string
+	}{
+		{
+			name:             "encoded response",
+			wantStatus:       http.StatusOK,
+			wantAcceptRanges: "none",
+		},
+		{
+			name:  "streamed download",
+			query: "?_format=raw",
+			download: func(apiOp *types.APIRequest) (types.Download, error) {
+				return types.Download{Content: io.NopCloser(strings.NewReader("0123456789"))}, nil
+			},
+			wantStatus:       http.StatusOK,
+			wantAcceptRanges: "none",
+			wantBody:         "0123456789",
+		},
+		{
+			name:  "seekable download",
+			query: "?_format=raw",
+			download: func(apiOp *types.APIRequest) (types.Download, error) {
+				return types.Download{Content: seekableContent{strings.NewReader("0123456789")}}, nil
+			},
+			wantStatus:       http.StatusOK,
+			wantAcceptRanges: "bytes",
+			wantBody:         "0123456789",
+		},
+		{
+			name:        "seekable download range",
+			query:       "?_format=raw",
+			rangeHeader: "bytes=2-5",
+			download: func(apiOp *types.APIRequest) (types.Download, error) {
+				return types.Download{Content: seekableContent{strings.NewReader("0123456789")}}, nil
+			},
+			wantStatus:       http.StatusPartialContent,
+			wantAcceptRanges: "bytes",
+			wantBody:         "2345",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.Schemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:              "foo",
+					ResourceMethods: []string{http.MethodGet},
+				},
+				ByIDHandler: func(apiOp *types.APIRequest) (types.APIObject, error) {
+					return types.APIObject{Type: "foo", ID: apiOp.Name, Object: map[string]interface{}{}}, nil
+				},
+				DownloadHandler: tt.download,
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/foos/foo1"+tt.query, nil)
+			if tt.rangeHeader != "" {
+				req.Header.Set("Range", tt.rangeHeader)
+			}
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  req,
+				Response: resp,
+				Type:     "foo",
+				Name:     "foo1",
+			})
+
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			assert.Equal(t, tt.wantAcceptRanges, resp.Header().Get("Accept-Ranges"))
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, resp.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// RegisterResponseWriter serves the responses of requests asking for format in the _format query parameter with w.
+// To have an Accept header select the format too, register its media type with RegisterFormat. The built-in writers
+// are wrapped in a writer.GzipWriter, which w can be as well to compress its responses. It must be called before the
+// server handles requests.
+func (s *Server) RegisterResponseWriter(format string, w types.ResponseWriter) {
+	if s.ResponseWriters == nil {
+		s.ResponseWriters = map[string]types.ResponseWriter{}
+	}
+	s.ResponseWriters[strings.ToLower(format)] = w
+}
+
+// RegisterFormat has requests whose Accept header prefers mediaType written in format, by the writer registered with
+// RegisterResponseWriter. It must be called before the server handles requests.
+//
+// The media type is selected when it is the first of the Accept header, by quality, that is not a wildcard, so
+// "application/msgpack, */*" from a browser gets msgpack rather than HTML, while "application/json,
+// application/msgpack;q=0.5" still gets JSON. A media type with a quality of zero is never selected. A format set
+// with _format takes precedence over the Accept header.
+func (s *Server) RegisterFormat(format, mediaType string) {
+	if s.ResponseMediaTypes == nil {
+		s.ResponseMediaTypes = map[string]string{}
+	}
+	s.ResponseMediaTypes[strings.ToLower(mediaType)] = strings.ToLower(format)
+}
+
+// responseFormat returns the format of the response to apiOp, taking the formats of the server into account on top
+// of the format found by the parser.
+func (s *Server) responseFormat(apiOp *types.APIRequest) string {
+	if apiOp.Request == nil {
+		return apiOp.ResponseFormat
+	}
+	if format := strings.TrimSpace(strings.ToLower(apiOp.Request.URL.Query().Get("_format"))); format != "" {
+		if _, ok := s.ResponseWriters[format]; ok {
+			return format
+		}
+		return apiOp.ResponseFormat
+	}
+	if len(s.ResponseMediaTypes) == 0 {
+		return apiOp.ResponseFormat
+	}
+	if mediaType, ok := preferredMediaType(apiOp.Request.Header.Get("Accept")); ok {
+		if format, ok := s.ResponseMediaTypes[mediaType]; ok {
+			return format
+		}
+	}
+	return apiOp.ResponseFormat
+}
+
+// preferredMediaType returns the media type of accept with the highest quality, the first one listed among equals,
+// skipping wildcards and media types refused with a quality of zero.
+func preferredMediaType(accept string) (string, bool) {
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if mediaType == "" || strings.Contains(mediaType, "*") {
+			continue
+		}
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if q, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+					quality = parsed
+				}
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+		}
+	}
+	if len(ranges) == 0 {
+		return "", false
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	return ranges[0].mediaType, true
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/apiserver/pkg/writer"
+	"github.com/stretchr/testify/assert"
+)
+
+// prefixWriter writes the number of objects it is given after a prefix, as a stand-in for a custom format.
+type prefixWriter struct {
+	prefix string
+}
+
+func (p *prefixWriter) Write(apiOp *types.APIRequest, code int, obj types.APIObject) {
+	apiOp.Response.Header().Set("Content-Type", "application/x-prefixed")
+	apiOp.Response.WriteHeader(code)
+	_, _ = fmt.Fprintf(apiOp.Response, "%s %s", p.prefix, obj.Type)
+}
+
+func (p *prefixWriter) WriteList(apiOp *types.APIRequest, code int, list types.APIObjectList) {
+	apiOp.Response.Header().Set("Content-Type", "application/x-prefixed")
+	apiOp.Response.WriteHeader(code)
+	_, _ = fmt.Fprintf(apiOp.Response, "%s %d objects", p.prefix, len(list.Objects))
+}
+
+func TestRegisterResponseWriter(t *testing.T) {
+	srv := DefaultAPIServer()
+	srv.RegisterResponseWriter("prefixed", &writer.GzipWriter{ResponseWriter: &prefixWriter{prefix: "prefixed:"}})
+	srv.RegisterFormat("prefixed", "application/x-prefixed")
+
+	tests := []struct {
+		name        string
+		target      string
+		accept      string
+		userAgent   string
+		wantFormat  bool
+		contentType string
+	}{
+		{name: "format", target: "/v1/schemas?_format=prefixed", wantFormat: true},
+		{name: "media type", target: "/v1/schemas", accept: "application/x-prefixed", wantFormat: true},
+		{name: "media type from a browser", target: "/v1/schemas", accept: "application/x-prefixed, */*", userAgent: "Mozilla/5.0", wantFormat: true},
+		{name: "preferred media type", target: "/v1/schemas", accept: "application/json;q=0.5, application/x-prefixed", wantFormat: true},
+		{name: "other media type preferred", target: "/v1/schemas", accept: "application/yaml, application/x-prefixed;q=0.5", contentType: "application/yaml"},
+		{name: "media type refused", target: "/v1/schemas", accept: "application/x-prefixed;q=0", contentType: "application/json"},
+		{name: "media type prefix", target: "/v1/schemas", accept: "application/x-prefixed-v2", contentType: "application/json"},
+		{name: "format over media type", target: "/v1/schemas?_format=yaml", accept: "application/x-prefixed", contentType: "application/yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			if tt.userAgent != "" {
+				req.Header.Set("User-Agent", tt.userAgent)
+			}
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  req,
+				Response: resp,
+				Type:     "schema",
+			})
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+			if tt.wantFormat {
+				assert.Equal(t, "application/x-prefixed", resp.Header().Get("Content-Type"))
+				assert.Regexp(t, `^prefixed: \d+ objects$`, resp.Body.String())
+				return
+			}
+			assert.Equal(t, tt.contentType, resp.Header().Get("Content-Type"))
+		})
+	}
+
+	// formats belong to the server they are registered with
+	for _, target := range []string{"/v1/schemas?_format=prefixed", "/v1/schemas"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept", "application/x-prefixed")
+		resp := httptest.NewRecorder()
+		DefaultAPIServer().Handle(&types.APIRequest{
+			Request:  req,
+			Response: resp,
+			Type:     "schema",
+		})
+		assert.Equal(t, "application/json", resp.Header().Get("Content-Type"), target)
+	}
+}
+
+func TestPreferredMediaType(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "application/msgpack", want: "application/msgpack"},
+		{accept: "Application/MsgPack; charset=utf-8", want: "application/msgpack"},
+		{accept: "application/json, application/msgpack", want: "application/json"},
+		{accept: "application/json;q=0.8, application/msgpack;q=0.9", want: "application/msgpack"},
+		{accept: "*/*, application/msgpack;q=0.1", want: "application/msgpack"},
+		{accept: "application/msgpack;q=0"},
+		{accept: "*/*"},
+		{},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			got, ok := preferredMediaType(tt.accept)
+			assert.Equal(t, tt.want != "", ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/handlers"
+	"github.com/rancher/apiserver/pkg/parse"
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// DefaultListAction is the collection action listing resources with the ListOptions sent in the body of the request,
+// when Server.ListAction is not set.
+const DefaultListAction = "list"
+
+func (s *Server) listAction() string {
+	if s.ListAction == "" {
+		return DefaultListAction
+	}
+	return s.ListAction
+}
+
+// isListAction returns whether apiOp posts the list action to a collection, which is a list rather than a create,
+// unless the schema declares a collection action of the same name.
+func isListAction(apiOp *types.APIRequest, action string) bool {
+	if apiOp.Method != http.MethodPost || apiOp.Name != "" || apiOp.Link != "" || apiOp.Action != action {
+		return false
+	}
+	_, declared := apiOp.Schema.CollectionActions[action]
+	return !declared
+}
+
+// handleListAction reads the ListOptions of the request from its body and lists the collection as a GET would.
+func handleListAction(apiOp *types.APIRequest) (types.APIObjectList, error) {
+	if parse.ExpectsContinue(apiOp.Request) {
+		// reject unauthorized requests before reading the body makes the client send it
+		if err := apiOp.AccessControl.CanList(apiOp, apiOp.Schema); err != nil {
+			return types.APIObjectList{}, err
+		}
+	}
+
+	opts, err := parse.ListOptionsBody(apiOp.Request)
+	if err != nil {
+		return types.APIObjectList{}, err
+	}
+
+	apiOp.Method = http.MethodGet
+	apiOp.Action = ""
+	apiOp.ListOptions = opts
+	if err := parse.ValidateMethod(apiOp); err != nil {
+		return types.APIObjectList{}, err
+	}
+	return handleList(apiOp, apiOp.Schema.ListHandler, handlers.MetricsListHandler("200", handlers.ListHandler))
+}
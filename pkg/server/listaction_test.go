@@ -0,0 +1,129 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListAction(t *testing.T) {
+	tests := []struct {
+		name          string
+		listAction    string
+		action        string
+		body          string
+		declareAction bool
+		wantStatus    int
+		wantListed    bool
+		wantOptions   *types.ListOptions
+	}{
+		{
+			name:       "list with body",
+			action:     "list",
+			body:       `{"filters": [{"field": ["spec", "tier"], "value": "back"}], "sort": [{"field": ["name"], "descending": true}]}`,
+			wantStatus: http.StatusOK,
+			wantListed: true,
+			wantOptions: &types.ListOptions{
+				Filters: []types.ListFilter{{Field: []string{"spec", "tier"}, Modifier: types.ModifierEQ, Value: "back"}},
+				Sort:    []types.ListSort{{Field: []string{"name"}, Descending: true}},
+			},
+		},
+		{
+			name:       "list without body",
+			action:     "list",
+			wantStatus: http.StatusOK,
+			wantListed: true,
+		},
+		{
+			name:       "create",
+			body:       `{"name": "foo1"}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "configured action",
+			listAction: "query",
+			action:     "query",
+			body:       `{"page": 2, "pageSize": 10}`,
+			wantStatus: http.StatusOK,
+			wantListed: true,
+			wantOptions: &types.ListOptions{
+				Page:     2,
+				PageSize: 10,
+			},
+		},
+		{
+			name:          "declared collection action",
+			action:        "list",
+			declareAction: true,
+			wantStatus:    http.StatusOK,
+		},
+		{
+			name:       "invalid body",
+			action:     "list",
+			body:       `{"filters": [{"field": ["name"], "modifier": "gt", "value": "a"}]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				listed  bool
+				options *types.ListOptions
+				method  string
+			)
+			schema := types.APISchema{
+				Schema: &schemas.Schema{
+					ID:                "foo",
+					CollectionMethods: []string{http.MethodGet, http.MethodPost},
+				},
+				ListHandler: func(apiOp *types.APIRequest) (types.APIObjectList, error) {
+					listed = true
+					options = apiOp.ListOptions
+					method = apiOp.Method
+					return types.APIObjectList{}, nil
+				},
+				CreateHandler: func(apiOp *types.APIRequest) (types.APIObject, error) {
+					return types.APIObject{Type: "foo", ID: "foo1", Object: map[string]interface{}{}}, nil
+				},
+			}
+			if tt.declareAction {
+				schema.CollectionActions = map[string]schemas.Action{"list": {}}
+				schema.ActionRequestHandlers = map[string]types.RequestHandler{
+					"list": func(apiOp *types.APIRequest) (types.APIObject, error) {
+						return types.APIObject{Object: map[string]interface{}{}}, nil
+					},
+				}
+			}
+			srv := DefaultAPIServer()
+			srv.ListAction = tt.listAction
+			srv.Schemas.MustAddSchema(schema)
+
+			target := "/foos"
+			if tt.action != "" {
+				target += "?action=" + tt.action
+			}
+			req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  req,
+				Response: resp,
+				Type:     "foo",
+				Action:   tt.action,
+			})
+
+			assert.Equal(t, tt.wantStatus, resp.Code, resp.Body.String())
+			assert.Equal(t, tt.wantListed, listed)
+			if tt.wantListed {
+				assert.Equal(t, tt.wantOptions, options)
+				assert.Equal(t, http.MethodGet, method)
+				assert.Contains(t, resp.Body.String(), `"type":"collection"`)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package server
+
+import (
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// RegisterResource imports obj into schemas, backs it with store and allows the given collection and resource
+// methods. It panics if obj can not be imported, like MustImportAndCustomize. The returned schema can be
+// customized further.
+func RegisterResource(schemas *types.APISchemas, obj interface{}, store types.Store, collectionMethods, resourceMethods []string) *types.APISchema {
+	var result *types.APISchema
+	schemas.MustImportAndCustomize(obj, func(schema *types.APISchema) {
+		schema.Store = store
+		schema.CollectionMethods = collectionMethods
+		schema.ResourceMethods = resourceMethods
+		result = schema
+	})
+	return result
+}
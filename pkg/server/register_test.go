@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type registeredFoo struct {
+	Bar string `json:"bar"`
+}
+
+func TestRegisterResource(t *testing.T) {
+	schemas := types.EmptyAPISchemas()
+	store := &empty.Store{}
+
+	schema := RegisterResource(schemas, registeredFoo{}, store,
+		[]string{http.MethodGet, http.MethodPost},
+		[]string{http.MethodGet, http.MethodPut, http.MethodDelete})
+	require.NotNil(t, schema)
+
+	assert.Same(t, schema, schemas.LookupSchema("registeredFoo"))
+	assert.Same(t, store, schema.Store)
+	assert.Equal(t, []string{http.MethodGet, http.MethodPost}, schema.CollectionMethods)
+	assert.Equal(t, []string{http.MethodGet, http.MethodPut, http.MethodDelete}, schema.ResourceMethods)
+	assert.Contains(t, schema.ResourceFields, "bar")
+}
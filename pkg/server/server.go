@@ -1,19 +1,26 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/rancher/apiserver/pkg/apierror"
 	"github.com/rancher/apiserver/pkg/builtin"
 	"github.com/rancher/apiserver/pkg/handlers"
 	"github.com/rancher/apiserver/pkg/metrics"
 	"github.com/rancher/apiserver/pkg/parse"
+	"github.com/rancher/apiserver/pkg/store/schema"
 	"github.com/rancher/apiserver/pkg/subscribe"
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/apiserver/pkg/writer"
 	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
 )
 
 type RequestHandler interface {
@@ -23,12 +30,78 @@ type RequestHandler interface {
 	Handle(apiOp *types.APIRequest)
 }
 
+// InstanceIDHeader is the response header holding the InstanceID of the server.
+const InstanceIDHeader = "X-Served-By"
+
 type Server struct {
+	// ResponseWriters write responses, by format. Formats can be asked for with the _format query parameter.
 	ResponseWriters map[string]types.ResponseWriter
-	Schemas         *types.APISchemas
-	AccessControl   types.AccessControl
-	Parser          parse.Parser
-	URLParser       parse.URLParser
+	// ResponseMediaTypes maps media types of the Accept header to the format of ResponseWriters they select, for
+	// formats the parser does not detect itself, such as "application/msgpack" to "msgpack". See RegisterFormat.
+	ResponseMediaTypes map[string]string
+	// Schemas are the schemas served until SetSchemas is called. It must not be changed while requests are being
+	// served, use SetSchemas instead.
+	Schemas       *types.APISchemas
+	AccessControl types.AccessControl
+	Parser        parse.Parser
+	URLParser     parse.URLParser
+	// MaxResponseBytes limits the size of encoded response bodies. A response that exceeds it before any of it is
+	// sent is replaced with an error, otherwise it is truncated. Zero means unlimited.
+	MaxResponseBytes int64
+	// BufferResponseBytes, if positive, holds responses of up to this size back until they are complete, to send
+	// them with a Content-Length and an ETag. Larger responses are streamed, as are responses still being written
+	// once BufferResponseDelay has passed, if it is set.
+	BufferResponseBytes int64
+	BufferResponseDelay time.Duration
+	// FlushResponseBytes and FlushResponseInterval, if either is set, batch the writes of responses as they are
+	// streamed: writes are sent and flushed once FlushResponseBytes are pending or FlushResponseInterval has passed
+	// since the oldest pending write, for fewer network writes on responses made of many small items, such as jsonl
+	// lists, at the cost of a bounded delay. writer.DefaultFlushBytes or writer.DefaultFlushInterval is used for the
+	// one that is not set.
+	FlushResponseBytes    int
+	FlushResponseInterval time.Duration
+	// ListAction is the collection action of POST requests listing resources with ListOptions sent in the body
+	// instead of the query, for queries too large for a URL, as in POST /v1/pods?action=list. If empty,
+	// DefaultListAction is used. Collection actions declared by a schema take precedence.
+	ListAction string
+	// NamePolicy decides how resource names and namespaces with surrounding whitespace, a trailing dot or control
+	// characters are handled. By default they are passed on as they are; names with "." or ".." path segments are
+	// rejected with a 400 regardless.
+	NamePolicy parse.NamePolicy
+	// BodyLimits are the limits on the nesting depth of JSON and YAML request bodies and on the number of parts and
+	// size of multipart/form-data bodies. Zero fields use the defaults of the parse package.
+	BodyLimits parse.BodyLimits
+	// SanitizeServerErrors replaces the message of errors with a 5xx status, which may hold internal details such as
+	// queries or host names, by a generic one in responses, logging the original message instead. Messages of 4xx
+	// errors are kept. By default, all messages are returned to the client.
+	SanitizeServerErrors bool
+	// StrictQueryParameters rejects requests with query parameters that are not known to the server or declared
+	// in the QueryParameters of the schema.
+	StrictQueryParameters bool
+	// DefaultHeaders are added to every response, including errors, unless the header is already set. Handlers
+	// can still override them.
+	DefaultHeaders map[string]string
+	// SuggestTypes adds the closest matching types to the error returned for an unknown type. Only types the
+	// caller can list or get are suggested.
+	SuggestTypes bool
+	// InstanceID identifies the replica serving the request in the InstanceIDHeader of every response, to help
+	// debugging deployments with several replicas. If empty, the header is not set.
+	InstanceID string
+	// CSRFTokens generates and checks the tokens of the CSRF cookie of browser requests. If nil, tokens of 16
+	// random bytes are used, without signing.
+	CSRFTokens *CSRFTokens
+	// Timeouts bounds the time spent handling each type of operation. Operations that time out are answered
+	// with a 504.
+	Timeouts Timeouts
+	// CustomizeCapabilities, if set, is called with the capabilities returned for OPTIONS requests to the root of
+	// the API, to add what the server can not know about, such as the encodings of a compression middleware.
+	CustomizeCapabilities func(*Capabilities)
+	// URLBuilderFunc, if set, creates the URLBuilder of requests, so embedders can build links their own way.
+	// The default builder is used otherwise.
+	URLBuilderFunc types.URLBuilderFunc
+
+	schemas       atomic.Pointer[types.APISchemas]
+	schemaChanges schema.Notifier
 }
 
 func DefaultAPIServer() *Server {
@@ -37,24 +110,31 @@ func DefaultAPIServer() *Server {
 		ResponseWriters: map[string]types.ResponseWriter{
 			"json": &writer.GzipWriter{
 				ResponseWriter: &writer.EncodingResponseWriter{
-					ContentType: "application/json",
-					Encoder:     types.JSONEncoder,
+					ContentType:      "application/json",
+					Encoder:          types.JSONEncoder,
+					ReplaceNonFinite: writer.NullNonFinite,
 				},
 			},
 			"jsonl": &writer.GzipWriter{
 				ResponseWriter: &writer.EncodingResponseWriter{
-					ContentType: "application/jsonl",
-					Encoder:     types.JSONLinesEncoder,
+					ContentType:      "application/jsonl",
+					Encoder:          types.JSONLinesEncoder,
+					ReplaceNonFinite: writer.NullNonFinite,
 				},
 			},
 			"html": &writer.GzipWriter{
 				ResponseWriter: &writer.HTMLResponseWriter{
 					EncodingResponseWriter: writer.EncodingResponseWriter{
-						Encoder:     types.JSONEncoder,
-						ContentType: "application/json",
+						Encoder:          types.JSONEncoder,
+						ContentType:      "application/json",
+						ReplaceNonFinite: writer.NullNonFinite,
 					},
+					ErrorPages: true,
 				},
 			},
+			"csv": &writer.GzipWriter{
+				ResponseWriter: &writer.CSVWriter{},
+			},
 			"yaml": &writer.GzipWriter{
 				ResponseWriter: &writer.EncodingResponseWriter{
 					ContentType: "application/yaml",
@@ -73,6 +153,7 @@ func DefaultAPIServer() *Server {
 
 func (s *Server) setDefaults(ctx *types.APIRequest) {
 	if ctx.ResponseWriter == nil {
+		ctx.ResponseFormat = s.responseFormat(ctx)
 		ctx.ResponseWriter = s.ResponseWriters[ctx.ResponseFormat]
 		if ctx.ResponseWriter == nil {
 			ctx.ResponseWriter = s.ResponseWriters["json"]
@@ -82,12 +163,36 @@ func (s *Server) setDefaults(ctx *types.APIRequest) {
 	if ctx.ErrorHandler == nil {
 		ctx.ErrorHandler = handlers.ErrorHandler
 	}
+	if s.SanitizeServerErrors {
+		ctx.ErrorHandler = handlers.SanitizeServerErrors(ctx.ErrorHandler)
+	}
 
 	ctx.AccessControl = s.AccessControl
 
 	if ctx.Schemas == nil {
-		ctx.Schemas = s.Schemas
+		ctx.Schemas = s.GetSchemas()
+	}
+}
+
+// GetSchemas returns the schemas currently served.
+func (s *Server) GetSchemas() *types.APISchemas {
+	if schemas := s.schemas.Load(); schemas != nil {
+		return schemas
+	}
+	return s.Schemas
+}
+
+// SetSchemas replaces the schemas served, such as when types are added at runtime. It is safe to call while
+// requests are being served. Requests read the schemas once, so requests in flight finish with the schemas they
+// started with.
+//
+// Subscriptions to the "schema" resource type are sent the schemas added, changed and removed by each call.
+func (s *Server) SetSchemas(schemas *types.APISchemas) {
+	old := s.schemas.Swap(schemas)
+	if old == nil {
+		old = s.Schemas
 	}
+	s.schemaChanges.Notify(old, schemas)
 }
 
 func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
@@ -101,11 +206,35 @@ func (s *Server) Handle(apiOp *types.APIRequest) {
 	s.handle(apiOp, s.Parser)
 }
 
+func (s *Server) setDefaultHeaders(rw http.ResponseWriter) {
+	if rw == nil {
+		return
+	}
+	header := rw.Header()
+	if s.InstanceID != "" {
+		header.Set(InstanceIDHeader, s.InstanceID)
+	}
+	for k, v := range s.DefaultHeaders {
+		if _, ok := header[http.CanonicalHeaderKey(k)]; !ok {
+			header.Set(k, v)
+		}
+	}
+}
+
 func (s *Server) handle(apiOp *types.APIRequest, parser parse.Parser) {
 	if apiOp.Schemas == nil {
-		apiOp.Schemas = s.Schemas
+		apiOp.Schemas = s.GetSchemas()
+	}
+	if apiOp.URLBuilderFunc == nil {
+		apiOp.URLBuilderFunc = s.URLBuilderFunc
+	}
+	if apiOp.Request != nil {
+		ctx := schema.WithNotifier(apiOp.Request.Context(), &s.schemaChanges)
+		apiOp.Request = apiOp.Request.WithContext(parse.WithBodyLimits(ctx, s.BodyLimits))
 	}
 
+	s.setDefaultHeaders(apiOp.Response)
+
 	if err := parser(apiOp, parse.MuxURLParser); err != nil {
 		// ensure defaults set so writer is assigned
 		s.setDefaults(apiOp)
@@ -115,8 +244,15 @@ func (s *Server) handle(apiOp *types.APIRequest, parser parse.Parser) {
 
 	s.setDefaults(apiOp)
 
+	if s.StrictQueryParameters {
+		if err := parse.ValidateQuery(apiOp); err != nil {
+			apiOp.WriteError(err)
+			return
+		}
+	}
+
 	var cloned *types.APISchemas
-	for id, schema := range apiOp.Schemas.Schemas {
+	for id, schema := range apiOp.Schemas.All() {
 		if schema.RequestModifier == nil {
 			continue
 		}
@@ -138,11 +274,60 @@ func (s *Server) handle(apiOp *types.APIRequest, parser parse.Parser) {
 		apiOp.Schema = apiOp.Schema.RequestModifier(apiOp, apiOp.Schema)
 	}
 
+	if s.FlushResponseBytes > 0 || s.FlushResponseInterval > 0 {
+		rw := apiOp.Response
+		flushing := writer.NewFlushingResponseWriter(rw, s.FlushResponseBytes, s.FlushResponseInterval)
+		apiOp.Response = flushing
+		defer func() {
+			apiOp.Response = rw
+			if err := flushing.Close(); err != nil {
+				logrus.Debugf("Client closed connection while writing %s response: %v", apiOp.Type, err)
+			}
+		}()
+	}
+
+	if s.BufferResponseBytes > 0 {
+		rw := apiOp.Response
+		buffered := writer.NewBufferedResponseWriter(rw, s.BufferResponseBytes, s.BufferResponseDelay)
+		apiOp.Response = buffered
+		defer func() {
+			apiOp.Response = rw
+			if err := buffered.Close(); err != nil {
+				logrus.Debugf("Client closed connection while writing %s response: %v", apiOp.Type, err)
+			}
+		}()
+	}
+
+	if s.MaxResponseBytes > 0 {
+		rw := apiOp.Response
+		limited := writer.NewLimitedResponseWriter(rw, s.MaxResponseBytes)
+		apiOp.Response = limited
+		defer func() {
+			apiOp.Response = rw
+			if err := limited.Close(); errors.Is(err, writer.ErrResponseTooLarge) {
+				apiOp.WriteError(apierror.NewAPIError(validation.ServerError,
+					fmt.Sprintf("Response exceeds the maximum size of %d bytes", s.MaxResponseBytes)))
+			}
+		}()
+	}
+
+	if timeout := s.Timeouts.timeout(apiOp); timeout > 0 {
+		ctx, cancel := context.WithTimeout(apiOp.Context(), timeout)
+		defer cancel()
+		apiOp.Request = apiOp.Request.WithContext(ctx)
+	}
+
 	requestStart := time.Now()
 	var code int
 	var data interface{}
 	var err error
-	if code, data, err = s.handleOp(apiOp); err != nil {
+	code, data, err = s.handleOp(apiOp)
+	if err != nil && err != validation.ErrComplete && errors.Is(apiOp.Context().Err(), context.DeadlineExceeded) {
+		// the handler most likely failed because it was canceled, while an operation that succeeded past the
+		// deadline is still reported as such
+		code, data, err = http.StatusGatewayTimeout, nil, apierror.NewAPIError(apierror.Timeout, "")
+	}
+	if err != nil {
 		apiOp.WriteError(err)
 	} else if obj, ok := data.(types.APIObject); ok {
 		apiOp.WriteResponse(code, obj)
@@ -152,23 +337,56 @@ func (s *Server) handle(apiOp *types.APIRequest, parser parse.Parser) {
 		apiOp.Response.WriteHeader(code)
 	}
 
-	metrics.RecordResponseTime(apiOp.Type, apiOp.Method, strconv.Itoa(code), float64(time.Since(requestStart).Milliseconds()))
+	if metrics.Enabled() {
+		metrics.RecordResponseTime(apiOp.Type, apiOp.Method, strconv.Itoa(code), float64(time.Since(requestStart).Milliseconds()))
+	}
 }
 
 func (s *Server) handleOp(apiOp *types.APIRequest) (int, interface{}, error) {
-	if err := CheckCSRF(apiOp); err != nil {
+	if isCapabilitiesRequest(apiOp) {
+		// like a GET, reading the capabilities changes nothing, so there is no CSRF token to check
+		return http.StatusOK, s.handleCapabilities(apiOp), nil
+	}
+
+	if err := checkCSRF(apiOp, s.csrfTokens()); err != nil {
+		return 0, nil, err
+	}
+
+	if err := parse.ValidateNameWithPolicy(apiOp, s.NamePolicy); err != nil {
 		return 0, nil, err
 	}
 
 	if apiOp.Schema == nil {
+		if s.SuggestTypes && apiOp.Type != "" {
+			return 0, nil, unknownTypeError(apiOp)
+		}
 		return http.StatusNotFound, nil, nil
 	}
 
+	if isListAction(apiOp, s.listAction()) {
+		data, err := handleListAction(apiOp)
+		return http.StatusOK, data, err
+	}
+
 	action, err := ValidateAction(apiOp)
 	if err != nil {
 		return 0, nil, err
 	}
 
+	if parse.ExpectsContinue(apiOp.Request) {
+		// reject unauthorized requests before reading the body makes the client send it
+		if err := checkAccess(apiOp, action); err != nil {
+			return 0, nil, err
+		}
+		if err := parse.ParseBody(apiOp); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if err := ValidateActionInput(apiOp, action); err != nil {
+		return 0, nil, err
+	}
+
 	if action != nil {
 		if apiOp.Name != "" {
 			data, err := handle(apiOp, apiOp.Schema.ByIDHandler, handlers.ByIDHandler)
@@ -176,6 +394,13 @@ func (s *Server) handleOp(apiOp *types.APIRequest) (int, interface{}, error) {
 				return http.StatusOK, data, err
 			}
 		}
+		if handler, ok := apiOp.Schema.ActionRequestHandlers[apiOp.Action]; ok {
+			data, err := handler(apiOp)
+			if err == nil && data.Type == "" {
+				data.Type = action.Output
+			}
+			return http.StatusOK, data, err
+		}
 		return http.StatusOK, nil, handleAction(apiOp)
 	}
 
@@ -185,6 +410,9 @@ func (s *Server) handleOp(apiOp *types.APIRequest) (int, interface{}, error) {
 			data, err := handleList(apiOp, apiOp.Schema.ListHandler, handlers.MetricsListHandler("200", handlers.ListHandler))
 			return http.StatusOK, data, err
 		}
+		if apiOp.ResponseFormat == "raw" && apiOp.Schema.DownloadHandler != nil {
+			return http.StatusOK, nil, handleDownload(apiOp)
+		}
 		data, err := handle(apiOp, apiOp.Schema.ByIDHandler, handlers.MetricsHandler("200", handlers.ByIDHandler))
 		return http.StatusOK, data, err
 	case http.MethodPatch:
@@ -193,7 +421,14 @@ func (s *Server) handleOp(apiOp *types.APIRequest) (int, interface{}, error) {
 		data, err := handle(apiOp, apiOp.Schema.UpdateHandler, handlers.MetricsHandler("200", handlers.UpdateHandler))
 		return http.StatusOK, data, err
 	case http.MethodPost:
+		if parse.BatchCreate(apiOp) {
+			data, err := handleBatchCreate(apiOp)
+			return http.StatusOK, data, err
+		}
 		data, err := handle(apiOp, apiOp.Schema.CreateHandler, handlers.MetricsHandler("201", handlers.CreateHandler))
+		if err == nil {
+			setLocation(apiOp, data)
+		}
 		return http.StatusCreated, data, err
 	case http.MethodDelete:
 		data, err := handle(apiOp, apiOp.Schema.DeleteHandler, handlers.MetricsHandler("200", handlers.DeleteHandler))
@@ -203,6 +438,34 @@ func (s *Server) handleOp(apiOp *types.APIRequest) (int, interface{}, error) {
 	return http.StatusNotFound, nil, nil
 }
 
+func (s *Server) csrfTokens() *CSRFTokens {
+	if s.CSRFTokens == nil {
+		return defaultCSRFTokens
+	}
+	return s.CSRFTokens
+}
+
+// setLocation points the Location header of the response at the created object, if its ID is known.
+func setLocation(apiOp *types.APIRequest, obj types.APIObject) {
+	if apiOp.URLBuilder == nil {
+		return
+	}
+	schema := apiOp.Schema
+	if obj.Type != "" && apiOp.Schemas != nil {
+		if objSchema := apiOp.Schemas.LookupSchema(obj.Type); objSchema != nil {
+			schema = objSchema
+		}
+	}
+	id := obj.ID
+	if id == "" && schema.IDExtractor != nil {
+		id = schema.IDExtractor.ExtractID(obj)
+	}
+	if id == "" {
+		return
+	}
+	apiOp.Response.Header().Set("Location", apiOp.URLBuilder.ResourceLink(schema, id))
+}
+
 func handleList(apiOp *types.APIRequest, custom types.RequestListHandler, handler types.RequestListHandler) (types.APIObjectList, error) {
 	if custom != nil {
 		return custom(apiOp)
@@ -218,7 +481,7 @@ func handle(apiOp *types.APIRequest, custom types.RequestHandler, handler types.
 }
 
 func handleAction(context *types.APIRequest) error {
-	if err := context.AccessControl.CanAction(context, context.Schema, context.Action); err != nil {
+	if err := CanAction(context); err != nil {
 		return err
 	}
 	if handler, ok := context.Schema.ActionHandlers[context.Action]; ok {
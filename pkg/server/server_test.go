@@ -1,20 +1,30 @@
 package server
 
 import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/rancher/apiserver/pkg/apierror"
 	"github.com/rancher/apiserver/pkg/builtin"
 	"github.com/rancher/apiserver/pkg/fakes"
 	"github.com/rancher/apiserver/pkg/parse"
+	"github.com/rancher/apiserver/pkg/store/empty"
 	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/apiserver/pkg/urlbuilder"
 	"github.com/rancher/apiserver/pkg/writer"
 	"github.com/rancher/wrangler/v3/pkg/schemas"
 	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
@@ -179,7 +189,7 @@ func (p *ServerSuite) TestServer_handleOp() {
 					ByIDHandler: requestHandler,
 				},
 				Method: http.MethodGet,
-				Name:   ".",
+				Name:   "foo",
 			},
 			results: results{
 				Code: http.StatusOK,
@@ -187,6 +197,21 @@ func (p *ServerSuite) TestServer_handleOp() {
 				Err:  nil,
 			},
 		},
+		{
+			name: "GET Request with path traversal name",
+			fields: fields{
+				Schema: &types.APISchema{
+					ByIDHandler: requestHandler,
+				},
+				Method: http.MethodGet,
+				Name:   ".",
+			},
+			results: results{
+				Code: 0,
+				Data: nil,
+				Err:  apierror.NewAPIError(apierror.BadRequest, `Invalid resource name "."`),
+			},
+		},
 		{
 			name: "PATCH Request",
 			fields: fields{
@@ -469,6 +494,492 @@ func TestServeHTMLEscaping(t *testing.T) {
 	}
 }
 
+func TestMaxResponseBytes(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      int64
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "unlimited",
+			wantStatus: http.StatusOK,
+			wantBody:   `"id":"schema"`,
+		},
+		{
+			name:       "within limit",
+			limit:      1 << 20,
+			wantStatus: http.StatusOK,
+			wantBody:   `"id":"schema"`,
+		},
+		{
+			name:       "over limit",
+			limit:      16,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "Response exceeds the maximum size of 16 bytes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.MaxResponseBytes = tt.limit
+
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodGet, "/v1/schemas", nil),
+				Response: resp,
+				Type:     "schema",
+			})
+
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			assert.Contains(t, resp.Body.String(), tt.wantBody)
+		})
+	}
+}
+
+func TestCSVFormat(t *testing.T) {
+	srv := DefaultAPIServer()
+
+	resp := httptest.NewRecorder()
+	srv.Handle(&types.APIRequest{
+		Request:  httptest.NewRequest(http.MethodGet, "/v1/schemas?_format=csv", nil),
+		Response: resp,
+		Type:     "schema",
+	})
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(resp.Body.String()), "\n")
+	require.Greater(t, len(lines), 1)
+	assert.True(t, strings.HasPrefix(lines[0], "id,"), lines[0])
+	assert.Contains(t, resp.Body.String(), "\nschema,")
+}
+
+func TestFlushResponseBytes(t *testing.T) {
+	srv := DefaultAPIServer()
+	srv.FlushResponseBytes = 256
+
+	resp := httptest.NewRecorder()
+	srv.Handle(&types.APIRequest{
+		Request:  httptest.NewRequest(http.MethodGet, "/v1/schemas?_format=jsonl", nil),
+		Response: resp,
+		Type:     "schema",
+	})
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.True(t, resp.Flushed, "batches of 256 bytes are flushed as they are written")
+	assert.Contains(t, resp.Body.String(), `"id":"schema"`)
+	assert.True(t, strings.HasSuffix(resp.Body.String(), "}\n\n"), "the response is complete")
+}
+
+func TestBufferResponseBytes(t *testing.T) {
+	tests := []struct {
+		name         string
+		buffer       int64
+		wantBuffered bool
+	}{
+		{
+			name: "not buffered",
+		},
+		{
+			name:         "small response",
+			buffer:       1 << 20,
+			wantBuffered: true,
+		},
+		{
+			name:   "large response",
+			buffer: 16,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.BufferResponseBytes = tt.buffer
+
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodGet, "/v1/schemas", nil),
+				Response: resp,
+				Type:     "schema",
+			})
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Contains(t, resp.Body.String(), `"id":"schema"`)
+			if tt.wantBuffered {
+				assert.Equal(t, strconv.Itoa(resp.Body.Len()), resp.Header().Get("Content-Length"))
+				assert.NotEmpty(t, resp.Header().Get("ETag"))
+			} else {
+				assert.Empty(t, resp.Header().Get("Content-Length"))
+				assert.Empty(t, resp.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestNamePolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     parse.NamePolicy
+		wantStatus int
+		wantName   string
+	}{
+		{
+			name:       "accepted",
+			wantStatus: http.StatusOK,
+			wantName:   "foo. ",
+		},
+		{
+			name:       "rejected",
+			policy:     parse.RejectInvalidNames,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "normalized",
+			policy:     parse.NormalizeNames,
+			wantStatus: http.StatusOK,
+			wantName:   "foo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotName string
+			srv := DefaultAPIServer()
+			srv.NamePolicy = tt.policy
+			srv.Schemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:              "foo",
+					ResourceMethods: []string{http.MethodGet},
+				},
+				ByIDHandler: func(apiOp *types.APIRequest) (types.APIObject, error) {
+					gotName = apiOp.Name
+					return types.APIObject{Type: "foo", ID: apiOp.Name, Object: map[string]interface{}{}}, nil
+				},
+			})
+
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodGet, "/foos/foo.%20", nil),
+				Response: resp,
+				Type:     "foo",
+				Name:     "foo. ",
+			})
+
+			assert.Equal(t, tt.wantStatus, resp.Code, resp.Body.String())
+			assert.Equal(t, tt.wantName, gotName)
+		})
+	}
+}
+
+func TestBodyLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		limits     parse.BodyLimits
+		wantStatus int
+	}{
+		{
+			name:       "default",
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "configured",
+			limits:     parse.BodyLimits{MaxDepth: 2},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.BodyLimits = tt.limits
+			srv.Schemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:                "foo",
+					CollectionMethods: []string{http.MethodPost},
+				},
+				CreateHandler: func(apiOp *types.APIRequest) (types.APIObject, error) {
+					obj, err := parse.Body(apiOp.Request)
+					if err != nil {
+						return types.APIObject{}, err
+					}
+					return types.APIObject{Type: "foo", Object: obj.Object}, nil
+				},
+			})
+
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodPost, "/foos", strings.NewReader(`{"a":[[1]]}`)),
+				Response: resp,
+				Type:     "foo",
+			})
+
+			assert.Equal(t, tt.wantStatus, resp.Code, resp.Body.String())
+		})
+	}
+}
+
+func TestAccessErrorsLocalized(t *testing.T) {
+	defaultCatalog := apierror.DefaultCatalog
+	t.Cleanup(func() { apierror.DefaultCatalog = defaultCatalog })
+	apierror.DefaultCatalog = apierror.NewCatalog()
+	apierror.DefaultCatalog.Add(apierror.DefaultLanguage, map[string]string{apierror.CanNotCreateKey: "can not create %s"})
+	apierror.DefaultCatalog.Add("fr", map[string]string{apierror.CanNotCreateKey: "impossible de créer %s"})
+
+	schema := &types.APISchema{Schema: &schemas.Schema{ID: "foo"}}
+	err := (&SchemaBasedAccess{}).CanCreate(&types.APIRequest{}, schema)
+	require.Error(t, err)
+	assert.Equal(t, "can not create foo", err.(*apierror.APIError).Message)
+
+	message, language := apierror.DefaultCatalog.Localize("fr", err.(*apierror.APIError))
+	assert.Equal(t, "impossible de créer foo", message)
+	assert.Equal(t, "fr", language)
+}
+
+func TestSanitizeServerErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		sanitize    bool
+		err         error
+		wantStatus  int
+		wantMessage string
+	}{
+		{
+			name:        "verbose by default",
+			err:         apierror.NewAPIError(validation.ServerError, "pq: relation \"pods\" does not exist"),
+			wantStatus:  http.StatusInternalServerError,
+			wantMessage: "pq: relation \"pods\" does not exist",
+		},
+		{
+			name:        "server error",
+			sanitize:    true,
+			err:         apierror.NewAPIError(validation.ServerError, "pq: relation \"pods\" does not exist"),
+			wantStatus:  http.StatusInternalServerError,
+			wantMessage: "Internal Server Error",
+		},
+		{
+			name:        "unknown error",
+			sanitize:    true,
+			err:         errors.New("dial tcp db.internal:5432: connection refused"),
+			wantStatus:  http.StatusInternalServerError,
+			wantMessage: "Internal Server Error",
+		},
+		{
+			name:        "unavailable",
+			sanitize:    true,
+			err:         apierror.NewAPIError(apierror.ServiceUnavailable, "replica db-2.internal is down"),
+			wantStatus:  http.StatusServiceUnavailable,
+			wantMessage: "Service Unavailable",
+		},
+		{
+			name:        "client error",
+			sanitize:    true,
+			err:         apierror.NewAPIError(validation.NotFound, "pod web not found"),
+			wantStatus:  http.StatusNotFound,
+			wantMessage: "pod web not found",
+		},
+		{
+			name:        "conflict",
+			sanitize:    true,
+			err:         apierror.NewAPIError(validation.Conflict, "pod web already exists"),
+			wantStatus:  http.StatusConflict,
+			wantMessage: "pod web already exists",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.SanitizeServerErrors = tt.sanitize
+			srv.Schemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:              "foo",
+					ResourceMethods: []string{http.MethodGet},
+				},
+				ByIDHandler: func(apiOp *types.APIRequest) (types.APIObject, error) {
+					return types.APIObject{}, tt.err
+				},
+			})
+
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodGet, "/foos/foo1", nil),
+				Response: resp,
+				Type:     "foo",
+				Name:     "foo1",
+			})
+
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			var body map[string]interface{}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+			assert.Equal(t, tt.wantMessage, body["message"])
+		})
+	}
+}
+
+func TestStrictQueryParameters(t *testing.T) {
+	tests := []struct {
+		name       string
+		strict     bool
+		target     string
+		wantStatus int
+	}{
+		{
+			name:       "lenient ignores unknown parameters",
+			target:     "/v1/schemas?_fromat=yaml",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "strict rejects unknown parameters",
+			strict:     true,
+			target:     "/v1/schemas?_fromat=yaml",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "strict accepts known parameters",
+			strict:     true,
+			target:     "/v1/schemas?_format=yaml",
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.StrictQueryParameters = tt.strict
+
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodGet, tt.target, nil),
+				Response: resp,
+				Type:     "schema",
+			})
+
+			assert.Equal(t, tt.wantStatus, resp.Code)
+		})
+	}
+}
+
+func TestActionRequestHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		accept      string
+		handlerErr  error
+		wantStatus  int
+		wantType    string
+		wantContent string
+	}{
+		{
+			name:        "json",
+			wantStatus:  http.StatusOK,
+			wantType:    "application/json",
+			wantContent: `"replicas":3`,
+		},
+		{
+			name:        "yaml",
+			accept:      "application/yaml",
+			wantStatus:  http.StatusOK,
+			wantType:    "application/yaml",
+			wantContent: "replicas: 3",
+		},
+		{
+			name:        "error",
+			handlerErr:  apierror.NewAPIError(validation.Conflict, "already scaling"),
+			wantStatus:  http.StatusConflict,
+			wantType:    "application/json",
+			wantContent: "already scaling",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.Schemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:                "deployment",
+					CollectionActions: map[string]schemas.Action{"scale": {Output: "scaleOutput"}},
+				},
+				ActionRequestHandlers: map[string]types.RequestHandler{
+					"scale": func(apiOp *types.APIRequest) (types.APIObject, error) {
+						if tt.handlerErr != nil {
+							return types.APIObject{}, tt.handlerErr
+						}
+						return types.APIObject{Object: map[string]interface{}{"replicas": 3}}, nil
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/deployments?action=scale", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  req,
+				Response: resp,
+				Type:     "deployment",
+				Action:   "scale",
+			})
+
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			assert.Equal(t, tt.wantType, resp.Header().Get("Content-Type"))
+			assert.Contains(t, resp.Body.String(), tt.wantContent)
+		})
+	}
+}
+
+func TestBodyErrorStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		action     string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "malformed JSON",
+			target:     "/v1/deployments",
+			body:       `{"replicas": `,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "malformed action JSON",
+			target:     "/v1/deployments?action=scale",
+			action:     "scale",
+			body:       `{"replicas": `,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "failed field validation",
+			target:     "/v1/deployments?action=scale",
+			action:     "scale",
+			body:       `{"replicas": "three"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.Schemas.MustAddSchema(types.APISchema{Schema: &schemas.Schema{
+				ID:             "scaleInput",
+				ResourceFields: map[string]schemas.Field{"replicas": {Type: "int", Required: true}},
+			}})
+			srv.Schemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:                "deployment",
+					CollectionMethods: []string{http.MethodPost},
+					CollectionActions: map[string]schemas.Action{"scale": {Input: "scaleInput"}},
+				},
+				ActionHandlers: map[string]http.Handler{"scale": &fakes.DummyHandler{}},
+			})
+
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body)),
+				Response: resp,
+				Type:     "deployment",
+				Action:   tt.action,
+			})
+
+			assert.Equal(t, tt.wantStatus, resp.Code)
+		})
+	}
+}
+
 func sendTestRequest(url, cssURL, jssURL, apiUIVersion string) (string, error) {
 	resp := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, url, nil)
@@ -490,3 +1001,687 @@ func sendTestRequest(url, cssURL, jssURL, apiUIVersion string) (string, error) {
 func stringGetter(val string) writer.StringGetter {
 	return func() string { return val }
 }
+
+type fooListStore struct {
+	empty.Store
+}
+
+func (f *fooListStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	return types.APIObjectList{
+		Objects: []types.APIObject{{Type: "foo", ID: "foo1", Object: map[string]interface{}{"id": "foo1"}}},
+	}, nil
+}
+
+// readOnlyAccess allows every method of a schema except creating and deleting.
+type readOnlyAccess struct {
+	SchemaBasedAccess
+}
+
+func (*readOnlyAccess) CanCreate(apiOp *types.APIRequest, schema *types.APISchema) error {
+	return apierror.NewAPIError(validation.PermissionDenied, "can not create "+schema.ID)
+}
+
+func (*readOnlyAccess) CanDelete(apiOp *types.APIRequest, obj types.APIObject, schema *types.APISchema) error {
+	return apierror.NewAPIError(validation.PermissionDenied, "can not delete "+schema.ID)
+}
+
+func TestIncludeMethods(t *testing.T) {
+	tests := []struct {
+		name                  string
+		query                 string
+		access                types.AccessControl
+		wantCollectionMethods interface{}
+		wantResourceMethods   interface{}
+	}{
+		{
+			name:   "not requested",
+			access: &SchemaBasedAccess{},
+		},
+		{
+			name:                  "all allowed",
+			query:                 "?_methods=true",
+			access:                &SchemaBasedAccess{},
+			wantCollectionMethods: []interface{}{http.MethodGet, http.MethodPost},
+			wantResourceMethods:   []interface{}{http.MethodGet, http.MethodPut, http.MethodDelete},
+		},
+		{
+			name:                  "filtered by access control",
+			query:                 "?_methods=true",
+			access:                &readOnlyAccess{},
+			wantCollectionMethods: []interface{}{http.MethodGet},
+			wantResourceMethods:   []interface{}{http.MethodGet, http.MethodPut},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.AccessControl = tt.access
+			srv.StrictQueryParameters = true
+			srv.Schemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:                "foo",
+					CollectionMethods: []string{http.MethodGet, http.MethodPost},
+					ResourceMethods:   []string{http.MethodGet, http.MethodPut, http.MethodDelete},
+				},
+				Store: &fooListStore{},
+			})
+
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodGet, "/v1/foos"+tt.query, nil),
+				Response: resp,
+				Type:     "foo",
+			})
+			require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+			var collection map[string]interface{}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &collection))
+			assert.Equal(t, tt.wantCollectionMethods, collection["collectionMethods"])
+
+			data := collection["data"].([]interface{})
+			require.Len(t, data, 1)
+			assert.Equal(t, tt.wantResourceMethods, data[0].(map[string]interface{})["resourceMethods"])
+		})
+	}
+}
+
+func TestDefaultHeaders(t *testing.T) {
+	srv := DefaultAPIServer()
+	srv.DefaultHeaders = map[string]string{
+		"X-Api-Version": "v1",
+		"Deprecation":   "true",
+		"Cache-Control": "no-cache",
+	}
+	srv.Schemas.MustAddSchema(types.APISchema{
+		Schema: &schemas.Schema{
+			ID:                "foo",
+			CollectionMethods: []string{http.MethodGet},
+		},
+		ListHandler: func(apiOp *types.APIRequest) (types.APIObjectList, error) {
+			apiOp.Response.Header().Set("Cache-Control", "no-store")
+			return types.APIObjectList{}, nil
+		},
+	})
+
+	tests := []struct {
+		name         string
+		typeName     string
+		wantStatus   int
+		wantCacheHdr string
+	}{
+		{
+			name:         "handler overrides default",
+			typeName:     "foo",
+			wantStatus:   http.StatusOK,
+			wantCacheHdr: "no-store",
+		},
+		{
+			name:         "error response",
+			typeName:     "missing",
+			wantStatus:   http.StatusNotFound,
+			wantCacheHdr: "no-cache",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodGet, "/v1/"+tt.typeName, nil),
+				Response: resp,
+				Type:     tt.typeName,
+			})
+
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			assert.Equal(t, "v1", resp.Header().Get("X-Api-Version"))
+			assert.Equal(t, "true", resp.Header().Get("Deprecation"))
+			assert.Equal(t, tt.wantCacheHdr, resp.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestInstanceIDHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		instanceID string
+		typeName   string
+		wantStatus int
+	}{
+		{
+			name:       "configured",
+			instanceID: "replica-1",
+			typeName:   "foo",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "configured on error",
+			instanceID: "replica-1",
+			typeName:   "missing",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "not configured",
+			typeName:   "foo",
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.InstanceID = tt.instanceID
+			srv.Schemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:                "foo",
+					CollectionMethods: []string{http.MethodGet},
+				},
+				ListHandler: func(apiOp *types.APIRequest) (types.APIObjectList, error) {
+					return types.APIObjectList{}, nil
+				},
+			})
+
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodGet, "/"+tt.typeName, nil),
+				Response: resp,
+				Type:     tt.typeName,
+			})
+
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			if tt.instanceID == "" {
+				assert.NotContains(t, resp.Header(), InstanceIDHeader)
+			} else {
+				assert.Equal(t, tt.instanceID, resp.Header().Get(InstanceIDHeader))
+			}
+		})
+	}
+}
+
+func TestHTMLErrorPage(t *testing.T) {
+	const xss = "<script>alert('xss')</script>"
+
+	srv := DefaultAPIServer()
+	srv.Schemas.MustAddSchema(types.APISchema{
+		Schema: &schemas.Schema{
+			ID:                "foo",
+			CollectionMethods: []string{http.MethodGet},
+		},
+		ListHandler: func(apiOp *types.APIRequest) (types.APIObjectList, error) {
+			return types.APIObjectList{}, apierror.NewFieldAPIError(validation.InvalidOption, xss, "bad value "+xss)
+		},
+	})
+
+	tests := []struct {
+		name            string
+		browser         bool
+		wantContentType string
+	}{
+		{name: "browser", browser: true, wantContentType: "text/html; charset=utf-8"},
+		{name: "api client", wantContentType: "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/foos", nil)
+			if tt.browser {
+				req.Header.Set("Accept", "*/*")
+				req.Header.Set("User-Agent", "Mozilla")
+			}
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  req,
+				Response: resp,
+				Type:     "foo",
+			})
+
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+			assert.Equal(t, tt.wantContentType, resp.Header().Get("Content-Type"))
+			body := resp.Body.String()
+			assert.NotContains(t, body, xss)
+			if !tt.browser {
+				assert.Contains(t, body, `"code":"InvalidOption"`)
+				return
+			}
+
+			assert.Contains(t, body, "<h1>422 InvalidOption</h1>")
+			assert.Contains(t, body, "bad value &lt;script&gt;alert(&#39;xss&#39;)&lt;/script&gt;")
+
+			decoder := xml.NewDecoder(strings.NewReader(body))
+			decoder.Strict = true
+			for {
+				_, err := decoder.Token()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err, "error page is not well-formed")
+			}
+		})
+	}
+}
+
+func TestActionJSONPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantContent string
+	}{
+		{
+			name:        "patch",
+			contentType: types.JSONPatchContentType,
+			body:        `[{"op": "replace", "path": "/replicas", "value": 5}]`,
+			wantStatus:  http.StatusOK,
+			wantContent: `"replicas":5`,
+		},
+		{
+			name:        "patch of unsupported path",
+			contentType: types.JSONPatchContentType,
+			body:        `[{"op": "replace", "path": "/image", "value": "nginx"}]`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantContent: "only /replicas can be patched",
+		},
+		{
+			name:        "invalid patch",
+			contentType: types.JSONPatchContentType,
+			body:        `[{"op": "replace", "path": "/replicas"}]`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantContent: "replace requires a value",
+		},
+		{
+			name:        "plain input is validated",
+			contentType: "application/json",
+			body:        `{}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantContent: "MissingRequired",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.Schemas.MustAddSchema(types.APISchema{Schema: &schemas.Schema{
+				ID:             "scaleInput",
+				ResourceFields: map[string]schemas.Field{"replicas": {Type: "int", Required: true}},
+			}})
+			srv.Schemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:                "deployment",
+					CollectionActions: map[string]schemas.Action{"scale": {Input: "scaleInput", Output: "scaleInput"}},
+				},
+				ActionRequestHandlers: map[string]types.RequestHandler{
+					"scale": func(apiOp *types.APIRequest) (types.APIObject, error) {
+						replicas := 1
+						for _, op := range apiOp.Patch {
+							if op.Op != "replace" || op.Path != "/replicas" {
+								return types.APIObject{}, apierror.NewAPIError(validation.InvalidBodyContent, "only /replicas can be patched")
+							}
+							replicas = int(op.Value.(float64))
+						}
+						return types.APIObject{Object: map[string]interface{}{"replicas": replicas}}, nil
+					},
+				},
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/deployments?action=scale", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  req,
+				Response: resp,
+				Type:     "deployment",
+				Action:   "scale",
+			})
+
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			assert.Contains(t, resp.Body.String(), tt.wantContent)
+		})
+	}
+}
+
+func TestCreateLocation(t *testing.T) {
+	tests := []struct {
+		name         string
+		created      types.APIObject
+		extractor    types.IDExtractor
+		wantLocation string
+	}{
+		{
+			name:         "explicit ID",
+			created:      types.APIObject{Type: "foo", ID: "bar", Object: map[string]interface{}{"id": "bar"}},
+			wantLocation: "http://example.com/foos/bar",
+		},
+		{
+			name: "ID from metadata",
+			created: types.APIObject{Type: "foo", Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "bar"},
+			}},
+			extractor:    types.MetadataIDExtractor,
+			wantLocation: "http://example.com/foos/bar",
+		},
+		{
+			name:    "unknown ID",
+			created: types.APIObject{Type: "foo", Object: map[string]interface{}{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.Schemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:                "foo",
+					PluralName:        "foos",
+					CollectionMethods: []string{http.MethodPost},
+					ResourceMethods:   []string{http.MethodGet},
+				},
+				CreateHandler: func(apiOp *types.APIRequest) (types.APIObject, error) {
+					return tt.created, nil
+				},
+				IDExtractor: tt.extractor,
+			})
+
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodPost, "/v1/foos", strings.NewReader("{}")),
+				Response: resp,
+				Type:     "foo",
+			})
+
+			assert.Equal(t, http.StatusCreated, resp.Code)
+			assert.Equal(t, tt.wantLocation, resp.Header().Get("Location"))
+		})
+	}
+}
+
+func TestSetSchemasWhileServing(t *testing.T) {
+	newSchemas := func(ids ...string) *types.APISchemas {
+		apiSchemas := types.EmptyAPISchemas()
+		for _, id := range ids {
+			apiSchemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:                id,
+					CollectionMethods: []string{http.MethodGet},
+				},
+				Store: &fooListStore{},
+			})
+		}
+		return apiSchemas
+	}
+	small := newSchemas("foo")
+	large := newSchemas("foo", "bar")
+
+	srv := DefaultAPIServer()
+	srv.SetSchemas(small)
+	assert.Same(t, small, srv.GetSchemas())
+
+	done := make(chan struct{})
+	var swaps sync.WaitGroup
+	swaps.Add(1)
+	go func() {
+		defer swaps.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if i%2 == 0 {
+				srv.SetSchemas(large)
+			} else {
+				srv.SetSchemas(small)
+			}
+		}
+	}()
+
+	var requests sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		requests.Add(1)
+		go func() {
+			defer requests.Done()
+			for j := 0; j < 50; j++ {
+				for _, typeName := range []string{"foo", "bar"} {
+					resp := httptest.NewRecorder()
+					srv.Handle(&types.APIRequest{
+						Request:  httptest.NewRequest(http.MethodGet, "/v1/"+typeName, nil),
+						Response: resp,
+						Type:     typeName,
+					})
+					if typeName == "foo" {
+						assert.Equal(t, http.StatusOK, resp.Code)
+					} else {
+						assert.Contains(t, []int{http.StatusOK, http.StatusNotFound}, resp.Code)
+					}
+				}
+			}
+		}()
+	}
+	requests.Wait()
+	close(done)
+	swaps.Wait()
+
+	srv.SetSchemas(large)
+	resp := httptest.NewRecorder()
+	srv.Handle(&types.APIRequest{
+		Request:  httptest.NewRequest(http.MethodGet, "/v1/bar", nil),
+		Response: resp,
+		Type:     "bar",
+	})
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+// trackingBody is a request body recording whether it was read, which is when the server sends the 100 Continue.
+type trackingBody struct {
+	io.Reader
+	read atomic.Bool
+}
+
+func (b *trackingBody) Read(p []byte) (int, error) {
+	b.read.Store(true)
+	return b.Reader.Read(p)
+}
+
+func TestExpectContinue(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		access     types.AccessControl
+		wantStatus int
+		wantRead   bool
+	}{
+		{
+			name:       "unauthorized create",
+			method:     http.MethodPost,
+			target:     "/foos",
+			access:     &readOnlyAccess{},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "unauthorized delete",
+			method:     http.MethodDelete,
+			target:     "/foos/foo1",
+			access:     &readOnlyAccess{},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "authorized create",
+			method:     http.MethodPost,
+			target:     "/foos",
+			access:     &SchemaBasedAccess{},
+			wantStatus: http.StatusCreated,
+			wantRead:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.AccessControl = tt.access
+			srv.Schemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:                "foo",
+					CollectionMethods: []string{http.MethodPost},
+					ResourceMethods:   []string{http.MethodDelete},
+				},
+				CreateHandler: func(apiOp *types.APIRequest) (types.APIObject, error) {
+					_, err := parse.RequestBody(apiOp)
+					return types.APIObject{Type: "foo", ID: "foo1"}, err
+				},
+				DeleteHandler: func(apiOp *types.APIRequest) (types.APIObject, error) {
+					return types.APIObject{}, nil
+				},
+			})
+			body := &trackingBody{}
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				body.Reader = req.Body
+				req.Body = io.NopCloser(body)
+				srv.Handle(&types.APIRequest{
+					Request:  req,
+					Response: rw,
+					Type:     "foo",
+					Name:     strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, "/foos"), "/"),
+				})
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: &http.Transport{ExpectContinueTimeout: 10 * time.Second}}
+			content := `{"name":"` + strings.Repeat("x", 1<<16) + `"}`
+			req, err := http.NewRequest(tt.method, server.URL+tt.target, strings.NewReader(content))
+			require.NoError(t, err)
+			req.Header.Set("Expect", "100-continue")
+			req.Header.Set("Content-Type", "application/json")
+
+			start := time.Now()
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantRead, body.read.Load(), "body read")
+			assert.Less(t, time.Since(start), 5*time.Second, "the client must not wait for the continue timeout")
+		})
+	}
+}
+
+// relativeLinks builds resource links relative to the root of the server.
+type relativeLinks struct {
+	types.URLBuilder
+}
+
+func (r *relativeLinks) ResourceLink(schema *types.APISchema, id string) string {
+	return "/custom/" + schema.PluralName + "/" + id
+}
+
+func TestURLBuilderFunc(t *testing.T) {
+	tests := []struct {
+		name       string
+		builder    types.URLBuilderFunc
+		wantStatus int
+		wantSelf   string
+	}{
+		{
+			name:       "default",
+			wantStatus: http.StatusOK,
+			wantSelf:   "http://example.com/foos/foo1",
+		},
+		{
+			name: "custom",
+			builder: func(apiOp *types.APIRequest) (types.URLBuilder, error) {
+				builder, err := urlbuilder.New(apiOp.Request, &urlbuilder.DefaultPathResolver{Prefix: apiOp.URLPrefix}, apiOp.Schemas)
+				return &relativeLinks{URLBuilder: builder}, err
+			},
+			wantStatus: http.StatusOK,
+			wantSelf:   "/custom/foos/foo1",
+		},
+		{
+			name: "error",
+			builder: func(apiOp *types.APIRequest) (types.URLBuilder, error) {
+				return nil, apierror.NewAPIError(validation.ServerError, "no builder")
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.URLBuilderFunc = tt.builder
+			srv.Schemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:              "foo",
+					PluralName:      "foos",
+					ResourceMethods: []string{http.MethodGet},
+				},
+				ByIDHandler: func(apiOp *types.APIRequest) (types.APIObject, error) {
+					return types.APIObject{Type: "foo", ID: apiOp.Name, Object: map[string]interface{}{}}, nil
+				},
+			})
+
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodGet, "/foos/foo1", nil),
+				Response: resp,
+				Type:     "foo",
+				Name:     "foo1",
+			})
+
+			require.Equal(t, tt.wantStatus, resp.Code, resp.Body.String())
+			if tt.wantSelf == "" {
+				return
+			}
+			var resource struct {
+				Links map[string]string `json:"links"`
+			}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &resource))
+			assert.Equal(t, tt.wantSelf, resource.Links["self"])
+		})
+	}
+}
+
+func TestSubscribeSchemaChanges(t *testing.T) {
+	srv := DefaultAPIServer()
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		srv.Handle(&types.APIRequest{
+			Request:  req,
+			Response: rw,
+			Type:     "subscribe",
+		})
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/v1/subscribe?resourceType=schema", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	events := make(chan string)
+	go func() {
+		defer close(events)
+		lines := bufio.NewScanner(resp.Body)
+		for lines.Scan() {
+			if name, ok := strings.CutPrefix(lines.Text(), "event: "); ok && lines.Scan() {
+				events <- name + " " + lines.Text()
+			}
+		}
+	}()
+	next := func() string {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for an event")
+			return ""
+		}
+	}
+	require.True(t, strings.HasPrefix(next(), "resource.start "))
+
+	added := types.EmptyAPISchemas().MustAddSchemas(srv.Schemas)
+	added.MustAddSchema(types.APISchema{
+		Schema: &schemas.Schema{
+			ID:                "widget",
+			CollectionMethods: []string{http.MethodGet},
+		},
+	})
+	srv.SetSchemas(added)
+
+	event := next()
+	assert.True(t, strings.HasPrefix(event, types.CreateAPIEvent+" "), event)
+	assert.Contains(t, event, `"id":"widget"`)
+}
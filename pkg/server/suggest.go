@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/store/accessible"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+)
+
+const maxSuggestions = 3
+
+// unknownTypeError returns a NotFound error for the type of apiOp, suggesting the closest schemas the caller can
+// access.
+func unknownTypeError(apiOp *types.APIRequest) error {
+	var candidates []*types.APISchema
+	for _, id := range accessible.IDs(apiOp) {
+		candidates = append(candidates, apiOp.Schemas.LookupSchema(id))
+	}
+
+	suggestions := suggestTypes(apiOp.Type, candidates)
+	message := fmt.Sprintf("Unknown type %s", apiOp.Type)
+	if len(suggestions) > 0 {
+		message += fmt.Sprintf(", did you mean %s?", strings.Join(suggestions, ", "))
+	}
+	return &apierror.APIError{
+		Code:        validation.NotFound,
+		Message:     message,
+		Suggestions: suggestions,
+	}
+}
+
+// suggestTypes returns the IDs of up to maxSuggestions schemas whose ID or plural name is closest to name, ignoring
+// case. Schemas more than a third of the length of name away are not considered similar.
+func suggestTypes(name string, schemas []*types.APISchema) []string {
+	name = strings.ToLower(name)
+	maxDistance := len(name) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+
+	type suggestion struct {
+		id       string
+		distance int
+	}
+	var suggestions []suggestion
+	for _, schema := range schemas {
+		distance := editDistance(name, strings.ToLower(schema.ID))
+		if schema.PluralName != "" {
+			distance = min(distance, editDistance(name, strings.ToLower(schema.PluralName)))
+		}
+		if distance <= maxDistance {
+			suggestions = append(suggestions, suggestion{id: schema.ID, distance: distance})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].distance != suggestions[j].distance {
+			return suggestions[i].distance < suggestions[j].distance
+		}
+		return suggestions[i].id < suggestions[j].id
+	})
+
+	var result []string
+	for i := 0; i < len(suggestions) && i < maxSuggestions; i++ {
+		result = append(result, suggestions[i].id)
+	}
+	return result
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"pod", "", 3},
+		{"", "pod", 3},
+		{"pods", "pods", 0},
+		{"pds", "pods", 1},
+		{"deploymnet", "deployment", 2},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, editDistance(tt.a, tt.b), "%s -> %s", tt.a, tt.b)
+	}
+}
+
+func newSuggestSchemas(ids ...string) []*types.APISchema {
+	var result []*types.APISchema
+	for _, id := range ids {
+		result = append(result, &types.APISchema{Schema: &schemas.Schema{ID: id, PluralName: id + "s"}})
+	}
+	return result
+}
+
+func TestSuggestTypes(t *testing.T) {
+	candidates := newSuggestSchemas("deployment", "daemonset", "pod", "node", "namespace", "service", "serviceAccount")
+
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{name: "typo in ID", in: "deploymnet", want: []string{"deployment"}},
+		{name: "typo in plural", in: "deploymnets", want: []string{"deployment"}},
+		{name: "case insensitive", in: "ServiceAcount", want: []string{"serviceAccount"}},
+		{name: "short name", in: "pds", want: []string{"pod"}},
+		{name: "closest first", in: "servic", want: []string{"service"}},
+		{name: "nothing similar", in: "configmap", want: nil},
+		{name: "limited", in: "nod", want: []string{"node", "pod"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, suggestTypes(tt.in, candidates))
+		})
+	}
+}
+
+// hiddenSchemaAccess denies listing and getting the hidden schemas.
+type hiddenSchemaAccess struct {
+	SchemaBasedAccess
+	hidden map[string]bool
+}
+
+func (h *hiddenSchemaAccess) CanList(apiOp *types.APIRequest, schema *types.APISchema) error {
+	if h.hidden[schema.ID] {
+		return apierror.NewAPIError(validation.PermissionDenied, "can not list "+schema.ID)
+	}
+	return h.SchemaBasedAccess.CanList(apiOp, schema)
+}
+
+func (h *hiddenSchemaAccess) CanGet(apiOp *types.APIRequest, schema *types.APISchema) error {
+	if h.hidden[schema.ID] {
+		return apierror.NewAPIError(validation.PermissionDenied, "can not get "+schema.ID)
+	}
+	return h.SchemaBasedAccess.CanGet(apiOp, schema)
+}
+
+func TestUnknownTypeSuggestions(t *testing.T) {
+	tests := []struct {
+		name     string
+		suggest  bool
+		wantBody map[string]interface{}
+	}{
+		{
+			name: "disabled",
+		},
+		{
+			name:    "enabled",
+			suggest: true,
+			wantBody: map[string]interface{}{
+				"type":        "error",
+				"status":      float64(http.StatusNotFound),
+				"code":        "NotFound",
+				"message":     "Unknown type secrts, did you mean secret?",
+				"suggestions": []interface{}{"secret"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.SuggestTypes = tt.suggest
+			srv.AccessControl = &hiddenSchemaAccess{hidden: map[string]bool{"secretz": true}}
+			for _, id := range []string{"secret", "secretz"} {
+				srv.Schemas.MustAddSchema(types.APISchema{
+					Schema: &schemas.Schema{
+						ID:                id,
+						CollectionMethods: []string{http.MethodGet},
+						ResourceMethods:   []string{http.MethodGet},
+					},
+				})
+			}
+
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodGet, "/v1/secrts", nil),
+				Response: resp,
+				Type:     "secrts",
+			})
+
+			assert.Equal(t, http.StatusNotFound, resp.Code)
+			if tt.wantBody == nil {
+				assert.Empty(t, resp.Body.String())
+				return
+			}
+			var body map[string]interface{}
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+			delete(body, "links")
+			assert.Equal(t, tt.wantBody, body)
+		})
+	}
+}
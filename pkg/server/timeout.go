@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// Timeouts are the maximum durations of operations, by type of operation. A zero duration does not time out the
+// operation. Timing out cancels the context of the request, so handlers and stores must stop once it is done.
+//
+// Watches are never timed out, as they are meant to last as long as the client keeps them open.
+type Timeouts struct {
+	List   time.Duration
+	Get    time.Duration
+	Create time.Duration
+	Update time.Duration
+	Delete time.Duration
+	Action time.Duration
+}
+
+// timeout returns the timeout of the operation of apiOp, which must be parsed.
+func (t Timeouts) timeout(apiOp *types.APIRequest) time.Duration {
+	if apiOp.Type == "subscribe" {
+		return 0
+	}
+	if apiOp.Action != "" {
+		return t.Action
+	}
+	switch apiOp.Method {
+	case http.MethodGet:
+		if apiOp.Name == "" {
+			return t.List
+		}
+		return t.Get
+	case http.MethodPost:
+		return t.Create
+	case http.MethodPut, http.MethodPatch:
+		return t.Update
+	case http.MethodDelete:
+		return t.Delete
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeouts(t *testing.T) {
+	const timeout = 20 * time.Millisecond
+
+	// slow waits for the request to be canceled, or for twice the timeout
+	slow := func(apiOp *types.APIRequest) (types.APIObjectList, error) {
+		select {
+		case <-apiOp.Context().Done():
+			return types.APIObjectList{}, apiOp.Context().Err()
+		case <-time.After(2 * timeout):
+			return types.APIObjectList{}, nil
+		}
+	}
+
+	tests := []struct {
+		name       string
+		typeName   string
+		timeouts   Timeouts
+		wantStatus int
+	}{
+		{
+			name:       "list times out",
+			typeName:   "foo",
+			timeouts:   Timeouts{List: timeout},
+			wantStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:       "list without timeout",
+			typeName:   "foo",
+			timeouts:   Timeouts{Create: timeout, Get: timeout},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "watch does not time out",
+			typeName:   "subscribe",
+			timeouts:   Timeouts{List: timeout, Get: timeout},
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := DefaultAPIServer()
+			srv.Timeouts = tt.timeouts
+			srv.Schemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:                "foo",
+					CollectionMethods: []string{http.MethodGet},
+				},
+				ListHandler: slow,
+			})
+			subscribe := srv.Schemas.LookupSchema("subscribe")
+			require.NotNil(t, subscribe)
+			subscribe.ListHandler = slow
+
+			resp := httptest.NewRecorder()
+			srv.Handle(&types.APIRequest{
+				Request:  httptest.NewRequest(http.MethodGet, "/"+tt.typeName, nil),
+				Response: resp,
+				Type:     tt.typeName,
+			})
+
+			assert.Equal(t, tt.wantStatus, resp.Code, resp.Body.String())
+		})
+	}
+}
+
+func TestTimeoutsClassification(t *testing.T) {
+	timeouts := Timeouts{
+		List:   1 * time.Second,
+		Get:    2 * time.Second,
+		Create: 3 * time.Second,
+		Update: 4 * time.Second,
+		Delete: 5 * time.Second,
+		Action: 6 * time.Second,
+	}
+	tests := []struct {
+		name  string
+		apiOp *types.APIRequest
+		want  time.Duration
+	}{
+		{name: "list", apiOp: &types.APIRequest{Type: "foo", Method: http.MethodGet}, want: timeouts.List},
+		{name: "get", apiOp: &types.APIRequest{Type: "foo", Name: "a", Method: http.MethodGet}, want: timeouts.Get},
+		{name: "create", apiOp: &types.APIRequest{Type: "foo", Method: http.MethodPost}, want: timeouts.Create},
+		{name: "update", apiOp: &types.APIRequest{Type: "foo", Name: "a", Method: http.MethodPut}, want: timeouts.Update},
+		{name: "patch", apiOp: &types.APIRequest{Type: "foo", Name: "a", Method: http.MethodPatch}, want: timeouts.Update},
+		{name: "delete", apiOp: &types.APIRequest{Type: "foo", Name: "a", Method: http.MethodDelete}, want: timeouts.Delete},
+		{name: "action", apiOp: &types.APIRequest{Type: "foo", Name: "a", Action: "run", Method: http.MethodPost}, want: timeouts.Action},
+		{name: "watch", apiOp: &types.APIRequest{Type: "subscribe", Method: http.MethodGet}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, timeouts.timeout(tt.apiOp))
+		})
+	}
+}
+
+func TestTimeoutsSucceededLate(t *testing.T) {
+	const timeout = 10 * time.Millisecond
+
+	srv := DefaultAPIServer()
+	srv.Timeouts = Timeouts{Create: timeout}
+	srv.Schemas.MustAddSchema(types.APISchema{
+		Schema: &schemas.Schema{
+			ID:                "foo",
+			CollectionMethods: []string{http.MethodPost},
+		},
+		// the create goes through although it finishes after the deadline
+		CreateHandler: func(apiOp *types.APIRequest) (types.APIObject, error) {
+			time.Sleep(3 * timeout)
+			return types.APIObject{Type: "foo", ID: "created", Object: map[string]interface{}{}}, nil
+		},
+	})
+
+	resp := httptest.NewRecorder()
+	srv.Handle(&types.APIRequest{
+		Request:  httptest.NewRequest(http.MethodPost, "/foo", strings.NewReader("{}")),
+		Response: resp,
+		Type:     "foo",
+	})
+
+	assert.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	assert.Contains(t, resp.Body.String(), `"id":"created"`)
+}
@@ -1,10 +1,11 @@
 package server
 
 import (
-	"crypto/rand"
-	"encoding/hex"
+	"bytes"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/rancher/apiserver/pkg/apierror"
 	"github.com/rancher/apiserver/pkg/parse"
@@ -23,7 +24,7 @@ func ValidateAction(request *types.APIRequest) (*schemas.Action, error) {
 		return nil, nil
 	}
 
-	if err := request.AccessControl.CanAction(request, request.Schema, request.Action); err != nil {
+	if err := CanAction(request); err != nil {
 		return nil, err
 	}
 
@@ -40,27 +41,164 @@ func ValidateAction(request *types.APIRequest) (*schemas.Action, error) {
 	return &action, nil
 }
 
+// CanAction checks whether the request is allowed to perform its action. Actions configured with a verb are checked
+// with the AccessControl method for that verb, all others with CanAction.
+func CanAction(request *types.APIRequest) error {
+	schema := request.Schema
+	if schema == nil || schema.ActionConfigs[request.Action].Verb == "" {
+		return request.AccessControl.CanAction(request, schema, request.Action)
+	}
+
+	switch verb := schema.ActionConfigs[request.Action].Verb; verb {
+	case "get":
+		return request.AccessControl.CanGet(request, schema)
+	case "list":
+		return request.AccessControl.CanList(request, schema)
+	case "watch":
+		return request.AccessControl.CanWatch(request, schema)
+	case "create":
+		return request.AccessControl.CanCreate(request, schema)
+	case "update", "patch":
+		return request.AccessControl.CanUpdate(request, types.APIObject{}, schema)
+	case "delete":
+		return request.AccessControl.CanDelete(request, types.APIObject{}, schema)
+	default:
+		return apierror.NewAPIError(validation.PermissionDenied, fmt.Sprintf("can not %s %s", verb, schema.ID))
+	}
+}
+
+// checkAccess checks whether the request is allowed by access control, as far as it can be without the object it
+// applies to.
+func checkAccess(request *types.APIRequest, action *schemas.Action) error {
+	if action != nil {
+		return CanAction(request)
+	}
+	switch request.Method {
+	case http.MethodPost:
+		return request.AccessControl.CanCreate(request, request.Schema)
+	case http.MethodPut, http.MethodPatch:
+		return request.AccessControl.CanUpdate(request, types.APIObject{}, request.Schema)
+	case http.MethodDelete:
+		return request.AccessControl.CanDelete(request, types.APIObject{}, request.Schema)
+	}
+	return nil
+}
+
+// ValidateActionInput validates the body of an action request against the input type of the action, if it declares
+// one. The body of the request is restored afterwards so it can still be read by the action handler.
+func ValidateActionInput(request *types.APIRequest, action *schemas.Action) error {
+	if action == nil || action.Input == "" || request.Schema.ActionConfigs[request.Action].SkipInputValidation {
+		return nil
+	}
+	// a JSON Patch describes changes to the input rather than the input itself
+	if request.Patch != nil {
+		return nil
+	}
+
+	inputSchema := request.Schemas.LookupSchema(action.Input)
+	if inputSchema == nil {
+		return nil
+	}
+
+	content, err := parse.ReadBodyBytes(request.Request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		request.Request.Body = io.NopCloser(bytes.NewReader(content))
+	}()
+
+	input := map[string]interface{}{}
+	if len(content) > 0 {
+		request.Request.Body = io.NopCloser(bytes.NewReader(content))
+		obj, err := parse.Body(request.Request)
+		if err != nil {
+			return err
+		}
+		input = obj.Data()
+	}
+
+	return validateFields(inputSchema, input)
+}
+
+func validateFields(schema *types.APISchema, data map[string]interface{}) error {
+	for name, field := range schema.ResourceFields {
+		value, ok := data[name]
+		if !ok || value == nil || value == "" {
+			if field.Required && field.Default == nil {
+				return apierror.NewFieldAPIError(validation.MissingRequired, name, "")
+			}
+			continue
+		}
+
+		converted, err := convertField(field.Type, value)
+		if err != nil {
+			code, ok := err.(validation.ErrorCode)
+			if !ok {
+				code = validation.InvalidFormat
+			}
+			return apierror.NewFieldAPIError(code, name, fmt.Sprintf("Invalid value for field %s of type %s", name, field.Type))
+		}
+
+		if err := validation.CheckFieldCriteria(name, field, converted); err != nil {
+			if code, ok := err.(validation.ErrorCode); ok {
+				return apierror.NewFieldAPIError(code, name, "")
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func convertField(fieldType string, value interface{}) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(fieldType, "array["):
+		if _, ok := value.([]interface{}); !ok {
+			return nil, validation.InvalidType
+		}
+		return value, nil
+	case strings.HasPrefix(fieldType, "map["):
+		if _, ok := value.(map[string]interface{}); !ok {
+			return nil, validation.InvalidType
+		}
+		return value, nil
+	}
+
+	converted, err := validation.ConvertSimple(fieldType, value)
+	if err == validation.ErrComplexType {
+		// the field is of another schema type, which is not validated further
+		return value, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		if fieldType != "json" {
+			return nil, validation.InvalidType
+		}
+	}
+	return converted, nil
+}
+
+// CheckCSRF checks the CSRF token of browser requests, setting the CSRF cookie if it is missing.
 func CheckCSRF(apiOp *types.APIRequest) error {
+	return checkCSRF(apiOp, defaultCSRFTokens)
+}
+
+func checkCSRF(apiOp *types.APIRequest, tokens *CSRFTokens) error {
 	if !parse.IsBrowser(apiOp.Request, false) {
 		return nil
 	}
 
 	cookie, err := apiOp.Request.Cookie(csrfCookie)
 	if err == http.ErrNoCookie {
-		// 16 bytes = 32 Hex Char = 128 bit entropy
-		bytes := make([]byte, 16)
-		_, err := rand.Read(bytes)
+		token, err := tokens.Generate()
 		if err != nil {
 			return apierror.WrapAPIError(err, validation.ServerError, "Failed in CSRF processing")
 		}
 
-		cookie = &http.Cookie{
-			Name:   csrfCookie,
-			Value:  hex.EncodeToString(bytes),
-			Path:   "/",
-			Secure: true,
-		}
-
+		cookie = tokens.cookie(token)
 		http.SetCookie(apiOp.Response, cookie)
 	} else if err != nil {
 		return apierror.NewAPIError(validation.InvalidCSRFToken, "Failed to parse cookies")
@@ -68,7 +206,9 @@ func CheckCSRF(apiOp *types.APIRequest) error {
 		/*
 		 * Very important to use apiOp.Method and not apiOp.Request.Method. The client can override the HTTP method with _method
 		 */
-		if cookie.Value == apiOp.Request.Header.Get(csrfHeader) {
+		if !tokens.Valid(cookie.Value) {
+			return apierror.NewAPIError(validation.InvalidCSRFToken, "Invalid CSRF token")
+		} else if cookie.Value == apiOp.Request.Header.Get(csrfHeader) {
 			// Good
 		} else if cookie.Value == apiOp.Request.URL.Query().Get(csrfCookie) {
 			// Good
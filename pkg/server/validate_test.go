@@ -0,0 +1,241 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/fakes"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newActionSchemas() *types.APISchemas {
+	s := types.EmptyAPISchemas()
+	s.MustAddSchema(types.APISchema{Schema: &schemas.Schema{
+		ID: "scaleInput",
+		ResourceFields: map[string]schemas.Field{
+			"replicas": {Type: "int", Required: true, Min: &[]int64{0}[0]},
+			"reason":   {Type: "string", Nullable: true},
+			"labels":   {Type: "map[string]", Nullable: true},
+		},
+	}})
+	s.MustAddSchema(types.APISchema{
+		Schema: &schemas.Schema{
+			ID: "deployment",
+			ResourceActions: map[string]schemas.Action{
+				"scale":     {Input: "scaleInput"},
+				"restart":   {},
+				"selfScale": {Input: "scaleInput"},
+			},
+		},
+		ActionConfigs: map[string]types.ActionConfig{
+			"selfScale": {SkipInputValidation: true},
+		},
+	})
+	return s
+}
+
+func TestValidateActionInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		action    string
+		body      string
+		wantCode  validation.ErrorCode
+		wantField string
+	}{
+		{
+			name:   "valid input",
+			action: "scale",
+			body:   `{"replicas": 3, "reason": "load", "labels": {"a": "b"}}`,
+		},
+		{
+			name:      "missing required field",
+			action:    "scale",
+			body:      `{"reason": "load"}`,
+			wantCode:  validation.MissingRequired,
+			wantField: "replicas",
+		},
+		{
+			name:      "empty body missing required field",
+			action:    "scale",
+			wantCode:  validation.MissingRequired,
+			wantField: "replicas",
+		},
+		{
+			name:      "wrong type",
+			action:    "scale",
+			body:      `{"replicas": "three"}`,
+			wantCode:  validation.InvalidFormat,
+			wantField: "replicas",
+		},
+		{
+			name:      "object for string field",
+			action:    "scale",
+			body:      `{"replicas": 1, "reason": {"a": "b"}}`,
+			wantCode:  validation.InvalidType,
+			wantField: "reason",
+		},
+		{
+			name:      "field criteria",
+			action:    "scale",
+			body:      `{"replicas": -1}`,
+			wantCode:  validation.MinLimitExceeded,
+			wantField: "replicas",
+		},
+		{
+			name:   "action without input",
+			action: "restart",
+			body:   `{"anything": true}`,
+		},
+		{
+			name:   "validation skipped",
+			action: "selfScale",
+			body:   `{"replicas": "three"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newActionSchemas()
+			schema := s.LookupSchema("deployment")
+			action := schema.ResourceActions[tt.action]
+			apiOp := &types.APIRequest{
+				Request: httptest.NewRequest(http.MethodPost, "/v1/deployments/a?action="+tt.action, strings.NewReader(tt.body)),
+				Schemas: s,
+				Schema:  schema,
+				Action:  tt.action,
+			}
+
+			err := ValidateActionInput(apiOp, &action)
+			if tt.wantCode.Code == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				apiErr := err.(*apierror.APIError)
+				assert.Equal(t, tt.wantCode, apiErr.Code)
+				assert.Equal(t, tt.wantField, apiErr.FieldName)
+			}
+
+			// the body can still be read by the action handler
+			body, err := io.ReadAll(apiOp.Request.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, string(body))
+		})
+	}
+}
+
+func TestValidateActionInputTooLarge(t *testing.T) {
+	s := newActionSchemas()
+	schema := s.LookupSchema("deployment")
+	action := schema.ResourceActions["scale"]
+	body := `{"replicas": 3, "reason": "` + strings.Repeat("a", 3<<20) + `"}`
+	apiOp := &types.APIRequest{
+		Request: httptest.NewRequest(http.MethodPost, "/v1/deployments/a?action=scale", strings.NewReader(body)),
+		Schemas: s,
+		Schema:  schema,
+		Action:  "scale",
+	}
+
+	err := ValidateActionInput(apiOp, &action)
+	require.Error(t, err)
+	assert.Equal(t, apierror.RequestEntityTooLarge, err.(*apierror.APIError).Code)
+}
+
+func TestCanAction(t *testing.T) {
+	denied := apierror.NewAPIError(validation.PermissionDenied, "denied")
+
+	tests := []struct {
+		name   string
+		verb   string
+		expect func(ac *fakes.MockAccessControl, apiOp *types.APIRequest)
+		err    error
+	}{
+		{
+			name: "default",
+			expect: func(ac *fakes.MockAccessControl, apiOp *types.APIRequest) {
+				ac.EXPECT().CanAction(apiOp, apiOp.Schema, "scale").Return(denied)
+			},
+			err: denied,
+		},
+		{
+			name: "update",
+			verb: "update",
+			expect: func(ac *fakes.MockAccessControl, apiOp *types.APIRequest) {
+				ac.EXPECT().CanUpdate(apiOp, types.APIObject{}, apiOp.Schema).Return(nil)
+			},
+		},
+		{
+			name: "patch",
+			verb: "patch",
+			expect: func(ac *fakes.MockAccessControl, apiOp *types.APIRequest) {
+				ac.EXPECT().CanUpdate(apiOp, types.APIObject{}, apiOp.Schema).Return(denied)
+			},
+			err: denied,
+		},
+		{
+			name: "get",
+			verb: "get",
+			expect: func(ac *fakes.MockAccessControl, apiOp *types.APIRequest) {
+				ac.EXPECT().CanGet(apiOp, apiOp.Schema).Return(nil)
+			},
+		},
+		{
+			name: "list",
+			verb: "list",
+			expect: func(ac *fakes.MockAccessControl, apiOp *types.APIRequest) {
+				ac.EXPECT().CanList(apiOp, apiOp.Schema).Return(nil)
+			},
+		},
+		{
+			name: "watch",
+			verb: "watch",
+			expect: func(ac *fakes.MockAccessControl, apiOp *types.APIRequest) {
+				ac.EXPECT().CanWatch(apiOp, apiOp.Schema).Return(nil)
+			},
+		},
+		{
+			name: "create",
+			verb: "create",
+			expect: func(ac *fakes.MockAccessControl, apiOp *types.APIRequest) {
+				ac.EXPECT().CanCreate(apiOp, apiOp.Schema).Return(nil)
+			},
+		},
+		{
+			name: "delete",
+			verb: "delete",
+			expect: func(ac *fakes.MockAccessControl, apiOp *types.APIRequest) {
+				ac.EXPECT().CanDelete(apiOp, types.APIObject{}, apiOp.Schema).Return(nil)
+			},
+		},
+		{
+			name:   "unknown verb",
+			verb:   "escalate",
+			expect: func(*fakes.MockAccessControl, *types.APIRequest) {},
+			err:    apierror.NewAPIError(validation.PermissionDenied, "can not escalate deployment"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			accessControl := fakes.NewMockAccessControl(ctrl)
+			apiOp := &types.APIRequest{
+				Action:        "scale",
+				AccessControl: accessControl,
+				Schema: &types.APISchema{
+					Schema:        &schemas.Schema{ID: "deployment"},
+					ActionConfigs: map[string]types.ActionConfig{"scale": {Verb: tt.verb}},
+				},
+			}
+			tt.expect(accessControl, apiOp)
+
+			assert.Equal(t, tt.err, CanAction(apiOp))
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package accessible
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+)
+
+const SchemaID = "accessibleSchema"
+
+// Register adds the accessibleSchema type, which lists the IDs of the schemas the caller is allowed to list or get.
+// Only the access controller is consulted, so no resources are listed from the underlying stores.
+func Register(apiSchemas *types.APISchemas) {
+	apiSchemas.MustAddSchema(types.APISchema{
+		Schema: &schemas.Schema{
+			ID:                SchemaID,
+			CollectionMethods: []string{http.MethodGet},
+			ResourceFields: map[string]schemas.Field{
+				"pluralName": {Type: "string"},
+			},
+		},
+		Store: NewStore(),
+	})
+}
+
+type Store struct {
+	empty.Store
+}
+
+func NewStore() types.Store {
+	return &Store{}
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	var result types.APIObjectList
+	for _, id := range IDs(apiOp) {
+		result.Objects = append(result.Objects, types.APIObject{
+			Type: SchemaID,
+			ID:   id,
+			Object: map[string]interface{}{
+				"id":         id,
+				"pluralName": apiOp.Schemas.LookupSchema(id).PluralName,
+			},
+		})
+	}
+	return result, nil
+}
+
+// IDs returns the sorted IDs of the schemas in apiOp.Schemas that the caller can list or get.
+func IDs(apiOp *types.APIRequest) []string {
+	var ids []string
+	for id, schema := range apiOp.Schemas.All() {
+		if id == SchemaID || !canAccess(apiOp, schema) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func canAccess(apiOp *types.APIRequest, schema *types.APISchema) bool {
+	if len(schema.CollectionMethods) == 0 && len(schema.ResourceMethods) == 0 {
+		return false
+	}
+	if apiOp.AccessControl == nil {
+		return true
+	}
+	return apiOp.AccessControl.CanList(apiOp, schema) == nil || apiOp.AccessControl.CanGet(apiOp, schema) == nil
+}
@@ -0,0 +1,95 @@
+package accessible
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type allowListAccess struct {
+	types.AccessControl
+	list map[string]bool
+	get  map[string]bool
+}
+
+func (a *allowListAccess) CanList(apiOp *types.APIRequest, schema *types.APISchema) error {
+	if a.list[schema.ID] {
+		return nil
+	}
+	return apierror.NewAPIError(validation.PermissionDenied, "can not list "+schema.ID)
+}
+
+func (a *allowListAccess) CanGet(apiOp *types.APIRequest, schema *types.APISchema) error {
+	if a.get[schema.ID] {
+		return nil
+	}
+	return apierror.NewAPIError(validation.PermissionDenied, "can not get "+schema.ID)
+}
+
+func newSchemas(ids ...string) *types.APISchemas {
+	apiSchemas := types.EmptyAPISchemas()
+	for _, id := range ids {
+		apiSchemas.MustAddSchema(types.APISchema{
+			Schema: &schemas.Schema{
+				ID:                id,
+				CollectionMethods: []string{http.MethodGet},
+				ResourceMethods:   []string{http.MethodGet},
+			},
+		})
+	}
+	apiSchemas.MustAddSchema(types.APISchema{
+		Schema: &schemas.Schema{ID: "embedded"},
+	})
+	Register(apiSchemas)
+	return apiSchemas
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		name    string
+		access  types.AccessControl
+		wantIDs []string
+	}{
+		{
+			name: "filtered by access control",
+			access: &allowListAccess{
+				list: map[string]bool{"pods": true},
+				get:  map[string]bool{"secrets": true},
+			},
+			wantIDs: []string{"pods", "secrets"},
+		},
+		{
+			name:    "everything denied",
+			access:  &allowListAccess{},
+			wantIDs: nil,
+		},
+		{
+			name:    "no access control",
+			wantIDs: []string{"configmaps", "pods", "secrets"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiOp := &types.APIRequest{
+				Schemas:       newSchemas("pods", "secrets", "configmaps"),
+				AccessControl: tt.access,
+			}
+
+			list, err := NewStore().List(apiOp, apiOp.Schemas.LookupSchema(SchemaID))
+			require.NoError(t, err)
+
+			var ids []string
+			for _, obj := range list.Objects {
+				assert.Equal(t, SchemaID, obj.Type)
+				ids = append(ids, obj.ID)
+			}
+			assert.Equal(t, tt.wantIDs, ids)
+		})
+	}
+}
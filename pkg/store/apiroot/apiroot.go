@@ -38,7 +38,7 @@ func Formatter(apiOp *types.APIRequest, resource *types.RawResource) {
 
 	if data, isAPIRoot := data["apiVersion"].(map[string]interface{}); isAPIRoot {
 		apiVersion := apiVersionFromMap(apiOp.Schemas, data)
-		for _, schema := range apiOp.Schemas.Schemas {
+		for _, schema := range apiOp.Schemas.All() {
 			addCollectionLink(apiOp, schema, apiVersion, resource.Links)
 		}
 		resource.Links["self"] = apiOp.URLBuilder.RelativeToRoot(apiVersion)
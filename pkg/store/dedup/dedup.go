@@ -0,0 +1,80 @@
+package dedup
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"golang.org/x/sync/singleflight"
+)
+
+// KeyFunc returns the key identifying a List call. Concurrent calls with the same key share one call to the
+// embedded store.
+type KeyFunc func(apiOp *types.APIRequest, schema *types.APISchema) string
+
+// Store coalesces concurrent identical List calls into a single call to the embedded store and gives every caller
+// the same result. Each caller is still checked with AccessControl.CanList before it joins a call. The other
+// operations are passed through.
+type Store struct {
+	types.Store
+	// Key identifies identical List calls. It must include everything the embedded store's result depends on.
+	Key KeyFunc
+
+	group singleflight.Group
+}
+
+// New returns a Store coalescing List calls of the same user, see UserListKey.
+func New(store types.Store) *Store {
+	return &Store{
+		Store: store,
+		Key:   UserListKey,
+	}
+}
+
+// ListKey identifies a List call by its schema, namespace, query parameters, which include selectors and
+// pagination, and the ListOptions sent in the body of list actions. It can be used as the Key of stores whose
+// results do not depend on the user.
+func ListKey(apiOp *types.APIRequest, schema *types.APISchema) string {
+	query := apiOp.Query
+	if query == nil && apiOp.Request != nil {
+		query = apiOp.Request.URL.Query()
+	}
+	var options []byte
+	if apiOp.ListOptions != nil {
+		// the fields of a struct are encoded in a fixed order, so equal options give equal keys
+		options, _ = json.Marshal(apiOp.ListOptions)
+	}
+	// url.Values.Encode sorts by key, so the order of the parameters doesn't matter
+	return strings.Join([]string{schema.ID, apiOp.Namespace, query.Encode(), string(options)}, "\x00")
+}
+
+// UserListKey is ListKey, with the name of the user added, for stores filtering their results by user.
+func UserListKey(apiOp *types.APIRequest, schema *types.APISchema) string {
+	return ListKey(apiOp, schema) + "\x00" + apiOp.GetUser()
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	if err := apiOp.AccessControl.CanList(apiOp, schema); err != nil {
+		return types.APIObjectList{}, err
+	}
+
+	result := s.group.DoChan(s.Key(apiOp, schema), func() (interface{}, error) {
+		// the call is shared, so it must not fail because the request that happened to start it went away
+		shared := apiOp.WithContext(context.WithoutCancel(apiOp.Context()))
+		return s.Store.List(shared, schema)
+	})
+
+	select {
+	case <-apiOp.Context().Done():
+		return types.APIObjectList{}, apiOp.Context().Err()
+	case r := <-result:
+		if r.Err != nil {
+			return types.APIObjectList{}, r.Err
+		}
+		list := r.Val.(types.APIObjectList)
+		// callers may append to or reorder the objects
+		list.Objects = append([]types.APIObject(nil), list.Objects...)
+		return list, nil
+	}
+}
@@ -0,0 +1,272 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// slowStore blocks List calls until release is closed.
+type slowStore struct {
+	empty.Store
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (s *slowStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	s.calls.Add(1)
+	<-s.release
+	if err := apiOp.Context().Err(); err != nil {
+		return types.APIObjectList{}, err
+	}
+	return types.APIObjectList{
+		Objects: []types.APIObject{{Type: schema.ID, ID: apiOp.Namespace + "/a"}},
+	}, nil
+}
+
+// listAccess allows listing for every user but "guest", and counts the checks so tests know when a caller is
+// about to join a call.
+type listAccess struct {
+	types.AccessControl
+	checked sync.WaitGroup
+}
+
+func (l *listAccess) CanList(apiOp *types.APIRequest, schema *types.APISchema) error {
+	defer l.checked.Done()
+	if apiOp.GetUser() == "guest" {
+		return errors.New("forbidden")
+	}
+	return nil
+}
+
+var testSchema = &types.APISchema{Schema: &schemas.Schema{ID: "foo"}}
+
+func newRequest(ctx context.Context, access types.AccessControl, userName, namespace, query string) *types.APIRequest {
+	req := httptest.NewRequest("GET", "/v1/foos?"+query, nil)
+	ctx = request.WithUser(ctx, &user.DefaultInfo{Name: userName})
+	return &types.APIRequest{
+		Namespace:     namespace,
+		Request:       req.WithContext(ctx),
+		Query:         req.URL.Query(),
+		AccessControl: access,
+	}
+}
+
+type result struct {
+	list types.APIObjectList
+	err  error
+}
+
+// listConcurrently starts a List call for every request, and releases the store once they all passed the
+// access check.
+func listConcurrently(s *Store, access *listAccess, inner *slowStore, apiOps []*types.APIRequest) []result {
+	access.checked.Add(len(apiOps))
+	results := make([]result, len(apiOps))
+	var wg sync.WaitGroup
+	for i, apiOp := range apiOps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			list, err := s.List(apiOp, testSchema)
+			results[i] = result{list: list, err: err}
+		}()
+	}
+	access.checked.Wait()
+	// give the callers time to join the call after the access check
+	time.Sleep(20 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+	return results
+}
+
+func TestListCoalesces(t *testing.T) {
+	inner := &slowStore{release: make(chan struct{})}
+	access := &listAccess{}
+	s := New(inner)
+
+	var apiOps []*types.APIRequest
+	for i := 0; i < 5; i++ {
+		apiOps = append(apiOps, newRequest(context.Background(), access, "alice", "default", "limit=10&filter=x"))
+	}
+	results := listConcurrently(s, access, inner, apiOps)
+
+	assert.Equal(t, int32(1), inner.calls.Load())
+	for _, r := range results {
+		require.NoError(t, r.err)
+		assert.Equal(t, []types.APIObject{{Type: "foo", ID: "default/a"}}, r.list.Objects)
+	}
+
+	// results are shared, not cached
+	access.checked.Add(1)
+	_, err := s.List(newRequest(context.Background(), access, "alice", "default", "limit=10&filter=x"), testSchema)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), inner.calls.Load())
+}
+
+func TestListDistinctKeys(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       KeyFunc
+		apiOps    func(access types.AccessControl) []*types.APIRequest
+		wantCalls int32
+	}{
+		{
+			name: "query order does not matter",
+			key:  UserListKey,
+			apiOps: func(access types.AccessControl) []*types.APIRequest {
+				return []*types.APIRequest{
+					newRequest(context.Background(), access, "alice", "default", "a=1&b=2"),
+					newRequest(context.Background(), access, "alice", "default", "b=2&a=1"),
+				}
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "different namespaces",
+			key:  UserListKey,
+			apiOps: func(access types.AccessControl) []*types.APIRequest {
+				return []*types.APIRequest{
+					newRequest(context.Background(), access, "alice", "default", ""),
+					newRequest(context.Background(), access, "alice", "kube-system", ""),
+				}
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "different selectors",
+			key:  UserListKey,
+			apiOps: func(access types.AccessControl) []*types.APIRequest {
+				return []*types.APIRequest{
+					newRequest(context.Background(), access, "alice", "default", "labelSelector=app%3Dweb"),
+					newRequest(context.Background(), access, "alice", "default", "labelSelector=app%3Ddb"),
+				}
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "different list options",
+			key:  UserListKey,
+			apiOps: func(access types.AccessControl) []*types.APIRequest {
+				web := newRequest(context.Background(), access, "alice", "default", "")
+				web.ListOptions = &types.ListOptions{Filters: []types.ListFilter{{Field: []string{"app"}, Value: "web"}}}
+				db := newRequest(context.Background(), access, "alice", "default", "")
+				db.ListOptions = &types.ListOptions{Filters: []types.ListFilter{{Field: []string{"app"}, Value: "db"}}}
+				return []*types.APIRequest{web, db}
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "same list options",
+			key:  UserListKey,
+			apiOps: func(access types.AccessControl) []*types.APIRequest {
+				var apiOps []*types.APIRequest
+				for i := 0; i < 2; i++ {
+					apiOp := newRequest(context.Background(), access, "alice", "default", "")
+					apiOp.ListOptions = &types.ListOptions{Sort: []types.ListSort{{Field: []string{"name"}}}, Page: 2, PageSize: 10}
+					apiOps = append(apiOps, apiOp)
+				}
+				return apiOps
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "different users",
+			key:  UserListKey,
+			apiOps: func(access types.AccessControl) []*types.APIRequest {
+				return []*types.APIRequest{
+					newRequest(context.Background(), access, "alice", "default", ""),
+					newRequest(context.Background(), access, "bob", "default", ""),
+				}
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "different users sharing results",
+			key:  ListKey,
+			apiOps: func(access types.AccessControl) []*types.APIRequest {
+				return []*types.APIRequest{
+					newRequest(context.Background(), access, "alice", "default", ""),
+					newRequest(context.Background(), access, "bob", "default", ""),
+				}
+			},
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &slowStore{release: make(chan struct{})}
+			access := &listAccess{}
+			s := New(inner)
+			s.Key = tt.key
+
+			for _, r := range listConcurrently(s, access, inner, tt.apiOps(access)) {
+				assert.NoError(t, r.err)
+			}
+			assert.Equal(t, tt.wantCalls, inner.calls.Load())
+		})
+	}
+}
+
+func TestListAccessControl(t *testing.T) {
+	inner := &slowStore{release: make(chan struct{})}
+	access := &listAccess{}
+	s := New(inner)
+	s.Key = ListKey
+
+	results := listConcurrently(s, access, inner, []*types.APIRequest{
+		newRequest(context.Background(), access, "alice", "default", ""),
+		newRequest(context.Background(), access, "guest", "default", ""),
+	})
+
+	assert.NoError(t, results[0].err)
+	assert.Len(t, results[0].list.Objects, 1)
+	assert.EqualError(t, results[1].err, "forbidden")
+	assert.Empty(t, results[1].list.Objects)
+	assert.Equal(t, int32(1), inner.calls.Load())
+}
+
+func TestListCanceledCaller(t *testing.T) {
+	inner := &slowStore{release: make(chan struct{})}
+	access := &listAccess{}
+	s := New(inner)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := newRequest(ctx, access, "alice", "default", "")
+	follower := newRequest(context.Background(), access, "alice", "default", "")
+
+	access.checked.Add(2)
+	leaderErr := make(chan error)
+	go func() {
+		_, err := s.List(leader, testSchema)
+		leaderErr <- err
+	}()
+	followerResult := make(chan result)
+	go func() {
+		list, err := s.List(follower, testSchema)
+		followerResult <- result{list: list, err: err}
+	}()
+	access.checked.Wait()
+	time.Sleep(20 * time.Millisecond)
+
+	// the caller that started the call goes away, which must not fail the others
+	cancel()
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+	close(inner.release)
+
+	r := <-followerResult
+	require.NoError(t, r.err)
+	assert.Len(t, r.list.Objects, 1)
+	assert.Equal(t, int32(1), inner.calls.Load())
+}
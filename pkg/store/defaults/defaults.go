@@ -0,0 +1,47 @@
+package defaults
+
+import (
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// MutateFunc modifies an object before it is passed to the inner store. The request is available so the object
+// can be changed based on the caller, for example to record the requesting user.
+type MutateFunc func(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error)
+
+// Store applies Mutate to the data of every Create and Update before passing it to the embedded store. A nil Mutate
+// passes the data on unchanged.
+type Store struct {
+	types.Store
+	Mutate MutateFunc
+}
+
+// New returns a Store that applies mutate to incoming objects before they reach store.
+func New(store types.Store, mutate MutateFunc) *Store {
+	return &Store{
+		Store:  store,
+		Mutate: mutate,
+	}
+}
+
+func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	data, err := s.mutate(apiOp, schema, data)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	return s.Store.Create(apiOp, schema, data)
+}
+
+func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	data, err := s.mutate(apiOp, schema, data)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	return s.Store.Update(apiOp, schema, data, id)
+}
+
+func (s *Store) mutate(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	if s.Mutate == nil {
+		return data, nil
+	}
+	return s.Mutate(apiOp, schema, data)
+}
@@ -0,0 +1,111 @@
+package defaults
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/data"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+type recordingStore struct {
+	empty.Store
+	received []types.APIObject
+}
+
+func (r *recordingStore) Create(apiOp *types.APIRequest, schema *types.APISchema, obj types.APIObject) (types.APIObject, error) {
+	r.received = append(r.received, obj)
+	return obj, nil
+}
+
+func (r *recordingStore) Update(apiOp *types.APIRequest, schema *types.APISchema, obj types.APIObject, id string) (types.APIObject, error) {
+	r.received = append(r.received, obj)
+	return obj, nil
+}
+
+var schema = &types.APISchema{Schema: &schemas.Schema{ID: "configs"}}
+
+func stampUser(apiOp *types.APIRequest, schema *types.APISchema, obj types.APIObject) (types.APIObject, error) {
+	values := obj.Data()
+	values.SetNested(apiOp.GetUser(), "metadata", "labels", "createdBy")
+	if values.String("status") == "" {
+		values["status"] = "pending"
+	}
+	obj.Object = values
+	return obj, nil
+}
+
+func newRequest(username string) *types.APIRequest {
+	req := httptest.NewRequest(http.MethodPost, "/v1/configs", nil)
+	req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: username}))
+	return &types.APIRequest{Request: req}
+}
+
+func TestDefaultsApplied(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(store *Store, apiOp *types.APIRequest, obj types.APIObject) (types.APIObject, error)
+	}{
+		{
+			name: "create",
+			call: func(store *Store, apiOp *types.APIRequest, obj types.APIObject) (types.APIObject, error) {
+				return store.Create(apiOp, schema, obj)
+			},
+		},
+		{
+			name: "update",
+			call: func(store *Store, apiOp *types.APIRequest, obj types.APIObject) (types.APIObject, error) {
+				return store.Update(apiOp, schema, obj, "a")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inner := &recordingStore{}
+			store := New(inner, stampUser)
+			apiOp := newRequest("alice")
+
+			result, err := test.call(store, apiOp, types.APIObject{Object: map[string]interface{}{"name": "a"}})
+			require.NoError(t, err)
+
+			require.Len(t, inner.received, 1)
+			received := inner.received[0].Data()
+			assert.Equal(t, "a", received.String("name"))
+			assert.Equal(t, "pending", received.String("status"))
+			assert.Equal(t, "alice", received.String("metadata", "labels", "createdBy"))
+			assert.Equal(t, received, result.Data())
+		})
+	}
+}
+
+func TestDefaultsError(t *testing.T) {
+	inner := &recordingStore{}
+	store := New(inner, func(*types.APIRequest, *types.APISchema, types.APIObject) (types.APIObject, error) {
+		return types.APIObject{}, errors.New("invalid")
+	})
+
+	_, err := store.Create(newRequest("alice"), schema, types.APIObject{Object: data.Object{}})
+	assert.EqualError(t, err, "invalid")
+	assert.Empty(t, inner.received)
+}
+
+func TestDefaultsNilMutate(t *testing.T) {
+	inner := &recordingStore{}
+	store := New(inner, nil)
+	obj := types.APIObject{Object: map[string]interface{}{"name": "a"}}
+
+	_, err := store.Create(newRequest("alice"), schema, obj)
+	require.NoError(t, err)
+	_, err = store.Update(newRequest("alice"), schema, obj, "a")
+	require.NoError(t, err)
+
+	assert.Equal(t, []types.APIObject{obj, obj}, inner.received)
+}
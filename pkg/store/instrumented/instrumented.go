@@ -0,0 +1,69 @@
+package instrumented
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rancher/apiserver/pkg/metrics"
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// enabled is replaced in tests, since metrics can only be turned on through the environment.
+var enabled = metrics.Enabled
+
+// Store records how long each call to the embedded store takes, in milliseconds, labeled by resource and
+// operation. For Watch, only the time to start the watch is recorded. Nothing is recorded unless metrics are
+// enabled.
+type Store struct {
+	types.Store
+	Histogram *prometheus.HistogramVec
+}
+
+// New returns store instrumented with metrics.StoreTime.
+func New(store types.Store) *Store {
+	return &Store{
+		Store:     store,
+		Histogram: metrics.StoreTime,
+	}
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	defer s.observe(schema, "byID", time.Now())
+	return s.Store.ByID(apiOp, schema, id)
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	defer s.observe(schema, "list", time.Now())
+	return s.Store.List(apiOp, schema)
+}
+
+func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	defer s.observe(schema, "create", time.Now())
+	return s.Store.Create(apiOp, schema, data)
+}
+
+func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	defer s.observe(schema, "update", time.Now())
+	return s.Store.Update(apiOp, schema, data, id)
+}
+
+func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	defer s.observe(schema, "delete", time.Now())
+	return s.Store.Delete(apiOp, schema, id)
+}
+
+func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	defer s.observe(schema, "watch", time.Now())
+	return s.Store.Watch(apiOp, schema, w)
+}
+
+func (s *Store) observe(schema *types.APISchema, operation string, start time.Time) {
+	if !enabled() {
+		return
+	}
+	resource := "unknown"
+	if schema != nil {
+		resource = schema.ID
+	}
+	s.Histogram.WithLabelValues(resource, operation).Observe(float64(time.Since(start)) / float64(time.Millisecond))
+}
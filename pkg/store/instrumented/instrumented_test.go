@@ -0,0 +1,115 @@
+package instrumented
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingStore struct {
+	empty.Store
+	calls []string
+}
+
+func (r *recordingStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	r.calls = append(r.calls, "byID")
+	return types.APIObject{Type: schema.ID, ID: id}, nil
+}
+
+func (r *recordingStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	r.calls = append(r.calls, "list")
+	return types.APIObjectList{Objects: []types.APIObject{{Type: schema.ID, ID: "foo"}}}, nil
+}
+
+func (r *recordingStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	r.calls = append(r.calls, "create")
+	return data, nil
+}
+
+func (r *recordingStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	r.calls = append(r.calls, "update")
+	return data, nil
+}
+
+func (r *recordingStore) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	r.calls = append(r.calls, "delete")
+	return types.APIObject{Type: schema.ID, ID: id}, nil
+}
+
+func (r *recordingStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	r.calls = append(r.calls, "watch")
+	return make(chan types.APIEvent), nil
+}
+
+func newHistogram() *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "store_time"}, []string{"resource", "operation"})
+}
+
+func exercise(t *testing.T, store types.Store, schema *types.APISchema) {
+	apiOp := &types.APIRequest{}
+
+	obj, err := store.ByID(apiOp, schema, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", obj.ID)
+
+	list, err := store.List(apiOp, schema)
+	require.NoError(t, err)
+	assert.Len(t, list.Objects, 1)
+
+	_, err = store.Create(apiOp, schema, types.APIObject{ID: "foo"})
+	require.NoError(t, err)
+	_, err = store.Update(apiOp, schema, types.APIObject{ID: "foo"}, "foo")
+	require.NoError(t, err)
+	_, err = store.Delete(apiOp, schema, "foo")
+	require.NoError(t, err)
+
+	events, err := store.Watch(apiOp, schema, types.WatchRequest{})
+	require.NoError(t, err)
+	assert.NotNil(t, events)
+}
+
+func TestStore(t *testing.T) {
+	schema := &types.APISchema{Schema: &schemas.Schema{ID: "foo"}}
+	operations := []string{"byID", "list", "create", "update", "delete", "watch"}
+
+	tests := []struct {
+		name    string
+		enabled bool
+	}{
+		{name: "enabled", enabled: true},
+		{name: "disabled", enabled: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(old func() bool) { enabled = old }(enabled)
+			enabled = func() bool { return tt.enabled }
+
+			inner := &recordingStore{}
+			store := New(inner)
+			store.Histogram = newHistogram()
+
+			exercise(t, store, schema)
+
+			assert.Equal(t, operations, inner.calls)
+			if !tt.enabled {
+				assert.Equal(t, 0, testutil.CollectAndCount(store.Histogram))
+				return
+			}
+			assert.Equal(t, len(operations), testutil.CollectAndCount(store.Histogram))
+			for _, operation := range operations {
+				observer, err := store.Histogram.GetMetricWithLabelValues("foo", operation)
+				require.NoError(t, err)
+				var metric dto.Metric
+				require.NoError(t, observer.(prometheus.Metric).Write(&metric))
+				assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount(), operation)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package memory
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/data"
+	"github.com/rancher/wrangler/v3/pkg/data/convert"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// LabelSelectorParam is the query parameter List reads a label selector from. Schemas using the store should add
+// it to their QueryParameters.
+const LabelSelectorParam = "labelSelector"
+
+// watchBuffer is the number of events a watch can fall behind before it is closed.
+const watchBuffer = 100
+
+// Store keeps the objects of a single schema in memory. Objects are indexed by namespace, and by the value of the
+// labels passed to New, so lists of a namespace, or selecting an indexed label by equality, only look at the
+// matching objects instead of every object of the store.
+//
+// Objects are identified by their APIObject.ID, or by their metadata name and namespace if it is not set.
+// Watches that fall too far behind are closed, so clients start a new watch.
+type Store struct {
+	lock          sync.RWMutex
+	objects       map[string]types.APIObject
+	namespaces    map[string]map[string]bool
+	labels        map[string]map[string]bool
+	indexedLabels map[string]bool
+	revision      int
+	watches       map[*watch]bool
+}
+
+type watch struct {
+	namespace string
+	request   types.WatchRequest
+	selector  labels.Selector
+	events    chan types.APIEvent
+}
+
+// New returns an empty Store indexing the values of indexedLabels.
+func New(indexedLabels ...string) *Store {
+	s := &Store{
+		objects:       map[string]types.APIObject{},
+		namespaces:    map[string]map[string]bool{},
+		labels:        map[string]map[string]bool{},
+		indexedLabels: map[string]bool{},
+		watches:       map[*watch]bool{},
+	}
+	for _, label := range indexedLabels {
+		s.indexedLabels[label] = true
+	}
+	return s
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	obj, ok := s.objects[id]
+	if !ok {
+		return types.APIObject{}, apierror.NewAPIError(validation.NotFound, "not found")
+	}
+	return obj, nil
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	selector, err := labels.Parse(apiOp.Query.Get(LabelSelectorParam))
+	if err != nil {
+		return types.APIObjectList{}, apierror.NewAPIError(apierror.BadRequest, err.Error())
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	list := types.APIObjectList{
+		Revision: strconv.Itoa(s.revision),
+	}
+	match := func(obj types.APIObject) {
+		if apiOp.Namespace != "" && obj.Namespace() != apiOp.Namespace {
+			return
+		}
+		if !selector.Empty() && !selector.Matches(objectLabels(obj)) {
+			return
+		}
+		list.Objects = append(list.Objects, obj)
+	}
+
+	if ids, ok := s.candidates(apiOp.Namespace, selector); ok {
+		for id := range ids {
+			match(s.objects[id])
+		}
+	} else {
+		for _, obj := range s.objects {
+			match(obj)
+		}
+	}
+
+	sort.Slice(list.Objects, func(i, j int) bool {
+		return list.Objects[i].ID < list.Objects[j].ID
+	})
+	return list, nil
+}
+
+// candidates returns the IDs of the smallest index matching namespace and selector, or false if neither can use
+// an index. The objects still have to be matched, as the selector may have requirements that are not indexed.
+func (s *Store) candidates(namespace string, selector labels.Selector) (map[string]bool, bool) {
+	var best map[string]bool
+	indexed := false
+	if namespace != "" {
+		best, indexed = s.namespaces[namespace], true
+	}
+
+	requirements, _ := selector.Requirements()
+	for _, req := range requirements {
+		if !s.indexedLabels[req.Key()] || !isEquality(req) {
+			continue
+		}
+		ids := s.labels[labelKey(req.Key(), req.Values().List()[0])]
+		if !indexed || len(ids) < len(best) {
+			best, indexed = ids, true
+		}
+	}
+	return best, indexed
+}
+
+func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	data = withID(data, schema)
+	if data.ID == "" {
+		return types.APIObject{}, apierror.NewAPIError(validation.MissingRequired, "id is required")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.objects[data.ID]; ok {
+		return types.APIObject{}, apierror.NewAPIError(validation.Conflict, "already exists")
+	}
+	s.revision++
+	s.add(data)
+	s.notify(types.CreateAPIEvent, data)
+	return data, nil
+}
+
+func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	data = withID(data, schema)
+	data.ID = id
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	old, ok := s.objects[id]
+	if !ok {
+		return types.APIObject{}, apierror.NewAPIError(validation.NotFound, "not found")
+	}
+	s.revision++
+	s.remove(old)
+	s.add(data)
+	s.notify(types.ChangeAPIEvent, data)
+	return data, nil
+}
+
+func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	obj, ok := s.objects[id]
+	if !ok {
+		return types.APIObject{}, apierror.NewAPIError(validation.NotFound, "not found")
+	}
+	s.revision++
+	s.remove(obj)
+	s.notify(types.RemoveAPIEvent, obj)
+	return obj, nil
+}
+
+// Watch sends the changes made to the objects matching the namespace of the request, as well as the ID and
+// label selector of w, until the request is canceled.
+func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	selector, err := labels.Parse(w.Selector)
+	if err != nil {
+		return nil, apierror.NewAPIError(apierror.BadRequest, err.Error())
+	}
+
+	watch := &watch{
+		namespace: apiOp.Namespace,
+		request:   w,
+		selector:  selector,
+		events:    make(chan types.APIEvent, watchBuffer),
+	}
+
+	s.lock.Lock()
+	s.watches[watch] = true
+	s.lock.Unlock()
+
+	go func() {
+		<-apiOp.Context().Done()
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.stopWatch(watch)
+	}()
+
+	return watch.events, nil
+}
+
+func (s *Store) add(obj types.APIObject) {
+	s.objects[obj.ID] = obj
+	addIndex(s.namespaces, obj.Namespace(), obj.ID)
+	for key, value := range objectLabels(obj) {
+		if s.indexedLabels[key] {
+			addIndex(s.labels, labelKey(key, value), obj.ID)
+		}
+	}
+}
+
+func (s *Store) remove(obj types.APIObject) {
+	delete(s.objects, obj.ID)
+	removeIndex(s.namespaces, obj.Namespace(), obj.ID)
+	for key, value := range objectLabels(obj) {
+		if s.indexedLabels[key] {
+			removeIndex(s.labels, labelKey(key, value), obj.ID)
+		}
+	}
+}
+
+// notify sends an event to the watches matching obj. It must be called with the lock held.
+func (s *Store) notify(name string, obj types.APIObject) {
+	event := types.APIEvent{
+		Name:         name,
+		Namespace:    obj.Namespace(),
+		ResourceType: obj.Type,
+		ID:           obj.ID,
+		Revision:     strconv.Itoa(s.revision),
+		Object:       obj,
+	}
+	for watch := range s.watches {
+		if !watch.matches(obj) {
+			continue
+		}
+		select {
+		case watch.events <- event:
+		default:
+			s.stopWatch(watch)
+		}
+	}
+}
+
+// stopWatch closes the events of watch. It must be called with the lock held.
+func (s *Store) stopWatch(watch *watch) {
+	if s.watches[watch] {
+		delete(s.watches, watch)
+		close(watch.events)
+	}
+}
+
+func (w *watch) matches(obj types.APIObject) bool {
+	if w.namespace != "" && obj.Namespace() != w.namespace {
+		return false
+	}
+	if w.request.ID != "" && obj.ID != w.request.ID {
+		return false
+	}
+	return w.selector.Empty() || w.selector.Matches(objectLabels(obj))
+}
+
+func withID(obj types.APIObject, schema *types.APISchema) types.APIObject {
+	if obj.Type == "" && schema != nil {
+		obj.Type = schema.ID
+	}
+	if obj.ID == "" {
+		extractor := types.MetadataIDExtractor
+		if schema != nil && schema.IDExtractor != nil {
+			extractor = schema.IDExtractor
+		}
+		obj.ID = extractor.ExtractID(obj)
+	}
+	return obj
+}
+
+func objectLabels(obj types.APIObject) labels.Set {
+	result := labels.Set{}
+	for key, value := range convert.ToMapInterface(data.GetValueN(obj.Data(), "metadata", "labels")) {
+		result[key] = convert.ToString(value)
+	}
+	return result
+}
+
+func isEquality(req labels.Requirement) bool {
+	switch req.Operator() {
+	case selection.Equals, selection.DoubleEquals, selection.In:
+		return req.Values().Len() == 1
+	}
+	return false
+}
+
+func labelKey(key, value string) string {
+	return key + "=" + value
+}
+
+func addIndex(index map[string]map[string]bool, key, id string) {
+	ids, ok := index[key]
+	if !ok {
+		ids = map[string]bool{}
+		index[key] = ids
+	}
+	ids[id] = true
+}
+
+func removeIndex(index map[string]map[string]bool, key, id string) {
+	delete(index[key], id)
+	if len(index[key]) == 0 {
+		delete(index, key)
+	}
+}
@@ -0,0 +1,282 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var testSchema = &types.APISchema{Schema: &schemas.Schema{ID: "pod"}}
+
+func newObject(namespace, name string, objLabels map[string]string) types.APIObject {
+	l := map[string]interface{}{}
+	for k, v := range objLabels {
+		l[k] = v
+	}
+	return types.APIObject{
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+				"labels":    l,
+			},
+		},
+	}
+}
+
+func newRequest(ctx context.Context, namespace, selector string) *types.APIRequest {
+	query := url.Values{}
+	if selector != "" {
+		query.Set(LabelSelectorParam, selector)
+	}
+	return &types.APIRequest{
+		Namespace: namespace,
+		Query:     query,
+		Request:   httptest.NewRequest("GET", "/v1/pods?"+query.Encode(), nil).WithContext(ctx),
+	}
+}
+
+func newFilledStore(t *testing.T, indexedLabels ...string) *Store {
+	s := New(indexedLabels...)
+	for _, obj := range []types.APIObject{
+		newObject("default", "web-1", map[string]string{"app": "web", "tier": "frontend"}),
+		newObject("default", "web-2", map[string]string{"app": "web", "tier": "frontend"}),
+		newObject("default", "db-1", map[string]string{"app": "db", "tier": "backend"}),
+		newObject("prod", "web-1", map[string]string{"app": "web", "tier": "frontend"}),
+		newObject("", "node-1", nil),
+	} {
+		_, err := s.Create(newRequest(context.Background(), "", ""), testSchema, obj)
+		require.NoError(t, err)
+	}
+	return s
+}
+
+func listIDs(t *testing.T, s *Store, namespace, selector string) []string {
+	list, err := s.List(newRequest(context.Background(), namespace, selector), testSchema)
+	require.NoError(t, err)
+	var ids []string
+	for _, obj := range list.Objects {
+		ids = append(ids, obj.ID)
+	}
+	return ids
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		selector  string
+		want      []string
+	}{
+		{
+			name: "all",
+			want: []string{"default/db-1", "default/web-1", "default/web-2", "node-1", "prod/web-1"},
+		},
+		{
+			name:      "namespace",
+			namespace: "default",
+			want:      []string{"default/db-1", "default/web-1", "default/web-2"},
+		},
+		{
+			name:     "indexed label",
+			selector: "app=web",
+			want:     []string{"default/web-1", "default/web-2", "prod/web-1"},
+		},
+		{
+			name:      "namespace and indexed label",
+			namespace: "prod",
+			selector:  "app==web",
+			want:      []string{"prod/web-1"},
+		},
+		{
+			name:     "indexed and unindexed labels",
+			selector: "app in (db),tier=backend",
+			want:     []string{"default/db-1"},
+		},
+		{
+			name:     "unindexed label",
+			selector: "tier=frontend",
+			want:     []string{"default/web-1", "default/web-2", "prod/web-1"},
+		},
+		{
+			name:     "inequality",
+			selector: "app!=web",
+			want:     []string{"default/db-1", "node-1"},
+		},
+		{
+			name:     "no match",
+			selector: "app=cache",
+		},
+	}
+	s := newFilledStore(t, "app")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, listIDs(t, s, tt.namespace, tt.selector))
+		})
+	}
+}
+
+func TestListInvalidSelector(t *testing.T) {
+	_, err := New().List(newRequest(context.Background(), "", "app in ("), testSchema)
+	assert.Error(t, err)
+}
+
+func TestIndexesFollowChanges(t *testing.T) {
+	s := newFilledStore(t, "app")
+	apiOp := newRequest(context.Background(), "", "")
+
+	_, err := s.Update(apiOp, testSchema, newObject("default", "web-1", map[string]string{"app": "db"}), "default/web-1")
+	require.NoError(t, err)
+	_, err = s.Delete(apiOp, testSchema, "default/web-2")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"prod/web-1"}, listIDs(t, s, "", "app=web"))
+	assert.Equal(t, []string{"default/db-1", "default/web-1"}, listIDs(t, s, "default", "app=db"))
+	assert.Len(t, s.labels[labelKey("app", "web")], 1)
+
+	_, err = s.Delete(apiOp, testSchema, "prod/web-1")
+	require.NoError(t, err)
+	assert.NotContains(t, s.labels, labelKey("app", "web"))
+	assert.NotContains(t, s.namespaces, "prod")
+}
+
+func TestCreateErrors(t *testing.T) {
+	s := newFilledStore(t)
+	apiOp := newRequest(context.Background(), "", "")
+
+	_, err := s.Create(apiOp, testSchema, types.APIObject{Object: map[string]interface{}{}})
+	assert.ErrorContains(t, err, "id is required")
+	_, err = s.Create(apiOp, testSchema, newObject("default", "web-1", nil))
+	assert.ErrorContains(t, err, "already exists")
+	_, err = s.Update(apiOp, testSchema, newObject("default", "missing", nil), "default/missing")
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestWatch(t *testing.T) {
+	s := newFilledStore(t, "app")
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := s.Watch(newRequest(ctx, "default", ""), testSchema, types.WatchRequest{Selector: "app=web"})
+	require.NoError(t, err)
+
+	apiOp := newRequest(context.Background(), "", "")
+	_, err = s.Create(apiOp, testSchema, newObject("default", "web-3", map[string]string{"app": "web"}))
+	require.NoError(t, err)
+	_, err = s.Create(apiOp, testSchema, newObject("prod", "web-2", map[string]string{"app": "web"}))
+	require.NoError(t, err)
+	_, err = s.Create(apiOp, testSchema, newObject("default", "db-2", map[string]string{"app": "db"}))
+	require.NoError(t, err)
+	_, err = s.Delete(apiOp, testSchema, "default/web-1")
+	require.NoError(t, err)
+
+	event := <-events
+	assert.Equal(t, types.CreateAPIEvent, event.Name)
+	assert.Equal(t, "default/web-3", event.ID)
+	assert.Equal(t, "6", event.Revision)
+	event = <-events
+	assert.Equal(t, types.RemoveAPIEvent, event.Name)
+	assert.Equal(t, "default/web-1", event.ID)
+
+	cancel()
+	_, open := <-events
+	assert.False(t, open, "the watch must stop once the request is canceled")
+}
+
+func TestWatchSlowConsumer(t *testing.T) {
+	s := New()
+	events, err := s.Watch(newRequest(context.Background(), "", ""), testSchema, types.WatchRequest{})
+	require.NoError(t, err)
+
+	for i := 0; i <= watchBuffer; i++ {
+		_, err := s.Create(newRequest(context.Background(), "", ""), testSchema, newObject("", fmt.Sprint(i), nil))
+		require.NoError(t, err)
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+	assert.Equal(t, watchBuffer, received)
+}
+
+const (
+	benchmarkNamespaces = 100
+	benchmarkPerNS      = 100
+)
+
+func newBenchmarkStore(b *testing.B) *Store {
+	s := New("app")
+	apiOp := newRequest(context.Background(), "", "")
+	for ns := 0; ns < benchmarkNamespaces; ns++ {
+		for i := 0; i < benchmarkPerNS; i++ {
+			obj := newObject(fmt.Sprintf("ns-%d", ns), fmt.Sprintf("obj-%d", i), map[string]string{
+				"app": fmt.Sprintf("app-%d", i%10),
+			})
+			if _, err := s.Create(apiOp, testSchema, obj); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	return s
+}
+
+// linearList filters every object of the store, as a store without indexes would.
+func linearList(s *Store, namespace string, selector labels.Selector) []types.APIObject {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	var result []types.APIObject
+	for _, obj := range s.objects {
+		if namespace != "" && obj.Namespace() != namespace {
+			continue
+		}
+		if !selector.Matches(objectLabels(obj)) {
+			continue
+		}
+		result = append(result, obj)
+	}
+	return result
+}
+
+func BenchmarkListNamespace(b *testing.B) {
+	s := newBenchmarkStore(b)
+	apiOp := newRequest(context.Background(), "ns-42", "")
+
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := s.List(apiOp, testSchema); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			linearList(s, "ns-42", labels.Everything())
+		}
+	})
+}
+
+func BenchmarkListSelector(b *testing.B) {
+	s := newBenchmarkStore(b)
+	apiOp := newRequest(context.Background(), "ns-42", "app=app-3")
+	selector := labels.SelectorFromSet(labels.Set{"app": "app-3"})
+
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := s.List(apiOp, testSchema); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			linearList(s, "ns-42", selector)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package ownership
+
+import (
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/data"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+// OwnerFunc returns whether obj is owned by user. user is nil if the request is not authenticated.
+type OwnerFunc func(obj types.APIObject, user user.Info) bool
+
+// LabelOwner returns an OwnerFunc for objects owned by the user named in their metadata.labels[label].
+func LabelOwner(label string) OwnerFunc {
+	return func(obj types.APIObject, user user.Info) bool {
+		if user == nil || user.GetName() == "" {
+			return false
+		}
+		owner, _ := data.GetValueN(obj.Data(), "metadata", "labels", label).(string)
+		return owner == user.GetName()
+	}
+}
+
+// Store only lets callers see objects of the embedded store that they own. Objects owned by someone else are
+// left out of lists and watches, and are reported as not found by ByID. Update and Delete look the object up
+// through ByID first, so they fail the same way. Create and Update are denied unless the caller owns the object
+// sent, so objects cannot be created for, or handed over to, another user. To set the owner from the request
+// instead, wrap the Store in a defaults.Store that stamps it.
+type Store struct {
+	types.Store
+	Owns OwnerFunc
+}
+
+// New returns a Store restricting store to the objects for which owns returns true.
+func New(store types.Store, owns OwnerFunc) *Store {
+	return &Store{
+		Store: store,
+		Owns:  owns,
+	}
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, err := s.Store.ByID(apiOp, schema, id)
+	if err != nil {
+		return obj, err
+	}
+	if !s.owns(apiOp, obj) {
+		return types.APIObject{}, apierror.NewAPIError(validation.NotFound, "not found")
+	}
+	return obj, nil
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	list, err := s.Store.List(apiOp, schema)
+	if err != nil {
+		return list, err
+	}
+
+	objects := make([]types.APIObject, 0, len(list.Objects))
+	for _, obj := range list.Objects {
+		if s.owns(apiOp, obj) {
+			objects = append(objects, obj)
+		}
+	}
+	list.Objects = objects
+	return list, nil
+}
+
+func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	if !s.owns(apiOp, data) {
+		return types.APIObject{}, apierror.NewAPIError(validation.PermissionDenied, "can not create "+schema.ID+" owned by another user")
+	}
+	return s.Store.Create(apiOp, schema, data)
+}
+
+func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	if _, err := s.ByID(apiOp, schema, id); err != nil {
+		return types.APIObject{}, err
+	}
+	if !s.owns(apiOp, data) {
+		return types.APIObject{}, apierror.NewAPIError(validation.PermissionDenied, "can not change the owner of "+schema.ID)
+	}
+	return s.Store.Update(apiOp, schema, data, id)
+}
+
+func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	if _, err := s.ByID(apiOp, schema, id); err != nil {
+		return types.APIObject{}, err
+	}
+	return s.Store.Delete(apiOp, schema, id)
+}
+
+func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	events, err := s.Store.Watch(apiOp, schema, w)
+	if err != nil || events == nil {
+		return events, err
+	}
+
+	result := make(chan types.APIEvent)
+	go func() {
+		defer close(result)
+		for event := range events {
+			if event.Error == nil && event.Object.Object != nil && !s.owns(apiOp, event.Object) {
+				continue
+			}
+			select {
+			case result <- event:
+			case <-apiOp.Context().Done():
+				return
+			}
+		}
+	}()
+	return result, nil
+}
+
+func (s *Store) owns(apiOp *types.APIRequest, obj types.APIObject) bool {
+	info, _ := apiOp.GetUserInfo()
+	return s.Owns(obj, info)
+}
@@ -0,0 +1,217 @@
+package ownership
+
+import (
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const ownerLabel = "example.com/owner"
+
+type memoryStore struct {
+	empty.Store
+	objects map[string]map[string]interface{}
+	events  chan types.APIEvent
+}
+
+func newMemoryStore(owners map[string]string) *memoryStore {
+	m := &memoryStore{objects: map[string]map[string]interface{}{}}
+	for id, owner := range owners {
+		m.objects[id] = map[string]interface{}{
+			"id": id,
+			"metadata": map[string]interface{}{
+				"labels": map[string]interface{}{ownerLabel: owner},
+			},
+		}
+	}
+	return m
+}
+
+func (m *memoryStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, ok := m.objects[id]
+	if !ok {
+		return types.APIObject{}, validation.NotFound
+	}
+	return types.APIObject{Type: schema.ID, ID: id, Object: obj}, nil
+}
+
+func (m *memoryStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	var list types.APIObjectList
+	for id, obj := range m.objects {
+		list.Objects = append(list.Objects, types.APIObject{Type: schema.ID, ID: id, Object: obj})
+	}
+	sort.Slice(list.Objects, func(i, j int) bool { return list.Objects[i].ID < list.Objects[j].ID })
+	return list, nil
+}
+
+func (m *memoryStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	m.objects[data.ID] = data.Data()
+	return m.ByID(apiOp, schema, data.ID)
+}
+
+func (m *memoryStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	m.objects[id] = data.Data()
+	return m.ByID(apiOp, schema, id)
+}
+
+func (m *memoryStore) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, err := m.ByID(apiOp, schema, id)
+	delete(m.objects, id)
+	return obj, err
+}
+
+func (m *memoryStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	return m.events, nil
+}
+
+var schema = &types.APISchema{Schema: &schemas.Schema{ID: "projects"}}
+
+func newRequest(name string) *types.APIRequest {
+	req := httptest.NewRequest("GET", "/v1/projects", nil)
+	if name != "" {
+		req = req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: name}))
+	}
+	return &types.APIRequest{Request: req}
+}
+
+func ids(list types.APIObjectList) []string {
+	var result []string
+	for _, obj := range list.Objects {
+		result = append(result, obj.ID)
+	}
+	return result
+}
+
+func TestList(t *testing.T) {
+	store := New(newMemoryStore(map[string]string{"a": "alice", "b": "bob", "c": "alice"}), LabelOwner(ownerLabel))
+
+	tests := []struct {
+		user string
+		want []string
+	}{
+		{user: "alice", want: []string{"a", "c"}},
+		{user: "bob", want: []string{"b"}},
+		{user: "carol", want: nil},
+		{user: "", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.user, func(t *testing.T) {
+			list, err := store.List(newRequest(tt.user), schema)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ids(list))
+		})
+	}
+}
+
+func TestByID(t *testing.T) {
+	store := New(newMemoryStore(map[string]string{"a": "alice"}), LabelOwner(ownerLabel))
+
+	obj, err := store.ByID(newRequest("alice"), schema, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", obj.ID)
+
+	for _, name := range []string{"bob", ""} {
+		_, err = store.ByID(newRequest(name), schema, "a")
+		require.Error(t, err)
+		assert.Equal(t, validation.NotFound, err.(*apierror.APIError).Code)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	inner := newMemoryStore(map[string]string{"a": "alice"})
+	store := New(inner, LabelOwner(ownerLabel))
+
+	_, err := store.Delete(newRequest("bob"), schema, "a")
+	require.Error(t, err)
+	assert.Contains(t, inner.objects, "a")
+
+	_, err = store.Delete(newRequest("alice"), schema, "a")
+	require.NoError(t, err)
+	assert.NotContains(t, inner.objects, "a")
+}
+
+func ownedBy(id, owner string) types.APIObject {
+	return types.APIObject{ID: id, Object: map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{ownerLabel: owner},
+		},
+	}}
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		owner   string
+		wantErr bool
+	}{
+		{name: "own", user: "alice", owner: "alice"},
+		{name: "other user", user: "alice", owner: "bob", wantErr: true},
+		{name: "no owner", user: "alice", wantErr: true},
+		{name: "anonymous", owner: "alice", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := newMemoryStore(nil)
+			store := New(inner, LabelOwner(ownerLabel))
+
+			_, err := store.Create(newRequest(tt.user), schema, ownedBy("a", tt.owner))
+			if !tt.wantErr {
+				require.NoError(t, err)
+				assert.Contains(t, inner.objects, "a")
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, validation.PermissionDenied, err.(*apierror.APIError).Code)
+			assert.NotContains(t, inner.objects, "a")
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	inner := newMemoryStore(map[string]string{"a": "alice"})
+	store := New(inner, LabelOwner(ownerLabel))
+
+	_, err := store.Update(newRequest("bob"), schema, ownedBy("a", "bob"), "a")
+	require.Error(t, err)
+	assert.Equal(t, validation.NotFound, err.(*apierror.APIError).Code)
+
+	_, err = store.Update(newRequest("alice"), schema, ownedBy("a", "bob"), "a")
+	require.Error(t, err)
+	assert.Equal(t, validation.PermissionDenied, err.(*apierror.APIError).Code)
+	assert.Equal(t, "alice", inner.objects["a"]["metadata"].(map[string]interface{})["labels"].(map[string]interface{})[ownerLabel])
+
+	_, err = store.Update(newRequest("alice"), schema, ownedBy("a", "alice"), "a")
+	require.NoError(t, err)
+}
+
+func TestWatch(t *testing.T) {
+	inner := newMemoryStore(map[string]string{"a": "alice", "b": "bob"})
+	inner.events = make(chan types.APIEvent, 2)
+	store := New(inner, LabelOwner(ownerLabel))
+
+	events, err := store.Watch(newRequest("alice"), schema, types.WatchRequest{})
+	require.NoError(t, err)
+
+	for _, id := range []string{"b", "a"} {
+		obj, _ := inner.ByID(nil, schema, id)
+		inner.events <- types.APIEvent{Name: "resource.change", Object: obj}
+	}
+	close(inner.events)
+
+	var got []string
+	for event := range events {
+		got = append(got, event.Object.ID)
+	}
+	assert.Equal(t, []string{"a"}, got)
+}
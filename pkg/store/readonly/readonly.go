@@ -0,0 +1,36 @@
+package readonly
+
+import (
+	"fmt"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+)
+
+// Store passes ByID, List and Watch through to the embedded store and returns a MethodNotAllowed error for
+// Create, Update and Delete.
+type Store struct {
+	types.Store
+}
+
+// New returns a read-only view of store.
+func New(store types.Store) *Store {
+	return &Store{Store: store}
+}
+
+func (s *Store) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	return types.APIObject{}, readOnly(schema)
+}
+
+func (s *Store) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	return types.APIObject{}, readOnly(schema)
+}
+
+func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	return types.APIObject{}, readOnly(schema)
+}
+
+func readOnly(schema *types.APISchema) error {
+	return apierror.NewAPIError(validation.MethodNotAllowed, fmt.Sprintf("%s is read-only", schema.ID))
+}
@@ -0,0 +1,82 @@
+package readonly
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	empty.Store
+	writes int
+}
+
+func (f *fakeStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	return types.APIObject{Type: schema.ID, ID: id}, nil
+}
+
+func (f *fakeStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	return types.APIObjectList{Objects: []types.APIObject{{Type: schema.ID, ID: "a"}}}, nil
+}
+
+func (f *fakeStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, wr types.WatchRequest) (chan types.APIEvent, error) {
+	return make(chan types.APIEvent), nil
+}
+
+func (f *fakeStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	f.writes++
+	return data, nil
+}
+
+func (f *fakeStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	f.writes++
+	return data, nil
+}
+
+func (f *fakeStore) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	f.writes++
+	return types.APIObject{}, nil
+}
+
+var schema = &types.APISchema{Schema: &schemas.Schema{ID: "configs"}}
+
+func TestReadsPassThrough(t *testing.T) {
+	inner := &fakeStore{}
+	store := New(inner)
+	apiOp := &types.APIRequest{}
+
+	obj, err := store.ByID(apiOp, schema, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", obj.ID)
+
+	list, err := store.List(apiOp, schema)
+	require.NoError(t, err)
+	assert.Len(t, list.Objects, 1)
+
+	events, err := store.Watch(apiOp, schema, types.WatchRequest{})
+	require.NoError(t, err)
+	assert.NotNil(t, events)
+}
+
+func TestWritesRejected(t *testing.T) {
+	inner := &fakeStore{}
+	store := New(inner)
+	apiOp := &types.APIRequest{Method: http.MethodPost}
+
+	_, createErr := store.Create(apiOp, schema, types.APIObject{})
+	_, updateErr := store.Update(apiOp, schema, types.APIObject{}, "a")
+	_, deleteErr := store.Delete(apiOp, schema, "a")
+
+	for _, err := range []error{createErr, updateErr, deleteErr} {
+		require.Error(t, err)
+		assert.Equal(t, validation.MethodNotAllowed, err.(*apierror.APIError).Code)
+	}
+	assert.Zero(t, inner.writes)
+}
@@ -0,0 +1,144 @@
+package schema
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// notifierBuffer is the number of events a watch can fall behind before it is stopped.
+const notifierBuffer = 100
+
+type notifierKey struct{}
+
+// Notifier sends the schemas added, changed and removed when the schemas served are replaced to the watches of the
+// schema store, so clients can refresh their list of types without polling. The zero value is ready to use.
+type Notifier struct {
+	lock sync.Mutex
+	// watchers maps the channel of each watch to the ID of the schema it is limited to, if any.
+	watchers map[chan types.APIEvent]string
+}
+
+// WithNotifier returns a context for requests whose watches of schemas are notified by n.
+func WithNotifier(ctx context.Context, n *Notifier) context.Context {
+	return context.WithValue(ctx, notifierKey{}, n)
+}
+
+func notifierFrom(ctx context.Context) *Notifier {
+	n, _ := ctx.Value(notifierKey{}).(*Notifier)
+	return n
+}
+
+// Watch returns a channel of the changes of schemas, or of the schema with the given ID if it is not empty, closed
+// when ctx is done. Watches that fall too far behind are stopped by closing their channel, clients then subscribe
+// again and list the schemas.
+func (n *Notifier) Watch(ctx context.Context, id string) chan types.APIEvent {
+	c := make(chan types.APIEvent, notifierBuffer)
+
+	n.lock.Lock()
+	if n.watchers == nil {
+		n.watchers = map[chan types.APIEvent]string{}
+	}
+	n.watchers[c] = id
+	n.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		n.stop(c)
+	}()
+	return c
+}
+
+func (n *Notifier) stop(c chan types.APIEvent) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if _, ok := n.watchers[c]; ok {
+		delete(n.watchers, c)
+		close(c)
+	}
+}
+
+// Notify sends an event to every watch for each schema of newSchemas that is not in oldSchemas, each schema of
+// oldSchemas that is not in newSchemas and each schema that is in both but differs. Like List, it only considers
+// schemas with collection or resource methods.
+func (n *Notifier) Notify(oldSchemas, newSchemas *types.APISchemas) {
+	events := schemaEvents(oldSchemas, newSchemas)
+	if len(events) == 0 {
+		return
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for c, id := range n.watchers {
+		if !send(c, id, events) {
+			delete(n.watchers, c)
+			close(c)
+		}
+	}
+}
+
+// send sends the events of the schema id, or all events if id is empty, to c without blocking, returning false if c
+// is full.
+func send(c chan types.APIEvent, id string, events []types.APIEvent) bool {
+	for _, event := range events {
+		if id != "" && event.ID != id {
+			continue
+		}
+		select {
+		case c <- event:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func schemaEvents(oldSchemas, newSchemas *types.APISchemas) []types.APIEvent {
+	before := servedSchemas(oldSchemas)
+	after := servedSchemas(newSchemas)
+
+	var events []types.APIEvent
+	for id, schema := range after {
+		old, ok := before[id]
+		switch {
+		case !ok:
+			events = append(events, schemaEvent(types.CreateAPIEvent, schema))
+		case old != schema && !reflect.DeepEqual(old.Schema, schema.Schema):
+			events = append(events, schemaEvent(types.ChangeAPIEvent, schema))
+		}
+	}
+	for id, schema := range before {
+		if _, ok := after[id]; !ok {
+			events = append(events, schemaEvent(types.RemoveAPIEvent, schema))
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+	return events
+}
+
+func servedSchemas(apiSchemas *types.APISchemas) map[string]*types.APISchema {
+	result := map[string]*types.APISchema{}
+	if apiSchemas == nil {
+		return result
+	}
+	for id, schema := range apiSchemas.All() {
+		if len(schema.CollectionMethods) > 0 || len(schema.ResourceMethods) > 0 {
+			result[id] = schema
+		}
+	}
+	return result
+}
+
+func schemaEvent(name string, schema *types.APISchema) types.APIEvent {
+	return types.APIEvent{
+		Name:         name,
+		ResourceType: "schema",
+		ID:           schema.ID,
+		Object:       toAPIObject(schema),
+	}
+}
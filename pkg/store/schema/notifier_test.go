@@ -0,0 +1,99 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSchemas(t *testing.T, specs ...*schemas.Schema) *types.APISchemas {
+	t.Helper()
+	apiSchemas := types.EmptyAPISchemas()
+	for _, spec := range specs {
+		require.NoError(t, apiSchemas.AddSchema(types.APISchema{Schema: spec}))
+	}
+	return apiSchemas
+}
+
+func TestNotifier(t *testing.T) {
+	pods := &schemas.Schema{ID: "pod", CollectionMethods: []string{http.MethodGet}}
+	nodes := &schemas.Schema{ID: "node", CollectionMethods: []string{http.MethodGet}}
+	hidden := &schemas.Schema{ID: "hidden"}
+	before := newSchemas(t, pods, nodes, hidden)
+
+	tests := []struct {
+		name  string
+		id    string
+		after *types.APISchemas
+		want  []string
+	}{
+		{
+			name:  "unchanged",
+			after: newSchemas(t, pods, nodes, hidden),
+		},
+		{
+			name: "added, changed and removed",
+			after: newSchemas(t,
+				&schemas.Schema{ID: "deployment", CollectionMethods: []string{http.MethodGet}},
+				&schemas.Schema{ID: "pod", CollectionMethods: []string{http.MethodGet, http.MethodPost}},
+				&schemas.Schema{ID: "secret"},
+			),
+			want: []string{
+				types.CreateAPIEvent + " deployment",
+				types.RemoveAPIEvent + " node",
+				types.ChangeAPIEvent + " pod",
+			},
+		},
+		{
+			name:  "single schema",
+			id:    "pod",
+			after: newSchemas(t, &schemas.Schema{ID: "pod", ResourceMethods: []string{http.MethodGet}}),
+			want:  []string{types.ChangeAPIEvent + " pod"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			n := &Notifier{}
+			c := n.Watch(ctx, tt.id)
+
+			n.Notify(before, tt.after)
+			cancel()
+
+			var got []string
+			for event := range c {
+				assert.Equal(t, "schema", event.ResourceType)
+				assert.Equal(t, event.ID, event.Object.ID)
+				got = append(got, event.Name+" "+event.ID)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNotifierSlowWatch(t *testing.T) {
+	n := &Notifier{}
+	c := n.Watch(context.Background(), "")
+
+	empty := newSchemas(t)
+	full := newSchemas(t)
+	for i := 0; i < notifierBuffer+1; i++ {
+		require.NoError(t, full.AddSchema(types.APISchema{Schema: &schemas.Schema{
+			ID:              fmt.Sprintf("type%d", i),
+			ResourceMethods: []string{http.MethodGet},
+		}}))
+	}
+	n.Notify(empty, full)
+
+	count := 0
+	for range c {
+		count++
+	}
+	assert.Equal(t, notifierBuffer, count, "the watch is stopped once its buffer is full")
+}
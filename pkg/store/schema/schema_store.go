@@ -35,7 +35,17 @@ func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string
 }
 
 func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
-	return FilterSchemas(apiOp, apiOp.Schemas.Schemas), nil
+	return FilterSchemas(apiOp, apiOp.Schemas.All()), nil
+}
+
+// Watch sends the schemas added, changed and removed while the watch runs, when the server handling the request
+// notifies them with a Notifier. Otherwise, it sends nothing, like stores that do not support watching.
+func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	n := notifierFrom(apiOp.Context())
+	if n == nil {
+		return nil, nil
+	}
+	return n.Watch(apiOp.Context(), w.ID), nil
 }
 
 func FilterSchemas(apiOp *types.APIRequest, schemaMap map[string]*types.APISchema) types.APIObjectList {
@@ -0,0 +1,177 @@
+package sharedwatch
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// subscriberBuffer is the number of events a subscriber can fall behind before it is dropped.
+const subscriberBuffer = 100
+
+// KeyFunc returns the key identifying a Watch call. Concurrent calls with the same key share one watch of the
+// embedded store.
+type KeyFunc func(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) string
+
+// Store shares one Watch of the embedded store between the concurrent watches with the same key, sending each of its
+// events to all of them. The shared watch is started by the first watch of a key and stopped once the last one is
+// done. Watches joining a shared watch get the events sent from then on. Each caller is still checked with
+// AccessControl.CanWatch before it joins. The other operations are passed through.
+type Store struct {
+	types.Store
+	// Key identifies identical Watch calls. It must include everything the events of the embedded store depend on.
+	Key KeyFunc
+
+	lock    sync.Mutex
+	watches map[string]*sharedWatch
+}
+
+// sharedWatch is a watch of the embedded store and the channels of the watches sharing it.
+type sharedWatch struct {
+	// ready is closed once the watch of the embedded store is started, or failed to start with err.
+	ready       chan struct{}
+	err         error
+	cancel      context.CancelFunc
+	subscribers map[chan types.APIEvent]struct{}
+}
+
+// New returns a Store sharing the watches of the same user, see UserWatchKey.
+func New(store types.Store) *Store {
+	return &Store{
+		Store: store,
+		Key:   UserWatchKey,
+	}
+}
+
+// WatchKey identifies a Watch call by its schema, namespace and filters. It can be used as the Key of stores whose
+// events do not depend on the user.
+func WatchKey(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) string {
+	return strings.Join([]string{schema.ID, apiOp.Namespace, w.Namespace, w.ID, w.Selector, w.FieldSelector, w.Revision}, "\x00")
+}
+
+// UserWatchKey is WatchKey, with the name of the user added, for stores filtering their events by user.
+func UserWatchKey(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) string {
+	return WatchKey(apiOp, schema, w) + "\x00" + apiOp.GetUser()
+}
+
+func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	if err := apiOp.AccessControl.CanWatch(apiOp, schema); err != nil {
+		return nil, err
+	}
+
+	key := s.Key(apiOp, schema, w)
+	c := make(chan types.APIEvent, subscriberBuffer)
+
+	s.lock.Lock()
+	shared, ok := s.watches[key]
+	var ctx context.Context
+	if !ok {
+		// the watch is shared, so it must not stop because the request that happened to start it went away
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(context.WithoutCancel(apiOp.Context()))
+		shared = &sharedWatch{
+			ready:       make(chan struct{}),
+			cancel:      cancel,
+			subscribers: map[chan types.APIEvent]struct{}{},
+		}
+		if s.watches == nil {
+			s.watches = map[string]*sharedWatch{}
+		}
+		s.watches[key] = shared
+	}
+	shared.subscribers[c] = struct{}{}
+	s.lock.Unlock()
+
+	if !ok {
+		s.start(apiOp.WithContext(ctx), schema, w, key, shared)
+	}
+
+	select {
+	case <-apiOp.Context().Done():
+		s.unsubscribe(key, shared, c)
+		return nil, apiOp.Context().Err()
+	case <-shared.ready:
+	}
+	if shared.err != nil {
+		return nil, shared.err
+	}
+
+	go func() {
+		<-apiOp.Context().Done()
+		s.unsubscribe(key, shared, c)
+	}()
+	return c, nil
+}
+
+// start starts the watch of the embedded store and the fan-out of its events to the subscribers of shared.
+func (s *Store) start(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest, key string, shared *sharedWatch) {
+	events, err := s.Store.Watch(apiOp, schema, w)
+	if err != nil {
+		shared.err = err
+		s.stop(key, shared)
+		close(shared.ready)
+		return
+	}
+	if events == nil {
+		// stores without watch support send nothing until the watch is done
+		events = make(chan types.APIEvent)
+		go func() {
+			<-apiOp.Context().Done()
+			close(events)
+		}()
+	}
+	close(shared.ready)
+
+	go func() {
+		for event := range events {
+			s.lock.Lock()
+			for c := range shared.subscribers {
+				select {
+				case c <- event:
+				default:
+					// a subscriber too slow to keep up is stopped, and subscribes again
+					delete(shared.subscribers, c)
+					close(c)
+				}
+			}
+			s.lock.Unlock()
+		}
+		s.stop(key, shared)
+	}()
+}
+
+// stop closes the channels of the subscribers of shared and stops the watch of the embedded store.
+func (s *Store) stop(key string, shared *sharedWatch) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for c := range shared.subscribers {
+		close(c)
+	}
+	shared.subscribers = nil
+	s.remove(key, shared)
+	shared.cancel()
+}
+
+// unsubscribe stops sending events to c, and stops the shared watch once it has no subscribers left.
+func (s *Store) unsubscribe(key string, shared *sharedWatch, c chan types.APIEvent) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := shared.subscribers[c]; !ok {
+		return
+	}
+	delete(shared.subscribers, c)
+	close(c)
+	if len(shared.subscribers) == 0 {
+		s.remove(key, shared)
+		shared.cancel()
+	}
+}
+
+// remove forgets shared, unless another shared watch of the same key has replaced it already.
+func (s *Store) remove(key string, shared *sharedWatch) {
+	if s.watches[key] == shared {
+		delete(s.watches, key)
+	}
+}
@@ -0,0 +1,211 @@
+package sharedwatch
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// watchStore records the watches it starts, so tests can send events to them and see when they are stopped.
+type watchStore struct {
+	empty.Store
+	err error
+
+	lock    sync.Mutex
+	watches []*upstream
+}
+
+type upstream struct {
+	events  chan types.APIEvent
+	stopped chan struct{}
+}
+
+func (w *watchStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, wr types.WatchRequest) (chan types.APIEvent, error) {
+	if w.err != nil {
+		return nil, w.err
+	}
+	u := &upstream{events: make(chan types.APIEvent), stopped: make(chan struct{})}
+	w.lock.Lock()
+	w.watches = append(w.watches, u)
+	w.lock.Unlock()
+
+	go func() {
+		<-apiOp.Context().Done()
+		close(u.stopped)
+		close(u.events)
+	}()
+	return u.events, nil
+}
+
+func (w *watchStore) started() []*upstream {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return append([]*upstream(nil), w.watches...)
+}
+
+// watchAccess allows watching for every user but "guest".
+type watchAccess struct {
+	types.AccessControl
+}
+
+func (watchAccess) CanWatch(apiOp *types.APIRequest, schema *types.APISchema) error {
+	if apiOp.GetUser() == "guest" {
+		return errors.New("forbidden")
+	}
+	return nil
+}
+
+var testSchema = &types.APISchema{Schema: &schemas.Schema{ID: "foo"}}
+
+func newRequest(ctx context.Context, userName, namespace string) *types.APIRequest {
+	req := httptest.NewRequest("GET", "/v1/subscribe", nil)
+	ctx = request.WithUser(ctx, &user.DefaultInfo{Name: userName})
+	return &types.APIRequest{
+		Namespace:     namespace,
+		Request:       req.WithContext(ctx),
+		AccessControl: watchAccess{},
+	}
+}
+
+func receive(t *testing.T, c chan types.APIEvent) types.APIEvent {
+	t.Helper()
+	select {
+	case event := <-c:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+		return types.APIEvent{}
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	inner := &watchStore{}
+	s := New(inner)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	first, err := s.Watch(newRequest(ctx, "alice", "default"), testSchema, types.WatchRequest{})
+	require.NoError(t, err)
+	second, err := s.Watch(newRequest(ctx, "alice", "default"), testSchema, types.WatchRequest{})
+	require.NoError(t, err)
+	require.Len(t, inner.started(), 1, "identical watches share one upstream watch")
+
+	upstream := inner.started()[0]
+	upstream.events <- types.APIEvent{Name: types.CreateAPIEvent, ID: "a"}
+	upstream.events <- types.APIEvent{Name: types.RemoveAPIEvent, ID: "a"}
+
+	for _, c := range []chan types.APIEvent{first, second} {
+		assert.Equal(t, types.CreateAPIEvent, receive(t, c).Name)
+		assert.Equal(t, types.RemoveAPIEvent, receive(t, c).Name)
+	}
+}
+
+func TestSeparateKeys(t *testing.T) {
+	inner := &watchStore{}
+	s := New(inner)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, apiOp := range []*types.APIRequest{
+		newRequest(ctx, "alice", "default"),
+		newRequest(ctx, "alice", "kube-system"),
+		newRequest(ctx, "bob", "default"),
+	} {
+		_, err := s.Watch(apiOp, testSchema, types.WatchRequest{})
+		require.NoError(t, err)
+	}
+	_, err := s.Watch(newRequest(ctx, "alice", "default"), testSchema, types.WatchRequest{Selector: "app=web"})
+	require.NoError(t, err)
+
+	assert.Len(t, inner.started(), 4)
+}
+
+func TestTeardownOnLastUnsubscribe(t *testing.T) {
+	inner := &watchStore{}
+	s := New(inner)
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	secondCtx, cancelSecond := context.WithCancel(context.Background())
+	first, err := s.Watch(newRequest(firstCtx, "alice", "default"), testSchema, types.WatchRequest{})
+	require.NoError(t, err)
+	second, err := s.Watch(newRequest(secondCtx, "alice", "default"), testSchema, types.WatchRequest{})
+	require.NoError(t, err)
+	upstream := inner.started()[0]
+
+	// the watch that started the upstream watch leaves, the other one keeps receiving events
+	cancelFirst()
+	for range first {
+	}
+	upstream.events <- types.APIEvent{Name: types.ChangeAPIEvent, ID: "a"}
+	assert.Equal(t, types.ChangeAPIEvent, receive(t, second).Name)
+	select {
+	case <-upstream.stopped:
+		t.Fatal("the upstream watch must run while it has subscribers")
+	default:
+	}
+
+	cancelSecond()
+	select {
+	case <-upstream.stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the upstream watch is not stopped once the last subscriber left")
+	}
+	for range second {
+	}
+
+	// a new watch starts a new upstream watch
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err = s.Watch(newRequest(ctx, "alice", "default"), testSchema, types.WatchRequest{})
+	require.NoError(t, err)
+	assert.Len(t, inner.started(), 2)
+}
+
+func TestUpstreamClosed(t *testing.T) {
+	inner := &watchStore{}
+	s := New(inner)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := s.Watch(newRequest(ctx, "alice", "default"), testSchema, types.WatchRequest{})
+	require.NoError(t, err)
+
+	// the embedded store ends its watch, as when its connection is lost
+	s.lock.Lock()
+	for _, shared := range s.watches {
+		shared.cancel()
+	}
+	s.lock.Unlock()
+
+	// subscribers are told by their channel being closed
+	for range c {
+	}
+	s.lock.Lock()
+	assert.Empty(t, s.watches)
+	s.lock.Unlock()
+}
+
+func TestWatchErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := New(&watchStore{})
+	_, err := s.Watch(newRequest(ctx, "guest", "default"), testSchema, types.WatchRequest{})
+	assert.EqualError(t, err, "forbidden")
+
+	s = New(&watchStore{err: errors.New("watch failed")})
+	_, err = s.Watch(newRequest(ctx, "alice", "default"), testSchema, types.WatchRequest{})
+	assert.EqualError(t, err, "watch failed")
+	assert.Empty(t, s.watches)
+}
@@ -0,0 +1,130 @@
+package softdelete
+
+import (
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/parse"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/data"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+)
+
+// DefaultField is the field set on objects deleted through a Store that has no Field configured.
+var DefaultField = []string{"deletedAt"}
+
+// Store marks objects as deleted instead of removing them from the embedded store. Delete sets Field to the
+// current time through Update, and ByID, List and Watch hide objects that have Field set unless the request sets
+// the includeDeleted query parameter. Watchers see the change that marks an object deleted as its removal.
+type Store struct {
+	types.Store
+	// Field is the path of the field holding the deletion timestamp.
+	Field []string
+	// Now returns the deletion timestamp, time.Now if unset.
+	Now func() time.Time
+}
+
+// New returns a Store that soft-deletes objects of store by setting field, or DefaultField if field is empty.
+func New(store types.Store, field ...string) *Store {
+	if len(field) == 0 {
+		field = DefaultField
+	}
+	return &Store{
+		Store: store,
+		Field: field,
+	}
+}
+
+func (s *Store) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, err := s.Store.ByID(apiOp, schema, id)
+	if err != nil {
+		return obj, err
+	}
+	if s.deleted(obj) && !parse.IncludeDeleted(apiOp) {
+		return types.APIObject{}, apierror.NewAPIError(validation.NotFound, "not found")
+	}
+	return obj, nil
+}
+
+func (s *Store) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	list, err := s.Store.List(apiOp, schema)
+	if err != nil || parse.IncludeDeleted(apiOp) {
+		return list, err
+	}
+
+	objects := make([]types.APIObject, 0, len(list.Objects))
+	for _, obj := range list.Objects {
+		if !s.deleted(obj) {
+			objects = append(objects, obj)
+		}
+	}
+	list.Objects = objects
+	return list, nil
+}
+
+func (s *Store) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, err := s.Store.ByID(apiOp, schema, id)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	if s.deleted(obj) {
+		return types.APIObject{}, apierror.NewAPIError(validation.NotFound, "not found")
+	}
+
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+
+	// the object may be shared with the embedded store, so the maps along Field are copied before it is marked
+	values := copyPath(obj.Data(), s.Field)
+	data.PutValue(values, now().UTC().Format(time.RFC3339), s.Field...)
+	obj.Object = values
+	return s.Store.Update(apiOp, schema, obj, id)
+}
+
+func (s *Store) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	events, err := s.Store.Watch(apiOp, schema, w)
+	if err != nil || events == nil || parse.IncludeDeleted(apiOp) {
+		return events, err
+	}
+
+	result := make(chan types.APIEvent)
+	go func() {
+		defer close(result)
+		for event := range events {
+			if event.Error == nil && event.Object.Object != nil && s.deleted(event.Object) {
+				if event.Name != types.ChangeAPIEvent {
+					continue
+				}
+				event.Name = types.RemoveAPIEvent
+			}
+			select {
+			case result <- event:
+			case <-apiOp.Context().Done():
+				return
+			}
+		}
+	}()
+	return result, nil
+}
+
+func (s *Store) deleted(obj types.APIObject) bool {
+	value, ok := data.GetValue(obj.Data(), s.Field...)
+	return ok && value != nil && value != ""
+}
+
+// copyPath returns a copy of values in which the maps along path are copied too, so that setting path in it leaves
+// values unchanged.
+func copyPath(values map[string]interface{}, path []string) map[string]interface{} {
+	result := make(map[string]interface{}, len(values)+1)
+	for k, v := range values {
+		result[k] = v
+	}
+	if len(path) > 1 {
+		if nested, ok := result[path[0]].(map[string]interface{}); ok {
+			result[path[0]] = copyPath(nested, path[1:])
+		}
+	}
+	return result
+}
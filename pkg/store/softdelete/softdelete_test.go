@@ -0,0 +1,176 @@
+package softdelete
+
+import (
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/store/empty"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	empty.Store
+	objects map[string]map[string]interface{}
+	deletes int
+	events  chan types.APIEvent
+}
+
+func newMemoryStore(ids ...string) *memoryStore {
+	m := &memoryStore{objects: map[string]map[string]interface{}{}}
+	for _, id := range ids {
+		m.objects[id] = map[string]interface{}{"id": id}
+	}
+	return m
+}
+
+func (m *memoryStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	obj, ok := m.objects[id]
+	if !ok {
+		return types.APIObject{}, validation.NotFound
+	}
+	return types.APIObject{Type: schema.ID, ID: id, Object: obj}, nil
+}
+
+func (m *memoryStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	var list types.APIObjectList
+	for id, obj := range m.objects {
+		list.Objects = append(list.Objects, types.APIObject{Type: schema.ID, ID: id, Object: obj})
+	}
+	sort.Slice(list.Objects, func(i, j int) bool { return list.Objects[i].ID < list.Objects[j].ID })
+	return list, nil
+}
+
+func (m *memoryStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	m.objects[id] = data.Data()
+	return m.ByID(apiOp, schema, id)
+}
+
+func (m *memoryStore) Delete(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
+	m.deletes++
+	delete(m.objects, id)
+	return types.APIObject{}, nil
+}
+
+func (m *memoryStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	return m.events, nil
+}
+
+var schema = &types.APISchema{Schema: &schemas.Schema{ID: "configs"}}
+
+func newRequest(target string) *types.APIRequest {
+	return &types.APIRequest{Request: httptest.NewRequest("GET", target, nil)}
+}
+
+func ids(list types.APIObjectList) []string {
+	var result []string
+	for _, obj := range list.Objects {
+		result = append(result, obj.ID)
+	}
+	return result
+}
+
+func TestDeleteMarksThenHides(t *testing.T) {
+	inner := newMemoryStore("a", "b")
+	store := New(inner, "metadata", "deletedAt")
+	store.Now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	apiOp := newRequest("/v1/configs/a")
+
+	_, err := store.Delete(apiOp, schema, "a")
+	require.NoError(t, err)
+	assert.Zero(t, inner.deletes)
+	assert.Equal(t, map[string]interface{}{"deletedAt": "2024-01-02T03:04:05Z"}, inner.objects["a"]["metadata"])
+
+	_, err = store.ByID(apiOp, schema, "a")
+	require.Error(t, err)
+	assert.Equal(t, validation.NotFound, err.(*apierror.APIError).Code)
+
+	list, err := store.List(newRequest("/v1/configs"), schema)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, ids(list))
+
+	_, err = store.Delete(apiOp, schema, "a")
+	require.Error(t, err)
+	assert.Equal(t, validation.NotFound, err.(*apierror.APIError).Code)
+}
+
+func TestIncludeDeleted(t *testing.T) {
+	inner := newMemoryStore("a", "b")
+	store := New(inner)
+
+	_, err := store.Delete(newRequest("/v1/configs/a"), schema, "a")
+	require.NoError(t, err)
+
+	obj, err := store.ByID(newRequest("/v1/configs/a?includeDeleted=true"), schema, "a")
+	require.NoError(t, err)
+	assert.NotEmpty(t, obj.Data().String("deletedAt"))
+
+	list, err := store.List(newRequest("/v1/configs?includeDeleted=true"), schema)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, ids(list))
+}
+
+func TestDeleteCopiesObject(t *testing.T) {
+	inner := newMemoryStore("a")
+	original := map[string]interface{}{"metadata": map[string]interface{}{"name": "a"}}
+	inner.objects["a"] = original
+	store := New(inner, "metadata", "deletedAt")
+
+	_, err := store.Delete(newRequest("/v1/configs/a"), schema, "a")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{"metadata": map[string]interface{}{"name": "a"}}, original)
+	assert.NotEmpty(t, inner.objects["a"]["metadata"].(map[string]interface{})["deletedAt"])
+}
+
+func TestWatchHidesDeleted(t *testing.T) {
+	deleted := types.APIObject{Type: "configs", ID: "b", Object: map[string]interface{}{"deletedAt": "2024-01-02T03:04:05Z"}}
+	sent := []types.APIEvent{
+		{Name: types.CreateAPIEvent, Object: types.APIObject{Type: "configs", ID: "a", Object: map[string]interface{}{}}},
+		{Name: types.CreateAPIEvent, Object: deleted},
+		{Name: types.ChangeAPIEvent, Object: deleted},
+		{Name: types.RemoveAPIEvent, Object: deleted},
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		want   []string
+	}{
+		{
+			name:   "hidden",
+			target: "/v1/configs",
+			want:   []string{"resource.create a", "resource.remove b"},
+		},
+		{
+			name:   "included",
+			target: "/v1/configs?includeDeleted=true",
+			want:   []string{"resource.create a", "resource.create b", "resource.change b", "resource.remove b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := newMemoryStore()
+			inner.events = make(chan types.APIEvent, len(sent))
+			for _, event := range sent {
+				inner.events <- event
+			}
+			close(inner.events)
+
+			events, err := New(inner).Watch(newRequest(tt.target), schema, types.WatchRequest{})
+			require.NoError(t, err)
+
+			var got []string
+			for event := range events {
+				got = append(got, event.Name+" "+event.Object.ID)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
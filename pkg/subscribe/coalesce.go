@@ -0,0 +1,112 @@
+package subscribe
+
+import (
+	"context"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// coalesce returns a channel receiving the events of c, except for objects created and removed within window of
+// each other, whose events are all dropped. Create events are held for window to that end, changes of an object
+// whose create is held replacing the object of the create. Events of other objects and errors are not held, so
+// they can be received before the held creates that preceded them. The returned channel is closed once c is
+// closed and the held events are sent, or once ctx is done.
+func coalesce(ctx context.Context, c chan types.APIEvent, window time.Duration) chan types.APIEvent {
+	result := make(chan types.APIEvent)
+
+	go func() {
+		defer close(result)
+
+		var (
+			held      heldEvents
+			deadlines = map[string]time.Time{}
+			ready     []types.APIEvent
+			in        = c
+			timer     = time.NewTimer(window)
+		)
+		defer timer.Stop()
+
+		for in != nil || held.len() > 0 || len(ready) > 0 {
+			// creates are held in the order they arrived, and all for the same window, so the first expires first
+			for held.len() > 0 && (in == nil || !time.Now().Before(deadlines[held.keys[0]])) {
+				delete(deadlines, held.keys[0])
+				ready = append(ready, held.first())
+				held.remove()
+			}
+
+			var (
+				send  chan types.APIEvent
+				first types.APIEvent
+				wait  <-chan time.Time
+			)
+			if len(ready) > 0 {
+				send, first = result, ready[0]
+			}
+			if held.len() > 0 {
+				resetTimer(timer, time.Until(deadlines[held.keys[0]]))
+				wait = timer.C
+			}
+
+			select {
+			case event, ok := <-in:
+				if !ok {
+					in = nil
+					continue
+				}
+				key := event.ResourceType + "/" + eventObjectID(event)
+				if event.Error != nil || key == event.ResourceType+"/" {
+					ready = append(ready, event)
+					continue
+				}
+				_, isHeld := held.events[key]
+				switch {
+				case event.Name == types.CreateAPIEvent && !isHeld:
+					held.add(key, event)
+					deadlines[key] = time.Now().Add(window)
+				case (event.Name == types.CreateAPIEvent || event.Name == types.ChangeAPIEvent) && isHeld:
+					// the client has not seen the object yet, so it is still a create
+					event.Name = types.CreateAPIEvent
+					held.replace(key, event)
+				case event.Name == types.RemoveAPIEvent && isHeld:
+					held.delete(key)
+					delete(deadlines, key)
+				default:
+					ready = append(ready, event)
+				}
+			case send <- first:
+				ready = ready[1:]
+			case <-wait:
+			case <-ctx.Done():
+				if in != nil {
+					go func() {
+						for range in {
+						}
+					}()
+				}
+				return
+			}
+		}
+	}()
+
+	return result
+}
+
+// eventObjectID returns the ID of the object of event, falling back to the ID of the event.
+func eventObjectID(event types.APIEvent) string {
+	if event.Object.ID != "" {
+		return event.Object.ID
+	}
+	return event.ID
+}
+
+// resetTimer makes t fire after d, whether or not it already fired.
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
@@ -0,0 +1,135 @@
+package subscribe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_coalesceCreateDelete(t *testing.T) {
+	in := make(chan types.APIEvent, 10)
+	in <- types.APIEvent{Name: types.CreateAPIEvent, ResourceType: "pods", ID: "a"}
+	in <- types.APIEvent{Name: types.CreateAPIEvent, ResourceType: "pods", ID: "b"}
+	in <- types.APIEvent{Name: types.RemoveAPIEvent, ResourceType: "pods", ID: "a"}
+	close(in)
+
+	events := collect(coalesce(context.Background(), in, time.Minute))
+	require.Len(t, events, 1)
+	assert.Equal(t, types.CreateAPIEvent, events[0].Name)
+	assert.Equal(t, "b", events[0].ID)
+}
+
+func Test_coalesceCreateUpdateDelete(t *testing.T) {
+	in := make(chan types.APIEvent, 10)
+	in <- types.APIEvent{Name: types.CreateAPIEvent, ResourceType: "pods", Object: types.APIObject{ID: "a"}, Revision: "1"}
+	in <- types.APIEvent{Name: types.CreateAPIEvent, ResourceType: "pods", Object: types.APIObject{ID: "b"}, Revision: "2"}
+	in <- types.APIEvent{Name: types.ChangeAPIEvent, ResourceType: "pods", Object: types.APIObject{ID: "a"}, Revision: "3"}
+	in <- types.APIEvent{Name: types.ChangeAPIEvent, ResourceType: "pods", Object: types.APIObject{ID: "b"}, Revision: "4"}
+	in <- types.APIEvent{Name: types.RemoveAPIEvent, ResourceType: "pods", Object: types.APIObject{ID: "a"}, Revision: "5"}
+	in <- types.APIEvent{Name: types.ChangeAPIEvent, ResourceType: "pods", Object: types.APIObject{ID: "c"}, Revision: "6"}
+	close(in)
+
+	events := collect(coalesce(context.Background(), in, time.Minute))
+	require.Len(t, events, 2)
+	// events of objects without a held create are not delayed
+	assert.Equal(t, types.ChangeAPIEvent, events[0].Name)
+	assert.Equal(t, "c", events[0].Object.ID)
+	// the change of b is folded into its create
+	assert.Equal(t, types.CreateAPIEvent, events[1].Name)
+	assert.Equal(t, "b", events[1].Object.ID)
+	assert.Equal(t, "4", events[1].Revision)
+}
+
+func Test_coalesceWindowExpires(t *testing.T) {
+	in := make(chan types.APIEvent)
+	out := coalesce(context.Background(), in, 20*time.Millisecond)
+
+	in <- types.APIEvent{Name: types.CreateAPIEvent, ResourceType: "pods", ID: "a"}
+	in <- types.APIEvent{ResourceType: "pods", Error: errors.New("failed")}
+
+	// errors are not held back behind creates
+	event := <-out
+	assert.EqualError(t, event.Error, "failed")
+
+	start := time.Now()
+	event = <-out
+	assert.Equal(t, types.CreateAPIEvent, event.Name)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// once the create is sent, the remove has to be sent too
+	in <- types.APIEvent{Name: types.RemoveAPIEvent, ResourceType: "pods", ID: "a"}
+	close(in)
+	events := collect(out)
+	require.Len(t, events, 1)
+	assert.Equal(t, types.RemoveAPIEvent, events[0].Name)
+}
+
+func Test_streamCoalesce(t *testing.T) {
+	events := []types.APIEvent{
+		{Name: types.CreateAPIEvent, ResourceType: "coalesced-resource", Object: types.APIObject{ID: "job-1"}},
+		{Name: types.ChangeAPIEvent, ResourceType: "coalesced-resource", Object: types.APIObject{ID: "job-1"}},
+		{Name: types.RemoveAPIEvent, ResourceType: "coalesced-resource", Object: types.APIObject{ID: "job-1"}},
+		{Name: types.CreateAPIEvent, ResourceType: "coalesced-resource", Object: types.APIObject{ID: "job-2"}},
+	}
+
+	tests := []struct {
+		name   string
+		window time.Duration
+		want   []string
+	}{
+		{
+			name: "disabled",
+			want: []string{"resource.start", "job-1", "job-1", "job-1", "job-2"},
+		},
+		{
+			name:   "enabled",
+			window: time.Minute,
+			want:   []string{"resource.start", "job-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := WatchSession{
+				apiOp: &types.APIRequest{
+					Name: "test",
+					Schemas: &types.APISchemas{
+						Schemas: map[string]*types.APISchema{
+							"coalesced-resource": {
+								Schema: &schemas.Schema{
+									ID: "coalesced-resource",
+								},
+								Store: &eventsStore{events: events},
+							},
+						},
+					},
+					Request:       &http.Request{},
+					AccessControl: &mockAC{hasAccess: true},
+				},
+				getter:         DefaultGetter,
+				coalesceWindow: tt.window,
+			}
+
+			result := make(chan types.APIEvent, 10)
+			err := ws.stream(context.TODO(), Subscribe{ResourceType: "coalesced-resource"}, result)
+			require.NoError(t, err)
+			close(result)
+
+			var got []string
+			for event := range result {
+				if event.Object.ID != "" {
+					got = append(got, event.Object.ID)
+					continue
+				}
+				got = append(got, event.Name)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package subscribe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeEventName(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		event  string
+		want   string
+	}{
+		{
+			name:  "no prefix",
+			event: types.CreateAPIEvent,
+			want:  "resource.create",
+		},
+		{
+			name:   "prefix",
+			prefix: "pods",
+			event:  types.CreateAPIEvent,
+			want:   "pods.create",
+		},
+		{
+			name:   "dotted prefix",
+			prefix: "apps.deployments",
+			event:  "resource.stop",
+			want:   "apps.deployments.stop",
+		},
+		{
+			name:   "other event",
+			prefix: "pods",
+			event:  "ping",
+			want:   "ping",
+		},
+		{
+			name:   "invalid prefix",
+			prefix: "pods\nevent: forged",
+			event:  "resource.error",
+			want:   "resource.error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := Subscribe{EventPrefix: tt.prefix}
+			assert.Equal(t, tt.want, sub.eventName(tt.event))
+		})
+	}
+}
+
+func Test_errorEventName(t *testing.T) {
+	assert.Equal(t, "resource.error", errorEventName(""))
+	assert.Equal(t, "resource.error", errorEventName("ping"))
+	assert.Equal(t, "resource.error", errorEventName(types.ChangeAPIEvent))
+	assert.Equal(t, "apps.deployments.error", errorEventName("apps.deployments.change"))
+}
+
+func Test_streamEventPrefix(t *testing.T) {
+	ws := WatchSession{
+		apiOp: &types.APIRequest{
+			Name:          "test",
+			Schemas:       prefixedSchemas(),
+			Request:       &http.Request{},
+			AccessControl: &mockAC{hasAccess: true},
+		},
+		getter: DefaultGetter,
+	}
+
+	result := make(chan types.APIEvent, 10)
+	err := ws.stream(context.TODO(), Subscribe{ResourceType: "prefixed-resource", EventPrefix: "pods"}, result)
+	require.NoError(t, err)
+	close(result)
+
+	var names []string
+	for event := range result {
+		names = append(names, event.Name)
+	}
+	assert.Equal(t, []string{"pods.start", "pods.create", "pods.change", "pods.remove", "pods.error"}, names)
+}
+
+func TestWatchSessionEventPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		want   []string
+	}{
+		{
+			name:   "prefix",
+			prefix: "pods",
+			want:   []string{"pods.start", "pods.create", "pods.change", "pods.remove", "pods.error", "pods.stop"},
+		},
+		{
+			name: "default",
+			want: []string{"resource.start", "resource.create", "resource.change", "resource.remove", "resource.error", "resource.stop"},
+		},
+		{
+			name:   "invalid prefix",
+			prefix: "pods events",
+			want:   []string{"resource.error", "resource.stop"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiOp := &types.APIRequest{
+				Request:       httptest.NewRequest(http.MethodGet, "/v1/subscribe", nil),
+				Schemas:       prefixedSchemas(),
+				AccessControl: &mockAC{hasAccess: true},
+			}
+			ws := NewWatchSession(apiOp, DefaultGetter)
+			defer ws.Close()
+
+			result := make(chan types.APIEvent, 10)
+			ws.add(Subscribe{ResourceType: "prefixed-resource", EventPrefix: tt.prefix}, result)
+
+			var names []string
+			for len(names) == 0 || !strings.HasSuffix(names[len(names)-1], ".stop") {
+				names = append(names, prepareEvent(apiOp, DefaultGetter, <-result).Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestSSEEventPrefix(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/subscribe?resourceType=prefixed-resource&eventPrefix=pods", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	rw := httptest.NewRecorder()
+
+	_, err := Handler(&types.APIRequest{
+		Request:       req,
+		Response:      rw,
+		Schemas:       prefixedSchemas(),
+		AccessControl: &mockAC{hasAccess: true},
+	}, DefaultGetter, "v1")
+	// the stream ends with the stop event following the error of the store
+	assert.Equal(t, validation.ErrComplete, err)
+
+	var names []string
+	for _, line := range strings.Split(rw.Body.String(), "\n") {
+		if name, ok := strings.CutPrefix(line, "event: "); ok && name != "ping" {
+			names = append(names, name)
+		}
+	}
+	assert.Equal(t, []string{"pods.start", "pods.create", "pods.change", "pods.remove", "pods.error", "pods.stop"}, names)
+}
+
+func TestSSEInvalidEventPrefix(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/subscribe?resourceType=prefixed-resource&eventPrefix=pods%0Aevent:%20forged", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	rw := httptest.NewRecorder()
+
+	_, err := Handler(&types.APIRequest{
+		Request:       req,
+		Response:      rw,
+		Schemas:       prefixedSchemas(),
+		AccessControl: &mockAC{hasAccess: true},
+	}, DefaultGetter, "v1")
+
+	var apiErr *apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.BadRequest, apiErr.Code)
+	assert.Empty(t, rw.Body.String())
+}
+
+func prefixedSchemas() *types.APISchemas {
+	return &types.APISchemas{
+		Schemas: map[string]*types.APISchema{
+			"prefixed-resource": {
+				Schema: &schemas.Schema{ID: "prefixed-resource"},
+				Store: &eventsStore{events: []types.APIEvent{
+					{Name: types.CreateAPIEvent, ResourceType: "prefixed-resource"},
+					{Name: types.ChangeAPIEvent, ResourceType: "prefixed-resource"},
+					{Name: types.RemoveAPIEvent, ResourceType: "prefixed-resource"},
+					{Error: errors.New("connection lost")},
+				}},
+			},
+		},
+	}
+}
@@ -0,0 +1,19 @@
+package subscribe
+
+import (
+	"errors"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// expired returns whether err reports that a watch was resumed from a resource version that is too old, in which
+// case the client has to list the resources again. Stores report it with a Kubernetes "resource expired" or
+// "gone" error, or an APIError with the Gone code.
+func expired(err error) bool {
+	if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+		return true
+	}
+	var apiErr *apierror.APIError
+	return errors.As(err, &apiErr) && apiErr.Code == apierror.Gone
+}
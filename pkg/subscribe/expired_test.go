@@ -0,0 +1,169 @@
+package subscribe
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestExpired(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "resource expired",
+			err:  apierrors.NewResourceExpired("too old resource version: 1 (42)"),
+			want: true,
+		},
+		{
+			name: "gone",
+			err:  apierrors.NewGone("gone"),
+			want: true,
+		},
+		{
+			name: "gone API error",
+			err:  apierror.NewAPIError(apierror.Gone, "compacted"),
+			want: true,
+		},
+		{
+			name: "other error",
+			err:  errors.New("connection refused"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, expired(tt.err))
+		})
+	}
+}
+
+func TestSSEExpiredResourceVersion(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/subscribe?resourceType=expired-resource&resourceVersion=1", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	rw := httptest.NewRecorder()
+
+	_, err := Handler(&types.APIRequest{
+		Request:       req,
+		Response:      rw,
+		Schemas:       expiredSchemas(&expiredStore{}),
+		AccessControl: &mockAC{hasAccess: true},
+	}, DefaultGetter, "v1")
+
+	var apiErr *apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.Gone, apiErr.Code)
+	assert.Equal(t, http.StatusGone, apiErr.Code.Status)
+	assert.False(t, rw.Flushed, "the response must not be started")
+	assert.Empty(t, rw.Header().Get("Content-Type"))
+}
+
+func TestSSEExpiredAfterStart(t *testing.T) {
+	handler := NewHandler(DefaultGetter, "v1")
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = handler(&types.APIRequest{
+			Request:       req,
+			Response:      rw,
+			Schemas:       expiredSchemas(&expiredStore{inStream: true}),
+			AccessControl: &mockAC{hasAccess: true},
+		})
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"?resourceType=expired-resource&resourceVersion=1", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	lines := bufio.NewScanner(resp.Body)
+	for lines.Scan() && lines.Text() != "event: resource.error" {
+	}
+	require.True(t, lines.Scan())
+	data, ok := strings.CutPrefix(lines.Text(), "data: ")
+	require.True(t, ok)
+	assertGoneEvent(t, []byte(data))
+}
+
+func TestWebsocketExpiredResourceVersion(t *testing.T) {
+	handler := NewHandler(DefaultGetter, "v1")
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = handler(&types.APIRequest{
+			Request:       req,
+			Response:      rw,
+			Schemas:       expiredSchemas(&expiredStore{}),
+			AccessControl: &mockAC{hasAccess: true},
+		})
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteJSON(Subscribe{ResourceType: "expired-resource", ResourceVersion: "1"}))
+	_, message, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assertGoneEvent(t, message)
+}
+
+func assertGoneEvent(t *testing.T, message []byte) {
+	t.Helper()
+	var event struct {
+		Name string                 `json:"name"`
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(message, &event))
+	assert.Equal(t, "resource.error", event.Name)
+	assert.Equal(t, "Gone", event.Data["code"])
+	assert.Equal(t, float64(http.StatusGone), event.Data["status"])
+}
+
+func expiredSchemas(store types.Store) *types.APISchemas {
+	return &types.APISchemas{
+		Schemas: map[string]*types.APISchema{
+			"expired-resource": {
+				Schema: &schemas.Schema{
+					ID: "expired-resource",
+				},
+				Store: store,
+			},
+		},
+	}
+}
+
+// expiredStore fails watches as if their resource version was compacted, either when the watch is started or, if
+// inStream is set, with an error event.
+type expiredStore struct {
+	mockStore
+	inStream bool
+}
+
+func (e *expiredStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	err := apierrors.NewResourceExpired("too old resource version: " + w.Revision)
+	if !e.inStream {
+		return nil, err
+	}
+	c := make(chan types.APIEvent, 1)
+	c <- types.APIEvent{Error: err}
+	go func() {
+		<-apiOp.Context().Done()
+		close(c)
+	}()
+	return c, nil
+}
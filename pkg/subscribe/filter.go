@@ -0,0 +1,65 @@
+package subscribe
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// eventTypes maps the names accepted in Subscribe.EventTypes to the name of the events they select. Event names
+// themselves are accepted as well.
+var eventTypes = map[string]string{
+	"create": types.CreateAPIEvent,
+	"change": types.ChangeAPIEvent,
+	"update": types.ChangeAPIEvent,
+	"remove": types.RemoveAPIEvent,
+	"delete": types.RemoveAPIEvent,
+}
+
+// eventNames returns the set of event names selected by names, or nil if names is empty and every event is wanted.
+func eventNames(names []string) (map[string]bool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	result := map[string]bool{}
+	for _, name := range names {
+		if eventName, ok := eventTypes[name]; ok {
+			result[eventName] = true
+			continue
+		}
+		switch name {
+		case types.CreateAPIEvent, types.ChangeAPIEvent, types.RemoveAPIEvent:
+			result[name] = true
+		default:
+			return nil, fmt.Errorf("unknown event type %q", name)
+		}
+	}
+	return result, nil
+}
+
+// filterEvents returns a channel receiving the events of c whose name is in names. Errors are always passed on.
+// The returned channel is closed once c is closed or ctx is done.
+func filterEvents(ctx context.Context, c chan types.APIEvent, names map[string]bool) chan types.APIEvent {
+	result := make(chan types.APIEvent)
+
+	go func() {
+		defer close(result)
+		for event := range c {
+			if event.Error == nil && !names[event.Name] {
+				continue
+			}
+			select {
+			case result <- event:
+			case <-ctx.Done():
+				go func() {
+					for range c {
+					}
+				}()
+				return
+			}
+		}
+	}()
+
+	return result
+}
@@ -0,0 +1,137 @@
+package subscribe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_eventNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		types   []string
+		want    map[string]bool
+		wantErr bool
+	}{
+		{
+			name: "no filter",
+		},
+		{
+			name:  "delete",
+			types: []string{"delete"},
+			want:  map[string]bool{types.RemoveAPIEvent: true},
+		},
+		{
+			name:  "aliases and event names",
+			types: []string{"create", "update", types.RemoveAPIEvent},
+			want:  map[string]bool{types.CreateAPIEvent: true, types.ChangeAPIEvent: true, types.RemoveAPIEvent: true},
+		},
+		{
+			name:    "unknown type",
+			types:   []string{"delete", "resource.start"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := eventNames(tt.types)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_streamEventTypes(t *testing.T) {
+	events := []types.APIEvent{
+		{Name: types.CreateAPIEvent, ResourceType: "filtered-resource", ID: "a"},
+		{Name: types.ChangeAPIEvent, ResourceType: "filtered-resource", ID: "a"},
+		{Name: types.RemoveAPIEvent, ResourceType: "filtered-resource", ID: "a"},
+		{Name: types.CreateAPIEvent, ResourceType: "filtered-resource", ID: "b"},
+		{Error: errors.New("watch failed")},
+		{Name: types.RemoveAPIEvent, ResourceType: "filtered-resource", ID: "b"},
+	}
+	ws := WatchSession{
+		apiOp: &types.APIRequest{
+			Name: "test",
+			Schemas: &types.APISchemas{
+				Schemas: map[string]*types.APISchema{
+					"filtered-resource": {
+						Schema: &schemas.Schema{
+							ID: "filtered-resource",
+						},
+						Store: &eventsStore{events: events},
+					},
+				},
+			},
+			Request:       &http.Request{},
+			AccessControl: &mockAC{hasAccess: true},
+		},
+		getter: DefaultGetter,
+	}
+
+	result := make(chan types.APIEvent, 10)
+	err := ws.stream(context.TODO(), Subscribe{ResourceType: "filtered-resource", EventTypes: []string{"delete"}}, result)
+	require.NoError(t, err)
+	close(result)
+
+	var names []string
+	for event := range result {
+		if event.Error != nil {
+			names = append(names, event.Error.Error())
+			continue
+		}
+		names = append(names, event.Name)
+	}
+	assert.Equal(t, []string{"resource.start", types.RemoveAPIEvent, "watch failed", types.RemoveAPIEvent}, names)
+}
+
+func Test_streamUnknownEventType(t *testing.T) {
+	ws := WatchSession{
+		apiOp: &types.APIRequest{
+			Name: "test",
+			Schemas: &types.APISchemas{
+				Schemas: map[string]*types.APISchema{
+					"filtered-resource": {
+						Schema: &schemas.Schema{
+							ID: "filtered-resource",
+						},
+						Store: &eventsStore{},
+					},
+				},
+			},
+			Request:       &http.Request{},
+			AccessControl: &mockAC{hasAccess: true},
+		},
+		getter: DefaultGetter,
+	}
+
+	result := make(chan types.APIEvent, 10)
+	err := ws.stream(context.TODO(), Subscribe{ResourceType: "filtered-resource", EventTypes: []string{"deleted"}}, result)
+	assert.EqualError(t, err, `unknown event type "deleted"`)
+	assert.Empty(t, result)
+}
+
+// eventsStore sends the given events on each watch.
+type eventsStore struct {
+	mockStore
+	events []types.APIEvent
+}
+
+func (e *eventsStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	c := make(chan types.APIEvent, len(e.events))
+	for _, event := range e.events {
+		c <- event
+	}
+	close(c)
+	return c, nil
+}
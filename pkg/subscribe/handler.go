@@ -2,9 +2,14 @@ package subscribe
 
 import (
 	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/apierror"
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
 	"github.com/sirupsen/logrus"
@@ -22,27 +27,168 @@ type Subscribe struct {
 	Namespace       string `json:"namespace,omitempty"`
 	ID              string `json:"id,omitempty"`
 	Selector        string `json:"selector,omitempty"`
+	FieldSelector   string `json:"fieldSelector,omitempty"`
+	// SendInitialEvents asks for a create event for each existing object before the changes, followed by
+	// bookmarks, so the client does not need to list the objects first.
+	SendInitialEvents bool `json:"sendInitialEvents,omitempty"`
+	// EventTypes limits the changes sent to the given types of events, "create", "change" or "remove" (also
+	// accepted as "update" and "delete"). All changes are sent when it is empty. The start event, errors and the
+	// initial events are sent regardless.
+	EventTypes []string `json:"eventTypes,omitempty"`
+	// EventPrefix replaces "resource" in the names of the events of the subscription, so that with "pods" the client
+	// receives "pods.create", "pods.start" or "pods.error" events, and can route them without looking at their data.
+	// It is made of letters, digits, '.', '-' and '_'.
+	EventPrefix string `json:"eventPrefix,omitempty"`
 }
 
 func (s *Subscribe) key() string {
-	return s.ResourceType + "/" + s.Namespace + "/" + s.ID + "/" + s.Selector
+	return s.ResourceType + "/" + s.Namespace + "/" + s.ID + "/" + s.Selector + "/" + s.FieldSelector
+}
+
+// eventName returns the name of the event of the subscription with the given name, with its EventPrefix applied.
+// Invalid prefixes are ignored, so that the error reporting them can be sent.
+func (s *Subscribe) eventName(name string) string {
+	if s.EventPrefix == "" || !validEventPrefix(s.EventPrefix) {
+		return name
+	}
+	if suffix, ok := strings.CutPrefix(name, "resource."); ok {
+		return s.EventPrefix + "." + suffix
+	}
+	return name
+}
+
+// validEventPrefix returns whether prefix can be used as an EventPrefix. It must not break the framing of
+// server-sent events.
+func validEventPrefix(prefix string) bool {
+	for _, r := range prefix {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// errorEventName returns the name of an error event replacing the event with the given name, keeping its prefix.
+func errorEventName(name string) string {
+	if i := strings.LastIndex(name, "."); i > 0 {
+		return name[:i] + ".error"
+	}
+	return "resource.error"
+}
+
+// watchRequest returns the filters of the subscription passed to the store.
+func (s *Subscribe) watchRequest() types.WatchRequest {
+	return types.WatchRequest{
+		ResourceType:  s.ResourceType,
+		Namespace:     s.Namespace,
+		Revision:      s.ResourceVersion,
+		ID:            s.ID,
+		Selector:      s.Selector,
+		FieldSelector: s.FieldSelector,
+	}
+}
+
+// Options configures the subscribe handler.
+type Options struct {
+	// CheckOrigin returns whether the websocket handshake of the request is allowed based on its Origin header.
+	// Disallowed handshakes are rejected with a 403. If nil, only requests from the same origin as the server are
+	// allowed.
+	CheckOrigin func(req *http.Request) bool
+	// SSEKeepalive is the interval at which a comment is written to server-sent event streams that have been idle,
+	// so proxies that buffer responses pass events through. If zero, DefaultSSEKeepalive is used.
+	SSEKeepalive time.Duration
+	// PrepareSSEStream, if set, is called when a server-sent event stream starts, once its headers are set and
+	// before they are sent, so that they can be adjusted, for instance to add a Priority header (RFC 9218) hinting
+	// HTTP/2 and HTTP/3 clients and proxies at the urgency of the stream.
+	PrepareSSEStream func(apiOp *types.APIRequest)
+	// InitialEventsChunkSize is the number of initial events sent to subscriptions with SendInitialEvents between
+	// bookmarks. If zero, DefaultInitialEventsChunkSize is used.
+	InitialEventsChunkSize int
+	// MaxConcurrentInitialLists bounds the number of lists made by a handler at the same time for subscriptions with
+	// SendInitialEvents, so that many clients connecting at once do not overload the stores. Subscriptions wait for
+	// a slot before listing. If zero, lists are not limited.
+	MaxConcurrentInitialLists int
+	// CoalesceWindow, if set, hides objects that are created and removed within this duration from subscriptions,
+	// sending neither event, to spare clients the churn of very short-lived objects. Create events are delayed by
+	// this duration, changes made in the meantime being folded into them.
+	CoalesceWindow time.Duration
+
+	// initialLists are the slots shared by the requests of a handler, taken while listing initial events.
+	initialLists chan struct{}
+}
+
+// AllowedOrigins returns an origin check that allows handshakes with an Origin header matching one of origins,
+// such as "https://example.com", as well as requests without an Origin header, which do not come from browsers.
+func AllowedOrigins(origins ...string) func(req *http.Request) bool {
+	allowed := map[string]bool{}
+	for _, origin := range origins {
+		allowed[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+	return func(req *http.Request) bool {
+		origin := req.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+		return allowed[strings.ToLower(u.Scheme+"://"+u.Host)]
+	}
+}
+
+// AllowAllOrigins is an origin check that allows handshakes from any origin. It should only be used for
+// development, as it allows any website to open a watch with the credentials of the user's browser.
+func AllowAllOrigins(*http.Request) bool {
+	return true
 }
 
 func NewHandler(getter SchemasGetter, serverVersion string) types.RequestListHandler {
+	return NewHandlerWithOptions(getter, serverVersion, Options{})
+}
+
+func NewHandlerWithOptions(getter SchemasGetter, serverVersion string, opts Options) types.RequestListHandler {
+	if opts.MaxConcurrentInitialLists > 0 && opts.initialLists == nil {
+		opts.initialLists = make(chan struct{}, opts.MaxConcurrentInitialLists)
+	}
 	return func(apiOp *types.APIRequest) (types.APIObjectList, error) {
-		return Handler(apiOp, getter, serverVersion)
+		return HandlerWithOptions(apiOp, getter, serverVersion, opts)
 	}
 }
 
 func Handler(apiOp *types.APIRequest, getter SchemasGetter, serverVersion string) (types.APIObjectList, error) {
-	err := handler(apiOp, getter, serverVersion)
+	return HandlerWithOptions(apiOp, getter, serverVersion, Options{})
+}
+
+func HandlerWithOptions(apiOp *types.APIRequest, getter SchemasGetter, serverVersion string, opts Options) (types.APIObjectList, error) {
+	if isSSE(apiOp.Request) {
+		sub, err := sseSubscription(apiOp)
+		if err != nil {
+			return types.APIObjectList{}, err
+		}
+		if err := sseHandler(apiOp, getter, serverVersion, opts, sub); err != nil {
+			var apiErr *apierror.APIError
+			if errors.As(err, &apiErr) {
+				// the stream failed before it started, so the error can still be the response
+				return types.APIObjectList{}, err
+			}
+			logrus.Errorf("Error during subscribe %v", err)
+		}
+		return types.APIObjectList{}, validation.ErrComplete
+	}
+
+	err := handler(apiOp, getter, serverVersion, opts)
 	if err != nil {
 		logrus.Errorf("Error during subscribe %v", err)
 	}
 	return types.APIObjectList{}, validation.ErrComplete
 }
 
-func handler(apiOp *types.APIRequest, getter SchemasGetter, serverVersion string) error {
+func handler(apiOp *types.APIRequest, getter SchemasGetter, serverVersion string, opts Options) error {
+	upgrader := upgrader
+	upgrader.CheckOrigin = opts.CheckOrigin
 	c, err := upgrader.Upgrade(apiOp.Response, apiOp.Request, nil)
 	if err != nil {
 		return err
@@ -50,6 +196,9 @@ func handler(apiOp *types.APIRequest, getter SchemasGetter, serverVersion string
 	defer c.Close()
 
 	watches := NewWatchSession(apiOp, getter)
+	watches.initialEventsChunkSize = opts.InitialEventsChunkSize
+	watches.initialLists = opts.initialLists
+	watches.coalesceWindow = opts.CoalesceWindow
 	defer watches.Close()
 
 	events := watches.Watch(c)
@@ -73,26 +222,45 @@ func handler(apiOp *types.APIRequest, getter SchemasGetter, serverVersion string
 				return err
 			}
 		case <-t.C:
-			if err := writeData(apiOp, getter, c, types.APIEvent{
-				Name: "ping",
-				Object: types.APIObject{
-					Object: map[string]interface{}{"version": serverVersion},
-				},
-			}); err != nil {
+			if err := writeData(apiOp, getter, c, pingEvent(serverVersion)); err != nil {
 				return err
 			}
 		}
 	}
 }
 
-func writeData(apiOp *types.APIRequest, getter SchemasGetter, c *websocket.Conn, event types.APIEvent) error {
+func pingEvent(serverVersion string) types.APIEvent {
+	return types.APIEvent{
+		Name: "ping",
+		Object: types.APIObject{
+			Object: map[string]interface{}{"version": serverVersion},
+		},
+	}
+}
+
+func prepareEvent(apiOp *types.APIRequest, getter SchemasGetter, event types.APIEvent) types.APIEvent {
 	event = MarshallObject(apiOp, getter, event)
 	if event.Error != nil {
-		event.Name = "resource.error"
-		event.Data = map[string]interface{}{
+		event.Name = errorEventName(event.Name)
+		data := map[string]interface{}{
 			"error": event.Error.Error(),
 		}
+		var apiErr *apierror.APIError
+		if expired(event.Error) {
+			// clients relist on a Gone code, as they would on a 410 from Kubernetes
+			data["code"] = apierror.Gone.Code
+			data["status"] = apierror.Gone.Status
+		} else if errors.As(event.Error, &apiErr) {
+			data["code"] = apiErr.Code.Code
+			data["status"] = apiErr.Code.Status
+		}
+		event.Data = data
 	}
+	return event
+}
+
+func writeData(apiOp *types.APIRequest, getter SchemasGetter, c *websocket.Conn, event types.APIEvent) error {
+	event = prepareEvent(apiOp, getter, event)
 
 	messageWriter, err := c.NextWriter(websocket.TextMessage)
 	if err != nil {
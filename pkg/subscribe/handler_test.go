@@ -0,0 +1,266 @@
+package subscribe
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowedOrigins(t *testing.T) {
+	check := AllowedOrigins("https://rancher.example.com", "HTTP://localhost:8080/")
+
+	tests := []struct {
+		origin string
+		expect bool
+	}{
+		{origin: "", expect: true},
+		{origin: "https://rancher.example.com", expect: true},
+		{origin: "https://Rancher.Example.com", expect: true},
+		{origin: "http://localhost:8080", expect: true},
+		{origin: "http://rancher.example.com", expect: false},
+		{origin: "https://evil.example.com", expect: false},
+		{origin: "http://localhost:8081", expect: false},
+	}
+	for _, test := range tests {
+		t.Run(test.origin, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/subscribe", nil)
+			if test.origin != "" {
+				req.Header.Set("Origin", test.origin)
+			}
+			assert.Equal(t, test.expect, check(req))
+		})
+	}
+}
+
+func TestHandlerOrigin(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       Options
+		origin     func(serverURL string) string
+		wantStatus int
+	}{
+		{
+			name:       "same origin allowed by default",
+			origin:     func(serverURL string) string { return serverURL },
+			wantStatus: http.StatusSwitchingProtocols,
+		},
+		{
+			name:       "cross origin rejected by default",
+			origin:     func(string) string { return "https://evil.example.com" },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "allowlisted origin",
+			opts:       Options{CheckOrigin: AllowedOrigins("https://rancher.example.com")},
+			origin:     func(string) string { return "https://rancher.example.com" },
+			wantStatus: http.StatusSwitchingProtocols,
+		},
+		{
+			name:       "origin missing from allowlist",
+			opts:       Options{CheckOrigin: AllowedOrigins("https://rancher.example.com")},
+			origin:     func(serverURL string) string { return serverURL },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "allow all",
+			opts:       Options{CheckOrigin: AllowAllOrigins},
+			origin:     func(string) string { return "https://evil.example.com" },
+			wantStatus: http.StatusSwitchingProtocols,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := NewHandlerWithOptions(DefaultGetter, "v1", test.opts)
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				_, _ = handler(&types.APIRequest{
+					Request:  req,
+					Response: rw,
+					Schemas:  types.EmptyAPISchemas(),
+				})
+			}))
+			defer server.Close()
+
+			header := http.Header{"Origin": {test.origin(server.URL)}}
+			conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+			if conn != nil {
+				conn.Close()
+			}
+			require.NotNil(t, resp)
+			assert.Equal(t, test.wantStatus, resp.StatusCode)
+			if test.wantStatus == http.StatusSwitchingProtocols {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestSSEKeepalive(t *testing.T) {
+	handler := NewHandlerWithOptions(DefaultGetter, "v1", Options{SSEKeepalive: 50 * time.Millisecond})
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = handler(&types.APIRequest{
+			Request:  req,
+			Response: rw,
+			Schemas: &types.APISchemas{
+				Schemas: map[string]*types.APISchema{
+					"idle-resource": {
+						Schema: &schemas.Schema{
+							ID: "idle-resource",
+						},
+						Store: &idleStore{},
+					},
+				},
+			},
+			AccessControl: &mockAC{hasAccess: true},
+		})
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"?resourceType=idle-resource", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	lines := bufio.NewScanner(resp.Body)
+	require.True(t, lines.Scan())
+	assert.Equal(t, "event: resource.start", lines.Text())
+
+	start := time.Now()
+	var keepalives []time.Duration
+	for len(keepalives) < 3 && lines.Scan() {
+		if lines.Text() == ": keepalive" {
+			keepalives = append(keepalives, time.Since(start))
+		}
+	}
+	require.Len(t, keepalives, 3)
+	for i, at := range keepalives {
+		expected := time.Duration(i+1) * 50 * time.Millisecond
+		assert.GreaterOrEqual(t, at, expected-25*time.Millisecond, "keepalive %d", i)
+		assert.Less(t, at, expected+250*time.Millisecond, "keepalive %d", i)
+	}
+}
+
+func TestSSEMissingResourceType(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/subscribe", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	_, err := Handler(&types.APIRequest{
+		Request:  req,
+		Response: httptest.NewRecorder(),
+		Schemas:  types.EmptyAPISchemas(),
+	}, DefaultGetter, "v1")
+	assert.ErrorContains(t, err, "resourceType is required")
+}
+
+// idleStore is a store whose watches send nothing until the request is canceled.
+type idleStore struct {
+	mockStore
+}
+
+func (i *idleStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	c := make(chan types.APIEvent)
+	go func() {
+		<-apiOp.Context().Done()
+		close(c)
+	}()
+	return c, nil
+}
+
+func TestSSEHTTP2(t *testing.T) {
+	store := &pushStore{events: make(chan types.APIEvent)}
+	handler := NewHandlerWithOptions(DefaultGetter, "v1", Options{
+		PrepareSSEStream: func(apiOp *types.APIRequest) {
+			apiOp.Response.Header().Set("Priority", "u=1")
+		},
+	})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = handler(&types.APIRequest{
+			Request:  req,
+			Response: rw,
+			Schemas: &types.APISchemas{
+				Schemas: map[string]*types.APISchema{
+					"pushed-resource": {
+						Schema: &schemas.Schema{
+							ID: "pushed-resource",
+						},
+						Store: store,
+					},
+				},
+			},
+			AccessControl: &mockAC{hasAccess: true},
+		})
+	}))
+	server.EnableHTTP2 = true
+	// streams must outlive the write timeout of the server
+	server.Config.WriteTimeout = 200 * time.Millisecond
+	server.StartTLS()
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"?resourceType=pushed-resource", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, 2, resp.ProtoMajor)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "u=1", resp.Header.Get("Priority"))
+
+	lines := bufio.NewScanner(resp.Body)
+	require.True(t, lines.Scan())
+	assert.Equal(t, "event: resource.start", lines.Text())
+
+	// each event is sent only once the previous one was received, so the stream must not be buffered
+	for i, name := range []string{"first", "second", "after the write timeout"} {
+		if i == 2 {
+			time.Sleep(300 * time.Millisecond)
+		}
+		select {
+		case store.events <- types.APIEvent{
+			Name:         types.ChangeAPIEvent,
+			ResourceType: "pushed-resource",
+			Object:       types.APIObject{Type: "pushed-resource", Object: map[string]interface{}{"name": name}},
+		}:
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "the stream was closed", "event %q", name)
+		}
+		for lines.Scan() && lines.Text() != "event: "+types.ChangeAPIEvent {
+		}
+		require.True(t, lines.Scan(), "event %q was not received", name)
+		assert.Contains(t, lines.Text(), `"name":"`+name+`"`)
+	}
+}
+
+// pushStore sends the events sent on events to its watch.
+type pushStore struct {
+	mockStore
+	events chan types.APIEvent
+}
+
+func (p *pushStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	c := make(chan types.APIEvent)
+	go func() {
+		defer close(c)
+		for {
+			select {
+			case event := <-p.events:
+				c <- event
+			case <-apiOp.Context().Done():
+				return
+			}
+		}
+	}()
+	return c, nil
+}
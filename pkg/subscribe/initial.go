@@ -0,0 +1,139 @@
+package subscribe
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/data"
+	"github.com/rancher/wrangler/v3/pkg/data/convert"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// DefaultInitialEventsChunkSize is the number of initial events sent between bookmarks when
+// Options.InitialEventsChunkSize is not set.
+const DefaultInitialEventsChunkSize = 500
+
+// BookmarkEvent is the name of the events marking the progress of the initial events of a watch.
+const BookmarkEvent = "resource.bookmark"
+
+// sendInitialEvents sends a create event for each object of the subscription, as listed when the watch starts, so
+// that the client does not need a separate list. A bookmark follows every chunk of events, the last one holding
+// "initialEventsEnd": true, so clients can process a large list incrementally and tell when it is complete.
+//
+// Unlike the events of the watch, initial events are never dropped: sending them waits for the client.
+func (s *WatchSession) sendInitialEvents(ctx context.Context, apiOp *types.APIRequest, schema *types.APISchema, sub Subscribe, result chan<- types.APIEvent) error {
+	if err := apiOp.AccessControl.CanList(apiOp, schema); err != nil {
+		return err
+	}
+
+	selector, err := labels.Parse(sub.Selector)
+	if err != nil {
+		return err
+	}
+	fieldSelector, err := fields.ParseSelector(sub.FieldSelector)
+	if err != nil {
+		return err
+	}
+
+	list, err := s.initialList(ctx, apiOp, schema)
+	if err != nil || ctx.Err() != nil {
+		return err
+	}
+
+	chunkSize := s.initialEventsChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultInitialEventsChunkSize
+	}
+	send := func(event types.APIEvent) bool {
+		select {
+		case result <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	bookmark := func(end bool) bool {
+		return send(types.APIEvent{
+			Name:         sub.eventName(BookmarkEvent),
+			ResourceType: sub.ResourceType,
+			Namespace:    sub.Namespace,
+			ID:           sub.ID,
+			Selector:     sub.Selector,
+			Revision:     list.Revision,
+			Object: types.APIObject{
+				Object: map[string]interface{}{"initialEventsEnd": end},
+			},
+		})
+	}
+
+	sent := 0
+	for _, obj := range list.Objects {
+		if sub.ID != "" && obj.ID != sub.ID {
+			continue
+		}
+		if !selector.Empty() && !selector.Matches(objectLabels(obj)) {
+			continue
+		}
+		if !fieldSelector.Empty() && !fieldSelector.Matches(objectFields(obj.Data())) {
+			continue
+		}
+		if sent > 0 && sent%chunkSize == 0 && !bookmark(false) {
+			return nil
+		}
+		if !send(types.APIEvent{
+			Name:         sub.eventName(types.CreateAPIEvent),
+			ResourceType: sub.ResourceType,
+			Namespace:    sub.Namespace,
+			ID:           sub.ID,
+			Selector:     sub.Selector,
+			Revision:     list.Revision,
+			Object:       obj,
+		}) {
+			return nil
+		}
+		sent++
+	}
+	bookmark(true)
+	return nil
+}
+
+// initialList lists the objects of schema for the initial events, once one of the initialLists slots is free.
+func (s *WatchSession) initialList(ctx context.Context, apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	if s.initialLists != nil {
+		select {
+		case s.initialLists <- struct{}{}:
+			defer func() { <-s.initialLists }()
+		case <-ctx.Done():
+			return types.APIObjectList{}, nil
+		}
+	}
+
+	// the query of the subscribe request is not meant for the store
+	apiOp = apiOp.Clone()
+	apiOp.Query = url.Values{}
+	return schema.Store.List(apiOp, schema)
+}
+
+// objectFields are the fields of an object for field selectors, which name them by their dotted path such as
+// "metadata.name".
+type objectFields map[string]interface{}
+
+func (o objectFields) Has(field string) bool {
+	_, ok := data.GetValue(o, strings.Split(field, ".")...)
+	return ok
+}
+
+func (o objectFields) Get(field string) string {
+	return convert.ToString(data.GetValueN(o, strings.Split(field, ".")...))
+}
+
+func objectLabels(obj types.APIObject) labels.Set {
+	result := labels.Set{}
+	for key, value := range convert.ToMapInterface(data.GetValueN(obj.Data(), "metadata", "labels")) {
+		result[key] = convert.ToString(value)
+	}
+	return result
+}
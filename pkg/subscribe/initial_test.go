@@ -0,0 +1,275 @@
+package subscribe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listStore lists count objects named after their ID, the odd ones labeled tier=odd, and has no changes to watch.
+type listStore struct {
+	mockStore
+	count int
+}
+
+func (l *listStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	list := types.APIObjectList{Revision: "42"}
+	for i := 0; i < l.count; i++ {
+		tier := "even"
+		if i%2 == 1 {
+			tier = "odd"
+		}
+		list.Objects = append(list.Objects, types.APIObject{
+			Type: "listed-resource",
+			ID:   fmt.Sprintf("obj-%d", i),
+			Object: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name":   fmt.Sprintf("obj-%d", i),
+					"labels": map[string]interface{}{"tier": tier},
+				},
+			},
+		})
+	}
+	return list, nil
+}
+
+func (l *listStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	c := make(chan types.APIEvent)
+	close(c)
+	return c, nil
+}
+
+// listAC allows listing and watching.
+type listAC struct {
+	mockAC
+}
+
+func (l *listAC) CanList(apiOp *types.APIRequest, schema *types.APISchema) error {
+	return nil
+}
+
+// eventSummary describes an event as its name and object ID, or whether it ends the initial events for bookmarks.
+func eventSummary(event types.APIEvent) string {
+	if event.Name == BookmarkEvent {
+		return fmt.Sprintf("%s end=%v", event.Name, event.Object.Data()["initialEventsEnd"])
+	}
+	return event.Name + " " + event.Object.ID
+}
+
+func Test_streamInitialEvents(t *testing.T) {
+	tests := []struct {
+		name      string
+		count     int
+		chunkSize int
+		sub       Subscribe
+		want      []string
+	}{
+		{
+			name:      "chunks with bookmarks",
+			count:     5,
+			chunkSize: 2,
+			sub:       Subscribe{ResourceType: "listed-resource", SendInitialEvents: true},
+			want: []string{
+				"resource.start ",
+				"resource.create obj-0", "resource.create obj-1", "resource.bookmark end=false",
+				"resource.create obj-2", "resource.create obj-3", "resource.bookmark end=false",
+				"resource.create obj-4", "resource.bookmark end=true",
+			},
+		},
+		{
+			name:      "last chunk full",
+			count:     4,
+			chunkSize: 2,
+			sub:       Subscribe{ResourceType: "listed-resource", SendInitialEvents: true},
+			want: []string{
+				"resource.start ",
+				"resource.create obj-0", "resource.create obj-1", "resource.bookmark end=false",
+				"resource.create obj-2", "resource.create obj-3", "resource.bookmark end=true",
+			},
+		},
+		{
+			name:  "default chunk size",
+			count: 3,
+			sub:   Subscribe{ResourceType: "listed-resource", SendInitialEvents: true},
+			want: []string{
+				"resource.start ",
+				"resource.create obj-0", "resource.create obj-1", "resource.create obj-2", "resource.bookmark end=true",
+			},
+		},
+		{
+			name:      "selector",
+			count:     5,
+			chunkSize: 1,
+			sub:       Subscribe{ResourceType: "listed-resource", Selector: "tier=odd", SendInitialEvents: true},
+			want: []string{
+				"resource.start ",
+				"resource.create obj-1", "resource.bookmark end=false",
+				"resource.create obj-3", "resource.bookmark end=true",
+			},
+		},
+		{
+			name:  "field selector",
+			count: 5,
+			sub:   Subscribe{ResourceType: "listed-resource", FieldSelector: "metadata.name!=obj-0,metadata.name!=obj-3", SendInitialEvents: true},
+			want: []string{
+				"resource.start ",
+				"resource.create obj-1", "resource.create obj-2", "resource.create obj-4", "resource.bookmark end=true",
+			},
+		},
+		{
+			name:  "id",
+			count: 5,
+			sub:   Subscribe{ResourceType: "listed-resource", ID: "obj-2", SendInitialEvents: true},
+			want: []string{
+				"resource.start ",
+				"resource.create obj-2", "resource.bookmark end=true",
+			},
+		},
+		{
+			name: "empty",
+			sub:  Subscribe{ResourceType: "listed-resource", SendInitialEvents: true},
+			want: []string{"resource.start ", "resource.bookmark end=true"},
+		},
+		{
+			name:  "event prefix",
+			count: 1,
+			sub:   Subscribe{ResourceType: "listed-resource", SendInitialEvents: true, EventPrefix: "listed"},
+			want:  []string{"listed.start ", "listed.create obj-0", "listed.bookmark "},
+		},
+		{
+			name:  "not asked for",
+			count: 5,
+			sub:   Subscribe{ResourceType: "listed-resource"},
+			want:  []string{"resource.start "},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := WatchSession{
+				apiOp: &types.APIRequest{
+					Name: "test",
+					Schemas: &types.APISchemas{
+						Schemas: map[string]*types.APISchema{
+							"listed-resource": {
+								Schema: &schemas.Schema{
+									ID: "listed-resource",
+								},
+								Store: &listStore{count: tt.count},
+							},
+						},
+					},
+					Request:       &http.Request{},
+					AccessControl: &listAC{mockAC{hasAccess: true}},
+				},
+				getter:                 DefaultGetter,
+				initialEventsChunkSize: tt.chunkSize,
+			}
+
+			result := make(chan types.APIEvent, 20)
+			require.NoError(t, ws.stream(context.TODO(), tt.sub, result))
+			close(result)
+
+			var got []string
+			for event := range result {
+				if event.Name == types.CreateAPIEvent || event.Name == BookmarkEvent {
+					assert.Equal(t, "42", event.Revision)
+				}
+				got = append(got, eventSummary(event))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// slowListStore is a listStore whose lists take a while, recording how many run at the same time.
+type slowListStore struct {
+	listStore
+	lock    sync.Mutex
+	running int
+	peak    int
+	lists   int
+}
+
+func (s *slowListStore) List(apiOp *types.APIRequest, schema *types.APISchema) (types.APIObjectList, error) {
+	s.lock.Lock()
+	s.running++
+	s.lists++
+	s.peak = max(s.peak, s.running)
+	s.lock.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	s.lock.Lock()
+	s.running--
+	s.lock.Unlock()
+	return s.listStore.List(apiOp, schema)
+}
+
+func Test_streamInitialListLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+	}{
+		{
+			name:  "limited",
+			limit: 2,
+		},
+		{
+			name: "unlimited",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &slowListStore{listStore: listStore{count: 1}}
+			var initialLists chan struct{}
+			if tt.limit > 0 {
+				initialLists = make(chan struct{}, tt.limit)
+			}
+
+			var wg sync.WaitGroup
+			for i := 0; i < 6; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ws := WatchSession{
+						apiOp: &types.APIRequest{
+							Name: "test",
+							Schemas: &types.APISchemas{
+								Schemas: map[string]*types.APISchema{
+									"listed-resource": {
+										Schema: &schemas.Schema{
+											ID: "listed-resource",
+										},
+										Store: store,
+									},
+								},
+							},
+							Request:       &http.Request{},
+							AccessControl: &listAC{mockAC{hasAccess: true}},
+						},
+						getter:       DefaultGetter,
+						initialLists: initialLists,
+					}
+					result := make(chan types.APIEvent, 10)
+					assert.NoError(t, ws.stream(context.TODO(), Subscribe{ResourceType: "listed-resource", SendInitialEvents: true}, result))
+				}()
+			}
+			wg.Wait()
+
+			assert.Equal(t, 6, store.lists)
+			if tt.limit > 0 {
+				assert.LessOrEqual(t, store.peak, tt.limit)
+			} else {
+				assert.Greater(t, store.peak, 2)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package subscribe
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_streamNotWatchable(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema *types.APISchema
+	}{
+		{
+			name: "not watchable",
+			schema: &types.APISchema{
+				Schema:       &schemas.Schema{ID: "audit-resource"},
+				Store:        &eventsStore{},
+				NotWatchable: true,
+			},
+		},
+		{
+			name: "no store",
+			schema: &types.APISchema{
+				Schema: &schemas.Schema{ID: "audit-resource"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := WatchSession{
+				apiOp: &types.APIRequest{
+					Name: "test",
+					Schemas: &types.APISchemas{
+						Schemas: map[string]*types.APISchema{"audit-resource": tt.schema},
+					},
+					Request:       &http.Request{},
+					AccessControl: &mockAC{hasAccess: true},
+				},
+				getter: DefaultGetter,
+			}
+
+			result := make(chan types.APIEvent, 10)
+			err := ws.stream(context.TODO(), Subscribe{ResourceType: "audit-resource"}, result)
+
+			var apiErr *apierror.APIError
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, apierror.NotWatchable, apiErr.Code)
+			assert.Equal(t, "audit-resource does not support watching, list it to get its current state instead", apiErr.Message)
+			assert.Empty(t, result)
+		})
+	}
+}
+
+func TestSSENotWatchable(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/subscribe?resourceType=audit-resource", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	rw := httptest.NewRecorder()
+
+	_, err := Handler(&types.APIRequest{
+		Request:       req,
+		Response:      rw,
+		Schemas:       notWatchableSchemas(),
+		AccessControl: &mockAC{hasAccess: true},
+	}, DefaultGetter, "v1")
+
+	var apiErr *apierror.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, apierror.NotWatchable, apiErr.Code)
+	assert.Equal(t, http.StatusBadRequest, apiErr.Code.Status)
+	assert.Contains(t, apiErr.Message, "audit-resource does not support watching")
+	assert.False(t, rw.Flushed, "the response must not be started")
+}
+
+func TestWebsocketNotWatchable(t *testing.T) {
+	handler := NewHandler(DefaultGetter, "v1")
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = handler(&types.APIRequest{
+			Request:       req,
+			Response:      rw,
+			Schemas:       notWatchableSchemas(),
+			AccessControl: &mockAC{hasAccess: true},
+		})
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteJSON(Subscribe{ResourceType: "audit-resource"}))
+	_, message, err := conn.ReadMessage()
+	require.NoError(t, err)
+
+	var event struct {
+		Name string                 `json:"name"`
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(message, &event))
+	assert.Equal(t, "resource.error", event.Name)
+	assert.Equal(t, "NotWatchable", event.Data["code"])
+	assert.Equal(t, float64(http.StatusBadRequest), event.Data["status"])
+	assert.Contains(t, event.Data["error"], "audit-resource does not support watching")
+}
+
+func notWatchableSchemas() *types.APISchemas {
+	return &types.APISchemas{
+		Schemas: map[string]*types.APISchema{
+			"audit-resource": {
+				Schema:       &schemas.Schema{ID: "audit-resource"},
+				Store:        &eventsStore{},
+				NotWatchable: true,
+			},
+		},
+	}
+}
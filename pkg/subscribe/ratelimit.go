@@ -0,0 +1,145 @@
+package subscribe
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/sirupsen/logrus"
+)
+
+// maxHeldEvents bounds the number of events a rate limited watch holds back. Events of objects that are not
+// already held are dropped once it is reached.
+var maxHeldEvents = 1000
+
+// rateLimit returns a channel receiving the events of c at no more than limit per second. Events arriving faster
+// are held back, an event of an object that is already held replacing the held one, so a client catching up only
+// receives the latest state of each object. The returned channel is closed once c is closed and the held events
+// are sent, or once ctx is done.
+func rateLimit(ctx context.Context, c chan types.APIEvent, limit float64, resourceType string) chan types.APIEvent {
+	result := make(chan types.APIEvent)
+	interval := time.Duration(float64(time.Second) / limit)
+
+	go func() {
+		defer close(result)
+
+		var (
+			held      heldEvents
+			next      time.Time
+			coalesced bool
+			overflown bool
+			dropped   = droppedEvents(resourceType)
+			in        = c
+			unkeyedID int
+		)
+		for in != nil || held.len() > 0 {
+			var (
+				send  chan types.APIEvent
+				first types.APIEvent
+				wait  <-chan time.Time
+			)
+			if held.len() > 0 {
+				if d := time.Until(next); d > 0 {
+					wait = time.After(d)
+				} else {
+					send, first = result, held.first()
+				}
+			}
+
+			select {
+			case event, ok := <-in:
+				if !ok {
+					in = nil
+					continue
+				}
+				id := eventObjectID(event)
+				key := event.ResourceType + "/" + id
+				if id == "" || event.Error != nil {
+					// only events of identified objects can replace each other
+					unkeyedID++
+					key = "#" + strconv.Itoa(unkeyedID)
+				}
+				if held.replace(key, event) {
+					if !coalesced {
+						logrus.Warnf("watch of %s exceeds %g events per second, events are coalesced", resourceType, limit)
+						coalesced = true
+					}
+				} else if held.len() >= maxHeldEvents {
+					if !overflown {
+						logrus.Warnf("watch of %s holds %d events over its rate of %g events per second, events are dropped", resourceType, maxHeldEvents, limit)
+						overflown = true
+					}
+					dropped()
+				} else {
+					held.add(key, event)
+				}
+			case send <- first:
+				held.remove()
+				next = time.Now().Add(interval)
+			case <-wait:
+			case <-ctx.Done():
+				if in != nil {
+					go func() {
+						for range in {
+						}
+					}()
+				}
+				return
+			}
+		}
+	}()
+
+	return result
+}
+
+// heldEvents is a queue of events, keyed by the object they are about.
+type heldEvents struct {
+	keys   []string
+	events map[string]types.APIEvent
+}
+
+func (h *heldEvents) len() int {
+	return len(h.keys)
+}
+
+func (h *heldEvents) first() types.APIEvent {
+	return h.events[h.keys[0]]
+}
+
+func (h *heldEvents) remove() {
+	delete(h.events, h.keys[0])
+	h.keys = h.keys[1:]
+}
+
+func (h *heldEvents) add(key string, event types.APIEvent) {
+	if h.events == nil {
+		h.events = map[string]types.APIEvent{}
+	}
+	h.keys = append(h.keys, key)
+	h.events[key] = event
+}
+
+// replace replaces the held event with the same key, keeping its place in the queue. It returns false if there is
+// none.
+func (h *heldEvents) replace(key string, event types.APIEvent) bool {
+	if _, ok := h.events[key]; !ok {
+		return false
+	}
+	h.events[key] = event
+	return true
+}
+
+// delete removes the held event with the given key, wherever it is in the queue.
+func (h *heldEvents) delete(key string) {
+	if _, ok := h.events[key]; !ok {
+		return
+	}
+	delete(h.events, key)
+	for i, k := range h.keys {
+		if k == key {
+			h.keys = append(h.keys[:i], h.keys[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package subscribe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rancher/apiserver/pkg/metrics"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collect(c chan types.APIEvent) []types.APIEvent {
+	var result []types.APIEvent
+	for event := range c {
+		result = append(result, event)
+	}
+	return result
+}
+
+func Test_rateLimitCoalesces(t *testing.T) {
+	in := make(chan types.APIEvent)
+	out := rateLimit(context.Background(), in, 1000, "limited")
+
+	// nothing reads out until every event is held, so all events of an object are coalesced
+	in <- types.APIEvent{Name: types.CreateAPIEvent, ResourceType: "limited", ID: "a", Revision: "1"}
+	in <- types.APIEvent{Name: types.ChangeAPIEvent, ResourceType: "limited", ID: "b", Revision: "2"}
+	in <- types.APIEvent{Name: types.ChangeAPIEvent, ResourceType: "limited", ID: "a", Revision: "3"}
+	in <- types.APIEvent{ResourceType: "limited", Error: errors.New("failed")}
+	in <- types.APIEvent{Name: types.RemoveAPIEvent, ResourceType: "limited", ID: "b", Revision: "4"}
+	in <- types.APIEvent{ResourceType: "limited", Error: errors.New("failed again")}
+	close(in)
+
+	events := collect(out)
+	require.Len(t, events, 4)
+	assert.Equal(t, "a", events[0].ID)
+	assert.Equal(t, "3", events[0].Revision)
+	assert.Equal(t, "b", events[1].ID)
+	assert.Equal(t, types.RemoveAPIEvent, events[1].Name)
+	assert.EqualError(t, events[2].Error, "failed")
+	assert.EqualError(t, events[3].Error, "failed again")
+}
+
+func Test_rateLimitKeysOnObject(t *testing.T) {
+	in := make(chan types.APIEvent)
+	out := rateLimit(context.Background(), in, 1000, "limited")
+
+	// stores identify the object of an event by its Object.ID rather than the ID of the event
+	object := func(id string) types.APIObject {
+		return types.APIObject{Type: "limited", ID: id, Object: map[string]interface{}{}}
+	}
+	in <- types.APIEvent{Name: types.ChangeAPIEvent, ResourceType: "limited", Object: object("a"), Revision: "1"}
+	in <- types.APIEvent{Name: types.ChangeAPIEvent, ResourceType: "limited", Object: object("b"), Revision: "2"}
+	in <- types.APIEvent{Name: types.ChangeAPIEvent, ResourceType: "limited", Object: object("a"), Revision: "3"}
+	close(in)
+
+	events := collect(out)
+	require.Len(t, events, 2)
+	assert.Equal(t, "a", events[0].Object.ID)
+	assert.Equal(t, "3", events[0].Revision)
+	assert.Equal(t, "b", events[1].Object.ID)
+}
+
+func Test_rateLimitPaces(t *testing.T) {
+	in := make(chan types.APIEvent, 5)
+	for i := 0; i < 5; i++ {
+		in <- types.APIEvent{Name: types.ChangeAPIEvent, ResourceType: "limited", ID: fmt.Sprint(i)}
+	}
+	close(in)
+
+	start := time.Now()
+	events := collect(rateLimit(context.Background(), in, 20, "limited"))
+	assert.Len(t, events, 5)
+	// the first event is sent right away, the next ones every 50ms
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func Test_rateLimitDrops(t *testing.T) {
+	metricsEnabled = func() bool { return true }
+	defer func() { metricsEnabled = metrics.Enabled }()
+	maxHeldEvents = 3
+	defer func() { maxHeldEvents = 1000 }()
+
+	counter := metrics.DroppedEvents.WithLabelValues("overflowing")
+	before := testutil.ToFloat64(counter)
+
+	in := make(chan types.APIEvent)
+	out := rateLimit(context.Background(), in, 1000, "overflowing")
+	for i := 0; i < 5; i++ {
+		in <- types.APIEvent{Name: types.ChangeAPIEvent, ResourceType: "overflowing", ID: fmt.Sprint(i)}
+	}
+	// events of held objects still replace the held event
+	in <- types.APIEvent{Name: types.RemoveAPIEvent, ResourceType: "overflowing", ID: "1"}
+	close(in)
+
+	events := collect(out)
+	require.Len(t, events, 3)
+	assert.Equal(t, []string{"0", "1", "2"}, []string{events[0].ID, events[1].ID, events[2].ID})
+	assert.Equal(t, types.RemoveAPIEvent, events[1].Name)
+	assert.Equal(t, float64(2), testutil.ToFloat64(counter)-before)
+}
+
+func Test_rateLimitCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan types.APIEvent)
+	out := rateLimit(ctx, in, 1, "limited")
+	in <- types.APIEvent{Name: types.ChangeAPIEvent, ResourceType: "limited", ID: "a"}
+	in <- types.APIEvent{Name: types.ChangeAPIEvent, ResourceType: "limited", ID: "b"}
+	assert.Equal(t, "a", (<-out).ID)
+
+	cancel()
+	_, ok := <-out
+	assert.False(t, ok)
+	// the store can still send until it notices the cancellation
+	in <- types.APIEvent{Name: types.ChangeAPIEvent, ResourceType: "limited", ID: "c"}
+	close(in)
+}
+
+func Test_streamWatchRateLimit(t *testing.T) {
+	ws := WatchSession{
+		apiOp: &types.APIRequest{
+			Name: "test",
+			Schemas: &types.APISchemas{
+				Schemas: map[string]*types.APISchema{
+					"limited-resource": {
+						Schema: &schemas.Schema{
+							ID: "limited-resource",
+						},
+						Store:          &burstStore{events: 5},
+						WatchRateLimit: 50,
+					},
+				},
+			},
+			Request:       &http.Request{},
+			AccessControl: &mockAC{hasAccess: true},
+		},
+		getter: DefaultGetter,
+	}
+
+	result := make(chan types.APIEvent, 10)
+	start := time.Now()
+	err := ws.stream(context.TODO(), Subscribe{ResourceType: "limited-resource"}, result)
+	assert.NoError(t, err)
+	assert.Len(t, result, 6)
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+}
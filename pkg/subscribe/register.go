@@ -13,13 +13,18 @@ func DefaultGetter(apiOp *types.APIRequest) *types.APISchemas {
 }
 
 func Register(schemas *types.APISchemas, getter SchemasGetter, serverVersion string) {
+	RegisterWithOptions(schemas, getter, serverVersion, Options{})
+}
+
+func RegisterWithOptions(schemas *types.APISchemas, getter SchemasGetter, serverVersion string, opts Options) {
 	if getter == nil {
 		getter = DefaultGetter
 	}
 	schemas.MustImportAndCustomize(Subscribe{}, func(schema *types.APISchema) {
 		schema.CollectionMethods = []string{http.MethodGet}
 		schema.ResourceMethods = []string{}
-		schema.ListHandler = NewHandler(getter, serverVersion)
+		schema.ListHandler = NewHandlerWithOptions(getter, serverVersion, opts)
 		schema.PluralName = "subscribe"
+		schema.QueryParameters = sseQueryParameters
 	})
 }
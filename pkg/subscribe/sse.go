@@ -0,0 +1,173 @@
+package subscribe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultSSEKeepalive is the keepalive interval of server-sent event streams when Options.SSEKeepalive is not set.
+const DefaultSSEKeepalive = 15 * time.Second
+
+const sseContentType = "text/event-stream"
+
+// sseQueryParameters are the query parameters a server-sent event stream reads its subscription from.
+var sseQueryParameters = []string{"resourceType", "resourceVersion", "namespace", "id", "selector", "fieldSelector", "sendInitialEvents", "eventTypes", "eventPrefix"}
+
+// isSSE returns whether the request asks for a server-sent event stream rather than a websocket.
+func isSSE(req *http.Request) bool {
+	if websocket.IsWebSocketUpgrade(req) {
+		return false
+	}
+	for _, accept := range req.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), sseContentType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sseSubscription reads the subscription of a server-sent event stream from the query parameters. Unlike a
+// websocket, the client has no way to send subscriptions once the stream is open, so a stream watches one type.
+func sseSubscription(apiOp *types.APIRequest) (Subscribe, error) {
+	query := apiOp.Request.URL.Query()
+	sub := Subscribe{
+		ResourceType:    query.Get("resourceType"),
+		ResourceVersion: query.Get("resourceVersion"),
+		Namespace:       query.Get("namespace"),
+		ID:              query.Get("id"),
+		Selector:        query.Get("selector"),
+		FieldSelector:   query.Get("fieldSelector"),
+		EventPrefix:     query.Get("eventPrefix"),
+	}
+	sub.SendInitialEvents, _ = strconv.ParseBool(query.Get("sendInitialEvents"))
+	if eventTypes := query.Get("eventTypes"); eventTypes != "" {
+		sub.EventTypes = strings.Split(eventTypes, ",")
+	}
+	if sub.ResourceType == "" {
+		return sub, apierror.NewAPIError(apierror.BadRequest, "resourceType is required")
+	}
+	if !validEventPrefix(sub.EventPrefix) {
+		return sub, apierror.NewAPIError(apierror.BadRequest, fmt.Sprintf("invalid event prefix %q", sub.EventPrefix))
+	}
+	if _, ok := apiOp.Response.(http.Flusher); !ok {
+		return sub, apierror.NewAPIError(validation.ServerError, "streaming is not supported by the response writer")
+	}
+	return sub, nil
+}
+
+func sseHandler(apiOp *types.APIRequest, getter SchemasGetter, serverVersion string, opts Options, sub Subscribe) error {
+	rw := apiOp.Response
+	flusher := rw.(http.Flusher)
+
+	// the response is started with the first event, so that a watch failing right away because its resource
+	// version expired is answered with a 410 rather than an error event
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		rw.Header().Set("Content-Type", sseContentType)
+		rw.Header().Set("Cache-Control", "no-cache")
+		// nginx buffers responses unless told otherwise
+		rw.Header().Set("X-Accel-Buffering", "no")
+		rw.Header().Del("Content-Length")
+		if opts.PrepareSSEStream != nil {
+			opts.PrepareSSEStream(apiOp)
+		}
+		// the stream outlives the write timeout of the server, which would otherwise cut it
+		if err := http.NewResponseController(rw).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			logrus.Debugf("Failed to clear the write deadline of the event stream: %v", err)
+		}
+		rw.WriteHeader(http.StatusOK)
+		flusher.Flush()
+	}
+
+	watches := NewWatchSession(apiOp, getter)
+	watches.initialEventsChunkSize = opts.InitialEventsChunkSize
+	watches.initialLists = opts.initialLists
+	watches.coalesceWindow = opts.CoalesceWindow
+	events := make(chan types.APIEvent, 100)
+	defer func() {
+		// Ensure that events gets fully consumed while the watch stops
+		go func() {
+			for range events {
+			}
+		}()
+		watches.Close()
+		close(events)
+	}()
+	watches.add(sub, events)
+
+	keepaliveInterval := opts.SSEKeepalive
+	if keepaliveInterval <= 0 {
+		keepaliveInterval = DefaultSSEKeepalive
+	}
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+	ping := time.NewTicker(30 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-apiOp.Context().Done():
+			return nil
+		case event := <-events:
+			if !started && event.Error != nil {
+				var apiErr *apierror.APIError
+				if expired(event.Error) {
+					return apierror.NewAPIError(apierror.Gone, event.Error.Error())
+				} else if errors.As(event.Error, &apiErr) && apiErr.Code == apierror.NotWatchable {
+					return apiErr
+				}
+			}
+			start()
+			if err := writeSSE(apiOp, getter, rw, event); err != nil {
+				return err
+			}
+			if event.Name == sub.eventName("resource.stop") {
+				return nil
+			}
+			keepalive.Reset(keepaliveInterval)
+		case <-ping.C:
+			start()
+			if err := writeSSE(apiOp, getter, rw, pingEvent(serverVersion)); err != nil {
+				return err
+			}
+		case <-keepalive.C:
+			start()
+			if _, err := io.WriteString(rw, ": keepalive\n\n"); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeSSE(apiOp *types.APIRequest, getter SchemasGetter, rw http.ResponseWriter, event types.APIEvent) error {
+	event = prepareEvent(apiOp, getter, event)
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(rw, "event: %s\ndata: %s\n\n", event.Name, data); err != nil {
+		return err
+	}
+	rw.(http.Flusher).Flush()
+	return nil
+}
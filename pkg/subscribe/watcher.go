@@ -5,11 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/metrics"
 	"github.com/rancher/apiserver/pkg/types"
 )
 
+// metricsEnabled is replaced in tests, since metrics can only be turned on through the environment.
+var metricsEnabled = metrics.Enabled
+
 type WatchSession struct {
 	sync.Mutex
 
@@ -19,6 +25,10 @@ type WatchSession struct {
 	wg       sync.WaitGroup
 	ctx      context.Context
 	cancel   func()
+
+	initialEventsChunkSize int
+	initialLists           chan struct{}
+	coalesceWindow         time.Duration
 }
 
 func (s *WatchSession) stop(sub Subscribe, resp chan<- types.APIEvent) {
@@ -27,7 +37,7 @@ func (s *WatchSession) stop(sub Subscribe, resp chan<- types.APIEvent) {
 	if cancel, ok := s.watchers[sub.key()]; ok {
 		cancel()
 		resp <- types.APIEvent{
-			Name:         "resource.stop",
+			Name:         sub.eventName("resource.stop"),
 			ResourceType: sub.ResourceType,
 			Namespace:    sub.Namespace,
 			ID:           sub.ID,
@@ -60,34 +70,57 @@ func (s *WatchSession) stream(ctx context.Context, sub Subscribe, result chan<-
 	schema := schemas.LookupSchema(sub.ResourceType)
 	if schema == nil {
 		return fmt.Errorf("failed to find schema %s", sub.ResourceType)
-	} else if schema.Store == nil {
-		return fmt.Errorf("schema %s does not support watching", sub.ResourceType)
+	} else if schema.Store == nil || schema.NotWatchable {
+		return apierror.NewAPIError(apierror.NotWatchable,
+			fmt.Sprintf("%s does not support watching, list it to get its current state instead", sub.ResourceType))
 	}
 
 	if err := s.apiOp.AccessControl.CanWatch(s.apiOp, schema); err != nil {
 		return err
 	}
 
+	names, err := eventNames(sub.EventTypes)
+	if err != nil {
+		return err
+	}
+	if !validEventPrefix(sub.EventPrefix) {
+		return fmt.Errorf("invalid event prefix %q", sub.EventPrefix)
+	}
+
 	apiOp := s.apiOp.Clone().WithContext(ctx)
 	apiOp.Namespace = sub.Namespace
 	apiOp.Schemas = schemas
-	c, err := schema.Store.Watch(apiOp, schema, types.WatchRequest{
-		Revision: sub.ResourceVersion,
-		ID:       sub.ID,
-		Selector: sub.Selector,
-	})
+	c, err := schema.Store.Watch(apiOp, schema, sub.watchRequest())
 	if err != nil {
 		return err
 	}
 
 	result <- types.APIEvent{
-		Name:         "resource.start",
+		Name:         sub.eventName("resource.start"),
 		ResourceType: sub.ResourceType,
 		Namespace:    sub.Namespace,
 		ID:           sub.ID,
 		Selector:     sub.Selector,
 	}
 
+	if sub.SendInitialEvents {
+		if err := s.sendInitialEvents(ctx, apiOp, schema, sub, result); err != nil {
+			return err
+		}
+	}
+
+	if c != nil && s.coalesceWindow > 0 {
+		c = coalesce(ctx, c, s.coalesceWindow)
+	}
+
+	if c != nil && names != nil {
+		c = filterEvents(ctx, c, names)
+	}
+
+	if c != nil && schema.WatchRateLimit > 0 {
+		c = rateLimit(ctx, c, schema.WatchRateLimit, sub.ResourceType)
+	}
+
 	if c == nil {
 		<-s.apiOp.Context().Done()
 	} else {
@@ -95,18 +128,23 @@ func (s *WatchSession) stream(ctx context.Context, sub Subscribe, result chan<-
 			if event.Error == nil {
 				event.ID = sub.ID
 				event.Selector = sub.Selector
+				event.Name = sub.eventName(event.Name)
 				select {
 				case result <- event:
 				default:
 					// handle slow consumer
+					dropped := droppedEvents(sub.ResourceType)
+					dropped()
 					go func() {
 						for range c {
 							// continue to drain until close
+							dropped()
 						}
 					}()
 					return nil
 				}
 			} else {
+				// the watch goes on until the store closes the channel, which ends it with a resource.stop
 				sendErr(result, event.Error, sub)
 			}
 		}
@@ -115,6 +153,15 @@ func (s *WatchSession) stream(ctx context.Context, sub Subscribe, result chan<-
 	return nil
 }
 
+// droppedEvents returns a func counting one event of resourceType dropped for a slow consumer.
+func droppedEvents(resourceType string) func() {
+	if !metricsEnabled() {
+		return func() {}
+	}
+	counter := metrics.DroppedEvents.WithLabelValues(resourceType)
+	return counter.Inc
+}
+
 func NewWatchSession(apiOp *types.APIRequest, getter SchemasGetter) *WatchSession {
 	ws := &WatchSession{
 		apiOp:    apiOp,
@@ -175,6 +222,7 @@ func (s *WatchSession) watch(conn *websocket.Conn, resp chan types.APIEvent) err
 
 func sendErr(resp chan<- types.APIEvent, err error, sub Subscribe) {
 	resp <- types.APIEvent{
+		Name:         sub.eventName("resource.error"),
 		ResourceType: sub.ResourceType,
 		Namespace:    sub.Namespace,
 		ID:           sub.ID,
@@ -7,6 +7,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rancher/apiserver/pkg/metrics"
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/wrangler/v3/pkg/schemas"
 	"github.com/stretchr/testify/assert"
@@ -138,6 +140,193 @@ func Test_stream(t *testing.T) {
 	}
 }
 
+func Test_streamDroppedEvents(t *testing.T) {
+	metricsEnabled = func() bool { return true }
+	defer func() { metricsEnabled = metrics.Enabled }()
+
+	ws := WatchSession{
+		apiOp: &types.APIRequest{
+			Name: "test",
+			Schemas: &types.APISchemas{
+				Schemas: map[string]*types.APISchema{
+					"bursty-resource": {
+						Schema: &schemas.Schema{
+							ID: "bursty-resource",
+						},
+						Store: &burstStore{events: 5},
+					},
+				},
+			},
+			Request:       &http.Request{},
+			AccessControl: &mockAC{hasAccess: true},
+		},
+		getter: DefaultGetter,
+	}
+
+	counter := metrics.DroppedEvents.WithLabelValues("bursty-resource")
+	before := testutil.ToFloat64(counter)
+
+	// the start event fills the buffer, so every event from the store is dropped
+	result := make(chan types.APIEvent, 1)
+	err := ws.stream(context.TODO(), Subscribe{ResourceType: "bursty-resource"}, result)
+	assert.NoError(t, err)
+	assert.Equal(t, "resource.start", (<-result).Name)
+
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(counter)-before == 5
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_streamNoDroppedEvents(t *testing.T) {
+	metricsEnabled = func() bool { return true }
+	defer func() { metricsEnabled = metrics.Enabled }()
+
+	ws := WatchSession{
+		apiOp: &types.APIRequest{
+			Name: "test",
+			Schemas: &types.APISchemas{
+				Schemas: map[string]*types.APISchema{
+					"steady-resource": {
+						Schema: &schemas.Schema{
+							ID: "steady-resource",
+						},
+						Store: &burstStore{events: 5},
+					},
+				},
+			},
+			Request:       &http.Request{},
+			AccessControl: &mockAC{hasAccess: true},
+		},
+		getter: DefaultGetter,
+	}
+
+	result := make(chan types.APIEvent, 10)
+	err := ws.stream(context.TODO(), Subscribe{ResourceType: "steady-resource"}, result)
+	assert.NoError(t, err)
+	assert.Len(t, result, 6)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.DroppedEvents.WithLabelValues("steady-resource")))
+}
+
+func Test_streamWatchRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		sub  Subscribe
+		want types.WatchRequest
+	}{
+		{
+			name: "type only",
+			sub:  Subscribe{ResourceType: "recorded-resource"},
+			want: types.WatchRequest{ResourceType: "recorded-resource"},
+		},
+		{
+			name: "namespace, selector and resource version",
+			sub: Subscribe{
+				ResourceType:    "recorded-resource",
+				Namespace:       "default",
+				Selector:        "app=web",
+				ResourceVersion: "42",
+			},
+			want: types.WatchRequest{
+				ResourceType: "recorded-resource",
+				Namespace:    "default",
+				Selector:     "app=web",
+				Revision:     "42",
+			},
+		},
+		{
+			name: "ID and selector",
+			sub: Subscribe{
+				ResourceType: "recorded-resource",
+				Namespace:    "default",
+				ID:           "default/web",
+				Selector:     "app=web",
+			},
+			want: types.WatchRequest{
+				ResourceType: "recorded-resource",
+				Namespace:    "default",
+				ID:           "default/web",
+				Selector:     "app=web",
+			},
+		},
+		{
+			name: "all filters",
+			sub: Subscribe{
+				ResourceType:    "recorded-resource",
+				Namespace:       "kube-system",
+				ID:              "kube-system/dns",
+				Selector:        "k8s-app=kube-dns",
+				FieldSelector:   "status.phase=Running",
+				ResourceVersion: "7",
+			},
+			want: types.WatchRequest{
+				ResourceType:  "recorded-resource",
+				Namespace:     "kube-system",
+				ID:            "kube-system/dns",
+				Selector:      "k8s-app=kube-dns",
+				FieldSelector: "status.phase=Running",
+				Revision:      "7",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &recordingStore{}
+			ws := WatchSession{
+				apiOp: &types.APIRequest{
+					Name: "test",
+					Schemas: &types.APISchemas{
+						Schemas: map[string]*types.APISchema{
+							"recorded-resource": {
+								Schema: &schemas.Schema{
+									ID: "recorded-resource",
+								},
+								Store: store,
+							},
+						},
+					},
+					Request:       &http.Request{},
+					AccessControl: &mockAC{hasAccess: true},
+				},
+				getter: DefaultGetter,
+			}
+
+			result := make(chan types.APIEvent, 1)
+			assert.NoError(t, ws.stream(context.TODO(), tt.sub, result))
+			assert.Equal(t, tt.want, store.request)
+			assert.Equal(t, tt.sub.Namespace, store.namespace)
+		})
+	}
+}
+
+// recordingStore records the filters of the last watch it was asked for.
+type recordingStore struct {
+	mockStore
+	request   types.WatchRequest
+	namespace string
+}
+
+func (r *recordingStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	r.request = w
+	r.namespace = apiOp.Namespace
+	c := make(chan types.APIEvent)
+	close(c)
+	return c, nil
+}
+
+type burstStore struct {
+	mockStore
+	events int
+}
+
+func (b *burstStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	c := make(chan types.APIEvent, b.events)
+	for i := 0; i < b.events; i++ {
+		c <- types.APIEvent{Name: types.ChangeAPIEvent}
+	}
+	close(c)
+	return c, nil
+}
+
 type mockStore struct{}
 
 func (m *mockStore) ByID(apiOp *types.APIRequest, schema *types.APISchema, id string) (types.APIObject, error) {
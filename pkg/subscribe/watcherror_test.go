@@ -0,0 +1,163 @@
+package subscribe
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errWatchBroken = errors.New("watch of pods failed: etcd cluster is unavailable")
+
+func Test_streamStoreError(t *testing.T) {
+	ws := WatchSession{
+		apiOp: &types.APIRequest{
+			Name:          "test",
+			Schemas:       brokenSchemas(),
+			Request:       &http.Request{},
+			AccessControl: &mockAC{hasAccess: true},
+		},
+		getter: DefaultGetter,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result := make(chan types.APIEvent, 10)
+	err := ws.stream(ctx, Subscribe{ResourceType: "broken-resource"}, result)
+	require.NoError(t, err)
+	close(result)
+
+	var names []string
+	for event := range result {
+		names = append(names, event.Name)
+	}
+	assert.Equal(t, []string{"resource.start", types.ChangeAPIEvent, "resource.error"}, names)
+}
+
+func TestWebsocketStoreError(t *testing.T) {
+	handler := NewHandler(DefaultGetter, "v1")
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = handler(&types.APIRequest{
+			Request:       req,
+			Response:      rw,
+			Schemas:       brokenSchemas(),
+			AccessControl: &mockAC{hasAccess: true},
+		})
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	require.NoError(t, conn.WriteJSON(Subscribe{ResourceType: "broken-resource"}))
+	var events []brokenEvent
+	for len(events) == 0 || events[len(events)-1].Name != "resource.stop" {
+		_, message, err := conn.ReadMessage()
+		require.NoError(t, err, "the watch must end after the error")
+		var event brokenEvent
+		require.NoError(t, json.Unmarshal(message, &event))
+		events = append(events, event)
+	}
+	assertBrokenEvents(t, events)
+}
+
+func TestSSEStoreError(t *testing.T) {
+	handler := NewHandler(DefaultGetter, "v1")
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = handler(&types.APIRequest{
+			Request:       req,
+			Response:      rw,
+			Schemas:       brokenSchemas(),
+			AccessControl: &mockAC{hasAccess: true},
+		})
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?resourceType=broken-resource", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// the stream ends after the error, so the body is read to its end
+	var events []brokenEvent
+	lines := bufio.NewScanner(resp.Body)
+	for lines.Scan() {
+		data, ok := strings.CutPrefix(lines.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event brokenEvent
+		require.NoError(t, json.Unmarshal([]byte(data), &event))
+		if event.Name != "ping" {
+			events = append(events, event)
+		}
+	}
+	require.NoError(t, lines.Err())
+	assertBrokenEvents(t, events)
+}
+
+type brokenEvent struct {
+	Name         string                 `json:"name"`
+	ResourceType string                 `json:"resourceType"`
+	Data         map[string]interface{} `json:"data"`
+}
+
+func assertBrokenEvents(t *testing.T, events []brokenEvent) {
+	t.Helper()
+	var names []string
+	for _, event := range events {
+		names = append(names, event.Name)
+	}
+	require.Equal(t, []string{"resource.start", types.ChangeAPIEvent, "resource.error", "resource.stop"}, names)
+
+	event := events[2]
+	assert.Equal(t, "broken-resource", event.ResourceType)
+	assert.Equal(t, errWatchBroken.Error(), event.Data["error"])
+}
+
+func brokenSchemas() *types.APISchemas {
+	return &types.APISchemas{
+		Schemas: map[string]*types.APISchema{
+			"broken-resource": {
+				Schema: &schemas.Schema{ID: "broken-resource"},
+				Store:  &brokenStore{},
+			},
+		},
+	}
+}
+
+// brokenStore sends a change, then an error, and then ends its watch, as a store whose connection to its backend
+// was lost.
+type brokenStore struct {
+	mockStore
+}
+
+func (b *brokenStore) Watch(apiOp *types.APIRequest, schema *types.APISchema, w types.WatchRequest) (chan types.APIEvent, error) {
+	c := make(chan types.APIEvent, 2)
+	c <- types.APIEvent{
+		Name:         types.ChangeAPIEvent,
+		ResourceType: "broken-resource",
+		Object:       types.APIObject{Type: "broken-resource", Object: map[string]interface{}{"name": "web"}},
+	}
+	c <- types.APIEvent{Error: errWatchBroken}
+	close(c)
+	return c, nil
+}
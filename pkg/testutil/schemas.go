@@ -0,0 +1,101 @@
+// Package testutil helps writing tests of code built on the API server, such as stores and handlers, by building
+// the schemas and requests they are called with.
+package testutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/apiserver/pkg/urlbuilder"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+)
+
+// Prefix is the URL prefix of the requests built by Request.
+const Prefix = "v1"
+
+// SchemaSpec describes a schema added by Schemas.
+type SchemaSpec struct {
+	// ID is the type of the schema, such as "pod".
+	ID string
+	// Store serves the resources of the schema. It may be nil for schemas that are only referenced.
+	Store types.Store
+	// CollectionMethods and ResourceMethods are the HTTP methods allowed on the collection and on the resources of
+	// the schema. If both are nil, GET is allowed on both, making the schema read-only.
+	CollectionMethods []string
+	ResourceMethods   []string
+}
+
+// Schemas returns APISchemas holding a schema for each spec. The test fails if a schema can not be added.
+func Schemas(t testing.TB, specs ...SchemaSpec) *types.APISchemas {
+	t.Helper()
+
+	result := types.EmptyAPISchemas()
+	for _, spec := range specs {
+		collectionMethods, resourceMethods := spec.CollectionMethods, spec.ResourceMethods
+		if collectionMethods == nil && resourceMethods == nil {
+			collectionMethods = []string{http.MethodGet}
+			resourceMethods = []string{http.MethodGet}
+		}
+		err := result.AddSchema(types.APISchema{
+			Schema: &schemas.Schema{
+				ID:                spec.ID,
+				CollectionMethods: collectionMethods,
+				ResourceMethods:   resourceMethods,
+			},
+			Store: spec.Store,
+		})
+		if err != nil {
+			t.Fatalf("failed to add schema %s: %v", spec.ID, err)
+		}
+	}
+	return result
+}
+
+// Request returns a request for method on the resource of type typeName with the given name, or on the collection
+// of the type if name is empty, as parsed by the server. Its response is recorded by an httptest.ResponseRecorder
+// and everything is allowed by its AccessControl. The test fails if the type is not in apiSchemas.
+func Request(t testing.TB, apiSchemas *types.APISchemas, method, typeName, name string) *types.APIRequest {
+	t.Helper()
+
+	schema := apiSchemas.LookupSchema(typeName)
+	if schema == nil {
+		t.Fatalf("unknown type %s", typeName)
+	}
+
+	target := "/" + Prefix + "/" + schema.PluralName
+	if name != "" {
+		target += "/" + name
+	}
+	req := httptest.NewRequest(method, target, nil)
+	builder, err := urlbuilder.NewPrefixed(req, apiSchemas, Prefix)
+	if err != nil {
+		t.Fatalf("failed to create URL builder: %v", err)
+	}
+
+	return &types.APIRequest{
+		Type:          schema.ID,
+		Name:          name,
+		Method:        method,
+		Schema:        schema,
+		Schemas:       apiSchemas,
+		Query:         req.URL.Query(),
+		Request:       req,
+		Response:      httptest.NewRecorder(),
+		URLBuilder:    builder,
+		AccessControl: AllowAll{},
+	}
+}
+
+// AllowAll is an AccessControl allowing every request.
+type AllowAll struct{}
+
+func (AllowAll) CanAction(*types.APIRequest, *types.APISchema, string) error          { return nil }
+func (AllowAll) CanCreate(*types.APIRequest, *types.APISchema) error                  { return nil }
+func (AllowAll) CanList(*types.APIRequest, *types.APISchema) error                    { return nil }
+func (AllowAll) CanGet(*types.APIRequest, *types.APISchema) error                     { return nil }
+func (AllowAll) CanUpdate(*types.APIRequest, types.APIObject, *types.APISchema) error { return nil }
+func (AllowAll) CanDelete(*types.APIRequest, types.APIObject, *types.APISchema) error { return nil }
+func (AllowAll) CanWatch(*types.APIRequest, *types.APISchema) error                   { return nil }
+func (AllowAll) CanDo(*types.APIRequest, string, string, string, string) error        { return nil }
@@ -0,0 +1,80 @@
+package testutil_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/handlers"
+	"github.com/rancher/apiserver/pkg/server"
+	"github.com/rancher/apiserver/pkg/store/memory"
+	"github.com/rancher/apiserver/pkg/testutil"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemas(t *testing.T) {
+	store := memory.New()
+	apiSchemas := testutil.Schemas(t,
+		testutil.SchemaSpec{ID: "pod", Store: store},
+		testutil.SchemaSpec{
+			ID:                "secret",
+			CollectionMethods: []string{http.MethodGet, http.MethodPost},
+			ResourceMethods:   []string{http.MethodGet, http.MethodDelete},
+		},
+	)
+
+	pod := apiSchemas.LookupSchema("pods")
+	require.NotNil(t, pod)
+	assert.Equal(t, "pod", pod.ID)
+	assert.Equal(t, store, pod.Store)
+	assert.Equal(t, []string{http.MethodGet}, pod.CollectionMethods)
+	assert.Equal(t, []string{http.MethodGet}, pod.ResourceMethods)
+
+	secret := apiSchemas.LookupSchema("secret")
+	require.NotNil(t, secret)
+	assert.Nil(t, secret.Store)
+	assert.Equal(t, []string{http.MethodGet, http.MethodPost}, secret.CollectionMethods)
+	assert.Equal(t, []string{http.MethodGet, http.MethodDelete}, secret.ResourceMethods)
+}
+
+// TestStore shows how a store is tested against requests built from the schemas.
+func TestStore(t *testing.T) {
+	store := memory.New()
+	apiSchemas := testutil.Schemas(t, testutil.SchemaSpec{ID: "pod", Store: store})
+
+	apiOp := testutil.Request(t, apiSchemas, http.MethodPost, "pod", "")
+	_, err := store.Create(apiOp, apiOp.Schema, types.APIObject{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "web", "namespace": "default"},
+	}})
+	require.NoError(t, err)
+
+	apiOp = testutil.Request(t, apiSchemas, http.MethodGet, "pod", "default/web")
+	obj, err := handlers.ByIDHandler(apiOp)
+	require.NoError(t, err)
+	assert.Equal(t, "default/web", obj.ID)
+	assert.Equal(t, "http://example.com/v1/pods/default/web", apiOp.URLBuilder.ResourceLink(apiOp.Schema, obj.ID))
+
+	apiOp = testutil.Request(t, apiSchemas, http.MethodGet, "pod", "")
+	list, err := handlers.ListHandler(apiOp)
+	require.NoError(t, err)
+	assert.Len(t, list.Objects, 1)
+}
+
+// TestServer shows how the schemas are served, to test the responses of the server.
+func TestServer(t *testing.T) {
+	store := memory.New()
+	srv := server.DefaultAPIServer()
+	srv.Schemas.MustAddSchemas(testutil.Schemas(t, testutil.SchemaSpec{ID: "pod", Store: store}))
+
+	apiOp := testutil.Request(t, srv.Schemas, http.MethodGet, "pod", "")
+	srv.Handle(apiOp)
+
+	rw := apiOp.Response.(*httptest.ResponseRecorder)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	var collection types.Collection
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &collection))
+	assert.Equal(t, "pod", collection.ResourceType)
+}
@@ -25,6 +25,23 @@ func YAMLEncoder(writer io.Writer, v interface{}) error {
 }
 
 func JSONLinesEncoder(writer io.Writer, v interface{}) error {
+	if err := encodeJSONLines(writer, v); err != nil {
+		return err
+	}
+
+	// a blank newline at the end indicates the complete response was returned, if this is absent an error occurred in the middle of encoding
+	_, err := writer.Write([]byte("\n"))
+	return err
+}
+
+// StrictJSONLinesEncoder encodes like JSONLinesEncoder, without the blank line marking the end of the response, for
+// clients whose NDJSON parser rejects empty lines. These clients can not tell a truncated response from a complete
+// one.
+func StrictJSONLinesEncoder(writer io.Writer, v interface{}) error {
+	return encodeJSONLines(writer, v)
+}
+
+func encodeJSONLines(writer io.Writer, v interface{}) error {
 	if collection, ok := v.(*GenericCollection); ok {
 		encoder := json.NewEncoder(writer)
 
@@ -41,14 +58,9 @@ func JSONLinesEncoder(writer io.Writer, v interface{}) error {
 				return err
 			}
 		}
-	} else {
-		// if we receive a type that is not a collection fall back to standard json encoding
-		if err := json.NewEncoder(writer).Encode(v); err != nil {
-			return err
-		}
+		return nil
 	}
 
-	// a blank newline at the end indicates the complete response was returned, if this is absent an error occurred in the middle of encoding
-	_, err := writer.Write([]byte("\n"))
-	return err
+	// if we receive a type that is not a collection fall back to standard json encoding
+	return json.NewEncoder(writer).Encode(v)
 }
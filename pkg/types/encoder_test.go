@@ -63,3 +63,51 @@ func TestJSONLinesEncoder(t *testing.T) {
 		})
 	}
 }
+
+func TestStrictJSONLinesEncoder(t *testing.T) {
+	collection := types.Collection{
+		Links:        map[string]string{},
+		Actions:      map[string]string{},
+		ResourceType: "Test",
+	}
+
+	tests := []struct {
+		name       string
+		v          interface{}
+		wantWriter string
+		wantErr    bool
+	}{
+		{
+			name:       "empty collection list",
+			v:          &types.GenericCollection{collection, []*types.RawResource{}},
+			wantWriter: "{\"links\":{},\"actions\":{},\"resourceType\":\"Test\"}\n",
+		},
+		{
+			name:       "valid collection list",
+			v:          &types.GenericCollection{collection, []*types.RawResource{{}, {}}},
+			wantWriter: "{\"links\":{},\"actions\":{},\"resourceType\":\"Test\"}\n{\"links\":null}\n{\"links\":null}\n",
+		},
+		{
+			name:       "arbitrary type",
+			v:          "foobarbaz",
+			wantWriter: "\"foobarbaz\"\n",
+		},
+		{
+			name:    "invalid type",
+			v:       func() {},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &bytes.Buffer{}
+			err := types.StrictJSONLinesEncoder(writer, tt.v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("StrictJSONLinesEncoder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotWriter := writer.String(); gotWriter != tt.wantWriter {
+				t.Errorf("StrictJSONLinesEncoder() gotWriter = %q, want %q", gotWriter, tt.wantWriter)
+			}
+		})
+	}
+}
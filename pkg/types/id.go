@@ -0,0 +1,27 @@
+package types
+
+// IDExtractor computes the ID of an object whose APIObject.ID is not set.
+type IDExtractor interface {
+	// ExtractID returns the ID of obj, or an empty string if it has none.
+	ExtractID(obj APIObject) string
+}
+
+// IDExtractorFunc adapts a function to an IDExtractor.
+type IDExtractorFunc func(obj APIObject) string
+
+func (f IDExtractorFunc) ExtractID(obj APIObject) string {
+	return f(obj)
+}
+
+// MetadataIDExtractor takes the ID of an object from metadata.name, prefixed with metadata.namespace and a slash
+// for namespaced objects.
+var MetadataIDExtractor IDExtractor = IDExtractorFunc(func(obj APIObject) string {
+	name := obj.Name()
+	if name == "" {
+		return ""
+	}
+	if namespace := obj.Namespace(); namespace != "" {
+		return namespace + "/" + name
+	}
+	return name
+})
@@ -0,0 +1,53 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestMetadataIDExtractor(t *testing.T) {
+	tests := []struct {
+		name   string
+		object interface{}
+		want   string
+	}{
+		{
+			name: "namespaced",
+			object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "web", "namespace": "default"},
+			},
+			want: "default/web",
+		},
+		{
+			name: "cluster scoped",
+			object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "node-1"},
+			},
+			want: "node-1",
+		},
+		{
+			name: "unstructured",
+			object: &unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "web", "namespace": "kube-system"},
+			}},
+			want: "kube-system/web",
+		},
+		{
+			name: "namespace without name",
+			object: map[string]interface{}{
+				"metadata": map[string]interface{}{"namespace": "default"},
+			},
+		},
+		{
+			name:   "no metadata",
+			object: map[string]interface{}{"foo": "bar"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MetadataIDExtractor.ExtractID(APIObject{Object: tt.object}))
+		})
+	}
+}
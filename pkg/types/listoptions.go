@@ -0,0 +1,161 @@
+package types
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/rancher/wrangler/v3/pkg/data"
+	"github.com/rancher/wrangler/v3/pkg/data/convert"
+)
+
+// ListOptions are the filters, sort order and page of a list request, as parsed from its query. Stores able to
+// apply them while listing, in a database query for instance, set ListOptionsApplied on the list they return;
+// otherwise the writer applies them to the returned objects.
+type ListOptions struct {
+	Filters []ListFilter `json:"filters,omitempty"`
+	Sort    []ListSort   `json:"sort,omitempty"`
+	// Page is the 1-based page returned when PageSize is set.
+	Page     int `json:"page,omitempty"`
+	PageSize int `json:"pageSize,omitempty"`
+}
+
+// ListFilter keeps the objects whose field compares to Value according to Modifier, ModifierEQ or ModifierNE.
+// Values are compared in their string form, and a missing field is not equal to any value.
+type ListFilter struct {
+	Field    []string     `json:"field"`
+	Modifier ModifierType `json:"modifier,omitempty"`
+	Value    string       `json:"value"`
+}
+
+// ListSort orders objects by a field, numerically if the values of both objects are numbers.
+type ListSort struct {
+	Field      []string `json:"field"`
+	Descending bool     `json:"descending,omitempty"`
+}
+
+// Match returns whether obj passes every filter. It is safe to call on nil options.
+func (o *ListOptions) Match(obj APIObject) bool {
+	if o == nil {
+		return true
+	}
+	d := obj.Data()
+	for _, filter := range o.Filters {
+		value, ok := fieldValue(d, filter.Field)
+		equal := ok && value == filter.Value
+		if equal == (filter.Modifier == ModifierNE) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns list with its objects filtered, sorted and paged according to the options. When a page is
+// requested, Count is set to the number of matching objects and Pages to the number of pages. It is safe to call on
+// nil options.
+func (o *ListOptions) Apply(list APIObjectList) APIObjectList {
+	if o == nil || (len(o.Filters) == 0 && len(o.Sort) == 0 && o.PageSize <= 0) {
+		return list
+	}
+
+	objects := make([]APIObject, 0, len(list.Objects))
+	for _, obj := range list.Objects {
+		if o.Match(obj) {
+			objects = append(objects, obj)
+		}
+	}
+
+	if len(o.Sort) > 0 {
+		keys := make([]data.Object, len(objects))
+		for i := range objects {
+			keys[i] = objects[i].Data()
+		}
+		sort.Stable(&sortedObjects{objects: objects, data: keys, sort: o.Sort})
+	}
+
+	if o.PageSize > 0 {
+		list.Count = len(objects)
+		list.Pages = (len(objects) + o.PageSize - 1) / o.PageSize
+		page := o.Page
+		if page < 1 {
+			page = 1
+		}
+		start := min((page-1)*o.PageSize, len(objects))
+		end := min(start+o.PageSize, len(objects))
+		objects = objects[start:end]
+	}
+
+	list.Objects = objects
+	return list
+}
+
+func fieldValue(d data.Object, field []string) (string, bool) {
+	value, ok := data.GetValue(d, field...)
+	if !ok || value == nil {
+		return "", false
+	}
+	return convert.ToString(value), true
+}
+
+type sortedObjects struct {
+	objects []APIObject
+	data    []data.Object
+	sort    []ListSort
+}
+
+func (s *sortedObjects) Len() int {
+	return len(s.objects)
+}
+
+func (s *sortedObjects) Swap(i, j int) {
+	s.objects[i], s.objects[j] = s.objects[j], s.objects[i]
+	s.data[i], s.data[j] = s.data[j], s.data[i]
+}
+
+func (s *sortedObjects) Less(i, j int) bool {
+	for _, by := range s.sort {
+		c := compareFields(s.data[i], s.data[j], by.Field)
+		if c == 0 {
+			continue
+		}
+		if by.Descending {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
+}
+
+// compareFields compares the field of a and b, objects missing the field sorting first.
+func compareFields(a, b data.Object, field []string) int {
+	left, leftOK := fieldValue(a, field)
+	right, rightOK := fieldValue(b, field)
+	switch {
+	case !leftOK || !rightOK:
+		return boolInt(leftOK) - boolInt(rightOK)
+	case left == right:
+		return 0
+	}
+
+	leftNumber, leftErr := strconv.ParseFloat(left, 64)
+	rightNumber, rightErr := strconv.ParseFloat(right, 64)
+	if leftErr == nil && rightErr == nil {
+		switch {
+		case leftNumber < rightNumber:
+			return -1
+		case leftNumber > rightNumber:
+			return 1
+		}
+		return 0
+	}
+	if left < right {
+		return -1
+	}
+	return 1
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListOptionsApply(t *testing.T) {
+	newList := func() APIObjectList {
+		return APIObjectList{Objects: []APIObject{
+			{ID: "web", Object: map[string]interface{}{"name": "web", "spec": map[string]interface{}{"replicas": 3, "tier": "front"}}},
+			{ID: "api", Object: map[string]interface{}{"name": "api", "spec": map[string]interface{}{"replicas": 10, "tier": "back"}}},
+			{ID: "db", Object: map[string]interface{}{"name": "db", "spec": map[string]interface{}{"replicas": 1, "tier": "back"}}},
+			{ID: "cron", Object: map[string]interface{}{"name": "cron"}},
+		}}
+	}
+	ids := func(list APIObjectList) []string {
+		var result []string
+		for _, obj := range list.Objects {
+			result = append(result, obj.ID)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name      string
+		opts      *ListOptions
+		want      []string
+		wantCount int
+		wantPages int
+	}{
+		{
+			name: "nil options",
+			want: []string{"web", "api", "db", "cron"},
+		},
+		{
+			name: "equal filter",
+			opts: &ListOptions{Filters: []ListFilter{{Field: []string{"spec", "tier"}, Modifier: ModifierEQ, Value: "back"}}},
+			want: []string{"api", "db"},
+		},
+		{
+			name: "not equal filter keeps objects missing the field",
+			opts: &ListOptions{Filters: []ListFilter{{Field: []string{"spec", "tier"}, Modifier: ModifierNE, Value: "back"}}},
+			want: []string{"web", "cron"},
+		},
+		{
+			name: "filters are combined",
+			opts: &ListOptions{Filters: []ListFilter{
+				{Field: []string{"spec", "tier"}, Modifier: ModifierEQ, Value: "back"},
+				{Field: []string{"spec", "replicas"}, Modifier: ModifierEQ, Value: "10"},
+			}},
+			want: []string{"api"},
+		},
+		{
+			name: "numeric sort",
+			opts: &ListOptions{Sort: []ListSort{{Field: []string{"spec", "replicas"}}}},
+			want: []string{"cron", "db", "web", "api"},
+		},
+		{
+			name: "descending sort",
+			opts: &ListOptions{Sort: []ListSort{{Field: []string{"name"}, Descending: true}}},
+			want: []string{"web", "db", "cron", "api"},
+		},
+		{
+			name: "secondary sort",
+			opts: &ListOptions{Sort: []ListSort{{Field: []string{"spec", "tier"}}, {Field: []string{"name"}}}},
+			want: []string{"cron", "api", "db", "web"},
+		},
+		{
+			name:      "page",
+			opts:      &ListOptions{Sort: []ListSort{{Field: []string{"name"}}}, Page: 2, PageSize: 3},
+			want:      []string{"web"},
+			wantCount: 4,
+			wantPages: 2,
+		},
+		{
+			name:      "page past the end",
+			opts:      &ListOptions{Page: 3, PageSize: 3},
+			wantCount: 4,
+			wantPages: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.opts.Apply(newList())
+			assert.Equal(t, tt.want, ids(got))
+			assert.Equal(t, tt.wantCount, got.Count)
+			assert.Equal(t, tt.wantPages, got.Pages)
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package types
+
+// JSONPatchContentType is the content type of request bodies holding a JSON Patch (RFC 6902).
+const JSONPatchContentType = "application/json-patch+json"
+
+// PatchOperation is a single operation of a JSON Patch.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
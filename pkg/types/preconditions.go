@@ -0,0 +1,67 @@
+package types
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/wrangler/v3/pkg/data"
+	"github.com/rancher/wrangler/v3/pkg/data/convert"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	meta2 "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Preconditions must be met by the current state of an object for a request to modify it. Empty fields are not
+// checked.
+type Preconditions struct {
+	UID             string `json:"uid,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// Check returns a Conflict error if obj does not match the preconditions. It is safe to call on nil preconditions.
+func (p *Preconditions) Check(obj APIObject) error {
+	if p == nil {
+		return nil
+	}
+
+	uid, resourceVersion := objectVersion(obj)
+	if p.UID != "" && p.UID != uid {
+		return apierror.NewAPIError(validation.Conflict,
+			fmt.Sprintf("Precondition failed: UID in precondition: %s, UID in object: %s", p.UID, uid))
+	}
+	if p.ResourceVersion != "" && p.ResourceVersion != resourceVersion {
+		return apierror.NewAPIError(validation.Conflict,
+			fmt.Sprintf("Precondition failed: ResourceVersion in precondition: %s, ResourceVersion in object: %s",
+				p.ResourceVersion, resourceVersion))
+	}
+	return nil
+}
+
+// CheckUnmodifiedSince returns a PreconditionFailed error if the request has an If-Unmodified-Since date and the
+// object it modifies, last modified at modified, changed after that date. HTTP dates have a precision of a second,
+// so modified is truncated to the second before it is compared.
+func (r *APIRequest) CheckUnmodifiedSince(modified time.Time) error {
+	if r.IfUnmodifiedSince.IsZero() || modified.IsZero() {
+		return nil
+	}
+	if modified.Truncate(time.Second).After(r.IfUnmodifiedSince) {
+		return apierror.NewAPIError(apierror.PreconditionFailed,
+			fmt.Sprintf("Precondition failed: object modified at %s, after %s",
+				modified.UTC().Format(http.TimeFormat), r.IfUnmodifiedSince.UTC().Format(http.TimeFormat)))
+	}
+	return nil
+}
+
+func objectVersion(obj APIObject) (string, string) {
+	if ro, ok := obj.Object.(runtime.Object); ok {
+		meta, err := meta2.Accessor(ro)
+		if err == nil {
+			return string(meta.GetUID()), meta.GetResourceVersion()
+		}
+	}
+	d := obj.Data()
+	return convert.ToString(data.GetValueN(d, "metadata", "uid")),
+		convert.ToString(data.GetValueN(d, "metadata", "resourceVersion"))
+}
@@ -0,0 +1,114 @@
+package types
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPreconditionsCheck(t *testing.T) {
+	objects := map[string]APIObject{
+		"map": {Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"uid": "123", "resourceVersion": "5"},
+		}},
+		"runtime object": {Object: &unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"uid": "123", "resourceVersion": "5"},
+		}}},
+	}
+
+	tests := []struct {
+		name          string
+		preconditions *Preconditions
+		wantConflict  bool
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name:          "matching",
+			preconditions: &Preconditions{UID: "123", ResourceVersion: "5"},
+		},
+		{
+			name:          "matching uid only",
+			preconditions: &Preconditions{UID: "123"},
+		},
+		{
+			name:          "mismatched uid",
+			preconditions: &Preconditions{UID: "456", ResourceVersion: "5"},
+			wantConflict:  true,
+		},
+		{
+			name:          "mismatched resource version",
+			preconditions: &Preconditions{ResourceVersion: "4"},
+			wantConflict:  true,
+		},
+	}
+	for kind, obj := range objects {
+		for _, test := range tests {
+			t.Run(kind+" "+test.name, func(t *testing.T) {
+				err := test.preconditions.Check(obj)
+				if !test.wantConflict {
+					assert.NoError(t, err)
+					return
+				}
+				require.Error(t, err)
+				assert.Equal(t, validation.Conflict, err.(*apierror.APIError).Code)
+			})
+		}
+	}
+}
+
+func TestCheckUnmodifiedSince(t *testing.T) {
+	since := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		since      time.Time
+		modified   time.Time
+		wantFailed bool
+	}{
+		{
+			name:     "no header",
+			modified: since.Add(time.Hour),
+		},
+		{
+			name:     "modified before",
+			since:    since,
+			modified: since.Add(-time.Minute),
+		},
+		{
+			name:     "modified within the same second",
+			since:    since,
+			modified: since.Add(500 * time.Millisecond),
+		},
+		{
+			name:       "modified after",
+			since:      since,
+			modified:   since.Add(time.Second),
+			wantFailed: true,
+		},
+		{
+			name:  "unknown modification time",
+			since: since,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			apiOp := &APIRequest{IfUnmodifiedSince: test.since}
+			err := apiOp.CheckUnmodifiedSince(test.modified)
+			if !test.wantFailed {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, apierror.PreconditionFailed, err.(*apierror.APIError).Code)
+			assert.Equal(t, http.StatusPreconditionFailed, err.(*apierror.APIError).Code.Status)
+		})
+	}
+}
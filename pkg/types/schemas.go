@@ -2,16 +2,33 @@ package types
 
 import (
 	"strings"
+	"sync"
 
 	"github.com/rancher/wrangler/v3/pkg/schemas"
 	"github.com/sirupsen/logrus"
 )
 
+// APISchemas is a set of schemas. Its methods are safe to call concurrently, so schemas can be added while
+// requests are being served.
 type APISchemas struct {
 	InternalSchemas *schemas.Schemas
-	Schemas         map[string]*APISchema
-	index           map[string]*APISchema
-	Attributes      map[string]interface{}
+	// Schemas holds the schemas by ID. Reading it directly is not safe while schemas are being added, use
+	// LookupSchema or All instead.
+	Schemas    map[string]*APISchema
+	index      map[string]*APISchema
+	aliases    map[string]SchemaAlias
+	Attributes map[string]interface{}
+
+	lock sync.RWMutex
+}
+
+// SchemaAlias maps a type name that is no longer in use to the schema now serving it.
+type SchemaAlias struct {
+	// Target is the ID of the canonical schema.
+	Target string
+	// Redirect makes requests to the alias receive a permanent redirect to the canonical URL instead of being
+	// served by the canonical schema directly.
+	Redirect bool
 }
 
 func EmptyAPISchemas() *APISchemas {
@@ -22,7 +39,20 @@ func EmptyAPISchemas() *APISchemas {
 	}
 }
 
+// All returns a copy of the schemas by ID.
+func (a *APISchemas) All() map[string]*APISchema {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	result := make(map[string]*APISchema, len(a.Schemas))
+	for k, v := range a.Schemas {
+		result[k] = v
+	}
+	return result
+}
+
 func (a *APISchemas) ShallowCopy() *APISchemas {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
 	result := &APISchemas{
 		InternalSchemas: a.InternalSchemas,
 		Schemas:         map[string]*APISchema{},
@@ -34,6 +64,12 @@ func (a *APISchemas) ShallowCopy() *APISchemas {
 	for k, v := range a.index {
 		result.index[k] = v
 	}
+	if a.aliases != nil {
+		result.aliases = map[string]SchemaAlias{}
+		for k, v := range a.aliases {
+			result.aliases[k] = v
+		}
+	}
 	return result
 }
 
@@ -64,6 +100,8 @@ func (a *APISchemas) addInternalSchema(schema *schemas.Schema) *APISchema {
 }
 
 func (a *APISchemas) Import(obj interface{}) (*APISchema, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
 	schema, err := a.InternalSchemas.Import(obj)
 	if err != nil {
 		return nil, err
@@ -72,15 +110,35 @@ func (a *APISchemas) Import(obj interface{}) (*APISchema, error) {
 	return apiSchema, nil
 }
 
+// MustImportAndCustomize imports the schema of obj, adding it and its sub-types, and then lets f customize it. f
+// may look up the schema and its sub-types. It customizes a copy of the schema, which replaces the imported one once
+// f returns, so concurrent lookups see the schema as imported or as customized, never in between. InternalSchemas
+// keeps the schema as imported.
 func (a *APISchemas) MustImportAndCustomize(obj interface{}, f func(*APISchema)) {
+	a.lock.Lock()
 	schema, err := a.InternalSchemas.Import(obj)
 	if err != nil {
+		a.lock.Unlock()
 		panic(err)
 	}
-	apiSchema := a.addInternalSchema(schema)
-	if f != nil {
-		f(apiSchema)
+	imported := a.addInternalSchema(schema)
+	a.lock.Unlock()
+
+	if f == nil {
+		return
+	}
+	apiSchema := &APISchema{
+		Schema: schema.DeepCopy(),
+	}
+	f(apiSchema)
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.index[strings.ToLower(imported.PluralName)] == imported {
+		delete(a.index, strings.ToLower(imported.PluralName))
 	}
+	a.Schemas[apiSchema.ID] = apiSchema
+	a.addToIndex(apiSchema)
 }
 
 func (a *APISchemas) MustAddSchemas(schemas *APISchemas) *APISchemas {
@@ -91,7 +149,7 @@ func (a *APISchemas) MustAddSchemas(schemas *APISchemas) *APISchemas {
 }
 
 func (a *APISchemas) AddSchemas(schema *APISchemas) error {
-	for _, schema := range schema.Schemas {
+	for _, schema := range schema.All() {
 		if err := a.AddSchema(*schema); err != nil {
 			return err
 		}
@@ -105,6 +163,8 @@ func (a *APISchemas) addToIndex(schema *APISchema) {
 }
 
 func (a *APISchemas) AddSchema(schema APISchema) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
 	if err := a.InternalSchemas.AddSchema(*schema.Schema); err != nil {
 		return err
 	}
@@ -114,7 +174,44 @@ func (a *APISchemas) AddSchema(schema APISchema) error {
 	return nil
 }
 
+// AddAlias makes requests for the type alias be handled by the schema with the ID target. If redirect is true,
+// the client is redirected to the URL of target instead.
+func (a *APISchemas) AddAlias(alias, target string, redirect bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.aliases == nil {
+		a.aliases = map[string]SchemaAlias{}
+	}
+	a.aliases[strings.ToLower(alias)] = SchemaAlias{
+		Target:   target,
+		Redirect: redirect,
+	}
+}
+
+// LookupAlias returns the alias registered for name, if name does not match a schema itself.
+func (a *APISchemas) LookupAlias(name string) (SchemaAlias, bool) {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if a.lookupSchema(name) != nil {
+		return SchemaAlias{}, false
+	}
+	alias, ok := a.aliases[strings.ToLower(name)]
+	return alias, ok
+}
+
 func (a *APISchemas) LookupSchema(name string) *APISchema {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if s := a.lookupSchema(name); s != nil {
+		return s
+	}
+	if alias, ok := a.aliases[strings.ToLower(name)]; ok {
+		return a.lookupSchema(alias.Target)
+	}
+	return nil
+}
+
+func (a *APISchemas) lookupSchema(name string) *APISchema {
 	s, ok := a.Schemas[name]
 	if ok {
 		return s
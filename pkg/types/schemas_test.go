@@ -0,0 +1,107 @@
+package types
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupSchemaAlias(t *testing.T) {
+	s := EmptyAPISchemas()
+	s.MustAddSchema(APISchema{Schema: &schemas.Schema{ID: "thing", PluralName: "things"}})
+	s.MustAddSchema(APISchema{Schema: &schemas.Schema{ID: "other"}})
+	s.AddAlias("OldThing", "thing", true)
+	s.AddAlias("other", "thing", false)
+
+	assert.Equal(t, "thing", s.LookupSchema("oldthing").ID)
+	alias, ok := s.LookupAlias("oldThing")
+	assert.True(t, ok)
+	assert.Equal(t, SchemaAlias{Target: "thing", Redirect: true}, alias)
+
+	// an existing schema takes precedence over an alias of the same name
+	assert.Equal(t, "other", s.LookupSchema("other").ID)
+	_, ok = s.LookupAlias("other")
+	assert.False(t, ok)
+
+	assert.Nil(t, s.LookupSchema("missing"))
+	assert.Equal(t, "thing", s.ShallowCopy().LookupSchema("oldthing").ID)
+}
+
+type importedThing struct {
+	Name string `json:"name"`
+}
+
+func TestConcurrentSchemaAccess(t *testing.T) {
+	s := EmptyAPISchemas()
+	s.MustAddSchema(APISchema{Schema: &schemas.Schema{ID: "base", PluralName: "bases"}})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			s.MustAddSchema(APISchema{Schema: &schemas.Schema{ID: fmt.Sprintf("added%d", i)}})
+			s.AddAlias(fmt.Sprintf("alias%d", i), "base", false)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		s.MustImportAndCustomize(importedThing{}, func(schema *APISchema) {
+			schema.PluralName = "thingies"
+		})
+	}()
+
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, "base", s.LookupSchema("bases").ID)
+		s.LookupAlias(fmt.Sprintf("alias%d", i))
+		for id, schema := range s.All() {
+			assert.Equal(t, id, schema.ID)
+		}
+		// a schema is visible as imported or as customized, never in between
+		if schema := s.LookupSchema("importedThing"); schema != nil {
+			assert.Contains(t, []string{"importedThings", "thingies"}, schema.PluralName)
+		}
+		s.ShallowCopy()
+	}
+	wg.Wait()
+
+	assert.Len(t, s.All(), 102)
+	assert.Equal(t, "importedThing", s.LookupSchema("thingies").ID)
+}
+
+type thingSpec struct {
+	Size int `json:"size"`
+}
+
+type nestedThing struct {
+	Spec thingSpec `json:"spec"`
+}
+
+func TestMustImportAndCustomize(t *testing.T) {
+	s := EmptyAPISchemas()
+
+	var own, sub *APISchema
+	s.MustImportAndCustomize(nestedThing{}, func(schema *APISchema) {
+		own = s.LookupSchema("nestedThing")
+		sub = s.LookupSchema("thingSpec")
+		schema.PluralName = "nestedthingies"
+		schema.CollectionMethods = []string{"GET"}
+	})
+
+	// the callback can look up the schema and its sub-types, and does not change the one it looked up
+	if assert.NotNil(t, own) {
+		assert.Equal(t, "nestedThings", own.PluralName)
+	}
+	assert.NotNil(t, sub)
+
+	schema := s.LookupSchema("nestedthingies")
+	if assert.NotNil(t, schema) {
+		assert.Equal(t, "nestedThing", schema.ID)
+		assert.Equal(t, []string{"GET"}, schema.CollectionMethods)
+	}
+	assert.Same(t, schema, s.LookupSchema("nestedThing"))
+	assert.Nil(t, s.LookupSchema("nestedThings"))
+}
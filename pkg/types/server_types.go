@@ -3,13 +3,18 @@ package types
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"time"
 
+	"github.com/rancher/apiserver/pkg/apierror"
 	"github.com/rancher/wrangler/v3/pkg/data"
 	"github.com/rancher/wrangler/v3/pkg/data/convert"
 	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta2 "k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -17,7 +22,7 @@ import (
 	"k8s.io/apiserver/pkg/endpoints/request"
 )
 
-//go:generate mockgen -destination=../fakes/mock_server_types.go -package=fakes . ResponseWriter,AccessControl
+//go:generate mockgen -destination=../fakes/mock_server_types.go -package=fakes . ResponseWriter,AccessControl,Store
 type RawResource struct {
 	ID          string            `json:"id,omitempty" yaml:"id,omitempty"`
 	Type        string            `json:"type,omitempty" yaml:"type,omitempty"`
@@ -26,6 +31,8 @@ type RawResource struct {
 	Actions     map[string]string `json:"actions,omitempty" yaml:"actions,omitempty"`
 	ActionLinks bool              `json:"-" yaml:"-"`
 	APIObject   APIObject         `json:"-" yaml:"-"`
+	// ResourceMethods are the resource methods the caller is allowed to use. They are only set when requested.
+	ResourceMethods []string `json:"resourceMethods,omitempty" yaml:"resourceMethods,omitempty"`
 }
 
 type Pagination struct {
@@ -35,6 +42,12 @@ type Pagination struct {
 	Partial bool   `json:"partial,omitempty"`
 }
 
+// SummaryEntry counts the objects of a list by the values of one of their properties, for instance by state.
+type SummaryEntry struct {
+	Property string         `json:"property"`
+	Counts   map[string]int `json:"counts"`
+}
+
 func (r *RawResource) MarshalJSON() ([]byte, error) {
 	type r_ RawResource
 	outer, err := json.Marshal((*r_)(r))
@@ -73,8 +86,45 @@ type RequestHandler func(request *APIRequest) (APIObject, error)
 
 type RequestListHandler func(request *APIRequest) (APIObjectList, error)
 
+// DownloadHandler returns the raw content of the resource of the request, for resources that are really blobs.
+type DownloadHandler func(request *APIRequest) (Download, error)
+
+// Download is the raw content of a resource, written as is instead of being encoded.
+type Download struct {
+	// ContentType is the Content-Type of the response. If empty, application/octet-stream is used.
+	ContentType string
+	// Filename, if set, is the name under which clients save the content, sent in the Content-Disposition header.
+	Filename string
+	// Content is copied to the response, then closed. Content that is also an io.Seeker is served with support
+	// for range requests.
+	Content io.ReadCloser
+	// ModTime, if set, is the Last-Modified time of seekable content, used to answer conditional requests.
+	ModTime time.Time
+}
+
 type Formatter func(request *APIRequest, resource *RawResource)
 
+// OutputTransformer returns the object written in place of obj. Objects may be shared with the store, so they
+// must be copied rather than modified in place.
+type OutputTransformer func(request *APIRequest, obj APIObject) APIObject
+
+// Relationship is a reference from a resource to another resource.
+type Relationship struct {
+	// Rel is the name of the link to the related resource, such as "owner".
+	Rel string
+	// Type is the schema ID of the related resource.
+	Type string
+	// ID is the ID of the related resource.
+	ID string
+}
+
+// RelationshipResolver returns the resources referenced by obj, such as its owners.
+type RelationshipResolver func(request *APIRequest, obj APIObject) []Relationship
+
+// InputTransformer returns the object passed to the create or update handler in place of the decoded body obj.
+// Returning an error rejects the request.
+type InputTransformer func(request *APIRequest, obj APIObject) (APIObject, error)
+
 type RequestModifier func(request *APIRequest, schema *APISchema) *APISchema
 
 type CollectionFormatter func(request *APIRequest, collection *GenericCollection)
@@ -112,12 +162,33 @@ type APIRequest struct {
 	ErrorHandler   ErrorHandler
 	URLPrefix      string
 	URLBuilder     URLBuilder
+	// URLBuilderFunc, if set, is called by the parser to create the URLBuilder of the request, once its URL is
+	// parsed, instead of using the default builder.
+	URLBuilderFunc URLBuilderFunc
 	AccessControl  AccessControl
+	Files          []*UploadedFile
+	Preconditions  *Preconditions
+	// IfUnmodifiedSince is the date of the If-Unmodified-Since header of update and delete requests, zero if it is
+	// not set. Stores check it with CheckUnmodifiedSince.
+	IfUnmodifiedSince time.Time
+	// ListOptions are the filters, sort order and page asked for by a list request, nil if there are none.
+	ListOptions *ListOptions
+	// Patch is the JSON Patch sent as the body of an action request with the JSONPatchContentType.
+	Patch []PatchOperation
 
 	Request  *http.Request
 	Response http.ResponseWriter
 }
 
+// UploadedFile is a file sent as part of a multipart/form-data request body.
+type UploadedFile struct {
+	FieldName   string
+	Filename    string
+	ContentType string
+	Size        int64
+	Reader      io.Reader
+}
+
 type apiOpKey struct{}
 
 func GetAPIContext(ctx context.Context) *APIRequest {
@@ -175,6 +246,9 @@ func (r *APIRequest) WriteError(err error) {
 	r.ErrorHandler(r, err)
 }
 
+// URLBuilderFunc returns the URLBuilder of a request.
+type URLBuilderFunc func(apiOp *APIRequest) (URLBuilder, error)
+
 type URLBuilder interface {
 	Current() string
 
@@ -212,10 +286,23 @@ func DefaultByID(store Store, apiOp *APIRequest, schema *APISchema, id string) (
 	return APIObject{}, validation.NotFound
 }
 
+// WatchRequest holds the filters of a watch. All of the filters that are set apply, so a watch with both an ID and
+// a Selector only sends events for that object while it matches the selector.
 type WatchRequest struct {
+	// ResourceType is the schema ID of the watched objects.
+	ResourceType string
+	// Namespace limits the watch to the objects of a namespace. It matches the namespace of the request passed to
+	// Watch. If empty, objects of every namespace are watched.
+	Namespace string
+	// Revision is the revision to start watching from, usually the revision of a previous list. If empty, the
+	// watch starts from the current state.
 	Revision string
-	ID       string
+	// ID limits the watch to a single object.
+	ID string
+	// Selector is a label selector the watched objects must match.
 	Selector string
+	// FieldSelector is a field selector the watched objects must match, for stores that support it.
+	FieldSelector string
 }
 
 var (
@@ -243,6 +330,35 @@ type Warning struct {
 	Text  string
 }
 
+// ListError reports a part of a list, such as a namespace, that could not be listed while the rest of the list was.
+type ListError struct {
+	// Source is the part of the list that failed, for instance the name of the namespace.
+	Source  string `json:"source,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Status  int    `json:"status,omitempty"`
+	Message string `json:"message"`
+}
+
+// NewListError returns the ListError of source failing with err, keeping the code and status of APIErrors and
+// Kubernetes errors.
+func NewListError(source string, err error) ListError {
+	result := ListError{
+		Source:  source,
+		Message: err.Error(),
+	}
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) {
+		result.Code = apiErr.Code.Code
+		result.Status = apiErr.Code.Status
+		result.Message = apiErr.Message
+	} else if status, ok := err.(apierrors.APIStatus); ok {
+		result.Code = string(status.Status().Reason)
+		result.Status = int(status.Status().Code)
+		result.Message = status.Status().Message
+	}
+	return result
+}
+
 type APIObject struct {
 	Type     string
 	ID       string
@@ -255,8 +371,16 @@ type APIObjectList struct {
 	Continue string
 	Pages    int
 	Count    int
+	// Summary holds aggregate counts computed by the store. They are returned in the collection for every format.
+	Summary  []SummaryEntry
 	Objects  []APIObject
 	Warnings []Warning
+	// Errors are the non-fatal errors of a list that is only partially returned, such as a list across namespaces
+	// where some namespaces could not be listed. They are returned in the collection along with the objects.
+	Errors []ListError
+	// ListOptionsApplied is set by stores that already filtered, sorted and paged the objects according to the
+	// ListOptions of the request, so the writer does not apply them again.
+	ListOptionsApplied bool
 }
 
 func (a *APIObject) Data() data.Object {
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestNewListError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ListError
+	}{
+		{
+			name: "plain error",
+			err:  errors.New("connection refused"),
+			want: ListError{Source: "team-a", Message: "connection refused"},
+		},
+		{
+			name: "API error",
+			err:  fmt.Errorf("listing: %w", apierror.NewAPIError(validation.PermissionDenied, "can not list pods")),
+			want: ListError{Source: "team-a", Code: "PermissionDenied", Status: http.StatusForbidden, Message: "can not list pods"},
+		},
+		{
+			name: "Kubernetes error",
+			err:  apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "", errors.New("no access")),
+			want: ListError{Source: "team-a", Code: "Forbidden", Status: http.StatusForbidden, Message: `pods is forbidden: no access`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewListError("team-a", tt.err))
+		})
+	}
+}
@@ -17,6 +17,11 @@ type Collection struct {
 	Continue     string            `json:"continue,omitempty"`
 	Pages        int               `json:"pages,omitempty"`
 	Count        int               `json:"count,omitempty"`
+	Summary      []SummaryEntry    `json:"summary,omitempty"`
+	// Errors report the parts of a partial list that could not be listed.
+	Errors []ListError `json:"errors,omitempty"`
+	// CollectionMethods are the collection methods the caller is allowed to use. They are only set when requested.
+	CollectionMethods []string `json:"collectionMethods,omitempty"`
 }
 
 type GenericCollection struct {
@@ -80,6 +85,69 @@ type APISchema struct {
 	CollectionFormatter CollectionFormatter     `json:"-"`
 	ErrorHandler        ErrorHandler            `json:"-"`
 	Store               Store                   `json:"-"`
+	// QueryParameters are the query parameters understood by the handlers of this schema, in addition to the
+	// ones handled by the server itself.
+	QueryParameters []string `json:"-"`
+	// ActionRequestHandlers are action handlers whose result is written like any other response, using the
+	// format negotiated for the request. They take precedence over ActionHandlers of the same name.
+	ActionRequestHandlers map[string]RequestHandler `json:"-"`
+	// ActionConfigs holds optional settings for the actions of this schema, keyed by action name.
+	ActionConfigs map[string]ActionConfig `json:"-"`
+	// IDExtractor computes the ID of objects returned without one, such as Kubernetes-style objects carrying their
+	// identity in their metadata. If nil, objects without an ID are written without links.
+	IDExtractor IDExtractor `json:"-"`
+	// OutputMask lists fields the writer removes from the objects of this schema before they are returned.
+	OutputMask FieldMask `json:"-"`
+	// WatchRateLimit is the maximum number of events per second sent to each watch of this schema. Events over
+	// the rate are held back, and replaced by later events of the same object, so clients still end up with the
+	// latest state. If zero, watches are not rate limited.
+	WatchRateLimit float64 `json:"-"`
+	// NotWatchable declares that the resources of this schema can not be watched, for stores that can list them
+	// but have no way to follow their changes. Subscriptions to the schema fail with a NotWatchable error, as they
+	// do for schemas without a Store.
+	NotWatchable bool `json:"-"`
+	// Deprecated, if set, marks the schema as deprecated. It is the text of the Warning header added to responses
+	// returning resources of this schema, such as "v1 foos are deprecated, use v2 foos instead".
+	Deprecated string `json:"-"`
+	// DeprecatedFields marks fields of the schema as deprecated, mapping the field name to the text of the Warning
+	// header added to responses returning resources that have the field set.
+	DeprecatedFields map[string]string `json:"-"`
+	// DownloadHandler, if set, serves the raw content of the resources of this schema to GET requests by ID asking
+	// for the raw format, with _format=raw or an Accept header of application/octet-stream.
+	DownloadHandler DownloadHandler `json:"-"`
+	// HTMLView declares that the resources of this schema have a useful HTML representation, which the writer
+	// links to as the "view" link of each resource, so browsers can navigate to it.
+	HTMLView bool `json:"-"`
+	// OutputTransformer, if set, is applied by the writer to each resource of this schema before it is encoded,
+	// to redact values or add computed fields for instance. It runs before the Formatter.
+	OutputTransformer OutputTransformer `json:"-"`
+	// InputTransformer, if set, is applied to the decoded body of create and update requests of this schema, to
+	// normalize it or fill in defaults before the store sees it. Errors that are not APIErrors are returned as
+	// invalid body content.
+	InputTransformer InputTransformer `json:"-"`
+	// RelationshipResolver, if set, finds the resources referenced by each resource of this schema, such as the
+	// deployment owning a pod, which the writer links to under the name of the relationship. Relationships to
+	// types missing from the schemas of the request are skipped, and they never replace another link.
+	RelationshipResolver RelationshipResolver `json:"-"`
+}
+
+// FieldMask is a set of fields removed from responses by the server, regardless of what the client asked for.
+type FieldMask struct {
+	// Fields are the paths of the masked fields, such as {"status", "token"} for the token field of the status.
+	Fields [][]string
+	// Reads applies the mask to every response. By default, only the objects returned by create and update
+	// requests are masked.
+	Reads bool
+}
+
+// ActionConfig holds optional settings for an action of a schema.
+type ActionConfig struct {
+	// SkipInputValidation disables validating the request body against the input type of the action, for handlers
+	// that validate the input themselves.
+	SkipInputValidation bool
+	// Verb is the verb the action is authorized as, one of "get", "list", "watch", "create", "update", "patch" or
+	// "delete". If empty, the action is authorized with AccessControl.CanAction.
+	Verb string
 }
 
 func copyHandlers(m map[string]http.Handler) map[string]http.Handler {
@@ -97,6 +165,24 @@ func (a *APISchema) DeepCopy() *APISchema {
 	r := *a
 	r.ActionHandlers = copyHandlers(a.ActionHandlers)
 	r.LinkHandlers = copyHandlers(a.LinkHandlers)
+	if a.ActionRequestHandlers != nil {
+		r.ActionRequestHandlers = make(map[string]RequestHandler, len(a.ActionRequestHandlers))
+		for k, v := range a.ActionRequestHandlers {
+			r.ActionRequestHandlers[k] = v
+		}
+	}
+	if a.ActionConfigs != nil {
+		r.ActionConfigs = make(map[string]ActionConfig, len(a.ActionConfigs))
+		for k, v := range a.ActionConfigs {
+			r.ActionConfigs[k] = v
+		}
+	}
+	if a.DeprecatedFields != nil {
+		r.DeprecatedFields = make(map[string]string, len(a.DeprecatedFields))
+		for k, v := range a.DeprecatedFields {
+			r.DeprecatedFields[k] = v
+		}
+	}
 	r.Schema = r.Schema.DeepCopy()
 	return &r
 }
@@ -0,0 +1,137 @@
+package writer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// BufferedResponseWriter holds a response back until it is complete, so that it can be sent with a Content-Length
+// and an ETag, as long as it stays small and quick to produce. Once more than the threshold is written, more than
+// the delay has passed when writing, or the response is flushed, whatever was held back is sent and the rest of the
+// response is streamed, without a Content-Length or ETag.
+type BufferedResponseWriter struct {
+	rw        http.ResponseWriter
+	threshold int64
+	deadline  time.Time
+	status    int
+	buf       bytes.Buffer
+	streaming bool
+	hijacked  bool
+}
+
+// NewBufferedResponseWriter returns a BufferedResponseWriter that buffers up to threshold bytes written to rw. If
+// delay is positive, writes happening delay after the writer is created start streaming the response too.
+func NewBufferedResponseWriter(rw http.ResponseWriter, threshold int64, delay time.Duration) *BufferedResponseWriter {
+	b := &BufferedResponseWriter{
+		rw:        rw,
+		threshold: threshold,
+	}
+	if delay > 0 {
+		b.deadline = time.Now().Add(delay)
+	}
+	return b
+}
+
+func (b *BufferedResponseWriter) Header() http.Header {
+	return b.rw.Header()
+}
+
+func (b *BufferedResponseWriter) WriteHeader(statusCode int) {
+	if b.streaming {
+		b.rw.WriteHeader(statusCode)
+		return
+	}
+	if b.status == 0 {
+		b.status = statusCode
+	}
+}
+
+func (b *BufferedResponseWriter) Write(p []byte) (int, error) {
+	if !b.streaming {
+		if int64(b.buf.Len()+len(p)) <= b.threshold && (b.deadline.IsZero() || time.Now().Before(b.deadline)) {
+			return b.buf.Write(p)
+		}
+		if err := b.stream(); err != nil {
+			return 0, err
+		}
+	}
+	return b.rw.Write(p)
+}
+
+// Streaming returns whether the response is streamed rather than buffered.
+func (b *BufferedResponseWriter) Streaming() bool {
+	return b.streaming
+}
+
+// Close sends the response if it is still buffered, with its Content-Length and, for successful responses that do
+// not already have one, an ETag computed from the body.
+func (b *BufferedResponseWriter) Close() error {
+	if b.streaming || b.hijacked {
+		return nil
+	}
+	b.streaming = true
+
+	header := b.rw.Header()
+	header.Set("Content-Length", strconv.Itoa(b.buf.Len()))
+	status := b.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status >= 200 && status < 300 && header.Get("ETag") == "" {
+		sum := sha256.Sum256(b.buf.Bytes())
+		header.Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	}
+	b.rw.WriteHeader(status)
+	_, err := b.rw.Write(b.buf.Bytes())
+	b.buf.Reset()
+	return err
+}
+
+// stream sends the status and what was buffered, and passes later writes through.
+func (b *BufferedResponseWriter) stream() error {
+	b.streaming = true
+
+	header := b.rw.Header()
+	header.Del("Content-Length")
+	header.Del("ETag")
+	if b.status != 0 {
+		b.rw.WriteHeader(b.status)
+	}
+	if b.buf.Len() == 0 {
+		return nil
+	}
+	_, err := b.rw.Write(b.buf.Bytes())
+	b.buf.Reset()
+	return err
+}
+
+func (b *BufferedResponseWriter) Flush() {
+	if !b.streaming {
+		_ = b.stream()
+	}
+	if flusher, ok := b.rw.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped ResponseWriter, for http.ResponseController.
+func (b *BufferedResponseWriter) Unwrap() http.ResponseWriter {
+	return b.rw
+}
+
+func (b *BufferedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := b.rw.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("Upstream ResponseWriter of type %v does not implement http.Hijacker", reflect.TypeOf(b.rw))
+	}
+	b.hijacked = true
+	return hijacker.Hijack()
+}
@@ -0,0 +1,121 @@
+package writer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferedResponseWriterSmall(t *testing.T) {
+	rec := httptest.NewRecorder()
+	bw := NewBufferedResponseWriter(rec, 10, 0)
+
+	bw.Header().Set("Content-Type", "text/plain")
+	bw.WriteHeader(http.StatusCreated)
+	_, err := bw.Write([]byte("hello"))
+	require.NoError(t, err)
+	_, err = bw.Write([]byte("world"))
+	require.NoError(t, err)
+
+	// nothing is sent until the response is closed
+	assert.False(t, bw.Streaming())
+	assert.Empty(t, rec.Body.String())
+
+	require.NoError(t, bw.Close())
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "helloworld", rec.Body.String())
+	assert.Equal(t, "10", rec.Header().Get("Content-Length"))
+	etag := rec.Header().Get("ETag")
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+
+	// the same body gets the same ETag
+	again := httptest.NewRecorder()
+	bw = NewBufferedResponseWriter(again, 10, 0)
+	_, err = bw.Write([]byte("helloworld"))
+	require.NoError(t, err)
+	require.NoError(t, bw.Close())
+	assert.Equal(t, http.StatusOK, again.Code)
+	assert.Equal(t, etag, again.Header().Get("ETag"))
+}
+
+func TestBufferedResponseWriterKeepsETag(t *testing.T) {
+	rec := httptest.NewRecorder()
+	bw := NewBufferedResponseWriter(rec, 10, 0)
+
+	bw.Header().Set("ETag", `"v42"`)
+	_, err := bw.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, bw.Close())
+	assert.Equal(t, `"v42"`, rec.Header().Get("ETag"))
+}
+
+func TestBufferedResponseWriterNoETagOnError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	bw := NewBufferedResponseWriter(rec, 10, 0)
+
+	bw.WriteHeader(http.StatusNotFound)
+	_, err := bw.Write([]byte("missing"))
+	require.NoError(t, err)
+	require.NoError(t, bw.Close())
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "7", rec.Header().Get("Content-Length"))
+	assert.Empty(t, rec.Header().Get("ETag"))
+}
+
+func TestBufferedResponseWriterLarge(t *testing.T) {
+	rec := httptest.NewRecorder()
+	bw := NewBufferedResponseWriter(rec, 10, 0)
+
+	bw.Header().Set("Content-Length", "100")
+	bw.Header().Set("ETag", `"stale"`)
+	bw.WriteHeader(http.StatusAccepted)
+	_, err := bw.Write([]byte("hello"))
+	require.NoError(t, err)
+	assert.Empty(t, rec.Body.String())
+
+	_, err = bw.Write([]byte("world!"))
+	require.NoError(t, err)
+	assert.True(t, bw.Streaming())
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "helloworld!", rec.Body.String())
+
+	_, err = bw.Write([]byte("more"))
+	require.NoError(t, err)
+	require.NoError(t, bw.Close())
+	assert.Equal(t, "helloworld!more", rec.Body.String())
+	assert.Empty(t, rec.Header().Get("Content-Length"))
+	assert.Empty(t, rec.Header().Get("ETag"))
+}
+
+func TestBufferedResponseWriterSlow(t *testing.T) {
+	rec := httptest.NewRecorder()
+	bw := NewBufferedResponseWriter(rec, 1024, 10*time.Millisecond)
+
+	_, err := bw.Write([]byte("hello"))
+	require.NoError(t, err)
+	assert.False(t, bw.Streaming())
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = bw.Write([]byte("world"))
+	require.NoError(t, err)
+	assert.True(t, bw.Streaming())
+	assert.Equal(t, "helloworld", rec.Body.String())
+	require.NoError(t, bw.Close())
+	assert.Empty(t, rec.Header().Get("ETag"))
+}
+
+func TestBufferedResponseWriterFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	bw := NewBufferedResponseWriter(rec, 1024, 0)
+
+	_, err := bw.Write([]byte("event"))
+	require.NoError(t, err)
+	bw.Flush()
+	assert.True(t, bw.Streaming())
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, "event", rec.Body.String())
+}
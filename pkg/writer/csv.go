@@ -0,0 +1,168 @@
+package writer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/data/convert"
+)
+
+// CSVWriter writes objects as a CSV document with a row per object, for clients such as spreadsheets. The header
+// line starts with the id, followed by the fields of the schema and any other field of the objects in alphabetical
+// order. Nested objects are flattened into dotted-path columns such as "spec.replicas", and lists are written as
+// JSON.
+type CSVWriter struct {
+	EncodingResponseWriter
+}
+
+func (c *CSVWriter) start(apiOp *types.APIRequest, code int) {
+	AddCommonResponseHeader(apiOp)
+	apiOp.Response.Header().Set("content-type", "text/csv; charset=utf-8")
+	apiOp.Response.WriteHeader(code)
+}
+
+func (c *CSVWriter) Write(apiOp *types.APIRequest, code int, obj types.APIObject) {
+	addDeprecationWarnings(apiOp, obj)
+	c.start(apiOp, code)
+	schema := apiOp.Schemas.LookupSchema(obj.Type)
+	if schema == nil {
+		schema = apiOp.Schema
+	}
+	var resources []*types.RawResource
+	if resource := c.convert(apiOp, obj); resource != nil {
+		resources = append(resources, resource)
+	}
+	w := &errorTrackingWriter{Writer: apiOp.Response}
+	logWriteError(apiOp, w, writeCSV(w, schema, resources))
+}
+
+func (c *CSVWriter) WriteList(apiOp *types.APIRequest, code int, list types.APIObjectList) {
+	addDeprecationWarnings(apiOp, list.Objects...)
+	c.start(apiOp, code)
+	collection := c.convertList(apiOp, list)
+	w := &errorTrackingWriter{Writer: apiOp.Response}
+	if err := requestErr(apiOp); err != nil {
+		logWriteError(apiOp, w, err)
+		return
+	}
+	logWriteError(apiOp, w, writeCSV(w, apiOp.Schema, collection.Data))
+}
+
+func writeCSV(w io.Writer, schema *types.APISchema, resources []*types.RawResource) error {
+	rows := make([]map[string]interface{}, 0, len(resources))
+	for _, resource := range resources {
+		if resource == nil {
+			continue
+		}
+		row := map[string]interface{}{}
+		flatten(row, "", resource.APIObject.Data())
+		row["id"] = resource.ID
+		rows = append(rows, row)
+	}
+
+	columns := csvColumns(schema, rows)
+	out := csv.NewWriter(w)
+	if err := out.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, column := range columns {
+			record[i] = csvValue(row[column])
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// flatten adds the fields of obj to row, keyed by their dotted path.
+func flatten(row map[string]interface{}, prefix string, obj map[string]interface{}) {
+	for key, value := range obj {
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			flatten(row, prefix+key+".", nested)
+			continue
+		}
+		row[prefix+key] = value
+	}
+}
+
+// csvColumns returns the id column, then the columns of the schema fields, then the remaining columns, each group
+// in alphabetical order. A schema field whose values are objects is replaced by the columns of their fields.
+func csvColumns(schema *types.APISchema, rows []map[string]interface{}) []string {
+	seen := map[string]bool{"id": true}
+	columns := []string{"id"}
+
+	present := map[string]bool{}
+	for _, row := range rows {
+		for column := range row {
+			present[column] = true
+		}
+	}
+	var others []string
+	for column := range present {
+		if !seen[column] {
+			others = append(others, column)
+		}
+	}
+	sort.Strings(others)
+
+	if schema != nil && schema.Schema != nil {
+		var fields []string
+		for field := range schema.ResourceFields {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			nested := false
+			for _, column := range others {
+				if strings.HasPrefix(column, field+".") {
+					nested = true
+					if !seen[column] {
+						seen[column] = true
+						columns = append(columns, column)
+					}
+				}
+			}
+			if !nested && !seen[field] {
+				seen[field] = true
+				columns = append(columns, field)
+			}
+		}
+	}
+
+	for _, column := range others {
+		if !seen[column] {
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// csvValue returns the cell of value. Strings that spreadsheets would read as a formula, starting with "=", "+",
+// "-", "@", a tab or a carriage return, are prefixed with "'" so they are shown as text instead of evaluated.
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+			return "'" + v
+		}
+		return v
+	case []interface{}, map[string]interface{}:
+		content, err := json.Marshal(v)
+		if err != nil {
+			return convert.ToString(v)
+		}
+		return string(content)
+	}
+	return convert.ToString(value)
+}
@@ -0,0 +1,100 @@
+package writer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVWriter(t *testing.T) {
+	list := types.APIObjectList{
+		Objects: []types.APIObject{
+			{Type: "foo", ID: "web", Object: map[string]interface{}{
+				"name":     "web",
+				"replicas": 3,
+				"spec":     map[string]interface{}{"image": "nginx", "ports": []interface{}{80, 443}},
+				"note":     "a, \"quoted\" note",
+			}},
+			{Type: "foo", ID: "db", Object: map[string]interface{}{
+				"name":   "db",
+				"spec":   map[string]interface{}{"image": "postgres"},
+				"status": nil,
+			}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		fields map[string]schemas.Field
+		want   string
+	}{
+		{
+			name: "fields from the objects",
+			want: "id,name,note,replicas,spec.image,spec.ports,status\n" +
+				"web,web,\"a, \"\"quoted\"\" note\",3,nginx,\"[80,443]\",\n" +
+				"db,db,,,postgres,,\n",
+		},
+		{
+			name: "schema fields first",
+			fields: map[string]schemas.Field{
+				"spec":     {Type: "map[string]"},
+				"replicas": {Type: "int"},
+				"owner":    {Type: "string"},
+			},
+			want: "id,owner,replicas,spec.image,spec.ports,name,note,status\n" +
+				"web,,3,nginx,\"[80,443]\",web,\"a, \"\"quoted\"\" note\",\n" +
+				"db,,,postgres,,db,,\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			apiOp := newListRequest(t, context.Background(), rw)
+			apiOp.Schema.ResourceFields = tt.fields
+
+			writer := &CSVWriter{}
+			writer.WriteList(apiOp, http.StatusOK, list)
+
+			assert.Equal(t, http.StatusOK, rw.Code)
+			assert.Equal(t, "text/csv; charset=utf-8", rw.Header().Get("Content-Type"))
+			assert.Equal(t, tt.want, rw.Body.String())
+		})
+	}
+}
+
+func TestCSVWriterObject(t *testing.T) {
+	rw := httptest.NewRecorder()
+	apiOp := newListRequest(t, context.Background(), rw)
+
+	writer := &CSVWriter{}
+	writer.Write(apiOp, http.StatusOK, types.APIObject{Type: "foo", ID: "web", Object: map[string]interface{}{
+		"name": "web",
+	}})
+
+	assert.Equal(t, "id,name\nweb,web\n", rw.Body.String())
+}
+
+func TestCSVWriterFormulas(t *testing.T) {
+	rw := httptest.NewRecorder()
+	apiOp := newListRequest(t, context.Background(), rw)
+
+	writer := &CSVWriter{}
+	writer.Write(apiOp, http.StatusOK, types.APIObject{Type: "foo", ID: "web", Object: map[string]interface{}{
+		"a": "=HYPERLINK(\"http://example.com\")",
+		"b": "+1",
+		"c": "-1",
+		"d": "@SUM(A1)",
+		"e": "\tcmd",
+		"f": "\rcmd",
+		"g": "a=b",
+		"h": -1,
+	}})
+
+	assert.Equal(t, "id,a,b,c,d,e,f,g,h\n"+
+		"web,\"'=HYPERLINK(\"\"http://example.com\"\")\",'+1,'-1,'@SUM(A1),'\tcmd,\"'\rcmd\",a=b,-1\n", rw.Body.String())
+}
@@ -0,0 +1,63 @@
+package writer
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// deprecatedWarnCode is the warn-code of RFC 7234 for miscellaneous persistent warnings.
+const deprecatedWarnCode = "299"
+
+// addDeprecationWarnings adds a Warning header for the deprecated schemas and fields of objects. It must be called
+// before the header is written.
+func addDeprecationWarnings(apiOp *types.APIRequest, objects ...types.APIObject) {
+	var texts []string
+	add := func(text string) {
+		if !slices.Contains(texts, text) {
+			texts = append(texts, text)
+		}
+	}
+
+	for _, obj := range objects {
+		schema := apiOp.Schemas.LookupSchema(obj.Type)
+		if schema == nil {
+			schema = apiOp.Schema
+		}
+		if schema == nil {
+			continue
+		}
+		if schema.Deprecated != "" {
+			add(schema.Deprecated)
+		}
+		if len(schema.DeprecatedFields) == 0 || obj.Object == nil {
+			continue
+		}
+		data := obj.Data()
+		fields := make([]string, 0, len(schema.DeprecatedFields))
+		for field := range schema.DeprecatedFields {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			if data[field] != nil {
+				add(schema.DeprecatedFields[field])
+			}
+		}
+	}
+
+	// a list can be empty, but the type it lists can still be deprecated
+	if len(objects) == 0 && apiOp.Schema != nil && apiOp.Schema.Deprecated != "" {
+		add(apiOp.Schema.Deprecated)
+	}
+
+	header := apiOp.Response.Header()
+	for _, text := range texts {
+		warning := deprecatedWarnCode + " - " + strconv.Quote(text)
+		if !slices.Contains(header.Values("Warning"), warning) {
+			header.Add("Warning", warning)
+		}
+	}
+}
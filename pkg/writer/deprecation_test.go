@@ -0,0 +1,78 @@
+package writer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecationWarnings(t *testing.T) {
+	tests := []struct {
+		name       string
+		deprecated string
+		fields     map[string]string
+		list       bool
+		objects    []types.APIObject
+		want       []string
+	}{
+		{
+			name:       "deprecated schema",
+			deprecated: "foo is deprecated, use bar instead",
+			objects:    []types.APIObject{{Type: "foo", ID: "a", Object: map[string]interface{}{"id": "a"}}},
+			want:       []string{`299 - "foo is deprecated, use bar instead"`},
+		},
+		{
+			name:       "deprecated schema list",
+			deprecated: "foo is deprecated",
+			list:       true,
+			objects: []types.APIObject{
+				{Type: "foo", ID: "a", Object: map[string]interface{}{"id": "a"}},
+				{Type: "foo", ID: "b", Object: map[string]interface{}{"id": "b"}},
+			},
+			want: []string{`299 - "foo is deprecated"`},
+		},
+		{
+			name:       "empty list of deprecated schema",
+			deprecated: "foo is deprecated",
+			list:       true,
+			want:       []string{`299 - "foo is deprecated"`},
+		},
+		{
+			name:    "deprecated field set",
+			fields:  map[string]string{"legacy": "foo.legacy is deprecated", "old": "foo.old is deprecated"},
+			objects: []types.APIObject{{Type: "foo", ID: "a", Object: map[string]interface{}{"id": "a", "legacy": "x"}}},
+			want:    []string{`299 - "foo.legacy is deprecated"`},
+		},
+		{
+			name:    "deprecated field not set",
+			fields:  map[string]string{"legacy": "foo.legacy is deprecated"},
+			objects: []types.APIObject{{Type: "foo", ID: "a", Object: map[string]interface{}{"id": "a"}}},
+		},
+		{
+			name:    "not deprecated",
+			objects: []types.APIObject{{Type: "foo", ID: "a", Object: map[string]interface{}{"id": "a", "legacy": "x"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			apiOp := newListRequest(t, context.Background(), rw)
+			apiOp.Schema.Deprecated = tt.deprecated
+			apiOp.Schema.DeprecatedFields = tt.fields
+
+			writer := &EncodingResponseWriter{ContentType: "application/json", Encoder: types.JSONEncoder}
+			if tt.list {
+				writer.WriteList(apiOp, http.StatusOK, types.APIObjectList{Objects: tt.objects})
+			} else {
+				writer.Write(apiOp, http.StatusOK, tt.objects[0])
+			}
+
+			assert.Equal(t, http.StatusOK, rw.Code)
+			assert.Equal(t, tt.want, rw.Header().Values("Warning"))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package writer
+
+import (
+	"io"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/sirupsen/logrus"
+)
+
+// errorTrackingWriter remembers the first error returned by the underlying writer, so that failing to send the
+// response can be told apart from failing to encode it.
+type errorTrackingWriter struct {
+	io.Writer
+	err error
+}
+
+func (e *errorTrackingWriter) Write(b []byte) (int, error) {
+	n, err := e.Writer.Write(b)
+	if err != nil && e.err == nil {
+		e.err = err
+	}
+	return n, err
+}
+
+// clientGone returns whether the client of apiOp went away, either because its request was canceled or because
+// writing to the connection failed.
+func clientGone(apiOp *types.APIRequest, w *errorTrackingWriter) bool {
+	if w.err != nil {
+		return true
+	}
+	return requestErr(apiOp) != nil
+}
+
+// logWriteError logs err, returned while writing the response of apiOp to w. Disconnected clients are expected
+// for large or slow responses and are only logged at debug level.
+func logWriteError(apiOp *types.APIRequest, w *errorTrackingWriter, err error) {
+	if err == nil {
+		return
+	}
+	if clientGone(apiOp, w) {
+		logrus.Debugf("Client closed connection while writing %s response: %v", apiOp.Type, err)
+		return
+	}
+	logrus.Errorf("Failed to encode %s response: %v", apiOp.Type, err)
+}
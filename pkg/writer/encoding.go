@@ -3,14 +3,28 @@ package writer
 import (
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 
+	"github.com/rancher/apiserver/pkg/parse"
 	"github.com/rancher/apiserver/pkg/types"
 )
 
 type EncodingResponseWriter struct {
 	ContentType string
 	Encoder     func(io.Writer, interface{}) error
+	// KeyTransform, if set, renames the top-level fields of every object before it is encoded, for example with
+	// SnakeCase. Fields of nested objects are renamed too if TransformNestedKeys is set.
+	KeyTransform        func(string) string
+	TransformNestedKeys bool
+	// ReplaceNonFinite, if set, is called for every NaN or infinite float of an object, and the value it returns
+	// is encoded instead, since JSON cannot represent such floats. NullNonFinite encodes them as null. Only
+	// objects made of maps and slices, such as unstructured objects, are checked.
+	ReplaceNonFinite func(float64) interface{}
+	// Marshalers, if set, encodes the values of the registered types its own way, for types such as time.Time or
+	// quantities whose standard JSON encoding is not what clients expect. Like ReplaceNonFinite, it only applies to
+	// objects made of maps and slices.
+	Marshalers Marshalers
 }
 
 func (j *EncodingResponseWriter) start(apiOp *types.APIRequest, code int) {
@@ -20,13 +34,17 @@ func (j *EncodingResponseWriter) start(apiOp *types.APIRequest, code int) {
 }
 
 func (j *EncodingResponseWriter) Write(apiOp *types.APIRequest, code int, obj types.APIObject) {
+	addDeprecationWarnings(apiOp, obj)
 	j.start(apiOp, code)
-	j.Body(apiOp, apiOp.Response, obj)
+	w := &errorTrackingWriter{Writer: apiOp.Response}
+	logWriteError(apiOp, w, j.Body(apiOp, w, obj))
 }
 
 func (j *EncodingResponseWriter) WriteList(apiOp *types.APIRequest, code int, list types.APIObjectList) {
+	addDeprecationWarnings(apiOp, list.Objects...)
 	j.start(apiOp, code)
-	j.BodyList(apiOp, apiOp.Response, list)
+	w := &errorTrackingWriter{Writer: apiOp.Response}
+	logWriteError(apiOp, w, j.BodyList(apiOp, w, list))
 }
 
 func (j *EncodingResponseWriter) Body(apiOp *types.APIRequest, writer io.Writer, obj types.APIObject) error {
@@ -34,12 +52,23 @@ func (j *EncodingResponseWriter) Body(apiOp *types.APIRequest, writer io.Writer,
 }
 
 func (j *EncodingResponseWriter) BodyList(apiOp *types.APIRequest, writer io.Writer, list types.APIObjectList) error {
-	return j.Encoder(writer, j.convertList(apiOp, list))
+	collection := j.convertList(apiOp, list)
+	// there is no point encoding a large list for a client that already went away
+	if err := requestErr(apiOp); err != nil {
+		return err
+	}
+	return j.Encoder(writer, collection)
 }
 
 func (j *EncodingResponseWriter) convertList(apiOp *types.APIRequest, input types.APIObjectList) *types.GenericCollection {
+	if !input.ListOptionsApplied {
+		input = apiOp.ListOptions.Apply(input)
+	}
 	collection := newCollection(apiOp, input)
 	for _, value := range input.Objects {
+		if requestErr(apiOp) != nil {
+			break
+		}
 		converted := j.convert(apiOp, value)
 		collection.Data = append(collection.Data, converted)
 	}
@@ -63,6 +92,12 @@ func (j *EncodingResponseWriter) convert(context *types.APIRequest, input types.
 	if schema == nil {
 		return nil
 	}
+	if input.ID == "" && schema.IDExtractor != nil {
+		input.ID = schema.IDExtractor.ExtractID(input)
+	}
+	if schema.OutputTransformer != nil {
+		input = schema.OutputTransformer(context, input)
+	}
 
 	rawResource := &types.RawResource{
 		ID:          input.ID,
@@ -75,11 +110,40 @@ func (j *EncodingResponseWriter) convert(context *types.APIRequest, input types.
 	}
 
 	j.addLinks(schema, context, input, rawResource)
+	if parse.IncludeMethods(context) {
+		rawResource.ResourceMethods = resourceMethods(context, schema, input)
+	}
 
 	if schema.Formatter != nil {
 		schema.Formatter(context, rawResource)
 	}
 
+	if masked(context, schema) && rawResource.APIObject.Object != nil {
+		rawResource.APIObject = types.APIObject{
+			Type:     rawResource.APIObject.Type,
+			ID:       rawResource.APIObject.ID,
+			Object:   maskFields(rawResource.APIObject.Data(), schema.OutputMask.Fields),
+			Warnings: rawResource.APIObject.Warnings,
+		}
+	}
+
+	if j.KeyTransform != nil && rawResource.APIObject.Object != nil {
+		rawResource.APIObject = types.APIObject{
+			Type:     rawResource.APIObject.Type,
+			ID:       rawResource.APIObject.ID,
+			Object:   transformKeys(rawResource.APIObject.Data(), j.KeyTransform, j.TransformNestedKeys),
+			Warnings: rawResource.APIObject.Warnings,
+		}
+	}
+
+	if j.ReplaceNonFinite != nil {
+		rawResource.APIObject.Object = replaceNonFinite(rawResource.APIObject.Object, j.ReplaceNonFinite)
+	}
+
+	if len(j.Marshalers) > 0 {
+		rawResource.APIObject.Object = applyMarshalers(rawResource.APIObject.Object, j.Marshalers)
+	}
+
 	return rawResource
 }
 
@@ -102,15 +166,54 @@ func (j *EncodingResponseWriter) addLinks(schema *types.APISchema, context *type
 			rawResource.Links["remove"] = self
 		}
 	}
+	if _, ok := rawResource.Links["view"]; !ok && schema.HTMLView {
+		rawResource.Links["view"] = viewLink(self)
+	}
 	for link := range schema.LinkHandlers {
 		rawResource.Links[link] = context.URLBuilder.Link(schema, rawResource.ID, link)
 	}
+	if schema.RelationshipResolver != nil {
+		for _, rel := range schema.RelationshipResolver(context, input) {
+			relSchema := context.Schemas.LookupSchema(rel.Type)
+			if relSchema == nil || rel.Rel == "" || rel.ID == "" {
+				continue
+			}
+			if _, ok := rawResource.Links[rel.Rel]; !ok {
+				rawResource.Links[rel.Rel] = context.URLBuilder.ResourceLink(relSchema, rel.ID)
+			}
+		}
+	}
 	for action := range schema.ActionHandlers {
 		if rawResource.Actions == nil {
 			rawResource.Actions = map[string]string{}
 		}
 		rawResource.Actions[action] = context.URLBuilder.Action(schema, rawResource.ID, action)
 	}
+	for action := range schema.ActionRequestHandlers {
+		if rawResource.Actions == nil {
+			rawResource.Actions = map[string]string{}
+		}
+		rawResource.Actions[action] = context.URLBuilder.Action(schema, rawResource.ID, action)
+	}
+}
+
+// viewLink returns the link to the HTML representation of the resource at self.
+func viewLink(self string) string {
+	u, err := url.Parse(self)
+	if err != nil {
+		return self
+	}
+	query := u.Query()
+	query.Set("_format", "html")
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func requestErr(apiOp *types.APIRequest) error {
+	if apiOp.Request == nil {
+		return nil
+	}
+	return apiOp.Request.Context().Err()
 }
 
 func getLimit(req *http.Request) int {
@@ -135,6 +238,8 @@ func newCollection(apiOp *types.APIRequest, list types.APIObjectList) *types.Gen
 			Revision: list.Revision,
 			Pages:    list.Pages,
 			Count:    list.Count,
+			Summary:  list.Summary,
+			Errors:   list.Errors,
 		},
 	}
 
@@ -156,5 +261,9 @@ func newCollection(apiOp *types.APIRequest, list types.APIObjectList) *types.Gen
 		}
 	}
 
+	if parse.IncludeMethods(apiOp) {
+		result.CollectionMethods = collectionMethods(apiOp, apiOp.Schema)
+	}
+
 	return result
 }
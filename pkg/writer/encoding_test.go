@@ -0,0 +1,562 @@
+package writer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/apiserver/pkg/urlbuilder"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type allowAll struct {
+	types.AccessControl
+}
+
+func (allowAll) CanCreate(*types.APIRequest, *types.APISchema) error                  { return nil }
+func (allowAll) CanUpdate(*types.APIRequest, types.APIObject, *types.APISchema) error { return nil }
+func (allowAll) CanDelete(*types.APIRequest, types.APIObject, *types.APISchema) error { return nil }
+
+// failingResponseWriter accepts up to limit bytes and then fails as if the client had closed the connection.
+type failingResponseWriter struct {
+	*httptest.ResponseRecorder
+	limit int
+}
+
+func (f *failingResponseWriter) Write(b []byte) (int, error) {
+	if f.Body.Len()+len(b) > f.limit {
+		n, _ := f.ResponseRecorder.Write(b[:f.limit-f.Body.Len()])
+		return n, syscall.EPIPE
+	}
+	return f.ResponseRecorder.Write(b)
+}
+
+func newListRequest(t *testing.T, ctx context.Context, rw http.ResponseWriter) *types.APIRequest {
+	apiSchemas := types.EmptyAPISchemas()
+	apiSchemas.MustAddSchema(types.APISchema{
+		Schema: &schemas.Schema{
+			ID:                "foo",
+			CollectionMethods: []string{http.MethodGet},
+			ResourceMethods:   []string{http.MethodGet},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/foos", nil).WithContext(ctx)
+	builder, err := urlbuilder.NewPrefixed(req, apiSchemas, "v1")
+	require.NoError(t, err)
+
+	return &types.APIRequest{
+		Type:          "foo",
+		Method:        http.MethodGet,
+		Schema:        apiSchemas.LookupSchema("foo"),
+		Schemas:       apiSchemas,
+		Request:       req,
+		Response:      rw,
+		URLBuilder:    builder,
+		AccessControl: allowAll{},
+	}
+}
+
+func newList(size int) types.APIObjectList {
+	var list types.APIObjectList
+	for i := 0; i < size; i++ {
+		list.Objects = append(list.Objects, types.APIObject{
+			Type:   "foo",
+			ID:     "foo",
+			Object: map[string]interface{}{"id": "foo"},
+		})
+	}
+	return list
+}
+
+func TestWriteListErrors(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		limit       int
+		encoder     func(io.Writer, interface{}) error
+		wantLevel   logrus.Level
+		wantEncoded bool
+	}{
+		{
+			name:        "client closed connection",
+			ctx:         context.Background(),
+			limit:       64,
+			wantLevel:   logrus.DebugLevel,
+			wantEncoded: true,
+		},
+		{
+			name:      "request canceled",
+			ctx:       canceled,
+			limit:     1 << 20,
+			wantLevel: logrus.DebugLevel,
+		},
+		{
+			name:  "encode error",
+			ctx:   context.Background(),
+			limit: 1 << 20,
+			encoder: func(io.Writer, interface{}) error {
+				return errors.New("unsupported value")
+			},
+			wantLevel:   logrus.ErrorLevel,
+			wantEncoded: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := test.NewGlobal()
+			level := logrus.GetLevel()
+			logrus.SetLevel(logrus.DebugLevel)
+			defer func() {
+				logrus.StandardLogger().ReplaceHooks(logrus.LevelHooks{})
+				logrus.SetLevel(level)
+			}()
+
+			encoded := false
+			encoder := func(w io.Writer, v interface{}) error {
+				encoded = true
+				if tt.encoder != nil {
+					return tt.encoder(w, v)
+				}
+				return json.NewEncoder(w).Encode(v)
+			}
+
+			rw := &failingResponseWriter{ResponseRecorder: httptest.NewRecorder(), limit: tt.limit}
+			writer := &EncodingResponseWriter{ContentType: "application/json", Encoder: encoder}
+			writer.WriteList(newListRequest(t, tt.ctx, rw), http.StatusOK, newList(100))
+
+			assert.Equal(t, tt.wantEncoded, encoded)
+			require.Len(t, hook.AllEntries(), 1)
+			assert.Equal(t, tt.wantLevel, hook.LastEntry().Level)
+		})
+	}
+}
+
+func TestConvertIDExtractor(t *testing.T) {
+	obj := types.APIObject{
+		Type: "foo",
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "web", "namespace": "default"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		extractor types.IDExtractor
+		object    types.APIObject
+		wantID    string
+		wantLinks bool
+	}{
+		{
+			name:   "no extractor",
+			object: obj,
+		},
+		{
+			name:      "from metadata",
+			extractor: types.MetadataIDExtractor,
+			object:    obj,
+			wantID:    "default/web",
+			wantLinks: true,
+		},
+		{
+			name:      "explicit ID wins",
+			extractor: types.MetadataIDExtractor,
+			object:    types.APIObject{Type: "foo", ID: "explicit", Object: obj.Object},
+			wantID:    "explicit",
+			wantLinks: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiOp := newListRequest(t, context.Background(), httptest.NewRecorder())
+			apiOp.Schema.IDExtractor = tt.extractor
+
+			writer := &EncodingResponseWriter{ContentType: "application/json", Encoder: types.JSONEncoder}
+			resource := writer.convert(apiOp, tt.object)
+			require.NotNil(t, resource)
+			assert.Equal(t, tt.wantID, resource.ID)
+			assert.Equal(t, tt.wantID, resource.APIObject.ID)
+			if tt.wantLinks {
+				assert.Equal(t, "http://example.com/v1/foos/"+tt.wantID, resource.Links["self"])
+			} else {
+				assert.Empty(t, resource.Links)
+			}
+		})
+	}
+}
+
+func TestConvertViewLink(t *testing.T) {
+	tests := []struct {
+		name     string
+		htmlView bool
+		links    map[string]string
+		want     string
+	}{
+		{
+			name:     "html view",
+			htmlView: true,
+			want:     "http://example.com/v1/foos/foo1?_format=html",
+		},
+		{
+			name:     "formatter link wins",
+			htmlView: true,
+			links:    map[string]string{"view": "http://example.com/ui/foos/foo1"},
+			want:     "http://example.com/ui/foos/foo1",
+		},
+		{
+			name: "no html view",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiOp := newListRequest(t, context.Background(), httptest.NewRecorder())
+			apiOp.Schema.HTMLView = tt.htmlView
+			if tt.links != nil {
+				apiOp.Schema.Formatter = func(request *types.APIRequest, resource *types.RawResource) {
+					for name, link := range tt.links {
+						resource.Links[name] = link
+					}
+				}
+			}
+
+			writer := &EncodingResponseWriter{ContentType: "application/json", Encoder: types.JSONEncoder}
+			resource := writer.convert(apiOp, types.APIObject{Type: "foo", ID: "foo1", Object: map[string]interface{}{}})
+			require.NotNil(t, resource)
+			assert.Equal(t, "http://example.com/v1/foos/foo1", resource.Links["self"])
+			assert.Equal(t, tt.want, resource.Links["view"])
+		})
+	}
+}
+
+func TestWriteListSummary(t *testing.T) {
+	summary := []types.SummaryEntry{
+		{
+			Property: "state",
+			Counts:   map[string]int{"active": 2, "error": 1},
+		},
+	}
+	want := []interface{}{
+		map[string]interface{}{
+			"property": "state",
+			"counts":   map[string]interface{}{"active": float64(2), "error": float64(1)},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		encoder func(io.Writer, interface{}) error
+		summary []types.SummaryEntry
+		want    interface{}
+	}{
+		{
+			name:    "json",
+			encoder: types.JSONEncoder,
+			summary: summary,
+			want:    want,
+		},
+		{
+			name:    "jsonl",
+			encoder: types.JSONLinesEncoder,
+			summary: summary,
+			want:    want,
+		},
+		{
+			name:    "json without summary",
+			encoder: types.JSONEncoder,
+		},
+		{
+			name:    "jsonl without summary",
+			encoder: types.JSONLinesEncoder,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			list := newList(3)
+			list.Summary = tt.summary
+
+			writer := &EncodingResponseWriter{ContentType: "application/json", Encoder: tt.encoder}
+			writer.WriteList(newListRequest(t, context.Background(), rw), http.StatusOK, list)
+			require.Equal(t, http.StatusOK, rw.Code)
+
+			// the collection is the whole json body, and the first line of a jsonl body
+			var collection map[string]interface{}
+			require.NoError(t, json.NewDecoder(rw.Body).Decode(&collection))
+			assert.Equal(t, tt.want, collection["summary"])
+		})
+	}
+}
+
+func TestOutputTransformer(t *testing.T) {
+	redact := func(request *types.APIRequest, obj types.APIObject) types.APIObject {
+		data := obj.Data()
+		if _, ok := data["data"]; !ok {
+			return obj
+		}
+		redacted := map[string]interface{}{}
+		for k, v := range data {
+			redacted[k] = v
+		}
+		redacted["data"] = "[REDACTED]"
+		obj.Object = redacted
+		return obj
+	}
+	newObject := func() types.APIObject {
+		return types.APIObject{
+			Type:   "foo",
+			ID:     "foo1",
+			Object: map[string]interface{}{"id": "foo1", "data": "s3cr3t"},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		transformer types.OutputTransformer
+		want        interface{}
+	}{
+		{
+			name:        "redacted",
+			transformer: redact,
+			want:        "[REDACTED]",
+		},
+		{
+			name: "no transformer",
+			want: "s3cr3t",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" single", func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			apiOp := newListRequest(t, context.Background(), rw)
+			apiOp.Schema.OutputTransformer = tt.transformer
+
+			obj := newObject()
+			writer := &EncodingResponseWriter{ContentType: "application/json", Encoder: types.JSONEncoder}
+			writer.Write(apiOp, http.StatusOK, obj)
+
+			var resource map[string]interface{}
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &resource))
+			assert.Equal(t, tt.want, resource["data"])
+			assert.Equal(t, newObject(), obj, "the store's object must not be modified")
+		})
+		t.Run(tt.name+" list", func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			apiOp := newListRequest(t, context.Background(), rw)
+			apiOp.Schema.OutputTransformer = tt.transformer
+
+			writer := &EncodingResponseWriter{ContentType: "application/json", Encoder: types.JSONEncoder}
+			writer.WriteList(apiOp, http.StatusOK, types.APIObjectList{Objects: []types.APIObject{newObject(), newObject()}})
+
+			var collection struct {
+				Data []map[string]interface{} `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &collection))
+			require.Len(t, collection.Data, 2)
+			for _, resource := range collection.Data {
+				assert.Equal(t, tt.want, resource["data"])
+			}
+		})
+	}
+}
+
+func TestWriteListListOptions(t *testing.T) {
+	opts := &types.ListOptions{
+		Filters:  []types.ListFilter{{Field: []string{"tier"}, Modifier: types.ModifierEQ, Value: "back"}},
+		Sort:     []types.ListSort{{Field: []string{"id"}, Descending: true}},
+		PageSize: 2,
+	}
+	newObject := func(id, tier string) types.APIObject {
+		return types.APIObject{Type: "foo", ID: id, Object: map[string]interface{}{"id": id, "tier": tier}}
+	}
+
+	tests := []struct {
+		name      string
+		list      types.APIObjectList
+		wantIDs   []string
+		wantCount int
+		wantPages int
+	}{
+		{
+			name: "applied by the writer",
+			list: types.APIObjectList{Objects: []types.APIObject{
+				newObject("a", "back"), newObject("b", "front"), newObject("c", "back"), newObject("d", "back"),
+			}},
+			wantIDs:   []string{"d", "c"},
+			wantCount: 3,
+			wantPages: 2,
+		},
+		{
+			name: "applied by the store",
+			list: types.APIObjectList{
+				Objects:            []types.APIObject{newObject("b", "front"), newObject("a", "back")},
+				Count:              7,
+				Pages:              4,
+				ListOptionsApplied: true,
+			},
+			wantIDs:   []string{"b", "a"},
+			wantCount: 7,
+			wantPages: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			apiOp := newListRequest(t, context.Background(), rw)
+			apiOp.ListOptions = opts
+
+			writer := &EncodingResponseWriter{ContentType: "application/json", Encoder: types.JSONEncoder}
+			writer.WriteList(apiOp, http.StatusOK, tt.list)
+
+			var collection struct {
+				Count int                      `json:"count"`
+				Pages int                      `json:"pages"`
+				Data  []map[string]interface{} `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &collection))
+			var ids []string
+			for _, resource := range collection.Data {
+				ids = append(ids, resource["id"].(string))
+			}
+			assert.Equal(t, tt.wantIDs, ids)
+			assert.Equal(t, tt.wantCount, collection.Count)
+			assert.Equal(t, tt.wantPages, collection.Pages)
+		})
+	}
+}
+
+func TestWriteListPartial(t *testing.T) {
+	rw := httptest.NewRecorder()
+	list := newList(2)
+	list.Errors = []types.ListError{
+		{Source: "team-b", Code: "PermissionDenied", Status: http.StatusForbidden, Message: "can not list foos"},
+	}
+
+	writer := &EncodingResponseWriter{ContentType: "application/json", Encoder: types.JSONEncoder}
+	writer.WriteList(newListRequest(t, context.Background(), rw), http.StatusOK, list)
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	var collection struct {
+		Data   []map[string]interface{} `json:"data"`
+		Errors []map[string]interface{} `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &collection))
+	assert.Len(t, collection.Data, 2)
+	assert.Equal(t, []map[string]interface{}{
+		{"source": "team-b", "code": "PermissionDenied", "status": float64(http.StatusForbidden), "message": "can not list foos"},
+	}, collection.Errors)
+
+	// complete lists have no errors field
+	rw = httptest.NewRecorder()
+	writer.WriteList(newListRequest(t, context.Background(), rw), http.StatusOK, newList(2))
+	assert.NotContains(t, rw.Body.String(), `"errors"`)
+}
+
+func TestRelationshipResolver(t *testing.T) {
+	owners := func(request *types.APIRequest, obj types.APIObject) []types.Relationship {
+		var result []types.Relationship
+		refs, _ := obj.Data().Map("metadata")["ownerReferences"].([]interface{})
+		for _, ref := range refs {
+			ref := ref.(map[string]interface{})
+			result = append(result, types.Relationship{
+				Rel:  "owner",
+				Type: ref["kind"].(string),
+				ID:   obj.Data().String("metadata", "namespace") + "/" + ref["name"].(string),
+			})
+		}
+		return result
+	}
+	pod := func(ownerKind string) types.APIObject {
+		return types.APIObject{
+			Type: "foo",
+			ID:   "default/web-1",
+			Object: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"namespace":       "default",
+					"ownerReferences": []interface{}{map[string]interface{}{"kind": ownerKind, "name": "web"}},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		resolver  types.RelationshipResolver
+		obj       types.APIObject
+		wantOwner bool
+	}{
+		{
+			name:      "owner",
+			resolver:  owners,
+			obj:       pod("deployment"),
+			wantOwner: true,
+		},
+		{
+			name:     "unknown type",
+			resolver: owners,
+			obj:      pod("replicaset"),
+		},
+		{
+			name: "no resolver",
+			obj:  pod("deployment"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			apiOp := newListRequest(t, context.Background(), rw)
+			apiOp.Schemas.MustAddSchema(types.APISchema{
+				Schema: &schemas.Schema{
+					ID:              "deployment",
+					ResourceMethods: []string{http.MethodGet},
+				},
+			})
+			apiOp.Schema.RelationshipResolver = tt.resolver
+
+			writer := &EncodingResponseWriter{ContentType: "application/json", Encoder: types.JSONEncoder}
+			writer.Write(apiOp, http.StatusOK, tt.obj)
+
+			var resource struct {
+				Links map[string]string `json:"links"`
+			}
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &resource))
+			assert.Equal(t, apiOp.URLBuilder.ResourceLink(apiOp.Schema, "default/web-1"), resource.Links["self"])
+			if !tt.wantOwner {
+				assert.NotContains(t, resource.Links, "owner")
+				return
+			}
+			want := apiOp.URLBuilder.ResourceLink(apiOp.Schemas.LookupSchema("deployment"), "default/web")
+			assert.Equal(t, want, resource.Links["owner"])
+			assert.Contains(t, want, "/v1/deployments/default/web")
+		})
+	}
+}
+
+func TestRelationshipResolverKeepsLinks(t *testing.T) {
+	rw := httptest.NewRecorder()
+	apiOp := newListRequest(t, context.Background(), rw)
+	apiOp.Schema.RelationshipResolver = func(*types.APIRequest, types.APIObject) []types.Relationship {
+		return []types.Relationship{{Rel: "self", Type: "foo", ID: "other"}}
+	}
+
+	writer := &EncodingResponseWriter{ContentType: "application/json", Encoder: types.JSONEncoder}
+	writer.Write(apiOp, http.StatusOK, types.APIObject{Type: "foo", ID: "foo1", Object: map[string]interface{}{}})
+
+	var resource struct {
+		Links map[string]string `json:"links"`
+	}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &resource))
+	assert.Equal(t, apiOp.URLBuilder.ResourceLink(apiOp.Schema, "foo1"), resource.Links["self"])
+}
@@ -0,0 +1,75 @@
+package writer
+
+import (
+	"math"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// NullNonFinite replaces NaN and infinite floats by null.
+func NullNonFinite(float64) interface{} {
+	return nil
+}
+
+// replaceNonFinite returns obj with the NaN and infinite floats of map based objects replaced by the result of
+// replace. Objects are only copied if they contain such a float.
+func replaceNonFinite(obj interface{}, replace func(float64) interface{}) interface{} {
+	switch obj := obj.(type) {
+	case map[string]interface{}:
+		if v, changed := replaceNonFiniteValue(obj, replace); changed {
+			return v
+		}
+	case *unstructured.Unstructured:
+		if v, changed := replaceNonFiniteValue(obj.Object, replace); changed {
+			return &unstructured.Unstructured{Object: v.(map[string]interface{})}
+		}
+	}
+	return obj
+}
+
+func replaceNonFiniteValue(v interface{}, replace func(float64) interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return replace(v), true
+		}
+	case float32:
+		if f := float64(v); math.IsNaN(f) || math.IsInf(f, 0) {
+			return replace(f), true
+		}
+	case map[string]interface{}:
+		var result map[string]interface{}
+		for k, item := range v {
+			replaced, changed := replaceNonFiniteValue(item, replace)
+			if !changed {
+				continue
+			}
+			if result == nil {
+				result = make(map[string]interface{}, len(v))
+				for k, item := range v {
+					result[k] = item
+				}
+			}
+			result[k] = replaced
+		}
+		if result != nil {
+			return result, true
+		}
+	case []interface{}:
+		var result []interface{}
+		for i, item := range v {
+			replaced, changed := replaceNonFiniteValue(item, replace)
+			if !changed {
+				continue
+			}
+			if result == nil {
+				result = append([]interface{}(nil), v...)
+			}
+			result[i] = replaced
+		}
+		if result != nil {
+			return result, true
+		}
+	}
+	return v, false
+}
@@ -0,0 +1,110 @@
+package writer
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestReplaceNonFinite(t *testing.T) {
+	metrics := map[string]interface{}{
+		"ratio":   math.NaN(),
+		"ceiling": math.Inf(1),
+		"samples": []interface{}{1.5, math.Inf(-1)},
+		"count":   3,
+	}
+	list := types.APIObjectList{
+		Objects: []types.APIObject{
+			{Type: "foo", ID: "map", Object: map[string]interface{}{"id": "map", "metrics": metrics}},
+			{Type: "foo", ID: "unstructured", Object: &unstructured.Unstructured{Object: map[string]interface{}{
+				"id":    "unstructured",
+				"ratio": float32(math.NaN()),
+			}}},
+			{Type: "foo", ID: "finite", Object: map[string]interface{}{"id": "finite", "ratio": 0.5}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		replace func(float64) interface{}
+		want    []map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:    "disabled",
+			wantErr: true,
+		},
+		{
+			name:    "null",
+			replace: NullNonFinite,
+			want: []map[string]interface{}{
+				{"id": "map", "metrics": map[string]interface{}{
+					"ratio":   nil,
+					"ceiling": nil,
+					"samples": []interface{}{1.5, nil},
+					"count":   float64(3),
+				}},
+				{"id": "unstructured", "ratio": nil},
+				{"id": "finite", "ratio": 0.5},
+			},
+		},
+		{
+			name: "sentinel",
+			replace: func(f float64) interface{} {
+				return strconv.FormatFloat(f, 'g', -1, 64)
+			},
+			want: []map[string]interface{}{
+				{"id": "map", "metrics": map[string]interface{}{
+					"ratio":   "NaN",
+					"ceiling": "+Inf",
+					"samples": []interface{}{1.5, "-Inf"},
+					"count":   float64(3),
+				}},
+				{"id": "unstructured", "ratio": "NaN"},
+				{"id": "finite", "ratio": 0.5},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &EncodingResponseWriter{
+				ContentType:      "application/json",
+				Encoder:          types.JSONEncoder,
+				ReplaceNonFinite: tt.replace,
+			}
+			rw := httptest.NewRecorder()
+			err := writer.BodyList(newListRequest(t, context.Background(), rw), rw.Body, list)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var collection struct {
+				Data []map[string]interface{} `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &collection))
+			for _, obj := range collection.Data {
+				delete(obj, "links")
+				delete(obj, "type")
+			}
+			assert.Equal(t, tt.want, collection.Data)
+			assert.True(t, math.IsNaN(metrics["ratio"].(float64)), "the store's object must not be modified")
+		})
+	}
+}
+
+func TestReplaceNonFiniteUnchanged(t *testing.T) {
+	obj := map[string]interface{}{"ratio": 0.5, "nested": []interface{}{map[string]interface{}{"n": 1.0}}}
+	replaced := replaceNonFinite(obj, NullNonFinite)
+	assert.Equal(t, reflect.ValueOf(obj).Pointer(), reflect.ValueOf(replaced).Pointer(), "objects without non-finite floats are not copied")
+}
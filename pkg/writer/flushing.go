@@ -0,0 +1,142 @@
+package writer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFlushBytes is the number of pending bytes sent at once by a FlushingResponseWriter created without a
+	// threshold.
+	DefaultFlushBytes = 32 << 10
+	// DefaultFlushInterval is the longest time a FlushingResponseWriter created without an interval holds writes
+	// back.
+	DefaultFlushInterval = 100 * time.Millisecond
+)
+
+// FlushingResponseWriter batches the writes of a response, for responses streamed as many small items such as
+// lists in the jsonl format. Writes are held back until threshold bytes are pending or interval has passed since
+// the oldest pending write, then sent and flushed together, so the client waits at most interval for any item
+// while the server makes fewer and larger network writes.
+type FlushingResponseWriter struct {
+	rw        http.ResponseWriter
+	threshold int
+	interval  time.Duration
+
+	lock   sync.Mutex
+	buf    bytes.Buffer
+	timer  *time.Timer
+	err    error
+	closed bool
+}
+
+// NewFlushingResponseWriter returns a FlushingResponseWriter sending the writes to rw once threshold bytes are
+// pending or interval has passed. DefaultFlushBytes and DefaultFlushInterval are used if they are not positive.
+func NewFlushingResponseWriter(rw http.ResponseWriter, threshold int, interval time.Duration) *FlushingResponseWriter {
+	if threshold <= 0 {
+		threshold = DefaultFlushBytes
+	}
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	return &FlushingResponseWriter{
+		rw:        rw,
+		threshold: threshold,
+		interval:  interval,
+	}
+}
+
+func (f *FlushingResponseWriter) Header() http.Header {
+	return f.rw.Header()
+}
+
+func (f *FlushingResponseWriter) WriteHeader(statusCode int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.send()
+	f.rw.WriteHeader(statusCode)
+}
+
+func (f *FlushingResponseWriter) Write(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.err != nil {
+		return 0, f.err
+	}
+	if f.closed {
+		return f.rw.Write(p)
+	}
+
+	f.buf.Write(p)
+	if f.buf.Len() >= f.threshold {
+		f.flush()
+	} else if f.timer == nil {
+		f.timer = time.AfterFunc(f.interval, f.flushPending)
+	}
+	return len(p), nil
+}
+
+// flushPending flushes the writes still pending once the interval has passed.
+func (f *FlushingResponseWriter) flushPending() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if !f.closed {
+		f.flush()
+	}
+}
+
+// send writes what is pending to the wrapped ResponseWriter, without flushing it.
+func (f *FlushingResponseWriter) send() {
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+	if f.buf.Len() == 0 || f.err != nil {
+		return
+	}
+	_, f.err = f.rw.Write(f.buf.Bytes())
+	f.buf.Reset()
+}
+
+func (f *FlushingResponseWriter) flush() {
+	f.send()
+	if flusher, ok := f.rw.(http.Flusher); ok && f.err == nil {
+		flusher.Flush()
+	}
+}
+
+// Flush sends what is pending right away, for responses such as event streams that decide themselves when to flush.
+func (f *FlushingResponseWriter) Flush() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.flush()
+}
+
+// Close sends what is pending. Later writes go straight to the wrapped ResponseWriter. It returns the error of the
+// first failed write, if any.
+func (f *FlushingResponseWriter) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.send()
+	f.closed = true
+	return f.err
+}
+
+// Unwrap returns the wrapped ResponseWriter, for http.ResponseController.
+func (f *FlushingResponseWriter) Unwrap() http.ResponseWriter {
+	return f.rw
+}
+
+func (f *FlushingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := f.rw.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("Upstream ResponseWriter of type %v does not implement http.Hijacker", reflect.TypeOf(f.rw))
+	}
+	return hijacker.Hijack()
+}
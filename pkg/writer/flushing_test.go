@@ -0,0 +1,142 @@
+package writer
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRecorder records a response and counts the writes and flushes it receives, from any goroutine.
+type countingRecorder struct {
+	lock    sync.Mutex
+	rec     *httptest.ResponseRecorder
+	writes  int
+	flushes int
+}
+
+func newCountingRecorder() *countingRecorder {
+	return &countingRecorder{rec: httptest.NewRecorder()}
+}
+
+func (c *countingRecorder) Header() http.Header {
+	return c.rec.Header()
+}
+
+func (c *countingRecorder) WriteHeader(statusCode int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.rec.WriteHeader(statusCode)
+}
+
+func (c *countingRecorder) Write(p []byte) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.writes++
+	return c.rec.Write(p)
+}
+
+func (c *countingRecorder) Flush() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.flushes++
+	c.rec.Flush()
+}
+
+func (c *countingRecorder) counts() (writes, flushes int, body string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.writes, c.flushes, c.rec.Body.String()
+}
+
+// writeItems writes 1000 lines of 16 bytes, like a writer streaming small items would.
+func writeItems(t *testing.T, rw http.ResponseWriter) string {
+	var want bytes.Buffer
+	line := []byte(strings.Repeat("x", 15) + "\n")
+	for i := 0; i < 1000; i++ {
+		_, err := rw.Write(line)
+		require.NoError(t, err)
+		want.Write(line)
+	}
+	return want.String()
+}
+
+func TestFlushingResponseWriterThreshold(t *testing.T) {
+	rec := newCountingRecorder()
+	fw := NewFlushingResponseWriter(rec, 4096, time.Hour)
+
+	fw.WriteHeader(http.StatusOK)
+	want := writeItems(t, fw)
+	require.NoError(t, fw.Close())
+
+	writes, flushes, body := rec.counts()
+	assert.Equal(t, want, body)
+	// 16000 bytes are sent in 3 full batches of 4096 and the rest on close
+	assert.Equal(t, 4, writes)
+	assert.Equal(t, 3, flushes)
+	assert.Equal(t, http.StatusOK, rec.rec.Code)
+}
+
+func TestFlushingResponseWriterInterval(t *testing.T) {
+	rec := newCountingRecorder()
+	fw := NewFlushingResponseWriter(rec, 1<<20, 20*time.Millisecond)
+	defer fw.Close()
+
+	_, err := fw.Write([]byte("first\n"))
+	require.NoError(t, err)
+	_, err = fw.Write([]byte("second\n"))
+	require.NoError(t, err)
+
+	_, _, body := rec.counts()
+	assert.Empty(t, body, "writes are held back until the interval has passed")
+
+	assert.Eventually(t, func() bool {
+		writes, flushes, body := rec.counts()
+		return writes == 1 && flushes == 1 && body == "first\nsecond\n"
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestFlushingResponseWriterFlush(t *testing.T) {
+	rec := newCountingRecorder()
+	fw := NewFlushingResponseWriter(rec, 1<<20, time.Hour)
+
+	_, err := fw.Write([]byte("event\n"))
+	require.NoError(t, err)
+	fw.Flush()
+
+	writes, flushes, body := rec.counts()
+	assert.Equal(t, 1, writes)
+	assert.Equal(t, 1, flushes)
+	assert.Equal(t, "event\n", body)
+
+	require.NoError(t, fw.Close())
+	_, err = fw.Write([]byte("after close\n"))
+	require.NoError(t, err)
+	_, _, body = rec.counts()
+	assert.Equal(t, "event\nafter close\n", body)
+}
+
+func BenchmarkFlushingResponseWriter(b *testing.B) {
+	line := []byte(strings.Repeat("x", 63) + "\n")
+	b.Run("unbatched", func(b *testing.B) {
+		rec := newCountingRecorder()
+		for i := 0; i < b.N; i++ {
+			_, _ = rec.Write(line)
+			rec.Flush()
+		}
+	})
+	b.Run("batched", func(b *testing.B) {
+		rec := newCountingRecorder()
+		fw := NewFlushingResponseWriter(rec, DefaultFlushBytes, DefaultFlushInterval)
+		for i := 0; i < b.N; i++ {
+			_, _ = fw.Write(line)
+		}
+		_ = fw.Close()
+	})
+}
@@ -6,11 +6,13 @@ import (
 
 func AddCommonResponseHeader(apiOp *types.APIRequest) error {
 	addExpires(apiOp)
+	// encoded responses are generated for each request, so a range of one can not be served
+	apiOp.Response.Header().Set("Accept-Ranges", "none")
 	return addSchemasHeader(apiOp)
 }
 
 func addSchemasHeader(apiOp *types.APIRequest) error {
-	schema := apiOp.Schemas.Schemas["schema"]
+	schema := apiOp.Schemas.LookupSchema("schema")
 	if schema == nil {
 		return nil
 	}
@@ -3,6 +3,7 @@ package writer
 import (
 	"encoding/json"
 	"fmt"
+	"html/template"
 	"strings"
 
 	"github.com/rancher/apiserver/pkg/types"
@@ -29,6 +30,22 @@ var data =
 `
 	end = []byte(`</script>
 `)
+
+	errorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8" />
+<title>{{.status}} {{.code}}</title>
+</head>
+<body>
+<h1>{{.status}} {{.code}}</h1>
+<p>{{.message}}</p>
+{{- if .fieldName}}
+<p>Field: {{.fieldName}}</p>
+{{- end}}
+</body>
+</html>
+`))
 )
 
 type StringGetter func() string
@@ -38,6 +55,8 @@ type HTMLResponseWriter struct {
 	CSSURL       StringGetter
 	JSURL        StringGetter
 	APIUIVersion StringGetter
+	// ErrorPages renders errors as a plain HTML page instead of loading them in the API UI.
+	ErrorPages bool
 }
 
 func (h *HTMLResponseWriter) start(apiOp *types.APIRequest, code int) {
@@ -47,16 +66,30 @@ func (h *HTMLResponseWriter) start(apiOp *types.APIRequest, code int) {
 }
 
 func (h *HTMLResponseWriter) Write(apiOp *types.APIRequest, code int, obj types.APIObject) {
+	if h.ErrorPages && obj.Type == "error" {
+		h.writeError(apiOp, code, obj)
+		return
+	}
+	addDeprecationWarnings(apiOp, obj)
 	h.write(apiOp, code, obj)
 }
 
+func (h *HTMLResponseWriter) writeError(apiOp *types.APIRequest, code int, obj types.APIObject) {
+	AddCommonResponseHeader(apiOp)
+	apiOp.Response.Header().Set("content-type", "text/html; charset=utf-8")
+	apiOp.Response.WriteHeader(code)
+	w := &errorTrackingWriter{Writer: apiOp.Response}
+	logWriteError(apiOp, w, errorPage.Execute(w, obj.Data()))
+}
+
 func (h *HTMLResponseWriter) WriteList(apiOp *types.APIRequest, code int, list types.APIObjectList) {
+	addDeprecationWarnings(apiOp, list.Objects...)
 	h.write(apiOp, code, list)
 }
 
 func (h *HTMLResponseWriter) write(apiOp *types.APIRequest, code int, obj interface{}) {
 	h.start(apiOp, code)
-	schemaSchema := apiOp.Schemas.Schemas["schema"]
+	schemaSchema := apiOp.Schemas.LookupSchema("schema")
 	headerString := start
 	if schemaSchema != nil {
 		headerString = strings.Replace(headerString, "%SCHEMAS%", jsonEncodeURL(apiOp.URLBuilder.Collection(schemaSchema)), 1)
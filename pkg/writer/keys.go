@@ -0,0 +1,55 @@
+package writer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SnakeCase converts a camelCase field name to snake_case, keeping runs of capitals such as acronyms together:
+// "apiVersion" becomes "api_version" and "serverURLPrefix" becomes "server_url_prefix".
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	b.Grow(len(name) + 4)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (isLowerOrDigit(runes[i-1]) || i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isLowerOrDigit(r rune) bool {
+	return unicode.IsLower(r) || unicode.IsDigit(r)
+}
+
+// transformKeys returns a copy of obj with its keys renamed by transform. Keys of nested objects, including
+// objects in arrays, are only renamed if nested is set.
+func transformKeys(obj map[string]interface{}, transform func(string) string, nested bool) map[string]interface{} {
+	result := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		if nested {
+			v = transformValue(v, transform)
+		}
+		result[transform(k)] = v
+	}
+	return result
+}
+
+func transformValue(v interface{}, transform func(string) string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return transformKeys(v, transform, true)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i := range v {
+			result[i] = transformValue(v[i], transform)
+		}
+		return result
+	}
+	return v
+}
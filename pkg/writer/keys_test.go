@@ -0,0 +1,119 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"":                  "",
+		"name":              "name",
+		"apiVersion":        "api_version",
+		"creationTimestamp": "creation_timestamp",
+		"serverURLPrefix":   "server_url_prefix",
+		"HTTPProxy":         "http_proxy",
+		"podCIDR":           "pod_cidr",
+		"ipv4Address":       "ipv4_address",
+		"already_snake":     "already_snake",
+	}
+	for input, want := range tests {
+		assert.Equal(t, want, SnakeCase(input), input)
+	}
+}
+
+func TestKeyTransform(t *testing.T) {
+	object := map[string]interface{}{
+		"id":          "foo",
+		"displayName": "Foo",
+		"nodeSpec": map[string]interface{}{
+			"podCIDR": "10.0.0.0/24",
+			"taintList": []interface{}{
+				map[string]interface{}{"effectName": "NoSchedule"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		nested bool
+		want   map[string]interface{}
+	}{
+		{
+			name: "top-level only",
+			want: map[string]interface{}{
+				"id":           "foo",
+				"type":         "foo",
+				"display_name": "Foo",
+				"node_spec": map[string]interface{}{
+					"podCIDR": "10.0.0.0/24",
+					"taintList": []interface{}{
+						map[string]interface{}{"effectName": "NoSchedule"},
+					},
+				},
+			},
+		},
+		{
+			name:   "nested",
+			nested: true,
+			want: map[string]interface{}{
+				"id":           "foo",
+				"type":         "foo",
+				"display_name": "Foo",
+				"node_spec": map[string]interface{}{
+					"pod_cidr": "10.0.0.0/24",
+					"taint_list": []interface{}{
+						map[string]interface{}{"effect_name": "NoSchedule"},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &EncodingResponseWriter{
+				ContentType:         "application/json",
+				Encoder:             types.JSONEncoder,
+				KeyTransform:        SnakeCase,
+				TransformNestedKeys: tt.nested,
+			}
+			apiOp := newListRequest(t, context.Background(), httptest.NewRecorder())
+
+			var buf bytes.Buffer
+			require.NoError(t, writer.Body(apiOp, &buf, types.APIObject{Type: "foo", ID: "foo", Object: object}))
+
+			var got map[string]interface{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+			delete(got, "links")
+			assert.Equal(t, tt.want, got)
+			// the object returned by the store must not be modified
+			assert.Contains(t, object, "displayName")
+		})
+	}
+}
+
+func TestKeyTransformKeepsWarnings(t *testing.T) {
+	writer := &EncodingResponseWriter{
+		ContentType:  "application/json",
+		Encoder:      types.JSONEncoder,
+		KeyTransform: SnakeCase,
+	}
+	apiOp := newListRequest(t, context.Background(), httptest.NewRecorder())
+	warnings := []types.Warning{{Code: 299, Agent: "-", Text: "displayName is deprecated"}}
+
+	resource := writer.convert(apiOp, types.APIObject{
+		Type:     "foo",
+		ID:       "foo",
+		Object:   map[string]interface{}{"displayName": "Foo"},
+		Warnings: warnings,
+	})
+	assert.Equal(t, "Foo", resource.APIObject.Data().String("display_name"))
+	assert.Equal(t, warnings, resource.APIObject.Warnings)
+}
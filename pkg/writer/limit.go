@@ -0,0 +1,145 @@
+package writer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+)
+
+// ErrResponseTooLarge is returned by LimitedResponseWriter once a response exceeds its limit.
+var ErrResponseTooLarge = errors.New("response exceeds the maximum size")
+
+// LimitedResponseWriter caps the number of bytes written to the response body. The status, headers and body are
+// held back until the response is complete, flushed or larger than the limit, so an oversized response can be
+// replaced by an error as long as nothing was sent. Once part of the response has been sent, writes past the limit
+// fail with ErrResponseTooLarge and the response is truncated.
+type LimitedResponseWriter struct {
+	rw        http.ResponseWriter
+	limit     int64
+	header    http.Header
+	status    int
+	buf       bytes.Buffer
+	written   int64
+	committed bool
+	exceeded  bool
+}
+
+// NewLimitedResponseWriter returns a LimitedResponseWriter that allows limit bytes to be written to rw.
+func NewLimitedResponseWriter(rw http.ResponseWriter, limit int64) *LimitedResponseWriter {
+	return &LimitedResponseWriter{
+		rw:     rw,
+		limit:  limit,
+		header: rw.Header().Clone(),
+	}
+}
+
+func (l *LimitedResponseWriter) Header() http.Header {
+	if l.committed {
+		return l.rw.Header()
+	}
+	return l.header
+}
+
+func (l *LimitedResponseWriter) WriteHeader(statusCode int) {
+	if l.committed {
+		l.rw.WriteHeader(statusCode)
+		return
+	}
+	if l.status == 0 {
+		l.status = statusCode
+	}
+}
+
+func (l *LimitedResponseWriter) Write(b []byte) (int, error) {
+	if l.exceeded {
+		return 0, ErrResponseTooLarge
+	}
+
+	if l.written+int64(len(b)) > l.limit {
+		l.exceeded = true
+		if !l.committed {
+			l.buf.Reset()
+			return 0, ErrResponseTooLarge
+		}
+		n, _ := l.rw.Write(b[:l.limit-l.written])
+		l.written += int64(n)
+		return n, ErrResponseTooLarge
+	}
+
+	l.written += int64(len(b))
+	if l.committed {
+		return l.rw.Write(b)
+	}
+	return l.buf.Write(b)
+}
+
+// Unwrap returns the wrapped ResponseWriter, for http.ResponseController.
+func (l *LimitedResponseWriter) Unwrap() http.ResponseWriter {
+	return l.rw
+}
+
+// Exceeded returns whether more than the limit was written to the response.
+func (l *LimitedResponseWriter) Exceeded() bool {
+	return l.exceeded
+}
+
+// Committed returns whether any of the response has been sent to the underlying ResponseWriter.
+func (l *LimitedResponseWriter) Committed() bool {
+	return l.committed
+}
+
+// Close sends the buffered response, unless the limit was exceeded before anything was sent, in which case
+// the response is dropped and the caller is responsible for writing an error to the underlying ResponseWriter.
+func (l *LimitedResponseWriter) Close() error {
+	if l.exceeded && !l.committed {
+		return ErrResponseTooLarge
+	}
+	return l.commit()
+}
+
+func (l *LimitedResponseWriter) commit() error {
+	if l.committed {
+		return nil
+	}
+	l.committed = true
+
+	header := l.rw.Header()
+	for k := range header {
+		delete(header, k)
+	}
+	for k, v := range l.header {
+		header[k] = v
+	}
+	if l.status != 0 {
+		l.rw.WriteHeader(l.status)
+	}
+	if l.buf.Len() == 0 {
+		return nil
+	}
+	_, err := l.rw.Write(l.buf.Bytes())
+	l.buf.Reset()
+	return err
+}
+
+func (l *LimitedResponseWriter) Flush() {
+	if l.exceeded && !l.committed {
+		return
+	}
+	_ = l.commit()
+	if flusher, ok := l.rw.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (l *LimitedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := l.rw.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("Upstream ResponseWriter of type %v does not implement http.Hijacker", reflect.TypeOf(l.rw))
+	}
+	l.committed = true
+	return hijacker.Hijack()
+}
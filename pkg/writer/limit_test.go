@@ -0,0 +1,66 @@
+package writer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitedResponseWriterWithinLimit(t *testing.T) {
+	rec := httptest.NewRecorder()
+	lw := NewLimitedResponseWriter(rec, 10)
+
+	lw.Header().Set("Content-Type", "text/plain")
+	lw.WriteHeader(http.StatusCreated)
+	_, err := lw.Write([]byte("hello"))
+	require.NoError(t, err)
+	_, err = lw.Write([]byte("world"))
+	require.NoError(t, err)
+
+	// nothing is sent until the response is closed
+	assert.False(t, rec.Flushed)
+	assert.Empty(t, rec.Body.String())
+
+	require.NoError(t, lw.Close())
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "helloworld", rec.Body.String())
+}
+
+func TestLimitedResponseWriterExceeded(t *testing.T) {
+	rec := httptest.NewRecorder()
+	lw := NewLimitedResponseWriter(rec, 10)
+
+	lw.Header().Set("Content-Encoding", "gzip")
+	lw.WriteHeader(http.StatusOK)
+	_, err := lw.Write([]byte("hello"))
+	require.NoError(t, err)
+	_, err = lw.Write([]byte("world!"))
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+
+	assert.True(t, lw.Exceeded())
+	assert.False(t, lw.Committed())
+	assert.ErrorIs(t, lw.Close(), ErrResponseTooLarge)
+	assert.Empty(t, rec.Body.String())
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+}
+
+func TestLimitedResponseWriterExceededAfterFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	lw := NewLimitedResponseWriter(rec, 10)
+
+	_, err := lw.Write([]byte("hello"))
+	require.NoError(t, err)
+	lw.Flush()
+	assert.True(t, lw.Committed())
+	assert.Equal(t, "hello", rec.Body.String())
+
+	n, err := lw.Write([]byte("world!"))
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+	assert.Equal(t, 5, n)
+	require.NoError(t, lw.Close())
+	assert.Equal(t, "helloworld", rec.Body.String())
+}
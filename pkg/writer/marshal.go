@@ -0,0 +1,88 @@
+package writer
+
+import (
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Marshalers maps Go types to the function encoding their values as JSON, replacing their standard encoding. Values
+// of other types are encoded as usual.
+type Marshalers map[reflect.Type]func(interface{}) ([]byte, error)
+
+// Register sets marshal as the function encoding values of the type of sample, which marshal can assert to.
+func (m Marshalers) Register(sample interface{}, marshal func(interface{}) ([]byte, error)) {
+	m[reflect.TypeOf(sample)] = marshal
+}
+
+// customValue is encoded by its marshaler, whose errors fail the encoding of the response.
+type customValue struct {
+	value   interface{}
+	marshal func(interface{}) ([]byte, error)
+}
+
+func (c customValue) MarshalJSON() ([]byte, error) {
+	return c.marshal(c.value)
+}
+
+// applyMarshalers returns obj with the values of map based objects that have a marshaler wrapped to be encoded by
+// it. Objects are only copied if they contain such a value.
+func applyMarshalers(obj interface{}, marshalers Marshalers) interface{} {
+	switch obj := obj.(type) {
+	case map[string]interface{}:
+		if v, changed := applyMarshalersValue(obj, marshalers); changed {
+			return v
+		}
+	case *unstructured.Unstructured:
+		if v, changed := applyMarshalersValue(obj.Object, marshalers); changed {
+			return &unstructured.Unstructured{Object: v.(map[string]interface{})}
+		}
+	}
+	return obj
+}
+
+func applyMarshalersValue(v interface{}, marshalers Marshalers) (interface{}, bool) {
+	if v == nil {
+		return v, false
+	}
+	if marshal, ok := marshalers[reflect.TypeOf(v)]; ok {
+		return customValue{value: v, marshal: marshal}, true
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		var result map[string]interface{}
+		for k, item := range v {
+			replaced, changed := applyMarshalersValue(item, marshalers)
+			if !changed {
+				continue
+			}
+			if result == nil {
+				result = make(map[string]interface{}, len(v))
+				for k, item := range v {
+					result[k] = item
+				}
+			}
+			result[k] = replaced
+		}
+		if result != nil {
+			return result, true
+		}
+	case []interface{}:
+		var result []interface{}
+		for i, item := range v {
+			replaced, changed := applyMarshalersValue(item, marshalers)
+			if !changed {
+				continue
+			}
+			if result == nil {
+				result = append([]interface{}(nil), v...)
+			}
+			result[i] = replaced
+		}
+		if result != nil {
+			return result, true
+		}
+	}
+	return v, false
+}
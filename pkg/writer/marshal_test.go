@@ -0,0 +1,136 @@
+package writer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type quantity struct {
+	value int64
+	unit  string
+}
+
+func TestMarshalers(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	list := types.APIObjectList{
+		Objects: []types.APIObject{
+			{Type: "foo", ID: "map", Object: map[string]interface{}{
+				"id":      "map",
+				"created": created,
+				"limits":  []interface{}{quantity{value: 2, unit: "Gi"}, "none"},
+			}},
+			{Type: "foo", ID: "unstructured", Object: &unstructured.Unstructured{Object: map[string]interface{}{
+				"id":     "unstructured",
+				"memory": quantity{value: 512, unit: "Mi"},
+			}}},
+		},
+	}
+
+	marshalers := Marshalers{}
+	marshalers.Register(time.Time{}, func(v interface{}) ([]byte, error) {
+		return json.Marshal(v.(time.Time).Unix())
+	})
+	marshalers.Register(quantity{}, func(v interface{}) ([]byte, error) {
+		q := v.(quantity)
+		return json.Marshal(fmt.Sprintf("%d%s", q.value, q.unit))
+	})
+
+	tests := []struct {
+		name       string
+		marshalers Marshalers
+		want       []map[string]interface{}
+	}{
+		{
+			name: "standard",
+			want: []map[string]interface{}{
+				{"id": "map", "created": "2024-03-01T12:30:00Z", "limits": []interface{}{map[string]interface{}{}, "none"}},
+				{"id": "unstructured", "memory": map[string]interface{}{}},
+			},
+		},
+		{
+			name:       "registered",
+			marshalers: marshalers,
+			want: []map[string]interface{}{
+				{"id": "map", "created": float64(created.Unix()), "limits": []interface{}{"2Gi", "none"}},
+				{"id": "unstructured", "memory": "512Mi"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &EncodingResponseWriter{
+				ContentType: "application/json",
+				Encoder:     types.JSONEncoder,
+				Marshalers:  tt.marshalers,
+			}
+			rw := httptest.NewRecorder()
+			require.NoError(t, writer.BodyList(newListRequest(t, context.Background(), rw), rw.Body, list))
+
+			var collection struct {
+				Data []map[string]interface{} `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &collection))
+			for _, obj := range collection.Data {
+				delete(obj, "links")
+				delete(obj, "type")
+			}
+			assert.Equal(t, tt.want, collection.Data)
+			assert.Equal(t, created, list.Objects[0].Data()["created"], "the store's object must not be modified")
+		})
+	}
+}
+
+func TestMarshalersYAML(t *testing.T) {
+	marshalers := Marshalers{}
+	marshalers.Register(quantity{}, func(v interface{}) ([]byte, error) {
+		q := v.(quantity)
+		return json.Marshal(fmt.Sprintf("%d%s", q.value, q.unit))
+	})
+	writer := &EncodingResponseWriter{
+		ContentType: "application/yaml",
+		Encoder:     types.YAMLEncoder,
+		Marshalers:  marshalers,
+	}
+	rw := httptest.NewRecorder()
+	apiOp := newListRequest(t, context.Background(), rw)
+	obj := types.APIObject{Type: "foo", ID: "a", Object: map[string]interface{}{"id": "a", "memory": quantity{value: 1, unit: "Gi"}}}
+	require.NoError(t, writer.Body(apiOp, rw.Body, obj))
+	assert.Contains(t, rw.Body.String(), "memory: 1Gi\n")
+}
+
+func TestMarshalersError(t *testing.T) {
+	marshalers := Marshalers{}
+	marshalers.Register(quantity{}, func(interface{}) ([]byte, error) {
+		return nil, errors.New("unknown unit")
+	})
+	writer := &EncodingResponseWriter{
+		ContentType: "application/json",
+		Encoder:     types.JSONEncoder,
+		Marshalers:  marshalers,
+	}
+	rw := httptest.NewRecorder()
+	apiOp := newListRequest(t, context.Background(), rw)
+	obj := types.APIObject{Type: "foo", ID: "a", Object: map[string]interface{}{"id": "a", "memory": quantity{unit: "?"}}}
+	assert.ErrorContains(t, writer.Body(apiOp, rw.Body, obj), "unknown unit")
+}
+
+func TestMarshalersUnchanged(t *testing.T) {
+	marshalers := Marshalers{}
+	marshalers.Register(quantity{}, func(interface{}) ([]byte, error) {
+		return []byte(`"q"`), nil
+	})
+	obj := map[string]interface{}{"name": "a", "nested": []interface{}{map[string]interface{}{"n": 1.0}}}
+	applied := applyMarshalers(obj, marshalers)
+	assert.Equal(t, reflect.ValueOf(obj).Pointer(), reflect.ValueOf(applied).Pointer(), "objects without registered types are not copied")
+}
@@ -0,0 +1,58 @@
+package writer
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// masked returns whether the OutputMask of schema applies to the response to apiOp.
+func masked(apiOp *types.APIRequest, schema *types.APISchema) bool {
+	if len(schema.OutputMask.Fields) == 0 {
+		return false
+	}
+	if schema.OutputMask.Reads {
+		return true
+	}
+	if apiOp.Action != "" {
+		return false
+	}
+	switch apiOp.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+// maskFields returns a copy of obj without the fields at paths. Only the maps along the paths are copied.
+func maskFields(obj map[string]interface{}, paths [][]string) map[string]interface{} {
+	for _, path := range paths {
+		obj = removePath(obj, path)
+	}
+	return obj
+}
+
+func removePath(obj map[string]interface{}, path []string) map[string]interface{} {
+	if len(path) == 0 {
+		return obj
+	}
+	value, ok := obj[path[0]]
+	if !ok {
+		return obj
+	}
+
+	result := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		result[k] = v
+	}
+	if len(path) == 1 {
+		delete(result, path[0])
+		return result
+	}
+	nested, ok := value.(map[string]interface{})
+	if !ok {
+		return obj
+	}
+	result[path[0]] = removePath(nested, path[1:])
+	return result
+}
@@ -0,0 +1,99 @@
+package writer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputMask(t *testing.T) {
+	newObject := func() map[string]interface{} {
+		return map[string]interface{}{
+			"id":   "foo",
+			"name": "foo",
+			"status": map[string]interface{}{
+				"token": "s3cr3t",
+				"ready": true,
+			},
+			"internal": "computed",
+		}
+	}
+	masked := map[string]interface{}{
+		"id":   "foo",
+		"name": "foo",
+		"status": map[string]interface{}{
+			"ready": true,
+		},
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		action string
+		mask   types.FieldMask
+		want   map[string]interface{}
+	}{
+		{
+			name:   "create",
+			method: http.MethodPost,
+			mask:   types.FieldMask{Fields: [][]string{{"status", "token"}, {"internal"}}},
+			want:   masked,
+		},
+		{
+			name:   "update",
+			method: http.MethodPut,
+			mask:   types.FieldMask{Fields: [][]string{{"status", "token"}, {"internal"}}},
+			want:   masked,
+		},
+		{
+			name:   "read not masked by default",
+			method: http.MethodGet,
+			mask:   types.FieldMask{Fields: [][]string{{"status", "token"}, {"internal"}}},
+			want:   newObject(),
+		},
+		{
+			name:   "read masked",
+			method: http.MethodGet,
+			mask:   types.FieldMask{Fields: [][]string{{"status", "token"}, {"internal"}}, Reads: true},
+			want:   masked,
+		},
+		{
+			name:   "action",
+			method: http.MethodPost,
+			action: "refresh",
+			mask:   types.FieldMask{Fields: [][]string{{"status", "token"}, {"internal"}}},
+			want:   newObject(),
+		},
+		{
+			name:   "missing and non-object paths",
+			method: http.MethodPost,
+			mask:   types.FieldMask{Fields: [][]string{{"spec", "token"}, {"name", "first"}}},
+			want:   newObject(),
+		},
+		{
+			name:   "no mask",
+			method: http.MethodPost,
+			want:   newObject(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiOp := newListRequest(t, context.Background(), httptest.NewRecorder())
+			apiOp.Method = tt.method
+			apiOp.Action = tt.action
+			apiOp.Schema.OutputMask = tt.mask
+
+			obj := newObject()
+			writer := &EncodingResponseWriter{ContentType: "application/json", Encoder: types.JSONEncoder}
+			resource := writer.convert(apiOp, types.APIObject{Type: "foo", ID: "foo", Object: obj})
+			require.NotNil(t, resource)
+			assert.Equal(t, tt.want, map[string]interface{}(resource.APIObject.Data()))
+			assert.Equal(t, newObject(), obj, "the store's object must not be modified")
+		})
+	}
+}
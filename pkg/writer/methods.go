@@ -0,0 +1,45 @@
+package writer
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+)
+
+// collectionMethods returns the collection methods of schema that the caller is allowed to use.
+func collectionMethods(apiOp *types.APIRequest, schema *types.APISchema) []string {
+	var result []string
+	for _, method := range schema.CollectionMethods {
+		var err error
+		switch method {
+		case http.MethodGet:
+			err = apiOp.AccessControl.CanList(apiOp, schema)
+		case http.MethodPost:
+			err = apiOp.AccessControl.CanCreate(apiOp, schema)
+		}
+		if err == nil {
+			result = append(result, method)
+		}
+	}
+	return result
+}
+
+// resourceMethods returns the resource methods of schema that the caller is allowed to use on obj.
+func resourceMethods(apiOp *types.APIRequest, schema *types.APISchema, obj types.APIObject) []string {
+	var result []string
+	for _, method := range schema.ResourceMethods {
+		var err error
+		switch method {
+		case http.MethodGet:
+			err = apiOp.AccessControl.CanGet(apiOp, schema)
+		case http.MethodPut, http.MethodPatch:
+			err = apiOp.AccessControl.CanUpdate(apiOp, obj, schema)
+		case http.MethodDelete:
+			err = apiOp.AccessControl.CanDelete(apiOp, obj, schema)
+		}
+		if err == nil {
+			result = append(result, method)
+		}
+	}
+	return result
+}